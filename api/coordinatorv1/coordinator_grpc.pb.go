@@ -0,0 +1,346 @@
+// gRPC contract of the coordinator's admin/control API, mirroring the REST
+// admin API (internal/admin) for Go services that want typed clients.
+//
+// Generate the Go code with `make proto` (needs protoc, protoc-gen-go and
+// protoc-gen-go-grpc).
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: coordinator/v1/coordinator.proto
+
+package coordinatorv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Coordinator_Status_FullMethodName        = "/coordinator.v1.Coordinator/Status"
+	Coordinator_ListTargets_FullMethodName   = "/coordinator.v1.Coordinator/ListTargets"
+	Coordinator_RestartTarget_FullMethodName = "/coordinator.v1.Coordinator/RestartTarget"
+	Coordinator_Quarantine_FullMethodName    = "/coordinator.v1.Coordinator/Quarantine"
+	Coordinator_StepDown_FullMethodName      = "/coordinator.v1.Coordinator/StepDown"
+	Coordinator_WatchEvents_FullMethodName   = "/coordinator.v1.Coordinator/WatchEvents"
+)
+
+// CoordinatorClient is the client API for Coordinator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CoordinatorClient interface {
+	// Status describes this coordinator (GET /status)
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// ListTargets lists the monitored targets (GET /targets)
+	ListTargets(ctx context.Context, in *ListTargetsRequest, opts ...grpc.CallOption) (*ListTargetsResponse, error)
+	// RestartTarget restarts a target; only the leader accepts it
+	// (POST /targets/{name}/restart)
+	RestartTarget(ctx context.Context, in *RestartTargetRequest, opts ...grpc.CallOption) (*RestartTargetResponse, error)
+	// Quarantine stops or resumes automatic recovery of a target
+	// (POST /targets/{name}/quarantine and /unquarantine)
+	Quarantine(ctx context.Context, in *QuarantineRequest, opts ...grpc.CallOption) (*QuarantineResponse, error)
+	// StepDown makes the leader hand over leadership (POST /leader/step-down)
+	StepDown(ctx context.Context, in *StepDownRequest, opts ...grpc.CallOption) (*StepDownResponse, error)
+	// WatchEvents streams check results and coordinator events as they happen
+	// (see internal/stream)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (Coordinator_WatchEventsClient, error)
+}
+
+type coordinatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCoordinatorClient(cc grpc.ClientConnInterface) CoordinatorClient {
+	return &coordinatorClient{cc}
+}
+
+func (c *coordinatorClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, Coordinator_Status_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorClient) ListTargets(ctx context.Context, in *ListTargetsRequest, opts ...grpc.CallOption) (*ListTargetsResponse, error) {
+	out := new(ListTargetsResponse)
+	err := c.cc.Invoke(ctx, Coordinator_ListTargets_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorClient) RestartTarget(ctx context.Context, in *RestartTargetRequest, opts ...grpc.CallOption) (*RestartTargetResponse, error) {
+	out := new(RestartTargetResponse)
+	err := c.cc.Invoke(ctx, Coordinator_RestartTarget_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorClient) Quarantine(ctx context.Context, in *QuarantineRequest, opts ...grpc.CallOption) (*QuarantineResponse, error) {
+	out := new(QuarantineResponse)
+	err := c.cc.Invoke(ctx, Coordinator_Quarantine_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorClient) StepDown(ctx context.Context, in *StepDownRequest, opts ...grpc.CallOption) (*StepDownResponse, error) {
+	out := new(StepDownResponse)
+	err := c.cc.Invoke(ctx, Coordinator_StepDown_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (Coordinator_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Coordinator_ServiceDesc.Streams[0], Coordinator_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &coordinatorWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Coordinator_WatchEventsClient interface {
+	Recv() (*WatchEventsResponse, error)
+	grpc.ClientStream
+}
+
+type coordinatorWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *coordinatorWatchEventsClient) Recv() (*WatchEventsResponse, error) {
+	m := new(WatchEventsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CoordinatorServer is the server API for Coordinator service.
+// All implementations must embed UnimplementedCoordinatorServer
+// for forward compatibility
+type CoordinatorServer interface {
+	// Status describes this coordinator (GET /status)
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	// ListTargets lists the monitored targets (GET /targets)
+	ListTargets(context.Context, *ListTargetsRequest) (*ListTargetsResponse, error)
+	// RestartTarget restarts a target; only the leader accepts it
+	// (POST /targets/{name}/restart)
+	RestartTarget(context.Context, *RestartTargetRequest) (*RestartTargetResponse, error)
+	// Quarantine stops or resumes automatic recovery of a target
+	// (POST /targets/{name}/quarantine and /unquarantine)
+	Quarantine(context.Context, *QuarantineRequest) (*QuarantineResponse, error)
+	// StepDown makes the leader hand over leadership (POST /leader/step-down)
+	StepDown(context.Context, *StepDownRequest) (*StepDownResponse, error)
+	// WatchEvents streams check results and coordinator events as they happen
+	// (see internal/stream)
+	WatchEvents(*WatchEventsRequest, Coordinator_WatchEventsServer) error
+	mustEmbedUnimplementedCoordinatorServer()
+}
+
+// UnimplementedCoordinatorServer must be embedded to have forward compatible implementations.
+type UnimplementedCoordinatorServer struct {
+}
+
+func (UnimplementedCoordinatorServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedCoordinatorServer) ListTargets(context.Context, *ListTargetsRequest) (*ListTargetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTargets not implemented")
+}
+func (UnimplementedCoordinatorServer) RestartTarget(context.Context, *RestartTargetRequest) (*RestartTargetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestartTarget not implemented")
+}
+func (UnimplementedCoordinatorServer) Quarantine(context.Context, *QuarantineRequest) (*QuarantineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Quarantine not implemented")
+}
+func (UnimplementedCoordinatorServer) StepDown(context.Context, *StepDownRequest) (*StepDownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StepDown not implemented")
+}
+func (UnimplementedCoordinatorServer) WatchEvents(*WatchEventsRequest, Coordinator_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedCoordinatorServer) mustEmbedUnimplementedCoordinatorServer() {}
+
+// UnsafeCoordinatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CoordinatorServer will
+// result in compilation errors.
+type UnsafeCoordinatorServer interface {
+	mustEmbedUnimplementedCoordinatorServer()
+}
+
+func RegisterCoordinatorServer(s grpc.ServiceRegistrar, srv CoordinatorServer) {
+	s.RegisterService(&Coordinator_ServiceDesc, srv)
+}
+
+func _Coordinator_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coordinator_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coordinator_ListTargets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTargetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServer).ListTargets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coordinator_ListTargets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServer).ListTargets(ctx, req.(*ListTargetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coordinator_RestartTarget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartTargetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServer).RestartTarget(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coordinator_RestartTarget_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServer).RestartTarget(ctx, req.(*RestartTargetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coordinator_Quarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServer).Quarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coordinator_Quarantine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServer).Quarantine(ctx, req.(*QuarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coordinator_StepDown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StepDownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServer).StepDown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coordinator_StepDown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServer).StepDown(ctx, req.(*StepDownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coordinator_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CoordinatorServer).WatchEvents(m, &coordinatorWatchEventsServer{stream})
+}
+
+type Coordinator_WatchEventsServer interface {
+	Send(*WatchEventsResponse) error
+	grpc.ServerStream
+}
+
+type coordinatorWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *coordinatorWatchEventsServer) Send(m *WatchEventsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Coordinator_ServiceDesc is the grpc.ServiceDesc for Coordinator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Coordinator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "coordinator.v1.Coordinator",
+	HandlerType: (*CoordinatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Status",
+			Handler:    _Coordinator_Status_Handler,
+		},
+		{
+			MethodName: "ListTargets",
+			Handler:    _Coordinator_ListTargets_Handler,
+		},
+		{
+			MethodName: "RestartTarget",
+			Handler:    _Coordinator_RestartTarget_Handler,
+		},
+		{
+			MethodName: "Quarantine",
+			Handler:    _Coordinator_Quarantine_Handler,
+		},
+		{
+			MethodName: "StepDown",
+			Handler:    _Coordinator_StepDown_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Coordinator_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "coordinator/v1/coordinator.proto",
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// Alert channel types
+const (
+	alertChannelWebhook  = "webhook"
+	alertChannelEmail    = "email"
+	alertChannelTelegram = "telegram"
+)
+
+// AlertsConfig declares where alerts go
+type AlertsConfig struct {
+	Channels []AlertChannelConfig `yaml:"channels"`
+
+	// Escalation re-sends unacknowledged alerts about critical or
+	// quarantined targets to more channels as time passes
+	Escalation []EscalationStepConfig `yaml:"escalation"`
+}
+
+// EscalationStepConfig declares an escalation step: after how long since
+// the alert was raised, and to which channels (by name)
+type EscalationStepConfig struct {
+	After    string   `yaml:"after"`
+	Channels []string `yaml:"channels"`
+}
+
+// AlertChannelConfig declares an alert channel. Secrets (password, token)
+// may reference environment variables as ${NAME}.
+type AlertChannelConfig struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	MinSeverity string   `yaml:"min_severity"`
+	Events      []string `yaml:"events"`
+
+	// webhook
+	URL string `yaml:"url"`
+
+	// email
+	SMTPAddress string   `yaml:"smtp_address"`
+	Username    string   `yaml:"username"`
+	Password    string   `yaml:"password"`
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
+
+	// telegram
+	Token  string `yaml:"token"`
+	ChatID string `yaml:"chat_id"`
+}
+
+// toChannel builds the channel a declaration describes
+func (c AlertChannelConfig) toChannel() (alert.Channel, error) {
+	if c.Name == "" {
+		c.Name = c.Type
+	}
+	severity, err := alert.ParseSeverity(c.MinSeverity)
+	if err != nil {
+		return alert.Channel{}, fmt.Errorf("alert channel %s: %w", c.Name, err)
+	}
+	channel := alert.Channel{Name: c.Name, MinSeverity: severity, Events: c.Events}
+
+	switch c.Type {
+	case alertChannelWebhook:
+		if c.URL == "" {
+			return alert.Channel{}, fmt.Errorf("alert channel %s: url is required", c.Name)
+		}
+		channel.Notifier = alert.Webhook{URL: c.URL}
+	case alertChannelEmail:
+		if c.SMTPAddress == "" || c.From == "" || len(c.To) == 0 {
+			return alert.Channel{}, fmt.Errorf("alert channel %s: smtp_address, from and to are required", c.Name)
+		}
+		channel.Notifier = alert.Email{
+			Address:  c.SMTPAddress,
+			Username: c.Username,
+			Password: os.ExpandEnv(c.Password),
+			From:     c.From,
+			To:       c.To,
+		}
+	case alertChannelTelegram:
+		token := os.ExpandEnv(c.Token)
+		if token == "" || c.ChatID == "" {
+			return alert.Channel{}, fmt.Errorf("alert channel %s: token and chat_id are required", c.Name)
+		}
+		channel.Notifier = alert.Telegram{Token: token, ChatID: c.ChatID}
+	default:
+		return alert.Channel{}, fmt.Errorf("alert channel %s: invalid type %q (expected %s, %s or %s)",
+			c.Name, c.Type, alertChannelWebhook, alertChannelEmail, alertChannelTelegram)
+	}
+	return channel, nil
+}
+
+// alertPolicy builds the configured alert channels, plus a webhook channel
+// for every event when ALERT_WEBHOOK_URL is set, and the escalation chain
+func alertPolicy(config AlertsConfig) ([]alert.Channel, []alert.EscalationStep, error) {
+	channels := make([]alert.Channel, 0, len(config.Channels)+1)
+	names := make(map[string]bool, len(config.Channels))
+	for _, declared := range config.Channels {
+		channel, err := declared.toChannel()
+		if err != nil {
+			return nil, nil, err
+		}
+		if names[channel.Name] {
+			return nil, nil, fmt.Errorf("duplicate alert channel %s", channel.Name)
+		}
+		names[channel.Name] = true
+		channels = append(channels, channel)
+	}
+	if url := getEnv("ALERT_WEBHOOK_URL", ""); url != "" {
+		channels = append(channels, alert.Channel{Name: "ALERT_WEBHOOK_URL", Notifier: alert.Webhook{URL: url}})
+	}
+
+	escalation := make([]alert.EscalationStep, 0, len(config.Escalation))
+	var previous time.Duration
+	for i, declared := range config.Escalation {
+		after, err := time.ParseDuration(declared.After)
+		if err != nil || after <= previous {
+			return nil, nil, fmt.Errorf("escalation step %d: after must be a duration longer than the previous step's", i+1)
+		}
+		previous = after
+		if len(declared.Channels) == 0 {
+			return nil, nil, fmt.Errorf("escalation step %d: channels are required", i+1)
+		}
+		for _, name := range declared.Channels {
+			if !names[name] {
+				return nil, nil, fmt.Errorf("escalation step %d: unknown alert channel %s", i+1, name)
+			}
+		}
+		escalation = append(escalation, alert.EscalationStep{After: after, Channels: declared.Channels})
+	}
+	return channels, escalation, nil
+}
+
+// reportSplitBrains publishes an event if another coordinator claimed
+// leadership while this one led since the last call, and returns the
+// current count
+func reportSplitBrains(elector election.Elector, publisher events.Publisher, myID, reported int) int {
+	count := elector.Stats().SplitBrains
+	if count > reported {
+		publisher.Publish(events.Event{
+			Type:          events.TypeSplitBrain,
+			CoordinatorID: myID,
+			LeaderID:      elector.GetLeaderID(),
+			Message:       fmt.Sprintf("another coordinator claimed leadership %d time(s)", count-reported),
+		})
+	}
+	return count
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/scaling"
+)
+
+// AutoscaleRule declares a worker that gets extra replicas while its input
+// queue is backed up
+type AutoscaleRule struct {
+	Template         string `yaml:"template"`
+	DockerHost       string `yaml:"docker_host"`
+	VHost            string `yaml:"vhost"`
+	Queue            string `yaml:"queue"`
+	ScaleUpBacklog   int    `yaml:"scale_up_backlog"`
+	ScaleDownBacklog int    `yaml:"scale_down_backlog"`
+	MaxExtra         int    `yaml:"max_extra"`
+	Cooldown         string `yaml:"cooldown"`
+	StopTimeout      string `yaml:"stop_timeout"`
+}
+
+// defaultScaleCooldown leaves time for a new replica to start consuming
+// before the backlog is judged again
+const defaultScaleCooldown = "1m"
+
+// toRule converts an autoscale declaration into a scaling.Rule
+func (r AutoscaleRule) toRule() (scaling.Rule, error) {
+	cooldown := r.Cooldown
+	if cooldown == "" {
+		cooldown = defaultScaleCooldown
+	}
+
+	rule := scaling.Rule{
+		Template:         r.Template,
+		DockerHost:       r.DockerHost,
+		VHost:            r.VHost,
+		Queue:            r.Queue,
+		ScaleUpBacklog:   r.ScaleUpBacklog,
+		ScaleDownBacklog: r.ScaleDownBacklog,
+		MaxExtra:         r.MaxExtra,
+	}
+
+	var err error
+	if rule.Cooldown, err = parseOptionalDuration(cooldown); err != nil {
+		return scaling.Rule{}, fmt.Errorf("autoscale %s: invalid cooldown: %w", r.Template, err)
+	}
+	if rule.StopTimeout, err = parseOptionalDuration(r.StopTimeout); err != nil {
+		return scaling.Rule{}, fmt.Errorf("autoscale %s: invalid stop_timeout: %w", r.Template, err)
+	}
+	return rule, rule.Validate()
+}
+
+// newScaler builds the scaler for the configured autoscale rules. It returns
+// nil when autoscaling is not configured or can't work.
+func newScaler(declared []AutoscaleRule, runtimes scaling.RuntimeResolver) *scaling.Scaler {
+	if len(declared) == 0 {
+		return nil
+	}
+
+	managementURL := getEnv("RABBITMQ_MANAGEMENT_URL", "")
+	if managementURL == "" {
+		log.Printf("WARNING: Autoscale rules configured but RABBITMQ_MANAGEMENT_URL is not set, autoscaling disabled")
+		return nil
+	}
+	backlog, err := scaling.NewRabbitMQManagement(managementURL)
+	if err != nil {
+		log.Printf("WARNING: Autoscaling disabled: %v", err)
+		return nil
+	}
+
+	rules := make([]scaling.Rule, 0, len(declared))
+	for _, d := range declared {
+		rule, err := d.toRule()
+		if err != nil {
+			log.Printf("WARNING: Skipping autoscale rule: %v", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	log.Printf("Autoscaling %d worker(s) on queue backlog", len(rules))
+	return scaling.NewScaler(rules, backlog, runtimes)
+}
+
+// runAutoscaling takes a round of scaling steps every interval while this
+// coordinator leads. It has a loop of its own so slow Docker or broker
+// calls don't hold up the checks, and a round never overlaps the last.
+func runAutoscaling(scaler *scaling.Scaler, publisher events.Publisher, myID int, elector election.Elector, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if elector.IsLeader() {
+			autoscale(scaler, publisher, myID, elector.GetLeaderID())
+		}
+	}
+}
+
+// autoscale takes one round of scaling steps and publishes them
+func autoscale(scaler *scaling.Scaler, publisher events.Publisher, myID, leaderID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+	defer cancel()
+
+	for _, change := range scaler.Reconcile(ctx) {
+		eventType := events.TypeScaledDown
+		if change.Up {
+			eventType = events.TypeScaledUp
+		}
+		publisher.Publish(events.Event{
+			Type:          eventType,
+			CoordinatorID: myID,
+			LeaderID:      leaderID,
+			Target:        change.Template,
+			ContainerName: change.Container,
+			Message:       fmt.Sprintf("backlog=%d", change.Backlog),
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/scaling"
+)
+
+const (
+	defaultBackpressureInterval = 10 * time.Second
+	defaultBackpressureSlowdown = 3
+)
+
+// backpressureMonitor watches the broker's total backlog. Under a load
+// spike, probing the workers and restarting the slow ones makes things
+// worse: while the backlog is above high, the supervisor checks
+// non-critical targets slowdown times less often and defers their
+// restarts, until the backlog drains below low.
+type backpressureMonitor struct {
+	management queueManagement
+	vhost      string
+	high       int
+	low        int
+	slowdown   int
+	supervisor *Supervisor
+}
+
+// newBackpressureMonitor reads the backpressure settings. It returns nil
+// when BACKPRESSURE_BACKLOG isn't set or the management API isn't
+// configured.
+func newBackpressureMonitor(supervisor *Supervisor) (*backpressureMonitor, error) {
+	high := getEnvInt("BACKPRESSURE_BACKLOG", 0)
+	if high <= 0 {
+		return nil, nil
+	}
+	low := getEnvInt("BACKPRESSURE_RESUME_BACKLOG", high/2)
+	slowdown := getEnvInt("BACKPRESSURE_SLOWDOWN", defaultBackpressureSlowdown)
+	if low < 0 || low >= high {
+		return nil, fmt.Errorf("BACKPRESSURE_RESUME_BACKLOG must be between 0 and BACKPRESSURE_BACKLOG (%d)", high)
+	}
+	if slowdown < 1 {
+		return nil, fmt.Errorf("BACKPRESSURE_SLOWDOWN must be at least 1")
+	}
+
+	managementURL := getEnv("RABBITMQ_MANAGEMENT_URL", "")
+	if managementURL == "" {
+		log.Printf("WARNING: BACKPRESSURE_BACKLOG set but RABBITMQ_MANAGEMENT_URL is not, load spikes won't be detected")
+		return nil, nil
+	}
+	management, err := scaling.NewRabbitMQManagement(managementURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backpressureMonitor{
+		management: management,
+		vhost:      getEnv("BACKPRESSURE_VHOST", "/"),
+		high:       high,
+		low:        low,
+		slowdown:   slowdown,
+		supervisor: supervisor,
+	}, nil
+}
+
+// run checks the backlog every interval, forever
+func (m *backpressureMonitor) run(interval time.Duration) {
+	log.Printf("Backing off above %d messages waiting (resuming below %d)", m.high, m.low)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.check()
+	}
+}
+
+// check reads the backlog and enters or leaves backpressure. A backlog that
+// can't be read leaves things as they are.
+func (m *backpressureMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	queues, err := m.management.Queues(ctx, m.vhost)
+	if err != nil {
+		log.Printf("WARNING: Failed to read the backlog: %v", err)
+		return
+	}
+	backlog := 0
+	for _, queue := range queues {
+		backlog += queue.Messages
+	}
+
+	active := m.supervisor.backpressureActive()
+	switch {
+	case !active && backlog > m.high:
+		m.supervisor.SetBackpressure(m.slowdown, fmt.Sprintf("%d messages waiting (more than %d)", backlog, m.high))
+	case active && backlog < m.low:
+		m.supervisor.SetBackpressure(0, fmt.Sprintf("%d messages waiting (less than %d)", backlog, m.low))
+	}
+}
+
+// SetBackpressure enters backpressure, checking non-critical targets
+// slowdown times less often and deferring their restarts, or leaves it
+// with zero. Every coordinator keeps its own, but only the leader
+// publishes it.
+func (s *Supervisor) SetBackpressure(slowdown int, message string) {
+	s.mu.Lock()
+	s.slowdown = slowdown
+	s.mu.Unlock()
+
+	eventType := events.TypeBackpressure
+	if slowdown > 0 {
+		log.Printf("WARNING: Load spike (%s), checking non-critical targets %dx less often and deferring their restarts",
+			message, slowdown)
+	} else {
+		eventType = events.TypeBackpressureOver
+		log.Printf("Load spike over (%s), resuming regular checks and restarts", message)
+	}
+	if s.elector.IsLeader() {
+		s.publisher.Publish(events.Event{
+			Type:          eventType,
+			CoordinatorID: s.myID,
+			LeaderID:      s.elector.GetLeaderID(),
+			Message:       message,
+		})
+	}
+}
+
+// backpressureActive reports whether the broker is under a load spike
+func (s *Supervisor) backpressureActive() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slowdown > 0
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Where bootstrap mounts things inside the coordinator containers
+const (
+	bootstrapComposePath = "/app/nodes-compose.yml"
+	bootstrapConfigPath  = "/app/coordinator.yml"
+	bootstrapSocketPath  = "/var/run/docker.sock"
+)
+
+// bootstrapService is a coordinator replica in the generated compose file
+type bootstrapService struct {
+	ContainerName string   `yaml:"container_name"`
+	Image         string   `yaml:"image,omitempty"`
+	Build         string   `yaml:"build,omitempty"`
+	Environment   []string `yaml:"environment"`
+	Volumes       []string `yaml:"volumes"`
+	Networks      []string `yaml:"networks"`
+	Restart       string   `yaml:"restart"`
+}
+
+// bootstrapCompose is the generated compose file
+type bootstrapCompose struct {
+	Services map[string]bootstrapService `yaml:"services"`
+	Networks map[string]ComposeResource  `yaml:"networks"`
+}
+
+// runBootstrap implements "coordinator bootstrap": it reads the nodes'
+// compose file and writes the compose services of the coordinator replicas
+// wired to it (IDs, the compose file and Docker socket mounted, the nodes'
+// network), after checking the mounted paths exist.
+func runBootstrap(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	composePath := flags.String("compose", "", "compose file of the nodes to monitor (required)")
+	replicas := flags.Int("replicas", 3, "coordinator replicas")
+	image := flags.String("image", "", "coordinator image (default: build from -build)")
+	build := flags.String("build", ".", "build context of the coordinator image, when -image isn't set")
+	network := flags.String("network", "", "network of the nodes to join (default: the compose file's only network)")
+	project := flags.String("project", "", "compose project of the nodes (default: the compose file's directory name)")
+	socket := flags.String("docker-socket", bootstrapSocketPath, "Docker socket on the host")
+	configPath := flags.String("config", "", "coordinator config file to mount as CONFIG_PATH")
+	output := flags.String("o", "", "file to write the services to (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *composePath == "":
+		return errors.New("-compose is required")
+	case *replicas < 1:
+		return fmt.Errorf("-replicas must be at least 1, got %d", *replicas)
+	}
+
+	// Bind mounts need absolute paths that exist on the host
+	compose, err := existingPath(*composePath, "compose file")
+	if err != nil {
+		return err
+	}
+	nodes, err := loadComposeServices([]string{compose})
+	if err != nil {
+		return err
+	}
+	if *socket, err = existingPath(*socket, "Docker socket"); err != nil {
+		return err
+	}
+	if *configPath != "" {
+		if *configPath, err = existingPath(*configPath, "config file"); err != nil {
+			return err
+		}
+		if _, err := loadFileConfig(*configPath); err != nil {
+			return err
+		}
+	}
+
+	if *project == "" {
+		*project = strings.ToLower(filepath.Base(filepath.Dir(compose)))
+	}
+	networkName, err := nodesNetwork(nodes, *network, *project)
+	if err != nil {
+		return err
+	}
+
+	generated := bootstrapCompose{
+		Services: make(map[string]bootstrapService, *replicas),
+		Networks: map[string]ComposeResource{networkName: {Name: networkName, External: true}},
+	}
+	for id := 1; id <= *replicas; id++ {
+		name := fmt.Sprintf("coordinator-%d", id)
+		service := bootstrapService{
+			ContainerName: name,
+			Image:         *image,
+			Environment: []string{
+				fmt.Sprintf("MY_ID=%d", id),
+				fmt.Sprintf("TOTAL_REPLICAS=%d", *replicas),
+				"COMPOSE_PATH=" + bootstrapComposePath,
+				"COMPOSE_PROJECT=" + *project,
+			},
+			Volumes: []string{
+				*socket + ":" + bootstrapSocketPath,
+				compose + ":" + bootstrapComposePath + ":ro",
+			},
+			Networks: []string{networkName},
+			Restart:  "unless-stopped",
+		}
+		if *image == "" {
+			service.Build = *build
+		}
+		if *configPath != "" {
+			service.Environment = append(service.Environment, "CONFIG_PATH="+bootstrapConfigPath)
+			service.Volumes = append(service.Volumes, *configPath+":"+bootstrapConfigPath+":ro")
+		}
+		generated.Services[name] = service
+	}
+
+	if len(nodes.Include) > 0 {
+		fmt.Fprintf(stderr, "WARNING: %s includes other compose files; mount them next to %s too\n", compose, bootstrapComposePath)
+	}
+
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(generated); err != nil {
+		return fmt.Errorf("failed to encode services: %w", err)
+	}
+	if *output == "" {
+		_, err = stdout.Write(data.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*output, data.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+	fmt.Fprintf(stderr, "Wrote %d coordinator replicas to %s\n", *replicas, *output)
+	return nil
+}
+
+// existingPath returns the absolute form of a path that must exist
+func existingPath(path, what string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s path %s: %w", what, path, err)
+	}
+	if _, err := os.Stat(absolute); err != nil {
+		return "", fmt.Errorf("%s %s: %w", what, absolute, err)
+	}
+	return absolute, nil
+}
+
+// nodesNetwork returns the Docker name of the network the coordinators
+// share with the nodes: the one given, or the compose file's only network
+func nodesNetwork(nodes *DockerCompose, network, project string) (string, error) {
+	if network != "" {
+		if resource, ok := nodes.Networks[network]; ok {
+			return resourceName(resource, network, project), nil
+		}
+		return network, nil
+	}
+
+	switch len(nodes.Networks) {
+	case 0:
+		return project + "_default", nil
+	case 1:
+		for name, resource := range nodes.Networks {
+			return resourceName(resource, name, project), nil
+		}
+	}
+	names := make([]string, 0, len(nodes.Networks))
+	for name := range nodes.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("the compose file declares several networks (%s), choose one with -network", strings.Join(names, ", "))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// checkLog logs check results as state changes instead of once per check, so
+// a target that stays down for hours doesn't flood the logs: it logs when a
+// target turns unhealthy or healthy again, when the error of a failing target
+// changes, and a reminder every summaryInterval while it stays down.
+type checkLog struct {
+	summaryInterval time.Duration
+
+	mu     sync.Mutex
+	states map[string]*targetLogState
+}
+
+// targetLogState is what was last logged about a target
+type targetLogState struct {
+	healthy    bool
+	since      time.Time // when the target entered its current state
+	checks     int       // checks in the current state
+	lastError  string
+	lastLogged time.Time
+	decision   string // last recovery decision logged while failing
+}
+
+func newCheckLog(summaryInterval time.Duration) *checkLog {
+	return &checkLog{summaryInterval: summaryInterval, states: make(map[string]*targetLogState)}
+}
+
+// Result logs a check result if it's news
+func (l *checkLog) Result(name string, result monitor.CheckResult, failingPeers []int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := result.Timestamp
+	state, known := l.states[name]
+	if !known || state.healthy != result.Healthy {
+		previous := state
+		state = &targetLogState{healthy: result.Healthy, since: now, lastLogged: now}
+		l.states[name] = state
+		state.checks = 1
+
+		switch {
+		case result.Healthy && len(failingPeers) > 0:
+			log.Printf("OK: %s is healthy (coordinators %v see it failing)", name, failingPeers)
+		case result.Healthy && known:
+			log.Printf("OK: %s is healthy again after %v down (%d failed checks)",
+				name, now.Sub(previous.since).Round(time.Second), previous.checks)
+		case result.Healthy:
+			log.Printf("OK: %s is healthy", name)
+		default:
+			state.lastError = result.Err.Error()
+			log.Printf("ERROR: %s is not responding to health checks: %v", name, result.Err)
+		}
+		return
+	}
+
+	state.checks++
+	if result.Healthy {
+		return
+	}
+
+	if err := result.Err.Error(); err != state.lastError {
+		state.lastError = err
+		state.lastLogged = now
+		log.Printf("ERROR: %s is still not responding to health checks, now: %v", name, result.Err)
+		return
+	}
+	if l.summaryInterval > 0 && now.Sub(state.lastLogged) >= l.summaryInterval {
+		state.lastLogged = now
+		log.Printf("ERROR: %s has been down for %v (%d failed checks): %v",
+			name, now.Sub(state.since).Round(time.Second), state.checks, result.Err)
+	}
+}
+
+// Decision logs why a failing target isn't being recovered, only when the
+// reason differs from the last one logged for the target while failing. It
+// reports whether it logged.
+func (l *checkLog) Decision(name, decision, format string, args ...interface{}) bool {
+	l.mu.Lock()
+	state := l.states[name]
+	repeated := state != nil && state.decision == decision
+	if state != nil {
+		state.decision = decision
+	}
+	l.mu.Unlock()
+
+	if !repeated {
+		log.Printf(format, args...)
+	}
+	return !repeated
+}
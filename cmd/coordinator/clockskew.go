@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/statesync"
+)
+
+// defaultClockSkewWarning is how far another coordinator's clock can be
+// from this one's before it's flagged
+const defaultClockSkewWarning = 2 * time.Second
+
+// clockSkews estimates how far each other coordinator's clock is from this
+// one's, from the wall clock timestamps on the messages they send. The
+// estimate includes the network delay, which is negligible next to the skews
+// worth flagging.
+type clockSkews struct {
+	warning time.Duration // zero disables flagging
+
+	mu    sync.Mutex
+	peers map[int]peerClock
+}
+
+// peerClock is the latest estimate for one coordinator
+type peerClock struct {
+	skew    time.Duration // its clock minus ours
+	flagged bool
+}
+
+func newClockSkews(warning time.Duration) *clockSkews {
+	return &clockSkews{warning: warning, peers: make(map[int]peerClock)}
+}
+
+// observe records a message coordinator id sent at sentAt (by its clock)
+// and this one received at receivedAt, and returns the estimated skew.
+// Messages without a timestamp (older coordinators) are ignored.
+func (c *clockSkews) observe(id int, sentAt, receivedAt time.Time) time.Duration {
+	if sentAt.IsZero() {
+		return 0
+	}
+	skew := sentAt.Sub(receivedAt)
+	flagged := c.warning > 0 && (skew >= c.warning || skew <= -c.warning)
+
+	c.mu.Lock()
+	previous := c.peers[id]
+	c.peers[id] = peerClock{skew: skew, flagged: flagged}
+	c.mu.Unlock()
+
+	switch {
+	case flagged && !previous.flagged:
+		log.Printf("WARNING: Clock of coordinator %d is %v off from ours (more than %v)", id, skew.Round(time.Millisecond), c.warning)
+	case !flagged && previous.flagged:
+		log.Printf("Clock of coordinator %d is back within %v of ours", id, c.warning)
+	}
+	return skew
+}
+
+// status returns the estimates, by coordinator ID
+func (c *clockSkews) status() []admin.PeerClock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clocks := make([]admin.PeerClock, 0, len(c.peers))
+	for id, peer := range c.peers {
+		clocks = append(clocks, admin.PeerClock{ID: id, Skew: peer.skew, Significant: peer.flagged})
+	}
+	sort.Slice(clocks, func(i, j int) bool { return clocks[i].ID < clocks[j].ID })
+	return clocks
+}
+
+// toLocalClock moves the leader's timestamps in a replicated state to this
+// coordinator's clock, so recovery deadlines don't shift with the skew
+func toLocalClock(state statesync.State, skew time.Duration) statesync.State {
+	if skew == 0 {
+		return state
+	}
+	recovering := make(map[string]time.Time, len(state.Recovering))
+	for name, since := range state.Recovering {
+		recovering[name] = since.Add(-skew)
+	}
+	state.Recovering = recovering
+	if !state.BusyUntil.IsZero() {
+		state.BusyUntil = state.BusyUntil.Add(-skew)
+	}
+	return state
+}
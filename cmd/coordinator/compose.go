@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"gopkg.in/yaml.v3"
+)
+
+// DockerCompose represents the structure of docker-compose.yml
+type DockerCompose struct {
+	Include  []ComposeInclude           `yaml:"include"`
+	Services map[string]Service         `yaml:"services"`
+	Networks map[string]ComposeResource `yaml:"networks"`
+	Volumes  map[string]ComposeResource `yaml:"volumes"`
+}
+
+// Service represents a service in docker-compose.yml. Besides what the
+// coordinator needs to monitor it, enough of the definition is kept to
+// recreate its container if it disappears.
+type Service struct {
+	ContainerName string       `yaml:"container_name"`
+	Labels        Labels       `yaml:"labels"`
+	Image         string       `yaml:"image"`
+	Build         yaml.Node    `yaml:"build"`
+	Command       StringOrList `yaml:"command"`
+	Entrypoint    StringOrList `yaml:"entrypoint"`
+	Environment   Environment  `yaml:"environment"`
+	Volumes       []yaml.Node  `yaml:"volumes"`
+	Networks      NetworkList  `yaml:"networks"`
+	DependsOn     NetworkList  `yaml:"depends_on"` // same list or mapping forms as networks
+
+	// dir is the directory of the compose file that defined the service,
+	// used to resolve relative bind mounts
+	dir string
+}
+
+// ComposeResource is a top-level network or volume declaration
+type ComposeResource struct {
+	Name     string `yaml:"name"`
+	External bool   `yaml:"external"`
+}
+
+// StringOrList is a compose field that accepts a string or a list of strings
+type StringOrList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *StringOrList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = strings.Fields(value.Value)
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Environment is the environment of a service, given as a mapping or as a
+// list of "KEY=value" strings. It's kept as a list of "KEY=value".
+type Environment []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (e *Environment) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*e = list
+		return nil
+	}
+
+	var m map[string]*string
+	if err := value.Decode(&m); err != nil {
+		return err
+	}
+	env := make([]string, 0, len(m))
+	for k, v := range m {
+		if v == nil {
+			env = append(env, k)
+		} else {
+			env = append(env, k+"="+*v)
+		}
+	}
+	sort.Strings(env)
+	*e = env
+	return nil
+}
+
+// NetworkList is the list of networks of a service, given as a list of
+// names or as a mapping keyed by name
+type NetworkList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (n *NetworkList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*n = list
+		return nil
+	}
+
+	var m map[string]yaml.Node
+	if err := value.Decode(&m); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	*n = names
+	return nil
+}
+
+// ComposeInclude is an entry of the top-level include list. Compose accepts
+// either a plain path or a mapping whose path is a string or a list.
+type ComposeInclude struct {
+	Paths []string
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (i *ComposeInclude) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		i.Paths = []string{value.Value}
+		return nil
+	}
+
+	var entry struct {
+		Path yaml.Node `yaml:"path"`
+	}
+	if err := value.Decode(&entry); err != nil {
+		return err
+	}
+
+	switch entry.Path.Kind {
+	case yaml.ScalarNode:
+		i.Paths = []string{entry.Path.Value}
+	case yaml.SequenceNode:
+		return entry.Path.Decode(&i.Paths)
+	default:
+		return fmt.Errorf("include entry without path")
+	}
+	return nil
+}
+
+// loadComposeServices reads the given compose files in order and merges them.
+// Later files override earlier ones the way `docker compose -f a -f b` does:
+// scalar fields are replaced when set and labels are merged key by key.
+// Files listed under include are loaded before the file that includes them.
+func loadComposeServices(paths []string) (*DockerCompose, error) {
+	merged := &DockerCompose{
+		Services: make(map[string]Service),
+		Networks: make(map[string]ComposeResource),
+		Volumes:  make(map[string]ComposeResource),
+	}
+	for _, path := range paths {
+		if err := mergeComposeFile(merged, path, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// mergeComposeFile merges a compose file (and its includes) into merged
+func mergeComposeFile(merged *DockerCompose, path string, visiting map[string]bool) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid compose path %s: %w", path, err)
+	}
+	if visiting[absPath] {
+		return fmt.Errorf("compose include cycle at %s", path)
+	}
+	visiting[absPath] = true
+	defer delete(visiting, absPath)
+
+	// Read the compose file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+
+	// Parse YAML
+	var compose DockerCompose
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return fmt.Errorf("failed to parse compose file %s: %w", path, err)
+	}
+
+	// Included paths are relative to the including file
+	for _, include := range compose.Include {
+		for _, includePath := range include.Paths {
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(path), includePath)
+			}
+			if err := mergeComposeFile(merged, includePath, visiting); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, override := range compose.Services {
+		override.dir = filepath.Dir(absPath)
+		merged.Services[name] = mergeService(merged.Services[name], override)
+	}
+	for name, network := range compose.Networks {
+		merged.Networks[name] = network
+	}
+	for name, volume := range compose.Volumes {
+		merged.Volumes[name] = volume
+	}
+	return nil
+}
+
+// mergeService applies an override service definition on top of a base one
+func mergeService(base, override Service) Service {
+	merged := base
+
+	if override.ContainerName != "" {
+		merged.ContainerName = override.ContainerName
+	}
+
+	if override.Image != "" {
+		merged.Image = override.Image
+	}
+	if !override.Build.IsZero() {
+		merged.Build = override.Build
+	}
+	if len(override.Command) > 0 {
+		merged.Command = override.Command
+	}
+	if len(override.Entrypoint) > 0 {
+		merged.Entrypoint = override.Entrypoint
+	}
+	if len(override.Environment) > 0 {
+		merged.Environment = override.Environment
+	}
+	if len(override.Volumes) > 0 {
+		merged.Volumes = override.Volumes
+	}
+	if len(override.Networks) > 0 {
+		merged.Networks = override.Networks
+	}
+	if override.dir != "" && merged.dir == "" {
+		merged.dir = override.dir
+	}
+
+	if len(override.Labels) > 0 {
+		labels := Labels{}
+		for k, v := range base.Labels {
+			labels[k] = v
+		}
+		for k, v := range override.Labels {
+			labels[k] = v
+		}
+		merged.Labels = labels
+	}
+
+	return merged
+}
+
+// containerSpec builds the definition used to recreate the service's container
+// if it disappears. Resource names follow compose's <project>_<name> scheme.
+// Relative bind mounts are resolved against the compose file's directory as
+// the coordinator sees it, so the file should be mounted at its host path for
+// them to be right. It returns nil when the service's image can't be determined.
+func (compose *DockerCompose) containerSpec(serviceName, project string) *docker.ContainerSpec {
+	service := compose.Services[serviceName]
+
+	image := service.Image
+	if image == "" && !service.Build.IsZero() {
+		image = project + "-" + serviceName
+	}
+	if image == "" || project == "" {
+		return nil
+	}
+
+	labels := map[string]string{
+		docker.LabelComposeProject: project,
+		docker.LabelComposeService: serviceName,
+		docker.LabelComposeNumber:  "1",
+	}
+	for k, v := range service.Labels {
+		labels[k] = v
+	}
+
+	networks := []string{}
+	serviceNetworks := service.Networks
+	if len(serviceNetworks) == 0 {
+		serviceNetworks = NetworkList{"default"}
+	}
+	for _, name := range serviceNetworks {
+		networks = append(networks, resourceName(compose.Networks[name], name, project))
+	}
+
+	binds := []string{}
+	for _, volume := range service.Volumes {
+		// Only the short "source:target[:mode]" syntax is supported
+		if volume.Kind != yaml.ScalarNode {
+			continue
+		}
+		parts := strings.SplitN(volume.Value, ":", 2)
+		if len(parts) != 2 {
+			continue // anonymous volume
+		}
+		source := parts[0]
+		switch {
+		case strings.HasPrefix(source, "."):
+			source = filepath.Join(service.dir, source)
+		case strings.HasPrefix(source, "/"):
+		default:
+			source = resourceName(compose.Volumes[source], source, project)
+		}
+		binds = append(binds, source+":"+parts[1])
+	}
+
+	return &docker.ContainerSpec{
+		Image:      image,
+		Cmd:        service.Command,
+		Entrypoint: service.Entrypoint,
+		Env:        service.Environment,
+		Labels:     labels,
+		Binds:      binds,
+		Networks:   networks,
+		Aliases:    []string{serviceName},
+	}
+}
+
+// resourceName returns the actual name of a compose network or volume
+func resourceName(resource ComposeResource, name, project string) string {
+	if resource.Name != "" {
+		return resource.Name
+	}
+	if resource.External {
+		return name
+	}
+	return project + "_" + name
+}
@@ -1,89 +1,314 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
 	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
-	"gopkg.in/yaml.v3"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
 )
 
-// DockerCompose represents the structure of docker-compose.yml
-type DockerCompose struct {
-	Services map[string]Service `yaml:"services"`
+// containerLister resolves the containers that belong to a compose service
+type containerLister interface {
+	ListContainers(ctx context.Context, labels map[string]string) ([]docker.ContainerSummary, error)
 }
 
-// Service represents a service in docker-compose.yml
-type Service struct {
-	ContainerName string `yaml:"container_name"`
-}
-
-// loadWorkersFromCompose reads the docker-compose.yml and extracts worker services
-func loadWorkersFromCompose(composePath string) ([]monitor.CheckTarget, error) {
-	// Read the compose file
-	data, err := os.ReadFile(composePath)
+// loadWorkersFromCompose reads the compose files and extracts worker services.
+// Services without container_name (e.g. scaled with deploy.replicas) are
+// resolved to their actual containers through the compose labels; if that
+// fails for any (not just finds none), the other workers are returned along
+// with the error.
+func loadWorkersFromCompose(composePaths []string, lister containerLister, filter ServiceFilter) ([]monitor.CheckTarget, error) {
+	compose, err := loadComposeServices(composePaths)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read compose file: %w", err)
+		return nil, err
 	}
 
-	// Parse YAML
-	var compose DockerCompose
-	if err := yaml.Unmarshal(data, &compose); err != nil {
-		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	checkType := getEnv("HEALTH_CHECK_TYPE", monitor.CheckTypeTCP)
+	execCommand := strings.Fields(getEnv("HEALTH_EXEC_COMMAND", ""))
+	project, specProject := composeProject(composePaths)
+	swarmMode := getEnv("SWARM_MODE", "false") == "true"
+
+	// Loaders and clients that exit once done aren't restarted
+	oneShotCleanup := getEnv("ONESHOT_CLEANUP", cleanupDeregister)
+	if !validOneShotCleanup(oneShotCleanup) {
+		return nil, fmt.Errorf("invalid ONESHOT_CLEANUP %q (expected %s, %s or %s)", oneShotCleanup,
+			cleanupDeregister, cleanupPrune, cleanupRestart)
+	}
+	pausedAction := getEnv("PAUSED_ACTION", pausedAlert)
+	if !validPausedAction(pausedAction) {
+		return nil, fmt.Errorf("invalid PAUSED_ACTION %q (expected %s, %s or %s)", pausedAction,
+			pausedAlert, pausedIgnore, pausedUnpause)
 	}
 
-	// Extract all services as targets
+	// Extract all services as targets, keeping the targets of each service
+	// to resolve depends_on
 	targets := []monitor.CheckTarget{}
-	for _, service := range compose.Services {
+	services := make(map[string][]string)
+	var resolveErr error
+	for name, service := range compose.Services {
+		if !filter.Allows(name, service.ContainerName, service.Labels) {
+			log.Printf("Service %s excluded by filters", name)
+			continue
+		}
+
+		// Under Swarm the service is the unit of recovery: tasks are
+		// rescheduled into new containers, so there's no stable name to restart
+		if swarmMode {
+			target, err := swarmTarget(name, project, service, checkType, execCommand)
+			if err != nil {
+				log.Printf("WARNING: Skipping service %s: %v", name, err)
+				continue
+			}
+			targets = append(targets, target)
+			services[name] = append(services[name], target.Name)
+			continue
+		}
+
+		containerNames := []string{service.ContainerName}
 		if service.ContainerName == "" {
-			continue // Skip services without explicit container_name
+			resolved, err := resolveServiceContainers(lister, project, name)
+			if err != nil {
+				log.Printf("WARNING: Skipping service %s: %v", name, err)
+				if !errors.Is(err, errNoContainers) {
+					resolveErr = fmt.Errorf("failed to resolve compose service %s: %w", name, err)
+				}
+				continue
+			}
+			containerNames = resolved
 		}
 
-		targets = append(targets, monitor.CheckTarget{
-			Name:          service.ContainerName,
-			Host:          service.ContainerName,
-			Port:          healthPort,
-			ContainerName: service.ContainerName,
-		})
+		for _, containerName := range containerNames {
+			target := monitor.CheckTarget{
+				Name:           containerName,
+				Host:           containerName,
+				Port:           workerHealthPort(),
+				ContainerName:  containerName,
+				CheckType:      checkType,
+				ExecCommand:    execCommand,
+				OneShotCleanup: oneShotCleanup,
+				PausedAction:   pausedAction,
+				DependsOn:      append([]string(nil), service.DependsOn...),
+			}
+
+			// Only fixed-name containers can be recreated under the same name
+			if service.ContainerName != "" {
+				target.ContainerSpec = compose.containerSpec(name, specProject)
+			}
+
+			if err := applyLabels(&target, service.Labels); err != nil {
+				log.Printf("WARNING: Skipping service %s: %v", name, err)
+				break
+			}
+
+			targets = append(targets, target)
+			services[name] = append(services[name], target.Name)
+		}
+	}
+	resolveDependencies(targets, services)
+
+	log.Printf("Loaded %d worker nodes from compose files: %s", len(targets), strings.Join(composePaths, ", "))
+	return targets, resolveErr
+}
+
+// composeProject returns the compose project to look containers up in
+// (COMPOSE_PROJECT, empty meaning any) and the one new containers are
+// created for, which defaults to compose's: the first file's directory
+func composeProject(composePaths []string) (project, specProject string) {
+	project = getEnv("COMPOSE_PROJECT", "")
+	specProject = project
+	if specProject == "" && len(composePaths) > 0 {
+		if absPath, err := filepath.Abs(composePaths[0]); err == nil {
+			specProject = strings.ToLower(filepath.Base(filepath.Dir(absPath)))
+		}
+	}
+	return project, specProject
+}
+
+// swarmTarget builds the target for a service deployed as a Swarm stack. The
+// service is reached through its virtual IP and recovered by forcing a
+// service update. Stack services are named <stack>_<service>.
+func swarmTarget(name, stack string, service Service, checkType string, execCommand []string) (monitor.CheckTarget, error) {
+	swarmService := name
+	if stack != "" {
+		swarmService = stack + "_" + name
+	}
+
+	target := monitor.CheckTarget{
+		Name:         swarmService,
+		Host:         name,
+		Port:         workerHealthPort(),
+		CheckType:    checkType,
+		ExecCommand:  execCommand,
+		Recovery:     recovery.ActionSwarm,
+		SwarmService: swarmService,
+		DependsOn:    append([]string(nil), service.DependsOn...),
+	}
+
+	if err := applyLabels(&target, service.Labels); err != nil {
+		return monitor.CheckTarget{}, err
+	}
+	return target, nil
+}
+
+// errNoContainers is returned when a compose service has no containers
+// (e.g. scaled down to zero)
+var errNoContainers = errors.New("no containers found")
+
+// resolveServiceContainers returns the names of the containers Docker Compose
+// created for a service, optionally restricted to a compose project
+func resolveServiceContainers(lister containerLister, project, service string) ([]string, error) {
+	if lister == nil {
+		return nil, fmt.Errorf("no container_name and no Docker client to resolve replicas")
+	}
+
+	labels := map[string]string{docker.LabelComposeService: service}
+	if project != "" {
+		labels[docker.LabelComposeProject] = project
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	containers, err := lister.ListContainers(ctx, labels)
+	if err != nil {
+		return nil, err
 	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("%w for compose service %s", errNoContainers, service)
+	}
+
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, container.Name())
+	}
+	sort.Strings(names)
 
-	log.Printf("Loaded %d worker nodes from compose file: %s", len(targets), composePath)
-	return targets, nil
+	log.Printf("Resolved compose service %s to %d container(s)", service, len(names))
+	return names, nil
 }
 
 // getMonitoredNodes generates the complete list of nodes to monitor dynamically
 // Includes workers (from docker-compose.yml) AND other coordinators (excluding self)
-func getMonitoredNodes(myID, totalReplicas int) []monitor.CheckTarget {
-	targets := []monitor.CheckTarget{}
+func getMonitoredNodes(myID, totalReplicas int, peers election.Peers, lister containerLister, config *FileConfig) []monitor.CheckTarget {
+	targets := coordinatorTargets(myID, totalReplicas, peers)
+
+	discovered, err := discoverTargets(lister, config)
+	if err != nil {
+		log.Printf("WARNING: Failed to load workers from compose file: %v", err)
+		if len(discovered) == 0 {
+			log.Printf("Continuing with only coordinator monitoring...")
+		}
+	}
+	targets = append(targets, discovered...)
+
+	if len(config.Groups) > 0 {
+		if err := applyGroupPolicies(targets, config.Groups); err != nil {
+			log.Printf("WARNING: Invalid group policy in config file: %v", err)
+		}
+	}
+	applyDefaultThreshold(targets, getEnvInt("FAILURE_THRESHOLD", 0))
+	if err := checkDependencies(targets); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+
+	return targets
+}
+
+// knownCheckTypes drops the targets whose check type has no registered
+// checker: monitoring them would only report failed checks, so a typo in
+// coffeeshop.health.type (or a plugin that isn't declared) is an error at
+// load time instead.
+func knownCheckTypes(targets []monitor.CheckTarget, checkers *monitor.Registry) []monitor.CheckTarget {
+	known := targets[:0]
+	for _, target := range targets {
+		if _, ok := checkers.Lookup(target.CheckType); !ok {
+			log.Printf("ERROR: Target %s has unknown check type %q, not monitoring it", target.Name, target.CheckType)
+			continue
+		}
+		known = append(known, target)
+	}
+	return known
+}
+
+// applyDefaultThreshold sets the failure threshold of the targets that
+// neither they nor their group set
+func applyDefaultThreshold(targets []monitor.CheckTarget, threshold int) {
+	for i := range targets {
+		if targets[i].FailureThreshold == 0 {
+			targets[i].FailureThreshold = threshold
+		}
+	}
+}
 
-	// ========================================
-	// COORDINATORS (Cross-Monitoring)
-	// ========================================
+// coordinatorTargets returns the other coordinators, which every
+// coordinator monitors
+func coordinatorTargets(myID, totalReplicas int, peers election.Peers) []monitor.CheckTarget {
+	targets := []monitor.CheckTarget{}
 	for i := 1; i <= totalReplicas; i++ {
 		// CRITICAL: Never monitor myself
 		if i == myID {
 			continue
 		}
 
-		containerName := fmt.Sprintf("coordinator-%d", i)
+		containerName := peers.Host(i)
 		targets = append(targets, monitor.CheckTarget{
 			Name:          fmt.Sprintf("Coordinator %d", i),
 			Host:          containerName,
-			Port:          healthPort,
+			Port:          coordinatorHealthPort(),
 			ContainerName: containerName,
+			Group:         coordinatorGroup,
+			// Coordinators don't take part in query runs
+			Critical: true,
 		})
 	}
+	return targets
+}
 
-	composePath := getEnv("COMPOSE_PATH", "/app/nodes-compose.yml")
+// discoverTargets returns the workers from the compose files and the static
+// targets from the config file. The static targets are returned even when
+// the compose files can't be loaded, along with the error.
+func discoverTargets(lister containerLister, config *FileConfig) ([]monitor.CheckTarget, error) {
+	filter := config.Filters.merge(ServiceFilter{
+		Include: splitList(getEnv("MONITOR_INCLUDE", "")),
+		Exclude: splitList(getEnv("MONITOR_EXCLUDE", "")),
+	})
 
-	workerTargets, err := loadWorkersFromCompose(composePath)
-	if err != nil {
-		log.Printf("WARNING: Failed to load workers from compose file: %v", err)
-		log.Printf("Continuing with only coordinator monitoring...")
-	} else {
-		targets = append(targets, workerTargets...)
+	targets, composeErr := loadWorkersFromCompose(composePaths(), lister, filter)
+
+	// Static targets from the config file
+	if len(config.Targets) > 0 {
+		if merged, err := mergeStaticTargets(targets, config.Targets); err != nil {
+			log.Printf("WARNING: Invalid static target in config file: %v", err)
+		} else {
+			log.Printf("Loaded %d static targets from config file", len(config.Targets))
+			targets = merged
+		}
 	}
+	return targets, composeErr
+}
 
-	return targets
+// composePaths returns the compose files to read. COMPOSE_PATHS is a
+// comma-separated list of base + override files; COMPOSE_PATH is kept for
+// single-file deployments.
+func composePaths() []string {
+	return splitList(getEnv("COMPOSE_PATHS", getEnv("COMPOSE_PATH", "/app/nodes-compose.yml")))
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
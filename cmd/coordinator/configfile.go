@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/plugin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the coordinator's own config file (CONFIG_PATH)
+type FileConfig struct {
+	Targets []StaticTarget `yaml:"targets"`
+	Filters ServiceFilter  `yaml:"filters"`
+	SSH     SSHConfig      `yaml:"ssh"`
+
+	// DockerHosts maps host names to Docker endpoints (unix:// or tcp://)
+	DockerHosts map[string]string `yaml:"docker_hosts"`
+
+	Autoscale []AutoscaleRule `yaml:"autoscale"`
+
+	// Groups holds the policies shared by the targets of each group
+	Groups map[string]GroupPolicy `yaml:"groups"`
+
+	Election ElectionConfig `yaml:"election"`
+
+	// Plugins are external checkers and recovery actions
+	Plugins []PluginConfig `yaml:"plugins"`
+
+	Alerts AlertsConfig `yaml:"alerts"`
+
+	// Maintenance holds recurring tasks the leader runs on a schedule
+	Maintenance []MaintenanceTaskConfig `yaml:"maintenance"`
+
+	// Desired declares the replicas the leader keeps of compose services
+	Desired []DesiredService `yaml:"desired"`
+
+	// Redelivery declares the queues watched for redelivery storms
+	Redelivery []RedeliveryRule `yaml:"redelivery"`
+
+	// Profile selects a profile unless PROFILE is set, and Profiles declares
+	// profiles as the environment variables they preset (see profiles.go)
+	Profile  string                       `yaml:"profile"`
+	Profiles map[string]map[string]string `yaml:"profiles"`
+}
+
+// PluginConfig declares a plugin: an executable speaking the protocol of
+// internal/plugin, registered as a check type or recovery action
+type PluginConfig struct {
+	Name    string   `yaml:"name"`
+	Kind    string   `yaml:"kind"`
+	Command []string `yaml:"command"`
+	Timeout string   `yaml:"timeout"`
+}
+
+// toPlugin converts a plugin declaration
+func (p PluginConfig) toPlugin() (plugin.Plugin, error) {
+	timeout, err := parseOptionalDuration(p.Timeout)
+	if err != nil {
+		return plugin.Plugin{}, fmt.Errorf("plugin %s: invalid timeout: %w", p.Name, err)
+	}
+	converted := plugin.Plugin{Name: p.Name, Kind: p.Kind, Command: p.Command, Timeout: timeout}
+	return converted, converted.Validate()
+}
+
+// registerPlugins registers the declared plugins as checkers and recovery
+// actions
+func registerPlugins(configs []PluginConfig, checkers *monitor.Registry, recoveries *recovery.Registry) error {
+	plugins := make([]plugin.Plugin, 0, len(configs))
+	for _, config := range configs {
+		p, err := config.toPlugin()
+		if err != nil {
+			return err
+		}
+		plugins = append(plugins, p)
+	}
+	if err := plugin.Register(plugins, checkers, recoveries); err != nil {
+		return err
+	}
+	for _, p := range plugins {
+		log.Printf("Registered %s plugin %s: %s", p.Kind, p.Name, strings.Join(p.Command, " "))
+	}
+	return nil
+}
+
+// SSHConfig holds the credentials for SSH-based recovery. Hosts not listed
+// use the defaults.
+type SSHConfig struct {
+	Defaults recovery.SSHHostConfig            `yaml:"defaults"`
+	Hosts    map[string]recovery.SSHHostConfig `yaml:"hosts"`
+}
+
+// StaticTarget is a target declared directly in the config file, for nodes
+// that don't live in the compose file (external databases, bare-metal brokers)
+type StaticTarget struct {
+	Name             string   `yaml:"name"`
+	Group            string   `yaml:"group"`
+	Host             string   `yaml:"host"`
+	Port             int      `yaml:"port"`
+	Type             string   `yaml:"type"`
+	Path             string   `yaml:"path"`
+	Command          []string `yaml:"command"`
+	ClientPort       int      `yaml:"client_port"`
+	Hello            string   `yaml:"hello"`
+	HelloReply       string   `yaml:"hello_reply"`
+	Interval         string   `yaml:"interval"`
+	FailureThreshold int      `yaml:"failure_threshold"`
+	MaxRestarts      int      `yaml:"max_restarts"`
+	StopTimeout      string   `yaml:"stop_timeout"`
+	RestartDeadline  string   `yaml:"restart_deadline"`
+	DrainTimeout     string   `yaml:"drain_timeout"`
+	VerifyTimeout    string   `yaml:"verify_timeout"`
+	Critical         bool     `yaml:"critical"`
+	Protected        bool     `yaml:"protected"`
+	ConfirmWebhook   string   `yaml:"confirm_webhook"`
+	ConfirmTimeout   string   `yaml:"confirm_timeout"`
+	ConfirmDefault   string   `yaml:"confirm_default"`
+	PreRestart       *Hook    `yaml:"pre_restart"`
+	PostRecovery     *Hook    `yaml:"post_recovery"`
+	ContainerName    string   `yaml:"container_name"`
+	Recovery         string   `yaml:"recovery"`
+	RecoveryCommand  []string `yaml:"recovery_command"`
+	WebhookURL       string   `yaml:"webhook_url"`
+	Unit             string   `yaml:"unit"`
+	SSHHost          string   `yaml:"ssh_host"`
+	DockerHost       string   `yaml:"docker_host"`
+	SwarmService     string   `yaml:"swarm_service"`
+	DependsOn        []string `yaml:"depends_on"`
+
+	PluginParams map[string]string `yaml:"plugin_params"`
+}
+
+// Hook is a pre-restart or post-recovery hook: a command run in the target's
+// container and/or a webhook
+type Hook struct {
+	Command  []string `yaml:"command"`
+	Webhook  string   `yaml:"webhook"`
+	Blocking bool     `yaml:"blocking"`
+}
+
+// toMonitorHook converts a hook declaration, nil staying nil
+func (h *Hook) toMonitorHook() *monitor.Hook {
+	if h == nil {
+		return nil
+	}
+	return &monitor.Hook{Command: h.Command, Webhook: h.Webhook, Blocking: h.Blocking}
+}
+
+// loadConfig loads the config file named by CONFIG_PATH. A missing or
+// invalid file is reported and an empty config is used instead.
+func loadConfig() *FileConfig {
+	configPath := getEnv("CONFIG_PATH", "")
+	if configPath == "" {
+		return &FileConfig{}
+	}
+
+	config, err := loadFileConfig(configPath)
+	if err != nil {
+		log.Printf("WARNING: Failed to load config file: %v", err)
+		return &FileConfig{}
+	}
+
+	log.Printf("Loaded config file %s", configPath)
+	return config
+}
+
+// loadFileConfig reads and parses the coordinator config file
+func loadFileConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config FileConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// toCheckTarget converts a static target declaration into a CheckTarget
+func (t StaticTarget) toCheckTarget() (monitor.CheckTarget, error) {
+	if t.Name == "" {
+		return monitor.CheckTarget{}, fmt.Errorf("static target without name")
+	}
+
+	target := monitor.CheckTarget{
+		Name:             t.Name,
+		Host:             t.Host,
+		Port:             workerHealthPort(),
+		ContainerName:    t.ContainerName,
+		Group:            t.Group,
+		CheckType:        t.Type,
+		Path:             t.Path,
+		ExecCommand:      t.Command,
+		Hello:            t.Hello,
+		HelloReply:       t.HelloReply,
+		FailureThreshold: t.FailureThreshold,
+		MaxRestarts:      t.MaxRestarts,
+		Recovery:         t.Recovery,
+		RecoveryCommand:  t.RecoveryCommand,
+		WebhookURL:       t.WebhookURL,
+		Unit:             t.Unit,
+		SSHHost:          t.SSHHost,
+		DockerHost:       t.DockerHost,
+		SwarmService:     t.SwarmService,
+		Critical:         t.Critical,
+		Protected:        t.Protected,
+		ConfirmWebhook:   t.ConfirmWebhook,
+		ConfirmDefault:   t.ConfirmDefault,
+		PreRestart:       t.PreRestart.toMonitorHook(),
+		PostRecovery:     t.PostRecovery.toMonitorHook(),
+		PluginParams:     t.PluginParams,
+		DependsOn:        t.DependsOn,
+	}
+
+	if target.Host == "" {
+		target.Host = t.Name
+	}
+	if t.Port != 0 {
+		target.Port = strconv.Itoa(t.Port)
+	}
+	if t.ClientPort != 0 {
+		target.ClientPort = strconv.Itoa(t.ClientPort)
+	}
+	if target.CheckType == monitor.CheckTypeGateway && target.ClientPort == "" {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: gateway checks need a client_port", t.Name)
+	}
+
+	if t.Interval != "" {
+		interval, err := time.ParseDuration(t.Interval)
+		if err != nil || interval <= 0 {
+			return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid interval %q", t.Name, t.Interval)
+		}
+		target.Interval = interval
+	}
+
+	var err error
+	if target.StopTimeout, err = parseOptionalDuration(t.StopTimeout); err != nil {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid stop_timeout: %w", t.Name, err)
+	}
+	if target.RestartDeadline, err = parseOptionalDuration(t.RestartDeadline); err != nil {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid restart_deadline: %w", t.Name, err)
+	}
+	if target.DrainTimeout, err = parseOptionalDuration(t.DrainTimeout); err != nil {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid drain_timeout: %w", t.Name, err)
+	}
+	if target.VerifyTimeout, err = parseOptionalDuration(t.VerifyTimeout); err != nil {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid verify_timeout: %w", t.Name, err)
+	}
+	if target.ConfirmTimeout, err = parseOptionalDuration(t.ConfirmTimeout); err != nil {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid confirm_timeout: %w", t.Name, err)
+	}
+	if !validConfirmDefault(t.ConfirmDefault) {
+		return monitor.CheckTarget{}, fmt.Errorf("target %s: invalid confirm_default %q", t.Name, t.ConfirmDefault)
+	}
+
+	// Without a container there is nothing to restart, unless it's a systemd unit
+	if target.Recovery == "" && target.ContainerName == "" {
+		target.Recovery = recovery.ActionNone
+		if target.Unit != "" {
+			target.Recovery = recovery.ActionSystemd
+		}
+	}
+
+	return target, nil
+}
+
+// parseOptionalDuration parses a non-negative duration; empty means zero
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", value)
+	}
+	return d, nil
+}
+
+// mergeStaticTargets adds the static targets to the discovered ones. A static
+// target replaces a discovered target with the same name.
+func mergeStaticTargets(targets []monitor.CheckTarget, static []StaticTarget) ([]monitor.CheckTarget, error) {
+	index := make(map[string]int, len(targets))
+	for i, target := range targets {
+		index[target.Name] = i
+	}
+
+	for _, declared := range static {
+		target, err := declared.toCheckTarget()
+		if err != nil {
+			return nil, err
+		}
+
+		if i, ok := index[target.Name]; ok {
+			targets[i] = target
+		} else {
+			index[target.Name] = len(targets)
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+)
+
+// confirmWebhookTimeout bounds a single call to a confirmation webhook
+const confirmWebhookTimeout = 30 * time.Second
+
+// timeoutApprover is recorded as the approver of restarts decided by a
+// target's confirm_default policy
+const timeoutApprover = "confirm_default policy"
+
+// pendingRestart is a restart waiting for confirmation
+type pendingRestart struct {
+	Since    time.Time
+	Action   string
+	Evidence string // error of the check that asked for it
+	Cause    string // and its reason (see events.Reason*)
+
+	// Denied is set once the restart was refused (by the webhook or the
+	// timeout policy); it's not asked for again until the target is healthy
+	// again, but an operator can still confirm it
+	Denied bool
+}
+
+// needsConfirmation reports whether a target's restarts must be confirmed
+func needsConfirmation(target monitor.CheckTarget) bool {
+	return target.Protected || target.ConfirmWebhook != ""
+}
+
+// validConfirmDefault reports whether value is a confirm_default policy
+func validConfirmDefault(value string) bool {
+	return value == "" || value == recovery.ConfirmDefaultSkip || value == recovery.ConfirmDefaultRestart
+}
+
+// requestConfirmation is called on every failed check of a target whose
+// restart needs confirmation. The first one records the pending restart,
+// alerts the operators and asks the confirmation webhook, if any; later ones
+// apply the target's confirm_default policy once confirm_timeout passes.
+func (s *Supervisor) requestConfirmation(target monitor.CheckTarget, action, cause string, err error) {
+	s.mu.Lock()
+	pending, requested := s.pending[target.Name]
+	if !requested {
+		pending = pendingRestart{Since: time.Now(), Action: action, Evidence: err.Error(), Cause: cause}
+		s.pending[target.Name] = pending
+	}
+	s.mu.Unlock()
+
+	if !requested {
+		log.Printf("Restart of %s needs confirmation, waiting for it (coordinatorctl confirm-restart %q)",
+			target.Name, target.Name)
+		s.publishCause(events.TypeRestartPending, target, cause, fmt.Sprintf("restart with %s awaiting confirmation: %v", action, err))
+		if target.ConfirmWebhook != "" {
+			s.mu.RLock()
+			ctx := s.confirmations
+			s.mu.RUnlock()
+			go s.askConfirmWebhook(ctx, target, pending)
+		}
+		return
+	}
+
+	if pending.Denied || target.ConfirmTimeout <= 0 || time.Since(pending.Since) < target.ConfirmTimeout {
+		return
+	}
+	log.Printf("Restart of %s wasn't confirmed within %v", target.Name, target.ConfirmTimeout)
+	if target.ConfirmDefault == recovery.ConfirmDefaultRestart {
+		s.confirm(target, timeoutApprover)
+	} else {
+		s.deny(target, timeoutApprover)
+	}
+}
+
+// askConfirmWebhook asks the target's confirmation webhook about a pending
+// restart. If it can't answer, the restart keeps waiting for an operator.
+// The request is abandoned if parent is canceled: the pending restart was
+// dropped when leadership was lost.
+func (s *Supervisor) askConfirmWebhook(parent context.Context, target monitor.CheckTarget, pending pendingRestart) {
+	ctx, cancel := context.WithTimeout(parent, confirmWebhookTimeout)
+	approval, err := recovery.RequestApproval(ctx, target, pending.Action, pending.Evidence)
+	cancel()
+	if parent.Err() != nil {
+		log.Printf("Lost leadership, dropping the confirmation request for %s", target.Name)
+		return
+	}
+	if err != nil {
+		log.Printf("WARNING: %v; the restart of %s waits for an operator", err, target.Name)
+		return
+	}
+	if approval.Approved {
+		s.confirm(target, approval.Approver)
+	} else {
+		s.deny(target, approval.Approver)
+	}
+}
+
+// confirm runs a pending restart approved by approver. Only the leader
+// restarts targets: a follower (or a deposed leader) refuses.
+func (s *Supervisor) confirm(target monitor.CheckTarget, approver string) error {
+	if !s.elector.IsLeader() {
+		return fmt.Errorf("%w: restart of %s must be confirmed on the leader", admin.ErrNotLeader, target.Name)
+	}
+
+	s.mu.Lock()
+	pending, ok := s.pending[target.Name]
+	if ok {
+		delete(s.pending, target.Name)
+		s.restartCount[target.Name]++
+		s.recovering[target.Name] = time.Now()
+	}
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", admin.ErrNoPendingRestart, target.Name)
+	}
+
+	log.Printf("Restart of %s approved by %s after %v", target.Name, approver, time.Since(pending.Since).Round(time.Second))
+	return s.recover(context.Background(), target, pending.Action, "health check failed, restart approved",
+		pending.Cause, pending.Evidence, approver)
+}
+
+// dropPending forgets the pending restarts and cancels the confirmation
+// webhooks still running. It's called when leadership is lost: the new
+// leader asks for the confirmations it needs, and an approval arriving here
+// afterwards must not restart anything.
+func (s *Supervisor) dropPending() {
+	s.mu.Lock()
+	dropped := len(s.pending)
+	s.pending = make(map[string]pendingRestart)
+	s.cancelConfirmations()
+	s.confirmations, s.cancelConfirmations = context.WithCancel(context.Background())
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Lost leadership, dropping %d pending restart(s)", dropped)
+	}
+}
+
+// deny refuses a pending restart, recording who refused it
+func (s *Supervisor) deny(target monitor.CheckTarget, approver string) {
+	s.mu.Lock()
+	pending, ok := s.pending[target.Name]
+	denied := ok && pending.Denied
+	if ok {
+		pending.Denied = true
+		s.pending[target.Name] = pending
+	}
+	s.mu.Unlock()
+	if !ok || denied {
+		return
+	}
+
+	log.Printf("Restart of %s denied by %s, not restarting it unless an operator confirms", target.Name, approver)
+	s.auditLog.Record(audit.Entry{
+		Action:        pending.Action,
+		Target:        target.Name,
+		ContainerName: target.ContainerName,
+		Reason:        "health check failed, restart denied",
+		Evidence:      pending.Evidence,
+		Approver:      approver,
+		LeaderID:      s.elector.GetLeaderID(),
+		Term:          s.elector.Stats().Term,
+		Outcome:       audit.OutcomeDenied,
+	})
+}
+
+// ConfirmRestart implements admin.Controller. Restarts are pending only on
+// the leader, so the other coordinators answer admin.ErrNotLeader (409)
+// instead of "no restart pending".
+func (s *Supervisor) ConfirmRestart(name, approver string) error {
+	if !s.elector.IsLeader() {
+		return admin.ErrNotLeader
+	}
+	target, err := s.findTarget(name)
+	if err != nil {
+		return err
+	}
+	if approver == "" {
+		approver = "operator"
+	}
+	return s.confirm(target, approver)
+}
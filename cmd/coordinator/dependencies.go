@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// heldRestarts are the failing dependents of a failing target, whose
+// restarts wait for it: when RabbitMQ is down every worker fails its
+// checks, and restarting them fixes nothing
+type heldRestarts struct {
+	dependents map[string]bool
+	announced  bool // the root cause event was published
+}
+
+// resolveDependencies replaces the compose services the targets depend on
+// with the targets of their containers. Other names (static targets) are
+// kept as they are.
+func resolveDependencies(targets []monitor.CheckTarget, services map[string][]string) {
+	for i := range targets {
+		if len(targets[i].DependsOn) == 0 {
+			continue
+		}
+		resolved := []string{}
+		for _, dependency := range targets[i].DependsOn {
+			if names, ok := services[dependency]; ok {
+				resolved = append(resolved, names...)
+			} else {
+				resolved = append(resolved, dependency)
+			}
+		}
+		targets[i].DependsOn = resolved
+	}
+}
+
+// checkDependencies reports dependency cycles. Dependencies on services that
+// aren't monitored (e.g. excluded by filters) are fine: they're never known
+// to fail.
+func checkDependencies(targets []monitor.CheckTarget) error {
+	byName := make(map[string]monitor.CheckTarget, len(targets))
+	for _, target := range targets {
+		byName[target.Name] = target
+	}
+
+	// Depth-first, with the targets on the current path in visiting
+	visiting, done := map[string]bool{}, map[string]bool{}
+	var visit func(target monitor.CheckTarget, path []string) error
+	visit = func(target monitor.CheckTarget, path []string) error {
+		if visiting[target.Name] {
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, target.Name), " -> "))
+		}
+		if done[target.Name] {
+			return nil
+		}
+		visiting[target.Name] = true
+		path = append(append([]string(nil), path...), target.Name)
+		for _, dependency := range target.DependsOn {
+			next, ok := lookupTarget(byName, dependency)
+			if !ok {
+				continue
+			}
+			if err := visit(next, path); err != nil {
+				return err
+			}
+		}
+		visiting[target.Name] = false
+		done[target.Name] = true
+		return nil
+	}
+	for _, target := range targets {
+		if err := visit(target, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupTarget finds a target by name or container name
+func lookupTarget(byName map[string]monitor.CheckTarget, name string) (monitor.CheckTarget, bool) {
+	if target, ok := byName[name]; ok {
+		return target, true
+	}
+	for _, target := range byName {
+		if target.ContainerName == name {
+			return target, true
+		}
+	}
+	return monitor.CheckTarget{}, false
+}
+
+// dependenciesFirst orders targets so each comes after the ones it depends
+// on, keeping the order otherwise: within a round a dependency's failure
+// is then known before its dependents' are handled
+func dependenciesFirst(targets []monitor.CheckTarget) []monitor.CheckTarget {
+	byName := make(map[string]monitor.CheckTarget, len(targets))
+	for _, target := range targets {
+		byName[target.Name] = target
+	}
+	depth := make(map[string]int, len(targets))
+	var depthOf func(target monitor.CheckTarget, seen map[string]bool) int
+	depthOf = func(target monitor.CheckTarget, seen map[string]bool) int {
+		if d, ok := depth[target.Name]; ok {
+			return d
+		}
+		if seen[target.Name] {
+			return 0 // a cycle; see checkDependencies
+		}
+		seen[target.Name] = true
+		d := 0
+		for _, dependency := range target.DependsOn {
+			if next, ok := lookupTarget(byName, dependency); ok {
+				d = max(d, depthOf(next, seen)+1)
+			}
+		}
+		depth[target.Name] = d
+		return d
+	}
+	for _, target := range targets {
+		depthOf(target, map[string]bool{})
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool { return depth[targets[i].Name] < depth[targets[j].Name] })
+	return targets
+}
+
+// failingDependencyLocked returns the failing target furthest down the
+// dependencies of target (the root cause of its failure), or "" if all of
+// them are healthy. s.mu must be held.
+func (s *Supervisor) failingDependencyLocked(target monitor.CheckTarget, seen map[string]bool) string {
+	for _, name := range target.DependsOn {
+		dependency, ok := s.lookupTargetLocked(name)
+		if !ok || seen[dependency.Name] {
+			continue
+		}
+		seen[dependency.Name] = true
+		if root := s.failingDependencyLocked(dependency, seen); root != "" {
+			return root
+		}
+		_, recovering := s.recovering[dependency.Name]
+		if s.failures[dependency.Name] > 0 || recovering {
+			return dependency.Name
+		}
+	}
+	return ""
+}
+
+// lookupTargetLocked finds a target by name or container name. s.mu must be
+// held.
+func (s *Supervisor) lookupTargetLocked(name string) (monitor.CheckTarget, bool) {
+	for _, target := range s.targets {
+		if target.Name == name || target.ContainerName == name {
+			return target, true
+		}
+	}
+	return monitor.CheckTarget{}, false
+}
+
+// holdForDependency holds the restart of a failing target whose dependency
+// root is failing too
+func (s *Supervisor) holdForDependency(target monitor.CheckTarget, root string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	held, ok := s.held[root]
+	if !ok {
+		held = &heldRestarts{dependents: make(map[string]bool)}
+		s.held[root] = held
+	}
+	held.dependents[target.Name] = true
+}
+
+// announceRootCauses publishes, once per outage, a failing dependency and
+// the dependents whose restarts it holds, instead of an alert per dependent
+func (s *Supervisor) announceRootCauses() {
+	type announcement struct {
+		root       monitor.CheckTarget
+		dependents []string
+	}
+	announcements := []announcement{}
+
+	s.mu.Lock()
+	for name, held := range s.held {
+		if held.announced || len(held.dependents) == 0 {
+			continue
+		}
+		root, ok := s.lookupTargetLocked(name)
+		if !ok {
+			continue
+		}
+		held.announced = true
+		dependents := make([]string, 0, len(held.dependents))
+		for dependent := range held.dependents {
+			dependents = append(dependents, dependent)
+		}
+		sort.Strings(dependents)
+		announcements = append(announcements, announcement{root: root, dependents: dependents})
+	}
+	s.mu.Unlock()
+
+	for _, a := range announcements {
+		message := fmt.Sprintf("%d dependent(s) failing because %s is: %s", len(a.dependents), a.root.Name,
+			strings.Join(a.dependents, ", "))
+		log.Printf("ERROR: Root cause: %s", message)
+		s.publish(events.TypeRootCause, a.root, message)
+	}
+}
+
+// heldByLocked returns the failing dependency a target's restarts wait for,
+// if any. s.mu must be held.
+func (s *Supervisor) heldByLocked(name string) string {
+	for root, held := range s.held {
+		if held.dependents[name] {
+			return root
+		}
+	}
+	return ""
+}
+
+// releaseHeldLocked forgets the held restarts involving a target that's
+// healthy again or no longer monitored: as a root cause its dependents are
+// recovered normally from now on. s.mu must be held.
+func (s *Supervisor) releaseHeldLocked(name string) {
+	delete(s.held, name)
+	for _, held := range s.held {
+		delete(held.dependents, name)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// defaultDesiredStateInterval is how often the leader reconciles the
+// containers with the desired state
+const defaultDesiredStateInterval = 30 * time.Second
+
+// DesiredService declares how many containers of a compose service should
+// exist and be running
+type DesiredService struct {
+	Service    string `yaml:"service"`
+	Replicas   int    `yaml:"replicas"`
+	DockerHost string `yaml:"docker_host"`
+}
+
+// desiredRuntime is the subset of the Docker client used to converge on the
+// desired state
+type desiredRuntime interface {
+	ListContainers(ctx context.Context, labels map[string]string) ([]docker.ContainerSummary, error)
+	CreateContainer(ctx context.Context, name string, spec docker.ContainerSpec) (string, error)
+	StartContainer(ctx context.Context, containerNameOrID string) error
+}
+
+// desiredState converges the containers of the declared services on their
+// desired replicas: stopped ones are started (e.g. after a host reboot),
+// missing ones are created from their compose definition and extra ones are
+// reported, never removed
+type desiredState struct {
+	services    []DesiredService
+	compose     *DockerCompose
+	project     string // compose project to look containers up in; empty means any
+	specProject string // compose project new containers belong to
+	runtimes    func(dockerHost string) (desiredRuntime, error)
+	publisher   events.Publisher
+	auditLog    *audit.Logger
+	myID        int
+	elector     election.Elector
+
+	extras map[string]int // extra containers last reported per service
+}
+
+// newDesiredState checks the declared services against the compose files.
+// It returns nil when no desired state is declared.
+func newDesiredState(services []DesiredService, runtimes func(dockerHost string) (desiredRuntime, error),
+	publisher events.Publisher, auditLog *audit.Logger, myID int, elector election.Elector) (*desiredState, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	paths := composePaths()
+	compose, err := loadComposeServices(paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, desired := range services {
+		service, ok := compose.Services[desired.Service]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("desired service %s is not in the compose files", desired.Service)
+		case desired.Replicas < 0:
+			return nil, fmt.Errorf("desired service %s: replicas must not be negative", desired.Service)
+		case service.ContainerName != "" && desired.Replicas > 1:
+			return nil, fmt.Errorf("desired service %s has a container_name, it can't have %d replicas",
+				desired.Service, desired.Replicas)
+		}
+	}
+
+	project, specProject := composeProject(paths)
+	return &desiredState{
+		services:    services,
+		compose:     compose,
+		project:     project,
+		specProject: specProject,
+		runtimes:    runtimes,
+		publisher:   publisher,
+		auditLog:    auditLog,
+		myID:        myID,
+		elector:     elector,
+		extras:      make(map[string]int),
+	}, nil
+}
+
+// run reconciles every interval while this coordinator is the leader
+func (d *desiredState) run(interval time.Duration) {
+	log.Printf("Reconciling %d service(s) with their desired replicas every %v", len(d.services), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !d.elector.IsLeader() {
+			continue
+		}
+		for _, desired := range d.services {
+			if err := d.reconcile(desired); err != nil {
+				log.Printf("ERROR: Failed to reconcile %s with its desired state: %v", desired.Service, err)
+			}
+		}
+	}
+}
+
+// reconcile converges one service on its desired replicas
+func (d *desiredState) reconcile(desired DesiredService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+	defer cancel()
+
+	runtime, err := d.runtimes(desired.DockerHost)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{docker.LabelComposeService: desired.Service}
+	if d.project != "" {
+		labels[docker.LabelComposeProject] = d.project
+	}
+	containers, err := runtime.ListContainers(ctx, labels)
+	if err != nil {
+		return err
+	}
+
+	// Stopped containers are started before anything is created, so a
+	// rebooted host gets its own containers back. Paused and restarting
+	// ones are left to the supervisor.
+	numbers := make(map[int]bool, len(containers))
+	for _, container := range containers {
+		if n, err := strconv.Atoi(container.Labels[docker.LabelComposeNumber]); err == nil {
+			numbers[n] = true
+		}
+		if container.State != docker.StatusExited && container.State != "created" {
+			continue
+		}
+		err := runtime.StartContainer(ctx, container.ID)
+		d.record(audit.ActionStartReplica, desired, container.Name(), "container stopped", err)
+		if err != nil {
+			log.Printf("ERROR: Failed to start stopped container %s of %s: %v", container.Name(), desired.Service, err)
+			continue
+		}
+		log.Printf("Started stopped container %s of %s", container.Name(), desired.Service)
+		d.publish(events.TypeReplicaStarted, desired, container.Name(), "container was stopped")
+	}
+
+	if extra := len(containers) - desired.Replicas; extra > 0 {
+		if d.extras[desired.Service] != extra {
+			log.Printf("WARNING: %s has %d containers, %d more than desired", desired.Service, len(containers), extra)
+			d.publish(events.TypeExtraReplicas, desired, "",
+				fmt.Sprintf("%d containers, %d desired", len(containers), desired.Replicas))
+		}
+		d.extras[desired.Service] = extra
+		return nil
+	}
+	delete(d.extras, desired.Service)
+
+	for missing := desired.Replicas - len(containers); missing > 0; missing-- {
+		name, number := d.nextName(desired.Service, numbers)
+		numbers[number] = true
+		if err := d.create(ctx, runtime, desired, name, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextName returns the name and number of the next container of a service:
+// its container_name, or <project>-<service>-<n> like compose for the lowest
+// number not taken
+func (d *desiredState) nextName(service string, numbers map[int]bool) (string, int) {
+	number := 1
+	for numbers[number] {
+		number++
+	}
+	if name := d.compose.Services[service].ContainerName; name != "" {
+		return name, number
+	}
+	return fmt.Sprintf("%s-%s-%d", d.specProject, service, number), number
+}
+
+// create creates and starts a missing container of a service
+func (d *desiredState) create(ctx context.Context, runtime desiredRuntime, desired DesiredService, name string, number int) error {
+	spec := d.compose.containerSpec(desired.Service, d.specProject)
+	if spec == nil {
+		return fmt.Errorf("can't create %s: the image of %s can't be determined", name, desired.Service)
+	}
+	spec.Labels[docker.LabelComposeNumber] = strconv.Itoa(number)
+
+	id, err := runtime.CreateContainer(ctx, name, *spec)
+	if err == nil {
+		err = runtime.StartContainer(ctx, id)
+	}
+	d.record(audit.ActionCreateReplica, desired, name, "container missing", err)
+	if err != nil {
+		return err
+	}
+	log.Printf("Created missing container %s of %s", name, desired.Service)
+	d.publish(events.TypeReplicaCreated, desired, name, fmt.Sprintf("%d replicas desired", desired.Replicas))
+	return nil
+}
+
+// record writes an audit entry for a container started or created
+func (d *desiredState) record(action string, desired DesiredService, container, reason string, err error) {
+	entry := audit.Entry{
+		Action:        action,
+		Target:        desired.Service,
+		ContainerName: container,
+		Reason:        reason,
+		LeaderID:      d.elector.GetLeaderID(),
+		Term:          d.elector.Stats().Term,
+		Outcome:       audit.OutcomeSuccess,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	}
+	d.auditLog.Record(entry)
+}
+
+// publish sends a desired-state event about a service
+func (d *desiredState) publish(eventType string, desired DesiredService, container, message string) {
+	d.publisher.Publish(events.Event{
+		Type:          eventType,
+		CoordinatorID: d.myID,
+		LeaderID:      d.elector.GetLeaderID(),
+		Target:        desired.Service,
+		ContainerName: container,
+		Message:       message,
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// runDiscovery rediscovers the targets every interval, so replicas added or
+// removed at runtime (docker compose up --scale) are monitored or dropped.
+// Every coordinator runs it: followers check targets too.
+func runDiscovery(interval time.Duration, lister containerLister, config *FileConfig, supervisor *Supervisor) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		discovered, err := discoverTargets(lister, config)
+		if err != nil {
+			// A partial discovery would drop the targets it missed
+			log.Printf("WARNING: Target discovery failed, keeping the current targets: %v", err)
+			continue
+		}
+		if len(config.Groups) > 0 {
+			if err := applyGroupPolicies(discovered, config.Groups); err != nil {
+				log.Printf("WARNING: Invalid group policy in config file: %v", err)
+			}
+		}
+		supervisor.Refresh(knownCheckTypes(discovered, supervisor.checkers))
+	}
+}
+
+// Refresh reconciles the targets with a fresh discovery: discovered targets
+// not monitored yet are added, and monitored ones that weren't discovered
+// again (their containers are gone) are removed. The other coordinators and
+// workers that registered themselves aren't discovered, so they're kept, as
+// are the settings of targets already monitored. One-shot containers that
+// completed aren't added back. Only the leader publishes the changes, since
+// every coordinator makes them.
+func (s *Supervisor) Refresh(discovered []monitor.CheckTarget) {
+	leader := s.elector.IsLeader()
+	fresh := make(map[string]bool, len(discovered))
+	for _, target := range discovered {
+		fresh[target.Name] = true
+	}
+
+	s.mu.RLock()
+	known := make(map[string]bool, len(s.targets))
+	removed := []monitor.CheckTarget{}
+	for _, target := range s.targets {
+		known[target.Name] = true
+		if !fresh[target.Name] && target.Group != coordinatorGroup && target.CheckType != monitor.CheckTypePush {
+			removed = append(removed, target)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, target := range removed {
+		log.Printf("Target %s is gone, no longer monitoring it", target.Name)
+		s.deregister(target.Name)
+		if leader {
+			s.publish(events.TypeRemoved, target, "container no longer exists")
+		}
+	}
+
+	for _, target := range discovered {
+		if known[target.Name] {
+			continue
+		}
+		s.mu.Lock()
+		completed := s.completed[target.Name]
+		if !completed {
+			s.targets = append(s.targets, target)
+		}
+		s.mu.Unlock()
+		if completed {
+			continue
+		}
+
+		log.Printf("Discovered %s, monitoring it", target.String())
+		if leader {
+			s.publish(events.TypeAdded, target, "container discovered")
+		}
+	}
+}
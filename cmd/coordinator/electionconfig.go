@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+)
+
+// ElectionConfig tunes the Bully election from the config file. Empty fields
+// keep the defaults, and the matching environment variables override them.
+type ElectionConfig struct {
+	Port              int    `yaml:"port"`
+	MessageTimeout    string `yaml:"message_timeout"`
+	HeartbeatInterval string `yaml:"heartbeat_interval"`
+	ElectionTimeout   string `yaml:"election_timeout"`
+	MissedHeartbeats  int    `yaml:"missed_heartbeats"`
+	Stickiness        string `yaml:"stickiness"`
+	StartupTimeout    string `yaml:"startup_timeout"`
+
+	// Priorities maps coordinator IDs to election priorities
+	Priorities map[int]int `yaml:"priorities"`
+
+	// PeerHostTemplate names the replicas, with "{id}" standing for the ID;
+	// PeerHosts overrides it for specific IDs
+	PeerHostTemplate string         `yaml:"peer_host_template"`
+	PeerHosts        map[int]string `yaml:"peer_hosts"`
+
+	// NodeIDs maps node identities (see NODE_ID_PATH) to coordinator IDs,
+	// for replicas started without MY_ID
+	NodeIDs map[string]int `yaml:"node_ids"`
+}
+
+// peerHosts builds the replica hostnames from the config file,
+// PEER_HOST_TEMPLATE and PEER_HOSTS (comma-separated id=host pairs)
+func peerHosts(config ElectionConfig) (election.Peers, error) {
+	peers := election.Peers{
+		Template:  getEnv("PEER_HOST_TEMPLATE", config.PeerHostTemplate),
+		Overrides: make(map[int]string, len(config.PeerHosts)),
+	}
+	if peers.Template == "" {
+		peers.Template = election.DefaultPeerHostTemplate
+	}
+	for id, host := range config.PeerHosts {
+		peers.Overrides[id] = host
+	}
+	for id, host := range parseKeyValues(getEnv("PEER_HOSTS", "")) {
+		parsedID, err := strconv.Atoi(id)
+		if err != nil {
+			return peers, fmt.Errorf("invalid PEER_HOSTS entry %s=%s", id, host)
+		}
+		peers.Overrides[parsedID] = host
+	}
+	return peers, nil
+}
+
+// bullyOptions builds the Bully election options from the defaults, the
+// config file and the environment, in that order of precedence
+func bullyOptions(config ElectionConfig, peers election.Peers) (election.BullyOptions, error) {
+	options := election.DefaultBullyOptions
+	options.Peers = peers
+	if config.Port != 0 {
+		options.Port = strconv.Itoa(config.Port)
+	}
+	if config.MissedHeartbeats != 0 {
+		options.MissedHeartbeats = config.MissedHeartbeats
+	}
+	options.Priorities = make(map[int]int, len(config.Priorities))
+	for id, priority := range config.Priorities {
+		options.Priorities[id] = priority
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"message_timeout", config.MessageTimeout, &options.MessageTimeout},
+		{"heartbeat_interval", config.HeartbeatInterval, &options.HeartbeatInterval},
+		{"election_timeout", config.ElectionTimeout, &options.ElectionTimeout},
+		{"stickiness", config.Stickiness, &options.Stickiness},
+		{"startup_timeout", config.StartupTimeout, &options.StartupTimeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		value, err := parseOptionalDuration(d.value)
+		if err != nil {
+			return options, fmt.Errorf("invalid election %s: %w", d.name, err)
+		}
+		*d.field = value
+	}
+
+	options.Port = getEnv("ELECTION_PORT", options.Port)
+	options.MessageTimeout = getEnvDuration("ELECTION_MESSAGE_TIMEOUT", options.MessageTimeout)
+	options.HeartbeatInterval = getEnvDuration("ELECTION_HEARTBEAT_INTERVAL", options.HeartbeatInterval)
+	options.ElectionTimeout = getEnvDuration("ELECTION_TIMEOUT", options.ElectionTimeout)
+	options.MissedHeartbeats = getEnvInt("ELECTION_MISSED_HEARTBEATS", options.MissedHeartbeats)
+	options.Stickiness = getEnvDuration("LEADER_STICKINESS", options.Stickiness)
+	options.StartupTimeout = getEnvDuration("ELECTION_STARTUP_TIMEOUT", options.StartupTimeout)
+	options.Limits = listenerLimits()
+
+	// ELECTION_PRIORITIES is a comma-separated list of id=priority pairs
+	for id, priority := range parseKeyValues(getEnv("ELECTION_PRIORITIES", "")) {
+		parsedID, idErr := strconv.Atoi(id)
+		parsedPriority, priorityErr := strconv.Atoi(priority)
+		if idErr != nil || priorityErr != nil {
+			return options, fmt.Errorf("invalid ELECTION_PRIORITIES entry %s=%s", id, priority)
+		}
+		options.Priorities[parsedID] = parsedPriority
+	}
+
+	return options, options.Validate()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// labelMonitor lets a service opt out of monitoring with coffeeshop.monitor=false
+const labelMonitor = "coffeeshop.monitor"
+
+// ServiceFilter decides which discovered compose services are managed.
+// Patterns are either shell globs matched against the service and container
+// names (e.g. "client-*") or label selectors ("label:key" or "label:key=value").
+type ServiceFilter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Allows reports whether a service should be monitored. When include patterns
+// are present a service must match at least one; it must never match an
+// exclude pattern.
+func (f ServiceFilter) Allows(serviceName, containerName string, labels Labels) bool {
+	if labels[labelMonitor] == "false" {
+		return false
+	}
+
+	if len(f.Include) > 0 && !matchAny(f.Include, serviceName, containerName, labels) {
+		return false
+	}
+
+	return !matchAny(f.Exclude, serviceName, containerName, labels)
+}
+
+// merge combines two filters
+func (f ServiceFilter) merge(other ServiceFilter) ServiceFilter {
+	return ServiceFilter{
+		Include: append(append([]string{}, f.Include...), other.Include...),
+		Exclude: append(append([]string{}, f.Exclude...), other.Exclude...),
+	}
+}
+
+// matchAny reports whether any pattern matches the service
+func matchAny(patterns []string, serviceName, containerName string, labels Labels) bool {
+	for _, pattern := range patterns {
+		if selector, ok := strings.CutPrefix(pattern, "label:"); ok {
+			key, value, hasValue := strings.Cut(selector, "=")
+			if actual, present := labels[key]; present && (!hasValue || actual == value) {
+				return true
+			}
+			continue
+		}
+
+		for _, name := range []string{serviceName, containerName} {
+			if name == "" {
+				continue
+			}
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
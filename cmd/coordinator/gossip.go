@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/gossip"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// gossipPort is the UDP port of the gossip failure detector
+const gossipPort = "12349"
+
+// startGossip starts the gossip failure detector. Its members are the
+// coordinators plus GOSSIP_MEMBERS (comma-separated name=host:port pairs),
+// for workers that run the protocol too.
+func startGossip(myID, totalReplicas int, peers election.Peers, bind string) (*gossip.Detector, error) {
+	members := parseKeyValues(getEnv("GOSSIP_MEMBERS", ""))
+	for i := 1; i <= totalReplicas; i++ {
+		if i != myID {
+			name := peers.Host(i)
+			members[name] = net.JoinHostPort(peers.Address(i), gossipPort)
+		}
+	}
+
+	defaults := gossip.DefaultConfig()
+	detector, err := gossip.New(gossip.Config{
+		Name:             peers.Host(myID),
+		BindAddress:      net.JoinHostPort(bind, gossipPort),
+		Peers:            members,
+		ProbeInterval:    getEnvDuration("GOSSIP_PROBE_INTERVAL", defaults.ProbeInterval),
+		ProbeTimeout:     defaults.ProbeTimeout,
+		SuspicionTimeout: getEnvDuration("GOSSIP_SUSPICION_TIMEOUT", defaults.SuspicionTimeout),
+		IndirectProbes:   defaults.IndirectProbes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	detector.Start()
+	return detector, nil
+}
+
+// useGossipVerdicts switches the targets that are gossip members to the
+// gossip checker: the group detects their failures and the leader only
+// executes the recovery
+func useGossipVerdicts(targets []monitor.CheckTarget, detector *gossip.Detector) {
+	for i := range targets {
+		if detector.Knows(targets[i].Host) {
+			targets[i].CheckType = monitor.CheckTypeGossip
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// coordinatorGroup is the group of the other coordinators
+const coordinatorGroup = "coordinators"
+
+// GroupPolicy holds the settings shared by a group of targets (filters,
+// joiners, group-bys, ...). They're defaults: a target's own labels or
+// static settings win.
+type GroupPolicy struct {
+	Interval         string `yaml:"interval"`
+	FailureThreshold int    `yaml:"failure_threshold"`
+	MaxRestarts      int    `yaml:"max_restarts"`
+	StopTimeout      string `yaml:"stop_timeout"`
+	Recovery         string `yaml:"recovery"`
+
+	// RestartConcurrency caps how many of the group's targets are recovering
+	// (restarted but not healthy yet) at once, so the pipeline never loses a
+	// whole stage; zero means unlimited
+	RestartConcurrency int `yaml:"restart_concurrency"`
+}
+
+// applyGroupPolicies fills in the unset settings of every grouped target
+// from its group's policy
+func applyGroupPolicies(targets []monitor.CheckTarget, groups map[string]GroupPolicy) error {
+	type parsedPolicy struct {
+		GroupPolicy
+		interval    time.Duration
+		stopTimeout time.Duration
+	}
+
+	parsed := make(map[string]parsedPolicy, len(groups))
+	for name, policy := range groups {
+		p := parsedPolicy{GroupPolicy: policy}
+		var err error
+		if p.interval, err = parseOptionalDuration(policy.Interval); err != nil {
+			return fmt.Errorf("group %s: invalid interval: %w", name, err)
+		}
+		if p.stopTimeout, err = parseOptionalDuration(policy.StopTimeout); err != nil {
+			return fmt.Errorf("group %s: invalid stop_timeout: %w", name, err)
+		}
+		if policy.FailureThreshold < 0 || policy.MaxRestarts < 0 || policy.RestartConcurrency < 0 {
+			return fmt.Errorf("group %s: negative failure_threshold, max_restarts or restart_concurrency", name)
+		}
+		parsed[name] = p
+	}
+
+	for i := range targets {
+		target := &targets[i]
+		policy, ok := parsed[target.Group]
+		if !ok {
+			continue
+		}
+
+		if target.Interval == 0 {
+			target.Interval = policy.interval
+		}
+		if target.FailureThreshold == 0 {
+			target.FailureThreshold = policy.FailureThreshold
+		}
+		if target.MaxRestarts == 0 {
+			target.MaxRestarts = policy.MaxRestarts
+		}
+		if target.StopTimeout == 0 {
+			target.StopTimeout = policy.stopTimeout
+		}
+		if target.Recovery == "" {
+			target.Recovery = policy.Recovery
+		}
+		target.GroupRestarts = policy.RestartConcurrency
+	}
+	return nil
+}
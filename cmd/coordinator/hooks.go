@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+)
+
+// runHook runs a target's hook for a stage. Failures are logged and
+// published as node.hook_failed; the error is only returned for blocking
+// hooks, whose failure fails the recovery.
+func (s *Supervisor) runHook(ctx context.Context, target monitor.CheckTarget, stage string) error {
+	hook := recovery.HookFor(target, stage)
+	if hook == nil {
+		return nil
+	}
+
+	log.Printf("Running %s hook of %s", stage, target.Name)
+	err := s.recoveries.RunHook(ctx, target, stage)
+	if err == nil {
+		return nil
+	}
+
+	s.publish(events.TypeHookFailed, target, err.Error())
+	if hook.Blocking {
+		log.Printf("ERROR: %v", err)
+		return err
+	}
+	log.Printf("WARNING: %v (non-blocking, continuing)", err)
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// minHostTargets is how many containers a host needs for all of them
+// failing at once to mean the host is down rather than the containers
+const minHostTargets = 2
+
+// localHostName names the local Docker daemon in host events
+const localHostName = "local"
+
+// hostOutage is a Docker host found down
+type hostOutage struct {
+	since     time.Time
+	reason    string
+	targets   []string
+	daemonErr bool // the daemon is unreachable, so nothing on the host can be recovered
+}
+
+// CheckHosts looks for Docker hosts that are down as a whole: their daemon
+// doesn't answer, or every container on them fails its checks at once. A
+// host going down or coming back is published once (host.down, host.up)
+// with the targets on it, instead of a node.down per container.
+func (s *Supervisor) CheckHosts() {
+	if s.containers == nil {
+		return
+	}
+
+	hosts := make(map[string][]string)
+	for _, target := range s.snapshotTargets() {
+		if target.ContainerName != "" && target.SwarmService == "" {
+			hosts[target.DockerHost] = append(hosts[target.DockerHost], target.Name)
+		}
+	}
+
+	for host, names := range hosts {
+		sort.Strings(names)
+		outage := hostOutage{since: time.Now(), targets: names}
+		if err := s.pingHost(host); err != nil {
+			outage.reason = "Docker daemon unreachable: " + err.Error()
+			outage.daemonErr = true
+		} else if len(names) >= minHostTargets && s.allFailing(names) {
+			outage.reason = fmt.Sprintf("all %d targets failing at once", len(names))
+		}
+
+		s.mu.Lock()
+		previous, wasDown := s.hostsDown[host]
+		switch {
+		case outage.reason != "" && !wasDown:
+			s.hostsDown[host] = outage
+		case outage.reason != "":
+			previous.reason, previous.daemonErr, previous.targets = outage.reason, outage.daemonErr, names
+			s.hostsDown[host] = previous
+		case wasDown:
+			delete(s.hostsDown, host)
+		}
+		s.mu.Unlock()
+
+		switch {
+		case outage.reason != "" && !wasDown:
+			s.publishHost(events.TypeHostDown, host, outage)
+		case outage.reason == "" && wasDown:
+			previous.reason = fmt.Sprintf("back after %v", time.Since(previous.since).Round(time.Second))
+			s.publishHost(events.TypeHostUp, host, previous)
+		}
+	}
+}
+
+// hostDown returns the outage of the Docker host a failing target runs on,
+// if it's down. A host not known to be down is pinged, so an unreachable
+// daemon is noticed before its containers are restarted in vain.
+func (s *Supervisor) hostDown(target monitor.CheckTarget) (hostOutage, bool) {
+	if target.ContainerName == "" || target.SwarmService != "" || s.containers == nil {
+		return hostOutage{}, false
+	}
+
+	s.mu.RLock()
+	outage, down := s.hostsDown[target.DockerHost]
+	s.mu.RUnlock()
+	if down {
+		return outage, true
+	}
+
+	err := s.pingHost(target.DockerHost)
+	if err == nil {
+		return hostOutage{}, false
+	}
+	outage = hostOutage{since: time.Now(), reason: "Docker daemon unreachable: " + err.Error(), daemonErr: true}
+	for _, other := range s.snapshotTargets() {
+		if other.DockerHost == target.DockerHost && other.ContainerName != "" && other.SwarmService == "" {
+			outage.targets = append(outage.targets, other.Name)
+		}
+	}
+	sort.Strings(outage.targets)
+
+	s.mu.Lock()
+	_, down = s.hostsDown[target.DockerHost]
+	if !down {
+		s.hostsDown[target.DockerHost] = outage
+	}
+	s.mu.Unlock()
+	if !down {
+		s.publishHost(events.TypeHostDown, target.DockerHost, outage)
+	}
+	return outage, true
+}
+
+// pingHost checks that a Docker host's daemon answers
+func (s *Supervisor) pingHost(host string) error {
+	runtime, err := s.containers(host)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	return runtime.Ping(ctx)
+}
+
+// allFailing reports whether every named target failed its last check
+func (s *Supervisor) allFailing(names []string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range names {
+		if s.failures[name] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// publishHost logs and publishes a host going down or coming back
+func (s *Supervisor) publishHost(eventType, host string, outage hostOutage) {
+	if host == "" {
+		host = localHostName
+	}
+	message := fmt.Sprintf("%s; targets: %s", outage.reason, strings.Join(outage.targets, ", "))
+	if eventType == events.TypeHostDown {
+		log.Printf("ERROR: Docker host %s is down (%s)", host, message)
+	} else {
+		log.Printf("Docker host %s is up again (%s)", host, message)
+	}
+
+	s.publisher.Publish(events.Event{
+		Type:          eventType,
+		CoordinatorID: s.myID,
+		LeaderID:      s.elector.GetLeaderID(),
+		Target:        host,
+		Message:       message,
+	})
+}
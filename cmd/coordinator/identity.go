@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+)
+
+// nodeIdentity returns this replica's node identity and its numeric ID.
+// The identity is NODE_ID or, without it, the one saved at NODE_ID_PATH,
+// created on the first start; with neither it's a new one every start. The
+// numeric ID is MY_ID or, when MY_ID isn't set, the one node_ids/NODE_IDS
+// maps the identity to, so replicas recreated with the same volume keep
+// their place in the election.
+func nodeIdentity(config ElectionConfig) (string, int, error) {
+	nodeID := strings.ToLower(getEnv("NODE_ID", ""))
+	switch path := getEnv("NODE_ID_PATH", ""); {
+	case nodeID != "":
+		if !election.ValidNodeID(nodeID) {
+			return "", 0, fmt.Errorf("invalid NODE_ID %q (expected a UUID)", nodeID)
+		}
+	case path != "":
+		loaded, err := election.LoadNodeID(path)
+		if err != nil {
+			return "", 0, err
+		}
+		nodeID = loaded
+	default:
+		nodeID = election.NewNodeID()
+	}
+
+	mapping, err := nodeIDs(config)
+	if err != nil {
+		return "", 0, err
+	}
+	mapped, isMapped := mapping[nodeID]
+	value := getEnv("MY_ID", "")
+	if value == "" {
+		if isMapped {
+			return nodeID, mapped, nil
+		}
+		if len(mapping) > 0 {
+			return "", 0, fmt.Errorf("node %s isn't in node_ids and MY_ID isn't set", nodeID)
+		}
+		value = "1"
+	}
+	myID, err := strconv.Atoi(value)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid MY_ID: %w", err)
+	}
+	if isMapped && mapped != myID {
+		return "", 0, fmt.Errorf("MY_ID %d doesn't match node_ids, which gives node %s ID %d", myID, nodeID, mapped)
+	}
+	return nodeID, myID, nil
+}
+
+// nodeIDs maps node identities to numeric IDs, from the config file and
+// NODE_IDS (comma-separated identity=id pairs)
+func nodeIDs(config ElectionConfig) (map[string]int, error) {
+	mapping := make(map[string]int, len(config.NodeIDs))
+	for nodeID, id := range config.NodeIDs {
+		mapping[strings.ToLower(nodeID)] = id
+	}
+	for nodeID, id := range parseKeyValues(getEnv("NODE_IDS", "")) {
+		parsedID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NODE_IDS entry %s=%s", nodeID, id)
+		}
+		mapping[strings.ToLower(nodeID)] = parsedID
+	}
+
+	owners := make(map[int]string, len(mapping))
+	for nodeID, id := range mapping {
+		if !election.ValidNodeID(nodeID) {
+			return nil, fmt.Errorf("invalid node identity %q in node_ids (expected a UUID)", nodeID)
+		}
+		if other, ok := owners[id]; ok {
+			return nil, fmt.Errorf("nodes %s and %s are both mapped to ID %d", other, nodeID, id)
+		}
+		owners[id] = nodeID
+	}
+	return mapping, nil
+}
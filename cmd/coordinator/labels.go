@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+	"gopkg.in/yaml.v3"
+)
+
+// Compose labels that configure how a service is monitored
+const (
+	labelHealthPort     = "coffeeshop.health.port"
+	labelHealthType     = "coffeeshop.health.type"
+	labelHealthInterval = "coffeeshop.health.interval"
+	labelHealthPath     = "coffeeshop.health.path"
+	labelHealthCommand  = "coffeeshop.health.command"
+	labelClientPort     = "coffeeshop.health.client_port"
+	labelHello          = "coffeeshop.health.hello"
+	labelHelloReply     = "coffeeshop.health.hello_reply"
+	labelHealthFailures = "coffeeshop.health.failure_threshold"
+	labelGroup          = "coffeeshop.group"
+	labelRestartMax     = "coffeeshop.restart.max"
+	labelStopTimeout    = "coffeeshop.restart.stop_timeout"
+	labelRestartLimit   = "coffeeshop.restart.deadline"
+	labelDrainTimeout   = "coffeeshop.restart.drain_timeout"
+	labelVerifyTimeout  = "coffeeshop.restart.verify_timeout"
+	labelCritical       = "coffeeshop.restart.critical"
+	labelProtected      = "coffeeshop.restart.protected"
+	labelConfirmHook    = "coffeeshop.restart.confirm_webhook"
+	labelConfirmTimeout = "coffeeshop.restart.confirm_timeout"
+	labelConfirmDefault = "coffeeshop.restart.confirm_default"
+	labelRecovery       = "coffeeshop.recovery"
+	labelRecoveryCmd    = "coffeeshop.recovery.command"
+	labelRecoveryHook   = "coffeeshop.recovery.webhook"
+	labelDockerHost     = "coffeeshop.docker.host"
+	labelOneShotCleanup = "coffeeshop.oneshot.cleanup"
+	labelPausedAction   = "coffeeshop.paused.action"
+	labelDependsOn      = "coffeeshop.depends_on"
+
+	// Hooks take the stage ("pre_restart" or "post_recovery") after this
+	// prefix, then ".command", ".webhook" or ".blocking"
+	labelHookPrefix = "coffeeshop.hook."
+
+	// Plugin parameters take their name after this prefix
+	labelPluginPrefix = "coffeeshop.plugin."
+)
+
+// Labels holds compose labels. Compose accepts both a mapping and a list of
+// "key=value" strings, so both forms are decoded into a map.
+type Labels map[string]string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *Labels) UnmarshalYAML(value *yaml.Node) error {
+	labels := Labels{}
+
+	switch value.Kind {
+	case yaml.MappingNode:
+		var m map[string]string
+		if err := value.Decode(&m); err != nil {
+			return err
+		}
+		for k, v := range m {
+			labels[k] = v
+		}
+	case yaml.SequenceNode:
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		for _, item := range list {
+			k, v, _ := strings.Cut(item, "=")
+			labels[k] = v
+		}
+	default:
+		return fmt.Errorf("labels must be a mapping or a list, got %v", value.Tag)
+	}
+
+	*l = labels
+	return nil
+}
+
+// applyLabels overrides the target's monitoring settings with the ones
+// declared in the service labels
+func applyLabels(target *monitor.CheckTarget, labels Labels) error {
+	if port, ok := labels[labelHealthPort]; ok {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", labelHealthPort, port, err)
+		}
+		target.Port = port
+	}
+
+	if checkType, ok := labels[labelHealthType]; ok {
+		target.CheckType = checkType
+	}
+
+	if interval, ok := labels[labelHealthInterval]; ok {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q", labelHealthInterval, interval)
+		}
+		target.Interval = d
+	}
+
+	if path, ok := labels[labelHealthPath]; ok {
+		target.Path = path
+	}
+
+	if command, ok := labels[labelHealthCommand]; ok {
+		target.ExecCommand = strings.Fields(command)
+	}
+
+	if port, ok := labels[labelClientPort]; ok {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", labelClientPort, port, err)
+		}
+		target.ClientPort = port
+	}
+	if hello, ok := labels[labelHello]; ok {
+		target.Hello = hello
+	}
+	if reply, ok := labels[labelHelloReply]; ok {
+		target.HelloReply = reply
+	}
+	if target.CheckType == monitor.CheckTypeGateway && target.ClientPort == "" {
+		return fmt.Errorf("%s gateway needs %s", labelHealthType, labelClientPort)
+	}
+
+	if value, ok := labels[labelHealthFailures]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q", labelHealthFailures, value)
+		}
+		target.FailureThreshold = n
+	}
+
+	if group, ok := labels[labelGroup]; ok {
+		target.Group = group
+	}
+
+	if max, ok := labels[labelRestartMax]; ok {
+		n, err := strconv.Atoi(max)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q", labelRestartMax, max)
+		}
+		target.MaxRestarts = n
+	}
+
+	if value, ok := labels[labelStopTimeout]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q", labelStopTimeout, value)
+		}
+		target.StopTimeout = d
+	}
+
+	if value, ok := labels[labelRestartLimit]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q", labelRestartLimit, value)
+		}
+		target.RestartDeadline = d
+	}
+
+	if value, ok := labels[labelDrainTimeout]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q", labelDrainTimeout, value)
+		}
+		target.DrainTimeout = d
+	}
+
+	if value, ok := labels[labelVerifyTimeout]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q", labelVerifyTimeout, value)
+		}
+		target.VerifyTimeout = d
+	}
+
+	if value, ok := labels[labelCritical]; ok {
+		critical, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", labelCritical, value)
+		}
+		target.Critical = critical
+	}
+
+	if value, ok := labels[labelProtected]; ok {
+		protected, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", labelProtected, value)
+		}
+		target.Protected = protected
+	}
+
+	if url, ok := labels[labelConfirmHook]; ok {
+		target.ConfirmWebhook = url
+	}
+
+	if value, ok := labels[labelConfirmTimeout]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q", labelConfirmTimeout, value)
+		}
+		target.ConfirmTimeout = d
+	}
+
+	if value, ok := labels[labelConfirmDefault]; ok {
+		if !validConfirmDefault(value) {
+			return fmt.Errorf("invalid %s %q", labelConfirmDefault, value)
+		}
+		target.ConfirmDefault = value
+	}
+
+	if action, ok := labels[labelRecovery]; ok {
+		target.Recovery = action
+	}
+
+	if command, ok := labels[labelRecoveryCmd]; ok {
+		target.RecoveryCommand = strings.Fields(command)
+	}
+
+	if url, ok := labels[labelRecoveryHook]; ok {
+		target.WebhookURL = url
+	}
+
+	if host, ok := labels[labelDockerHost]; ok {
+		target.DockerHost = host
+	}
+
+	if policy, ok := labels[labelOneShotCleanup]; ok {
+		if !validOneShotCleanup(policy) {
+			return fmt.Errorf("invalid %s %q", labelOneShotCleanup, policy)
+		}
+		target.OneShotCleanup = policy
+	}
+
+	if action, ok := labels[labelPausedAction]; ok {
+		if !validPausedAction(action) {
+			return fmt.Errorf("invalid %s %q", labelPausedAction, action)
+		}
+		target.PausedAction = action
+	}
+
+	if dependencies, ok := labels[labelDependsOn]; ok {
+		target.DependsOn = append(target.DependsOn, splitList(dependencies)...)
+	}
+
+	for key, value := range labels {
+		if param, ok := strings.CutPrefix(key, labelPluginPrefix); ok && param != "" {
+			if target.PluginParams == nil {
+				target.PluginParams = make(map[string]string)
+			}
+			target.PluginParams[param] = value
+		}
+	}
+
+	var err error
+	if target.PreRestart, err = parseHookLabels(labels, recovery.HookPreRestart, target.PreRestart); err != nil {
+		return err
+	}
+	if target.PostRecovery, err = parseHookLabels(labels, recovery.HookPostRecovery, target.PostRecovery); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseHookLabels reads the labels of a stage's hook. Without any, the hook
+// is left as it was.
+func parseHookLabels(labels Labels, stage string, hook *monitor.Hook) (*monitor.Hook, error) {
+	prefix := labelHookPrefix + stage
+	command, hasCommand := labels[prefix+".command"]
+	webhook, hasWebhook := labels[prefix+".webhook"]
+	blocking, hasBlocking := labels[prefix+".blocking"]
+	if !hasCommand && !hasWebhook && !hasBlocking {
+		return hook, nil
+	}
+
+	parsed := &monitor.Hook{}
+	if hook != nil {
+		*parsed = *hook
+	}
+	if hasCommand {
+		parsed.Command = strings.Fields(command)
+	}
+	if hasWebhook {
+		parsed.Webhook = webhook
+	}
+	if hasBlocking {
+		b, err := strconv.ParseBool(blocking)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s.blocking %q", prefix, blocking)
+		}
+		parsed.Blocking = b
+	}
+	return parsed, nil
+}
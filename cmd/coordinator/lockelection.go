@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+)
+
+const (
+	defaultLockKey = "coffeeshop/coordinator/leader"
+	// Consul doesn't accept session TTLs under 10s
+	defaultLockTTL = 10 * time.Second
+)
+
+// newLockElector creates an elector on the external lock store selected by
+// backend, configured from LOCK_URL, LOCK_KEY and LOCK_TTL
+func newLockElector(backend string, myID int) (election.Elector, error) {
+	address := getEnv("LOCK_URL", "")
+	if address == "" {
+		return nil, fmt.Errorf("LOCK_URL is required for the %s backend", backend)
+	}
+	key := getEnv("LOCK_KEY", defaultLockKey)
+	ttl := getEnvDuration("LOCK_TTL", defaultLockTTL)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("LOCK_TTL must be positive")
+	}
+
+	var lock election.Lock
+	switch backend {
+	case election.BackendConsul:
+		lock = election.NewConsulLock(address, key, ttl)
+	case election.BackendRedis:
+		redisLock, err := election.NewRedisLock(address, key, ttl)
+		if err != nil {
+			return nil, err
+		}
+		lock = redisLock
+	}
+	return election.NewLockElector(myID, lock, ttl), nil
+}
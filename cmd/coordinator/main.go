@@ -1,58 +1,512 @@
 package main
 
 import (
-	"io"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
 	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
 	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
 	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/scaling"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/statesync"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/supervise"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/tracing"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/vantage"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/connlimit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/healthserver"
 )
 
 const (
-	checkInterval = 5 * time.Second
-	healthPort    = "12346"
+	checkInterval   = 5 * time.Second
+	schedulerTick   = 250 * time.Millisecond // how often due targets are looked for
+	scheduleJitter  = 0.1                    // fraction of its interval a target's next check may move
+	checkTimeout    = 4 * time.Second
+	recoveryTimeout = 60 * time.Second
+	vantagePort     = "12348"
+
+	// peerVerdictTTL is how long a follower's verdict is taken into account
+	peerVerdictTTL = 3 * checkInterval
+
+	// leadershipPollInterval is how often running recovery actions check
+	// that this coordinator is still the leader
+	leadershipPollInterval = 500 * time.Millisecond
+
+	// defaultVerifyTimeout is how long a recovered target has to pass a
+	// check; verifyInterval is how often it's checked meanwhile
+	defaultVerifyTimeout = 30 * time.Second
+	verifyInterval       = time.Second
+
+	// recoveringTimeout is how long a restarted target counts against its
+	// group's restart limit while it isn't healthy again
+	recoveringTimeout = 2 * time.Minute
+
+	defaultAdminPort    = "12347"
+	defaultBusyTimeout  = 30 * time.Minute
+	defaultHistorySize  = 720 // one hour of results at checkInterval
+	defaultMTTRSamples  = 100 // recovery times kept per group for percentiles
+	historySaveInterval = time.Minute
+
+	// Adaptive check intervals: suspects are checked every
+	// defaultFastInterval, targets healthy for defaultStableAfter every
+	// defaultSlowInterval
+	defaultFastInterval = time.Second
+	defaultSlowInterval = 30 * time.Second
+	defaultStableAfter  = 10 * time.Minute
+
+	// defaultLogSummaryInterval is how often a target that stays down is
+	// logged again
+	defaultLogSummaryInterval = time.Minute
+
+	// defaultPartitionThreshold is the percentage of unreachable targets
+	// that makes the leader think it's partitioned; the heuristic is
+	// opt-in, so zero disables it
+	defaultPartitionThreshold = 0
+
+	// missedHeartbeats is how many pushed heartbeats a registered worker can
+	// miss before it's considered down
+	missedHeartbeats = 3
+)
+
+// Default connection limits of the health and election listeners, well
+// above what the coordinators and workers need
+const (
+	defaultMaxConnections = 128
+	defaultRatePerIP      = 20
+	defaultBurstPerIP     = 40
 )
 
+// defaultTCPKeepAlive is how long an outbound connection sits idle before
+// keepalive probes start, and the time between them: a worker that crashed
+// mid-connection is noticed in seconds rather than at Go's 15s default
+const defaultTCPKeepAlive = 5 * time.Second
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap" {
+		err := runBootstrap(os.Args[2:], os.Stdout, os.Stderr)
+		if err != nil && err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		if err := runValidate(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Println("Starting Coordinator Service...")
 
-	// Read environment variables for election
-	myID, err := strconv.Atoi(getEnv("MY_ID", "1"))
+	totalReplicas, err := strconv.Atoi(getEnv("TOTAL_REPLICAS", "3"))
 	if err != nil {
-		log.Fatalf("Invalid MY_ID: %v", err)
+		log.Fatalf("Invalid TOTAL_REPLICAS: %v", err)
 	}
 
-	totalReplicas, err := strconv.Atoi(getEnv("TOTAL_REPLICAS", "3"))
+	// Load the coordinator's own config file (optional)
+	config := loadConfig()
+
+	// The profile sets the defaults of the settings below
+	profile, err := selectProfile(config)
 	if err != nil {
-		log.Fatalf("Invalid TOTAL_REPLICAS: %v", err)
+		log.Fatalf("Invalid profile: %v", err)
+	}
+	if profile != "" {
+		log.Printf("Using profile %s", profile)
 	}
 
-	// Start health server for cross-monitoring
-	go startHealthServer(healthPort)
+	// The node identity is stable across recreations of the replica; the
+	// election's numeric ID is MY_ID or mapped from it
+	nodeID, myID, err := nodeIdentity(config.Election)
+	if err != nil {
+		log.Fatalf("Invalid node identity: %v", err)
+	}
+	log.Printf("Node identity: %s (MY_ID=%d)", nodeID, myID)
 
-	// Initialize Bully election with heartbeats
-	elector := election.NewCoordinator(myID, totalReplicas)
-	elector.Start()
+	// Every outbound connection gets the keepalive and dial settings
+	dialer.Configure(dialerOptions())
+
+	// Listeners bind to BIND_ADDR/BIND_INTERFACE, or every interface; the
+	// other coordinators dial ADVERTISE_ADDR, or the hostname
+	bind, err := bindAddress()
+	if err != nil {
+		log.Fatalf("Invalid bind address: %v", err)
+	}
+	advertise, err := advertiseAddress()
+	if err != nil {
+		log.Fatalf("Invalid advertise address: %v", err)
+	}
+
+	// Start health server for cross-monitoring, on every configured address
+	healthServer := healthserver.New("", healthserver.WithLimits(listenerLimits()))
+	healthAddresses := splitList(getEnv("HEALTH_LISTEN", ""))
+	if len(healthAddresses) == 0 {
+		healthAddresses = []string{net.JoinHostPort(bind, coordinatorHealthPort())}
+	}
+	for _, address := range healthAddresses {
+		address := address
+		go supervise.Run("Health server on "+address, func() error {
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+			log.Printf("Health server listening on %s", address)
+			if err := healthServer.Serve(listener); err != healthserver.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
+	}
+	defer healthServer.Shutdown(context.Background())
 
 	// Initialize Docker client
-	dockerClient, err := docker.NewClient()
+	dockerOptions := dockerOptionsFromEnv()
+	dockerClient, err := docker.NewClientWithOptions(dockerOptions)
 	if err != nil {
 		log.Fatalf("Failed to initialize Docker client: %v", err)
 	}
-	defer dockerClient.Close()
 
-	// Initialize health checker
-	healthChecker := monitor.NewHealthChecker()
+	// Replica hostnames, used for every kind of inter-coordinator traffic.
+	// With PEER_NETWORK they're dialed at their IP on that Docker network.
+	peers, err := peerHosts(config.Election)
+	if err != nil {
+		log.Fatalf("Invalid peer hosts: %v", err)
+	}
+	if network := getEnv("PEER_NETWORK", ""); network != "" {
+		peers.Resolve = newNetworkResolver(network, dockerClient).Resolve
+	}
+	// Addresses the replicas advertise in the election handshake come first
+	peers.Advertised = election.NewAdvertisedAddresses()
+
+	// Initialize leader election: Bully with heartbeats, or Raft
+	var elector election.Elector
+	switch backend := getEnv("ELECTION_BACKEND", election.BackendBully); backend {
+	case election.BackendBully:
+		options, err := bullyOptions(config.Election, peers)
+		if err != nil {
+			log.Fatalf("Invalid election settings: %v", err)
+		}
+		options.BindAddress = bind
+		options.NodeID = nodeID
+		options.AdvertiseAddress = advertise
+		elector, err = election.NewCoordinatorWithOptions(myID, totalReplicas, options)
+		if err != nil {
+			log.Fatalf("Failed to initialize Bully election: %v", err)
+		}
+	case election.BackendRaft:
+		elector, err = election.NewRaftElector(myID, totalReplicas, peers, bind, advertise, raftDataDir())
+		if err != nil {
+			log.Fatalf("Failed to initialize Raft election: %v", err)
+		}
+	case election.BackendConsul, election.BackendRedis:
+		elector, err = newLockElector(backend, myID)
+		if err != nil {
+			log.Fatalf("Failed to initialize %s election: %v", backend, err)
+		}
+	default:
+		log.Fatalf("Invalid ELECTION_BACKEND %q (expected %s, %s, %s or %s)", backend, election.BackendBully,
+			election.BackendRaft, election.BackendConsul, election.BackendRedis)
+	}
+	elector.Start()
+
+	dockerPool := docker.NewPool(dockerClient, dockerHosts(config), dockerOptions)
+	defer dockerPool.Close()
+
+	// In strict mode the configuration is validated like "coordinator
+	// validate" does, and any problem stops the startup
+	if getEnv("STRICT_CONFIG", "false") == "true" {
+		if _, problems := validateConfig(myID, totalReplicas, config, dockerPool); len(problems) > 0 {
+			for _, problem := range problems {
+				log.Printf("ERROR: %s", problem)
+			}
+			log.Fatalf("Invalid configuration (STRICT_CONFIG): %d problem(s) found", len(problems))
+		}
+	}
+
+	// Initialize trace export (optional)
+	if endpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""); endpoint != "" {
+		exporter := tracing.Enable(endpoint, getEnv("OTEL_SERVICE_NAME", fmt.Sprintf("coordinator-%d", myID)))
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := exporter.Shutdown(ctx); err != nil {
+				log.Printf("WARNING: Failed to flush traces: %v", err)
+			}
+		}()
+	}
+
+	// Initialize audit log (optional); with AUDIT_EVENTS alone, entries are
+	// only shipped as events
+	var auditLog *audit.Logger
+	auditEvents := getEnv("AUDIT_EVENTS", "false") == "true"
+	if auditPath := getEnv("AUDIT_LOG_PATH", ""); auditPath != "" {
+		auditLog, err = audit.NewLogger(auditPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize audit log: %v", err)
+		}
+		defer auditLog.Close()
+	} else if auditEvents {
+		auditLog = audit.NewShippingLogger()
+	}
+
+	// Initialize event publisher (optional)
+	var publisher events.Publisher = events.NopPublisher{}
+	if amqpURL := getEnv("RABBITMQ_URL", ""); amqpURL != "" {
+		amqpPublisher, err := events.NewAMQPPublisher(amqpURL)
+		if err != nil {
+			log.Fatalf("Failed to initialize event publisher: %v", err)
+		}
+		publisher = amqpPublisher
+	}
+
+	// In-process subsystems subscribe to check results and events here
+	bus := stream.NewBus()
+	publisher = events.MultiPublisher{publisher, bus}
+	defer publisher.Close()
+	if auditEvents {
+		auditLog.Ship(publisher, myID)
+	}
+
+	// Initialize health checkers and recovery actions
+	heartbeats := monitor.NewPushChecker()
+	checkers := newCheckers(dockerPool, heartbeats)
+	recoveries := newRecoveries(dockerPool, config)
 
 	// Get all monitored nodes dynamically (workers + other coordinators)
-	targets := getMonitoredNodes(myID, totalReplicas)
+	targets := getMonitoredNodes(myID, totalReplicas, peers, dockerClient, config)
+
+	// Optional SWIM-style failure detection among the coordinators
+	if getEnv("GOSSIP_ENABLED", "false") == "true" {
+		detector, err := startGossip(myID, totalReplicas, peers, bind)
+		if err != nil {
+			log.Fatalf("Failed to start gossip failure detector: %v", err)
+		}
+		defer detector.Close()
+		checkers.Register(monitor.CheckTypeGossip, monitor.NewGossipChecker(detector))
+		useGossipVerdicts(targets, detector)
+	}
+
+	// External checkers and recovery actions from the config file
+	if err := registerPlugins(config.Plugins, checkers, recoveries); err != nil {
+		log.Fatalf("Invalid plugins: %v", err)
+	}
+	targets = knownCheckTypes(targets, checkers)
+
+	scaler := newScaler(config.Autoscale, func(host string) (scaling.Runtime, error) {
+		return dockerPool.Client(host)
+	})
+	redeliveries, err := newRedeliveryMonitor(config.Redelivery, publisher, auditLog, myID, elector)
+	if err != nil {
+		log.Fatalf("Invalid redelivery rules: %v", err)
+	}
+
+	// Keep the recent check results of every target, optionally persisted
+	// so uptime reports survive restarts
+	history := monitor.NewHistory(getEnvInt("HEALTH_HISTORY_SIZE", defaultHistorySize))
+	if historyPath := getEnv("HEALTH_HISTORY_PATH", ""); historyPath != "" {
+		if err := history.Load(historyPath); err != nil {
+			log.Printf("WARNING: %v", err)
+		}
+		go saveHistoryPeriodically(history, historyPath)
+		defer saveHistory(history, historyPath)
+	}
+
+	// Availability is kept per minute for reports over longer ranges,
+	// optionally appended to a file
+	availability := monitor.NewAvailability(getEnv("AVAILABILITY_PATH", ""))
+	go saveAvailabilityPeriodically(availability)
+	defer saveAvailability(availability, true)
+
+	// Workers reporting activity over RabbitMQ are passed without probing
+	var activity *monitor.ActivityTracker
+	activityWindow := getEnvDuration("PASSIVE_LIVENESS_WINDOW", 0)
+	if activityWindow > 0 && getEnv("RABBITMQ_URL", "") != "" {
+		activity = monitor.NewActivityTracker(activityWindow)
+	}
+
+	mttr := monitor.NewRecoveryTimes(getEnvInt("MTTR_SAMPLES", defaultMTTRSamples))
+
+	// Optionally check suspects more and long-healthy targets less often
+	var adaptive adaptiveIntervals
+	if getEnv("ADAPTIVE_CHECKS", "false") == "true" {
+		adaptive = adaptiveIntervals{
+			Fast:          getEnvDuration("ADAPTIVE_FAST_INTERVAL", defaultFastInterval),
+			Slow:          getEnvDuration("ADAPTIVE_SLOW_INTERVAL", defaultSlowInterval),
+			StableAfter:   getEnvDuration("ADAPTIVE_STABLE_AFTER", defaultStableAfter),
+			RestartWindow: getEnvDuration("ADAPTIVE_RESTART_WINDOW", recoveringTimeout),
+		}
+	}
+
+	// Alert operators of the coordinator's events, escalating the ones
+	// about critical or quarantined targets until they're acknowledged
+	channels, escalation, err := alertPolicy(config.Alerts)
+	if err != nil {
+		log.Fatalf("Invalid alerts: %v", err)
+	}
+	if getEnv("ALERT_ESCALATION", "true") != "true" {
+		escalation = nil
+	}
+	alerter := alert.NewAlerter(channels, escalation, auditLog)
+
+	containers := func(host string) (containerRuntime, error) {
+		return dockerPool.Client(host)
+	}
+	supervisor := NewSupervisor(SupervisorDeps{
+		MyID:               myID,
+		NodeID:             nodeID,
+		Advertise:          advertise,
+		Targets:            targets,
+		Elector:            elector,
+		Recoveries:         recoveries,
+		Containers:         containers,
+		Checkers:           checkers,
+		Heartbeats:         heartbeats,
+		Activity:           activity,
+		History:            history,
+		Availability:       availability,
+		MTTR:               mttr,
+		AuditLog:           auditLog,
+		Publisher:          publisher,
+		Bus:                bus,
+		Alerter:            alerter,
+		Adaptive:           adaptive,
+		Quorum:             recoveryQuorum(totalReplicas),
+		BusyTimeout:        getEnvDuration("PIPELINE_BUSY_TIMEOUT", defaultBusyTimeout),
+		VerifyTimeout:      getEnvDuration("RECOVERY_VERIFY_TIMEOUT", defaultVerifyTimeout),
+		LogSummaryInterval: getEnvDuration("LOG_SUMMARY_INTERVAL", defaultLogSummaryInterval),
+		ClockSkewWarning:   getEnvDuration("CLOCK_SKEW_WARNING", defaultClockSkewWarning),
+	})
+	go supervisor.WatchLeadership()
+
+	// The gateway can announce query runs over RabbitMQ as well as the admin
+	// API, and workers can report their activity
+	if amqpURL := getEnv("RABBITMQ_URL", ""); amqpURL != "" {
+		events.ListenControl(amqpURL, func(message events.ControlMessage) {
+			switch message.Type {
+			case events.TypePipelineBusy:
+				supervisor.SetPipelineBusy(true)
+			case events.TypePipelineIdle:
+				supervisor.SetPipelineBusy(false)
+			default:
+				log.Printf("WARNING: Unknown control message type %q", message.Type)
+			}
+		})
+		if activity != nil {
+			events.ListenActivity(amqpURL, func(message events.ActivityMessage) {
+				supervisor.RecordActivity(message.Worker)
+			})
+		}
+		// Every coordinator gets the reports; only the leader acts on them
+		events.ListenSuspects(amqpURL, func(message events.SuspectMessage) {
+			err := supervisor.ReportSuspect(admin.SuspectReport{Target: message.Target, From: message.From, Reason: message.Reason})
+			if err != nil && !errors.Is(err, admin.ErrNotLeader) {
+				log.Printf("WARNING: Ignoring report of %s: %v", message.Target, err)
+			}
+		})
+	}
+
+	backpressure, err := newBackpressureMonitor(supervisor)
+	if err != nil {
+		log.Fatalf("Invalid backpressure settings: %v", err)
+	}
+	if backpressure != nil {
+		go backpressure.run(getEnvDuration("BACKPRESSURE_INTERVAL", defaultBackpressureInterval))
+	}
+
+	if len(channels) > 0 {
+		go alerter.Run(bus.Subscribe(0, stream.KindEvent), supervisor.escalates)
+	}
+
+	// Optionally follow replicas added or removed at runtime
+	if interval := getEnvDuration("DISCOVERY_INTERVAL", 0); interval > 0 {
+		go runDiscovery(interval, dockerClient, config, supervisor)
+	}
+
+	// The leader keeps the declared services at their desired replicas
+	desired, err := newDesiredState(config.Desired, func(host string) (desiredRuntime, error) {
+		return dockerPool.Client(host)
+	}, publisher, auditLog, myID, elector)
+	if err != nil {
+		log.Fatalf("Invalid desired state: %v", err)
+	}
+	if desired != nil {
+		go desired.run(getEnvDuration("DESIRED_STATE_INTERVAL", defaultDesiredStateInterval))
+	}
+
+	// Recurring maintenance, run by the leader
+	tasks, err := maintenanceTasks(config.Maintenance)
+	if err != nil {
+		log.Fatalf("Invalid maintenance tasks: %v", err)
+	}
+	if len(tasks) > 0 {
+		go runMaintenance(tasks, elector, supervisor)
+	}
+
+	// Start admin API
+	adminToken := getEnv("ADMIN_TOKEN", "")
+	if adminToken == "" {
+		log.Printf("WARNING: ADMIN_TOKEN is not set, anyone who reaches the admin API can restart targets")
+	}
+	adminBind, err := adminBindAddress(bind)
+	if err != nil {
+		log.Fatalf("Invalid admin bind address: %v", err)
+	}
+	adminServer := admin.NewServer(supervisor, admin.WithToken(adminToken))
+	adminAddress := net.JoinHostPort(adminBind, getEnv("ADMIN_PORT", defaultAdminPort))
+	go supervise.Run("Admin API", func() error {
+		return adminServer.ListenAndServe(adminAddress)
+	})
+
+	// Optional gRPC API, over the same operations
+	if grpcPort := getEnv("GRPC_PORT", ""); grpcPort != "" {
+		grpcServer := admin.NewGRPCServer(supervisor, admin.WithToken(adminToken))
+		grpcAddress := net.JoinHostPort(adminBind, grpcPort)
+		go supervise.Run("gRPC API", func() error {
+			return grpcServer.ListenAndServe(grpcAddress)
+		})
+	}
+
+	// Followers can check targets too and report to the leader
+	followerChecks := getEnv("FOLLOWER_CHECKS", "false") == "true"
+	partitionThreshold := getEnvInt("PARTITION_THRESHOLD", defaultPartitionThreshold)
+	massFailureThreshold := getEnvInt("MASS_FAILURE_THRESHOLD", 0)
+	go supervise.Run("Follower report listener", func() error {
+		return vantage.Listen(net.JoinHostPort(bind, vantagePort), supervisor.AddReport)
+	})
+
+	// The leader replicates its recovery state so a failover resumes it
+	go supervise.Run("State sync listener", func() error {
+		return statesync.Listen(net.JoinHostPort(bind, stateSyncPort), supervisor.ApplyState)
+	})
+	go replicateStatePeriodically(supervisor, elector, myID, totalReplicas, peers, checkInterval)
+
+	// The leader's slower rounds against the broker and Docker run in loops
+	// of their own, once per checkInterval, so they don't hold up the checks
+	if scaler != nil {
+		go runAutoscaling(scaler, publisher, myID, elector, checkInterval)
+	}
+	if redeliveries != nil {
+		go redeliveries.run(checkInterval)
+	}
 
 	log.Printf("Configured to monitor %d targets with interval: %v", len(targets), checkInterval)
 	log.Printf("Waiting for leader election...")
@@ -61,44 +515,82 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Create ticker for periodic health checks
-	ticker := time.NewTicker(checkInterval)
+	// Targets are checked as they come due on every scheduler tick; the rest
+	// of the leader's work runs once per checkInterval round
+	ticker := time.NewTicker(schedulerTick)
 	defer ticker.Stop()
+	var lastRound time.Time
+	splitBrains := elector.Stats().SplitBrains
+	leadership := elector.Subscribe()
+	defer elector.Unsubscribe(leadership)
+	wasLeader := false
 
 	// Main monitoring loop
 	for {
 		select {
 		case <-ticker.C:
-			if !elector.IsLeader() {
-				log.Printf("Not leader (Leader ID=%d), skipping health checks", elector.GetLeaderID())
-				continue
+			round := time.Since(lastRound) >= checkInterval-schedulerTick/2
+			if round {
+				lastRound = time.Now()
+				splitBrains = reportSplitBrains(elector, publisher, myID, splitBrains)
 			}
 
-			log.Printf("I am the leader, performing health checks...")
-
-			// Check health of all targets
-			for _, target := range targets {
-				if !healthChecker.IsAlive(target.Host, target.Port) {
-					log.Printf("ERROR: %s is not responding to health checks", target.Name)
-					log.Printf("Attempting to restart container: %s", target.ContainerName)
-
-					if err := dockerClient.RestartContainer(target.ContainerName); err != nil {
-						log.Printf("ERROR: Failed to restart container %s: %v", target.ContainerName, err)
-					} else {
-						log.Printf("SUCCESS: Container %s restarted", target.ContainerName)
+			if !elector.IsLeader() {
+				leaderID := elector.GetLeaderID()
+				if !followerChecks || leaderID < 0 {
+					if round {
+						log.Printf("Not leader (Leader ID=%d), skipping health checks", leaderID)
 					}
-				} else {
-					log.Printf("OK: %s is healthy", target.Name)
+					continue
+				}
+
+				verdicts := supervisor.ObserveChecks()
+				if len(verdicts) == 0 {
+					continue
 				}
+				leaderAddress := net.JoinHostPort(peers.Address(leaderID), vantagePort)
+				if err := vantage.Send(leaderAddress, myID, elector.Stats().Term, verdicts); err != nil {
+					log.Printf("WARNING: Failed to report checks to leader: %v", err)
+				}
+				continue
+			}
+
+			if round {
+				log.Printf("I am the leader, performing health checks...")
+			}
+			if round {
+				supervisor.CheckHosts()
+				supervisor.CheckPartition(partitionThreshold)
+			}
+			supervisor.RunChecks(massFailureThreshold)
+			if !round {
+				continue
+			}
+			if carrier, ok := elector.(election.ClusterStateCarrier); ok {
+				carrier.SetClusterState(supervisor.ClusterState())
 			}
 
-		case isLeader := <-elector.LeaderChan():
-			if isLeader {
-				log.Printf("*** BECAME LEADER - Starting active monitoring ***")
+		case change := <-leadership:
+			// Changes of leader among the other coordinators are only logged
+			if change.IsLeader == wasLeader {
+				log.Printf("Leader is now coordinator %d (term %d, %s)", change.LeaderID, change.Term, change.Reason)
+				continue
+			}
+			wasLeader = change.IsLeader
+			if change.IsLeader {
+				log.Printf("*** BECAME LEADER (term %d, %s) - Starting active monitoring ***", change.Term, change.Reason)
 			} else {
-				log.Printf("*** LOST LEADERSHIP - Entering standby mode ***")
+				log.Printf("*** LOST LEADERSHIP (%s) - Entering standby mode ***", change.Reason)
 			}
 
+			publisher.Publish(events.Event{
+				Type:          events.TypeLeadershipChange,
+				Timestamp:     change.Timestamp,
+				CoordinatorID: myID,
+				LeaderID:      change.LeaderID,
+				Message:       fmt.Sprintf("is_leader=%t term=%d reason=%s", change.IsLeader, change.Term, change.Reason),
+			})
+
 		case sig := <-sigChan:
 			log.Printf("Received signal %v, shutting down...", sig)
 			return
@@ -106,57 +598,170 @@ func main() {
 	}
 }
 
-// getEnv gets an environment variable with a default value
+// saveHistoryPeriodically saves the health history every historySaveInterval
+func saveHistoryPeriodically(history *monitor.History, path string) {
+	ticker := time.NewTicker(historySaveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		saveHistory(history, path)
+	}
+}
+
+// saveHistory saves the health history, logging failures
+func saveHistory(history *monitor.History, path string) {
+	if err := history.Save(path); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+}
+
+// saveAvailabilityPeriodically saves the closed availability buckets every
+// historySaveInterval
+func saveAvailabilityPeriodically(availability *monitor.Availability) {
+	ticker := time.NewTicker(historySaveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		saveAvailability(availability, false)
+	}
+}
+
+// saveAvailability saves the availability buckets, logging failures
+func saveAvailability(availability *monitor.Availability, all bool) {
+	if err := availability.Save(all); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+}
+
+// getEnvInt gets an integer environment variable with a default value
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return value
+}
+
+// getEnvDuration gets a duration environment variable with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return value
+}
+
+// parseKeyValues parses a comma-separated list of key=value pairs
+func parseKeyValues(value string) map[string]string {
+	pairs := make(map[string]string)
+	for _, item := range splitList(value) {
+		if k, v, ok := strings.Cut(item, "="); ok {
+			pairs[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return pairs
+}
+
+// getEnv gets an environment variable, falling back to the active profile's
+// preset and then to a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
+		if preset, ok := activeProfile[key]; ok {
+			return preset
+		}
 		return defaultValue
 	}
 	return value
 }
 
-// startHealthServer starts a TCP health check server
-func startHealthServer(port string) {
-	address := "0.0.0.0:" + port
+// coordinatorHealthPort returns the port the coordinators' health servers
+// listen on, which is also where they check each other
+func coordinatorHealthPort() string {
+	return getEnv("HEALTH_PORT", healthserver.DefaultPort)
+}
 
-	listener, err := net.Listen("tcp", address)
-	if err != nil {
-		log.Fatalf("Failed to start health server: %v", err)
+// listenerLimits returns the connection limits of the health and election
+// listeners; zero disables each one
+func listenerLimits() connlimit.Limits {
+	return connlimit.Limits{
+		MaxConns:   getEnvInt("LISTENER_MAX_CONNECTIONS", defaultMaxConnections),
+		RatePerIP:  getEnvInt("LISTENER_RATE_PER_IP", defaultRatePerIP),
+		BurstPerIP: getEnvInt("LISTENER_BURST_PER_IP", defaultBurstPerIP),
 	}
-	defer listener.Close()
-
-	log.Printf("Health server listening on port %s", port)
+}
 
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			log.Printf("Error accepting health connection: %v", err)
-			continue
-		}
+// raftDataDir returns where the Raft elector keeps its term, vote and log:
+// the raft directory under DATA_DIR, or "" (in memory) if it isn't set
+func raftDataDir() string {
+	dataDir := getEnv("DATA_DIR", "")
+	if dataDir == "" {
+		return ""
+	}
+	return filepath.Join(dataDir, "raft")
+}
 
-		go handleHealthCheck(conn)
+// dialerOptions returns the settings of every outbound TCP connection
+func dialerOptions() dialer.Options {
+	return dialer.Options{
+		Timeout:   getEnvDuration("DIAL_TIMEOUT", 0),
+		KeepAlive: getEnvDuration("TCP_KEEPALIVE", defaultTCPKeepAlive),
+		Nagle:     getEnv("TCP_NODELAY", "true") == "false",
 	}
 }
 
-// handleHealthCheck handles a single health check connection
-func handleHealthCheck(conn net.Conn) {
-	defer conn.Close()
+// workerHealthPort returns the port workers are checked on unless they set
+// their own (coffeeshop.health.port, or port on static targets)
+func workerHealthPort() string {
+	return getEnv("WORKER_HEALTH_PORT", healthserver.DefaultPort)
+}
 
-	buffer := make([]byte, 4)
-	n, err := conn.Read(buffer)
-	if err != nil {
-		if err != io.EOF {
-			log.Printf("Error reading health check: %v", err)
-		}
-		return
+// dockerOptionsFromEnv returns the settings of the Docker clients
+func dockerOptionsFromEnv() docker.Options {
+	return docker.Options{
+		Endpoint:   getEnv("DOCKER_HOST", ""),
+		APIVersion: getEnv("DOCKER_API_VERSION", ""),
+		Runtime:    getEnv("CONTAINER_RUNTIME", docker.RuntimeDocker),
+		Retry: docker.RetryPolicy{
+			MaxAttempts:      getEnvInt("DOCKER_RETRY_ATTEMPTS", docker.DefaultRetryPolicy.MaxAttempts),
+			InitialBackoff:   getEnvDuration("DOCKER_RETRY_BACKOFF", docker.DefaultRetryPolicy.InitialBackoff),
+			MaxBackoff:       docker.DefaultRetryPolicy.MaxBackoff,
+			BreakerThreshold: getEnvInt("DOCKER_BREAKER_THRESHOLD", docker.DefaultRetryPolicy.BreakerThreshold),
+			BreakerCooldown:  getEnvDuration("DOCKER_BREAKER_COOLDOWN", docker.DefaultRetryPolicy.BreakerCooldown),
+		},
 	}
+}
 
-	message := string(buffer[:n])
-
-	if message == "PING" {
-		_, err = conn.Write([]byte("PONG"))
-		if err != nil {
-			log.Printf("Error writing health response: %v", err)
-		}
+// dockerHosts returns the remote Docker hosts, from the config file and
+// DOCKER_HOSTS (comma-separated name=endpoint pairs)
+func dockerHosts(config *FileConfig) map[string]string {
+	hosts := parseKeyValues(getEnv("DOCKER_HOSTS", ""))
+	for name, endpoint := range config.DockerHosts {
+		hosts[name] = endpoint
 	}
+	return hosts
+}
+
+// newCheckers returns the registry of built-in health checkers
+func newCheckers(dockerPool *docker.Pool, heartbeats *monitor.PushChecker) *monitor.Registry {
+	checkers := monitor.NewRegistry()
+	checkers.Register(monitor.CheckTypePush, heartbeats)
+	checkers.Register(monitor.CheckTypeExec, monitor.NewExecChecker(func(host string) (monitor.Execer, error) {
+		return dockerPool.Client(host)
+	}))
+	checkers.Register(monitor.CheckTypeDockerHealth, monitor.NewDockerHealthChecker(func(host string) (monitor.Inspector, error) {
+		return dockerPool.Client(host)
+	}))
+	return checkers
+}
+
+// newRecoveries returns the registry of built-in recovery actions
+func newRecoveries(dockerPool *docker.Pool, config *FileConfig) *recovery.Registry {
+	recoveries := recovery.NewRegistry()
+	recovery.RegisterDocker(recoveries, func(host string) (recovery.DockerRuntime, error) {
+		return dockerPool.Client(host)
+	})
+	recoveries.Register(recovery.ActionWebhook, recovery.NewWebhook(getEnv("RECOVERY_WEBHOOK_URL", "")))
+	sshExecutor := recovery.NewSSHExecutor(config.SSH.Hosts, config.SSH.Defaults)
+	recoveries.Register(recovery.ActionSSH, recovery.NewSSHCommand(sshExecutor))
+	recoveries.Register(recovery.ActionSystemd, recovery.NewSystemd(recovery.LocalRunner{}, sshExecutor))
+	return recoveries
 }
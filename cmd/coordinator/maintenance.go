@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/cron"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+)
+
+// defaultMaintenanceTimeout bounds a maintenance command without its own
+// timeout
+const defaultMaintenanceTimeout = 10 * time.Minute
+
+// MaintenanceTaskConfig declares a recurring maintenance task: restart a
+// target or run a command on the coordinator's host, on a cron schedule
+type MaintenanceTaskConfig struct {
+	Name     string   `yaml:"name"`
+	Schedule string   `yaml:"schedule"`
+	Restart  string   `yaml:"restart"`
+	Command  []string `yaml:"command"`
+	Timeout  string   `yaml:"timeout"`
+}
+
+// maintenanceTask is a parsed MaintenanceTaskConfig
+type maintenanceTask struct {
+	name     string
+	schedule cron.Schedule
+	restart  string
+	command  []string
+	timeout  time.Duration
+}
+
+// toTask parses a task declaration
+func (c MaintenanceTaskConfig) toTask() (maintenanceTask, error) {
+	if c.Name == "" {
+		return maintenanceTask{}, fmt.Errorf("maintenance task without name")
+	}
+	schedule, err := cron.Parse(c.Schedule)
+	if err != nil {
+		return maintenanceTask{}, fmt.Errorf("maintenance task %s: %w", c.Name, err)
+	}
+	if (c.Restart == "") == (len(c.Command) == 0) {
+		return maintenanceTask{}, fmt.Errorf("maintenance task %s: set either restart or command", c.Name)
+	}
+	timeout, err := parseOptionalDuration(c.Timeout)
+	if err != nil {
+		return maintenanceTask{}, fmt.Errorf("maintenance task %s: invalid timeout: %w", c.Name, err)
+	}
+	if timeout == 0 {
+		timeout = defaultMaintenanceTimeout
+	}
+	return maintenanceTask{name: c.Name, schedule: schedule, restart: c.Restart, command: c.Command, timeout: timeout}, nil
+}
+
+// maintenanceTasks parses the declared maintenance tasks
+func maintenanceTasks(configs []MaintenanceTaskConfig) ([]maintenanceTask, error) {
+	tasks := make([]maintenanceTask, 0, len(configs))
+	for _, config := range configs {
+		task, err := config.toTask()
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// runMaintenance runs the tasks on their schedules, forever. Every
+// coordinator keeps the schedule but only the leader runs the tasks; runs
+// due while nobody leads are skipped rather than made up later. A task
+// still running when it comes due again isn't started twice.
+func runMaintenance(tasks []maintenanceTask, elector election.Elector, supervisor *Supervisor) {
+	next := make([]time.Time, len(tasks))
+	now := time.Now()
+	for i, task := range tasks {
+		next[i] = task.schedule.Next(now)
+		log.Printf("Maintenance task %s scheduled, next run at %s", task.name, next[i].Format(time.RFC3339))
+	}
+
+	var mu sync.Mutex
+	running := make(map[string]bool)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for i, task := range tasks {
+			if next[i].IsZero() || now.Before(next[i]) {
+				continue
+			}
+			next[i] = task.schedule.Next(now)
+			if !elector.IsLeader() {
+				continue
+			}
+
+			mu.Lock()
+			busy := running[task.name]
+			running[task.name] = true
+			mu.Unlock()
+			if busy {
+				log.Printf("WARNING: Maintenance task %s is still running, skipping this run", task.name)
+				continue
+			}
+
+			go func(task maintenanceTask) {
+				defer func() {
+					mu.Lock()
+					delete(running, task.name)
+					mu.Unlock()
+				}()
+				runMaintenanceTask(task, supervisor)
+			}(task)
+		}
+	}
+}
+
+// runMaintenanceTask runs a task once and logs how it went
+func runMaintenanceTask(task maintenanceTask, supervisor *Supervisor) {
+	log.Printf("Running maintenance task %s (%s)", task.name, task)
+	start := time.Now()
+
+	var err error
+	if task.restart != "" {
+		err = supervisor.ScheduledRestart(task.restart, task.name)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), task.timeout)
+		_, err = recovery.LocalRunner{}.Run(ctx, task.command)
+		cancel()
+	}
+
+	if err != nil {
+		log.Printf("ERROR: Maintenance task %s failed after %v: %v", task.name, time.Since(start).Round(time.Second), err)
+		return
+	}
+	log.Printf("Maintenance task %s done in %v", task.name, time.Since(start).Round(time.Second))
+}
+
+// String returns what a task does, for logs
+func (t maintenanceTask) String() string {
+	if t.restart != "" {
+		return "restart " + t.restart
+	}
+	return strings.Join(t.command, " ")
+}
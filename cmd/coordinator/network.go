@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+)
+
+// networkAddressTTL is how long a peer's resolved network address is reused
+// before inspecting its container again (its IP changes if it's recreated)
+const networkAddressTTL = 30 * time.Second
+
+// containerInspector is the subset of the Docker client used to resolve
+// peer addresses
+type containerInspector interface {
+	InspectContainer(ctx context.Context, containerNameOrID string) (docker.ContainerInfo, error)
+}
+
+// bindAddress returns the local address the coordinator's listeners bind
+// to: BIND_ADDR or BIND_ADDRESS (an IPv4 or IPv6 literal), else the first
+// address of the BIND_INTERFACE network interface, else "" for every
+// interface
+func bindAddress() (string, error) {
+	if address := getEnv("BIND_ADDR", getEnv("BIND_ADDRESS", "")); address != "" {
+		if net.ParseIP(address) == nil {
+			return "", fmt.Errorf("BIND_ADDR %q is not an IP address", address)
+		}
+		return address, nil
+	}
+
+	name := getEnv("BIND_INTERFACE", "")
+	if name == "" {
+		return "", nil
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", fmt.Errorf("BIND_INTERFACE: %w", err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("BIND_INTERFACE %s: %w", name, err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("BIND_INTERFACE %s has no usable address", name)
+}
+
+// adminBindAddress returns ADMIN_BIND_ADDR, the IP the admin and gRPC APIs
+// listen on (e.g. 127.0.0.1 to keep them to coordinatorctl), or bind if
+// unset
+func adminBindAddress(bind string) (string, error) {
+	address := getEnv("ADMIN_BIND_ADDR", "")
+	if address == "" {
+		return bind, nil
+	}
+	if net.ParseIP(address) == nil {
+		return "", fmt.Errorf("ADMIN_BIND_ADDR %q is not an IP address", address)
+	}
+	return address, nil
+}
+
+// advertiseAddress returns ADVERTISE_ADDR, the host the other coordinators
+// should dial to reach this one when it differs from its hostname and from
+// what it binds to; "" if unset
+func advertiseAddress() (string, error) {
+	address := getEnv("ADVERTISE_ADDR", "")
+	if address != "" && !election.ValidHost(address) {
+		return "", fmt.Errorf("ADVERTISE_ADDR %q is not an IP address or hostname (without port)", address)
+	}
+	return address, nil
+}
+
+// networkResolver maps peer hostnames to their container's IP on a Docker
+// network, so inter-coordinator traffic uses that network even when the
+// containers share several
+type networkResolver struct {
+	network   string
+	inspector containerInspector
+
+	mu    sync.Mutex
+	cache map[string]resolvedAddress
+}
+
+type resolvedAddress struct {
+	address    string
+	resolvedAt time.Time
+}
+
+func newNetworkResolver(network string, inspector containerInspector) *networkResolver {
+	return &networkResolver{
+		network:   network,
+		inspector: inspector,
+		cache:     make(map[string]resolvedAddress),
+	}
+}
+
+// Resolve returns host's IP on the network, or "" if it can't be found, in
+// which case the hostname is dialed as usual
+func (r *networkResolver) Resolve(host string) string {
+	r.mu.Lock()
+	cached, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && time.Since(cached.resolvedAt) < networkAddressTTL {
+		return cached.address
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	info, err := r.inspector.InspectContainer(ctx, host)
+	if err != nil {
+		log.Printf("WARNING: Failed to resolve %s on network %s: %v", host, r.network, err)
+		return cached.address
+	}
+	address := info.NetworkAddress(r.network)
+	if address == "" {
+		log.Printf("WARNING: %s is not attached to network %s", host, r.network)
+	}
+
+	r.mu.Lock()
+	r.cache[host] = resolvedAddress{address: address, resolvedAt: time.Now()}
+	r.mu.Unlock()
+	return address
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// Policies for a one-shot container (a loader or client) that ran to
+// completion, i.e. exited with code 0 and has no restart policy
+const (
+	cleanupDeregister = "deregister" // stop monitoring it (the default)
+	cleanupPrune      = "prune"      // stop monitoring it and remove its container
+	cleanupRestart    = "restart"    // treat it like any failed target
+)
+
+// containerRuntime is the subset of the Docker client used to tell why a
+// target's container fails (completed, stuck, paused, host down) and to act
+// on that
+type containerRuntime interface {
+	Ping(ctx context.Context) error
+	InspectContainer(ctx context.Context, containerNameOrID string) (docker.ContainerInfo, error)
+	RemoveContainer(ctx context.Context, containerNameOrID string) error
+	UnpauseContainer(ctx context.Context, containerNameOrID string) error
+}
+
+// containerResolver returns the containerRuntime for the Docker host a
+// target runs on
+type containerResolver func(dockerHost string) (containerRuntime, error)
+
+// inspect returns the state of a target's container and the runtime it runs
+// on
+func (s *Supervisor) inspect(ctx context.Context, target monitor.CheckTarget) (containerRuntime, docker.ContainerInfo, error) {
+	runtime, err := s.containers(target.DockerHost)
+	if err != nil {
+		return nil, docker.ContainerInfo{}, err
+	}
+	info, err := runtime.InspectContainer(ctx, target.ContainerName)
+	return runtime, info, err
+}
+
+func validOneShotCleanup(policy string) bool {
+	return policy == cleanupDeregister || policy == cleanupPrune || policy == cleanupRestart
+}
+
+// completedOneShot reports whether a failing target is a one-shot container
+// that completed, and if so applies its cleanup policy. Only the leader
+// prunes; followers just stop checking it, so a failover doesn't find the
+// container gone and recreate it. A missing container of a target whose
+// policy is prune was already pruned.
+func (s *Supervisor) completedOneShot(target monitor.CheckTarget, leader bool) bool {
+	policy := target.OneShotCleanup
+	if policy == "" || policy == cleanupRestart || target.ContainerName == "" || s.containers == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	runtime, info, err := s.inspect(ctx, target)
+	switch {
+	case errors.Is(err, docker.ErrNotFound) && policy == cleanupPrune:
+		log.Printf("Container %s of one-shot target %s no longer exists, no longer monitoring it",
+			target.ContainerName, target.Name)
+		s.deregisterCompleted(target.Name)
+		return true
+	case err != nil || !info.Completed():
+		return false
+	}
+
+	log.Printf("Target %s ran to completion (container %s exited with code 0), no longer monitoring it",
+		target.Name, target.ContainerName)
+	s.deregisterCompleted(target.Name)
+	if !leader {
+		return true
+	}
+
+	entry := audit.Entry{
+		Action:        audit.ActionDeregister,
+		Target:        target.Name,
+		ContainerName: target.ContainerName,
+		Reason:        "one-shot container completed",
+		Evidence:      fmt.Sprintf("exited with code 0 at %s", info.State.FinishedAt),
+		LeaderID:      s.elector.GetLeaderID(),
+		Term:          s.elector.Stats().Term,
+		Outcome:       audit.OutcomeSuccess,
+	}
+	if policy == cleanupPrune {
+		entry.Action = audit.ActionPrune
+		if err := runtime.RemoveContainer(ctx, target.ContainerName); err != nil {
+			log.Printf("WARNING: Failed to prune container %s: %v", target.ContainerName, err)
+			entry.Outcome = audit.OutcomeFailure
+			entry.Error = err.Error()
+		}
+	}
+	s.auditLog.Record(entry)
+	s.publish(events.TypeCompleted, target, entry.Evidence)
+	return true
+}
+
+// deregisterCompleted deregisters a one-shot target that completed, for
+// good: discovery doesn't add it back
+func (s *Supervisor) deregisterCompleted(name string) {
+	s.deregister(name)
+	s.mu.Lock()
+	s.completed[name] = true
+	s.mu.Unlock()
+}
+
+// deregister stops monitoring a target and forgets its state
+func (s *Supervisor) deregister(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, target := range s.targets {
+		if target.Name == name {
+			s.targets = append(s.targets[:i], s.targets[i+1:]...)
+			break
+		}
+	}
+	delete(s.quarantined, name)
+	delete(s.lastError, name)
+	delete(s.lastChecked, name)
+	delete(s.nextCheck, name)
+	delete(s.restartCount, name)
+	delete(s.failures, name)
+	delete(s.recovering, name)
+	delete(s.peerVerdicts, name)
+	delete(s.firstFailure, name)
+	delete(s.healthySince, name)
+	delete(s.restartedAt, name)
+	delete(s.pending, name)
+	delete(s.conditions, name)
+	delete(s.suspects, name)
+	s.releaseHeldLocked(name)
+	s.incidents.Removed(name, time.Now())
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// minPartitionTargets is how many targets there must be for most of them
+// being unreachable to mean the leader is partitioned
+const minPartitionTargets = 3
+
+// CheckPartition decides whether the leader is cut off from the network
+// rather than its targets being down: most of them are unreachable while
+// Docker has their containers running (or their hosts' daemons can't be
+// reached either), and the local daemon answers. The leader then enters
+// safe mode: it doesn't recover anything and publishes
+// leadership.partitioned. It only steps down once other coordinators
+// corroborate it, reporting most of those targets healthy; otherwise the
+// targets may really be down and a new leader wouldn't do better. Safe mode
+// ends once most targets are reachable again. threshold is the percentage
+// of unreachable targets that triggers it; zero disables the heuristic.
+func (s *Supervisor) CheckPartition(threshold int) {
+	if threshold <= 0 || s.containers == nil {
+		return
+	}
+
+	targets := s.snapshotTargets()
+	if len(targets) < minPartitionTargets {
+		return
+	}
+	unreachable, corroborated := 0, 0
+	for _, target := range targets {
+		if s.unreachable(target) {
+			unreachable++
+			if s.healthyPeers(target.Name) > 0 {
+				corroborated++
+			}
+		}
+	}
+	partitioned := unreachable*100 >= threshold*len(targets) && s.pingHost("") == nil
+
+	s.mu.Lock()
+	entered, left := partitioned && !s.safeMode, !partitioned && s.safeMode
+	s.safeMode = partitioned
+	s.mu.Unlock()
+
+	message := fmt.Sprintf("%d of %d targets unreachable", unreachable, len(targets))
+	switch {
+	case entered:
+		log.Printf("ERROR: Probably partitioned (%s while the local Docker daemon answers), entering safe mode: no recoveries", message)
+		s.publishPartition(events.TypePartitioned, message)
+	case left:
+		log.Printf("Targets reachable again (%s), leaving safe mode", message)
+		s.publishPartition(events.TypePartitionHealed, message)
+	}
+
+	if !partitioned {
+		return
+	}
+	if corroborated*2 <= unreachable {
+		if entered {
+			log.Printf("Other coordinators don't report the unreachable targets healthy (%d of %d), not stepping down", corroborated, unreachable)
+		}
+		return
+	}
+	log.Printf("Other coordinators reach %d of the %d unreachable targets, stepping down", corroborated, unreachable)
+	if err := s.elector.StepDown(); err != nil {
+		log.Printf("WARNING: Failed to step down while partitioned: %v", err)
+	}
+}
+
+// healthyPeers returns how many coordinators' latest fresh verdict on a
+// target is healthy
+func (s *Supervisor) healthyPeers(name string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	healthy := 0
+	for _, verdict := range s.peerVerdicts[name] {
+		if verdict.Healthy && time.Since(verdict.ReceivedAt) < peerVerdictTTL {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// unreachable reports whether a failing target looks cut off rather than
+// down: Docker has its container running, or its host's daemon doesn't
+// answer either. Targets already explained otherwise don't count: those on
+// a Docker host reported down (see CheckHosts) and stuck ones, whose
+// container runs but whose process hangs.
+func (s *Supervisor) unreachable(target monitor.CheckTarget) bool {
+	s.mu.RLock()
+	failing := s.failures[target.Name] > 0
+	_, hostDown := s.hostsDown[target.DockerHost]
+	stuck := s.conditions[target.Name] == conditionStuck
+	s.mu.RUnlock()
+	if !failing || hostDown || stuck || target.ContainerName == "" || target.SwarmService != "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	_, info, err := s.inspect(ctx, target)
+	if err != nil {
+		return s.pingHost(target.DockerHost) != nil
+	}
+	return info.State.Running && !info.State.Paused
+}
+
+// inSafeMode reports whether the leader thinks it's partitioned
+func (s *Supervisor) inSafeMode() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.safeMode
+}
+
+// publishPartition publishes the leader entering or leaving safe mode
+func (s *Supervisor) publishPartition(eventType, message string) {
+	s.publisher.Publish(events.Event{
+		Type:          eventType,
+		CoordinatorID: s.myID,
+		LeaderID:      s.elector.GetLeaderID(),
+		Message:       message,
+	})
+}
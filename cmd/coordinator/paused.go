@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// Policies for a target whose container is paused (e.g. `docker pause`
+// while debugging). None of them restarts it.
+const (
+	pausedAlert   = "alert"   // publish a node.paused event once (the default)
+	pausedIgnore  = "ignore"  // only log it
+	pausedUnpause = "unpause" // resume the container
+)
+
+// conditionPaused is the condition of a target whose container is paused
+const conditionPaused = "paused"
+
+func validPausedAction(action string) bool {
+	return action == pausedAlert || action == pausedIgnore || action == pausedUnpause
+}
+
+// paused reports whether a failing target's container is paused, and if so
+// applies its paused policy instead of recovering it
+func (s *Supervisor) paused(target monitor.CheckTarget) bool {
+	if target.ContainerName == "" || target.SwarmService != "" || s.containers == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	runtime, info, err := s.inspect(ctx, target)
+	if err != nil || !info.State.Paused {
+		return false
+	}
+	s.setCondition(target.Name, conditionPaused)
+
+	switch target.PausedAction {
+	case pausedIgnore:
+		s.checkLog.Decision(target.Name, conditionPaused, "Target %s is paused, leaving it alone", target.Name)
+
+	case pausedUnpause:
+		log.Printf("Target %s is paused, unpausing it", target.Name)
+		entry := audit.Entry{
+			Action:        audit.ActionUnpause,
+			Target:        target.Name,
+			ContainerName: target.ContainerName,
+			Reason:        "container paused",
+			LeaderID:      s.elector.GetLeaderID(),
+			Term:          s.elector.Stats().Term,
+			Outcome:       audit.OutcomeSuccess,
+		}
+		if err := runtime.UnpauseContainer(ctx, target.ContainerName); err != nil {
+			log.Printf("ERROR: Failed to unpause %s: %v", target.Name, err)
+			entry.Outcome = audit.OutcomeFailure
+			entry.Error = err.Error()
+		} else {
+			s.publish(events.TypeUnpaused, target, "container was paused")
+		}
+		s.auditLog.Record(entry)
+
+	default:
+		if s.checkLog.Decision(target.Name, conditionPaused,
+			"Target %s is paused, not restarting it", target.Name) {
+			s.publish(events.TypePaused, target, "container is paused")
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// profiles are the built-in presets selectable with PROFILE (or profile in
+// the config file). A profile supplies defaults for environment variables:
+// anything set in the environment still wins. The election timings must
+// match across replicas, so every coordinator should run the same profile.
+var profiles = map[string]map[string]string{
+	// dev is for the lab: slow to act, never pages anyone
+	"dev": {
+		"FAILURE_THRESHOLD":           "3",
+		"RECOVERY_VERIFY_TIMEOUT":     "60s",
+		"ELECTION_HEARTBEAT_INTERVAL": "3s",
+		"ELECTION_TIMEOUT":            "10s",
+		"LOG_SUMMARY_INTERVAL":        "5m",
+		"QUORUM_CONFIRMATION":         "false",
+		"ALERT_ESCALATION":            "false",
+	},
+	// demo recovers as fast as it can
+	"demo": {
+		"FAILURE_THRESHOLD":           "1",
+		"ADAPTIVE_CHECKS":             "true",
+		"ADAPTIVE_FAST_INTERVAL":      "1s",
+		"RECOVERY_VERIFY_TIMEOUT":     "15s",
+		"ELECTION_HEARTBEAT_INTERVAL": "1s",
+		"ELECTION_TIMEOUT":            "4s",
+		"LEADER_STICKINESS":           "10s",
+		"QUORUM_CONFIRMATION":         "false",
+		"ALERT_ESCALATION":            "false",
+	},
+	// prod only recovers what most coordinators see failing, and escalates
+	// alerts nobody acknowledges
+	"prod": {
+		"FAILURE_THRESHOLD":       "2",
+		"RECOVERY_VERIFY_TIMEOUT": "30s",
+		"FOLLOWER_CHECKS":         "true",
+		"QUORUM_CONFIRMATION":     "true",
+		"ALERT_ESCALATION":        "true",
+	},
+}
+
+// activeProfile holds the defaults of the selected profile; getEnv falls
+// back to them. It's set once at startup.
+var activeProfile map[string]string
+
+// loadedBeforeProfile are the variables read before a profile can be
+// selected: the profile comes from the config file, so it can't say where
+// that file is, or pick another profile.
+var loadedBeforeProfile = []string{"CONFIG_PATH", "PROFILE"}
+
+// selectProfile activates the profile named by PROFILE, or by the config
+// file. Profiles declared in the config file replace the built-in ones with
+// the same name. No profile keeps the plain defaults.
+func selectProfile(config *FileConfig) (string, error) {
+	name := getEnv("PROFILE", config.Profile)
+	if name == "" {
+		return "", nil
+	}
+	for declared, profile := range config.Profiles {
+		for _, key := range loadedBeforeProfile {
+			if _, ok := profile[key]; ok {
+				return "", fmt.Errorf("profile %q sets %s, which is read before any profile applies", declared, key)
+			}
+		}
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		profile, ok = profiles[name]
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown profile %q (expected %s)", name, strings.Join(profileNames(config), ", "))
+	}
+	activeProfile = profile
+	return name, nil
+}
+
+// profileNames returns the names of the built-in and declared profiles
+func profileNames(config *FileConfig) []string {
+	names := make([]string, 0, len(profiles)+len(config.Profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	for name := range config.Profiles {
+		if _, ok := profiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recoveryQuorum returns how many coordinators must see a target failing
+// before the leader recovers it: a majority with QUORUM_CONFIRMATION, none
+// otherwise. The followers' verdicts only arrive with FOLLOWER_CHECKS.
+func recoveryQuorum(totalReplicas int) int {
+	if getEnv("QUORUM_CONFIRMATION", "false") != "true" {
+		return 0
+	}
+	if getEnv("FOLLOWER_CHECKS", "false") != "true" {
+		log.Printf("WARNING: QUORUM_CONFIRMATION needs FOLLOWER_CHECKS, recovering on the leader's checks alone")
+		return 0
+	}
+	return totalReplicas/2 + 1
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// maxListedTargets bounds the failing targets named in a diagnosis
+const maxListedTargets = 10
+
+// massFailure is a mass failure in progress: more targets failing at once
+// than the threshold passed to RunChecks
+type massFailure struct {
+	since     time.Time
+	diagnosis string
+}
+
+// suspectGroup is a set of targets sharing something that may have made
+// them fail together: a dependency, a Docker host or a network
+type suspectGroup struct {
+	kind    string // "dependency", "host" or "network"
+	name    string
+	size    int // targets in the group
+	failing int // failing targets in the group
+}
+
+// score tells how well a group explains the failures: the fraction of the
+// failing targets in it times the fraction of it that's failing
+func (g suspectGroup) score(failing int) float64 {
+	return float64(g.failing) / float64(failing) * float64(g.failing) / float64(g.size)
+}
+
+// checkMassFailure starts a mass failure when more than threshold targets
+// are failing, publishing a single diagnosis of their likely common cause
+// instead of a node.down per target, and ends it once they're back under
+// the threshold. Zero disables it.
+func (s *Supervisor) checkMassFailure(threshold int) {
+	if threshold <= 0 {
+		return
+	}
+
+	s.mu.RLock()
+	failing := []monitor.CheckTarget{}
+	for _, target := range s.targets {
+		if s.failures[target.Name] > 0 {
+			failing = append(failing, target)
+		}
+	}
+	ongoing := s.massFailure != nil
+	s.mu.RUnlock()
+
+	switch {
+	case len(failing) > threshold && !ongoing:
+		root, diagnosis := s.diagnose(failing)
+		s.mu.Lock()
+		s.massFailure = &massFailure{since: time.Now(), diagnosis: diagnosis}
+		s.mu.Unlock()
+
+		log.Printf("ERROR: Mass failure: %s", diagnosis)
+		s.publishCause(events.TypeMassFailure, root, "", diagnosis)
+	case len(failing) <= threshold && ongoing:
+		s.mu.Lock()
+		since := s.massFailure.since
+		s.massFailure = nil
+		s.mu.Unlock()
+
+		message := fmt.Sprintf("%d target(s) failing after %v", len(failing), time.Since(since).Round(time.Second))
+		log.Printf("Mass failure over: %s", message)
+		s.publish(events.TypeMassFailureOver, monitor.CheckTarget{}, message)
+	}
+}
+
+// inMassFailure reports whether a mass failure is in progress
+func (s *Supervisor) inMassFailure() bool {
+	return s.massFailureDiagnosis() != ""
+}
+
+// massFailureDiagnosis returns the diagnosis of the mass failure in
+// progress, if any
+func (s *Supervisor) massFailureDiagnosis() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.massFailure == nil {
+		return ""
+	}
+	return s.massFailure.diagnosis
+}
+
+// diagnose correlates the failing targets: the dependency, Docker host or
+// network most of them share, where most of the targets sharing it fail,
+// is the suspected root cause. Declared dependencies are the strongest
+// evidence, so hosts and networks are only looked at when no dependency
+// explains the failures. The root cause is returned when it's a target, so
+// the diagnosis joins its incident.
+func (s *Supervisor) diagnose(failing []monitor.CheckTarget) (monitor.CheckTarget, string) {
+	best := bestGroup(s.dependencyGroups(failing), len(failing))
+	if best == nil {
+		best = bestGroup(append(s.hostGroups(), s.networkGroups()...), len(failing))
+	}
+
+	names := make([]string, 0, len(failing))
+	for _, target := range failing {
+		names = append(names, target.Name)
+	}
+	sort.Strings(names)
+	if len(names) > maxListedTargets {
+		names = append(names[:maxListedTargets], fmt.Sprintf("%d more", len(failing)-maxListedTargets))
+	}
+	listed := strings.Join(names, ", ")
+
+	if best == nil {
+		return monitor.CheckTarget{}, fmt.Sprintf("%d targets failing with no common cause found (%s)", len(failing), listed)
+	}
+	var root monitor.CheckTarget
+	if best.kind == "dependency" {
+		root, _ = s.findTarget(best.name)
+	}
+	return root, fmt.Sprintf("%d targets failing, suspected root cause: %s %s (%d of its %d targets failing) (%s)",
+		len(failing), best.kind, best.name, best.failing, best.size, listed)
+}
+
+// bestGroup returns the group that best explains failing failures, if any
+// holds most of them with most of its own targets failing
+func bestGroup(groups []suspectGroup, failing int) *suspectGroup {
+	var best *suspectGroup
+	for i := range groups {
+		group := &groups[i]
+		if group.failing*2 <= failing || group.failing*2 < group.size {
+			continue
+		}
+		if best == nil || group.score(failing) > best.score(failing) {
+			best = group
+		}
+	}
+	return best
+}
+
+// dependencyGroups groups the failing targets by the failing dependency at
+// the root of their failure (see failingDependencyLocked), the dependency
+// itself included
+func (s *Supervisor) dependencyGroups(failing []monitor.CheckTarget) []suspectGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dependents := make(map[string]int)
+	for _, target := range failing {
+		if root := s.failingDependencyLocked(target, map[string]bool{target.Name: true}); root != "" {
+			dependents[root]++
+		}
+	}
+	groups := make([]suspectGroup, 0, len(dependents))
+	for root, count := range dependents {
+		size := 1
+		for _, target := range s.targets {
+			if target.Name != root && s.dependsOnLocked(target, root, map[string]bool{}) {
+				size++
+			}
+		}
+		groups = append(groups, suspectGroup{kind: "dependency", name: root, size: size, failing: count + 1})
+	}
+	return groups
+}
+
+// dependsOnLocked reports whether target depends on root, directly or
+// not. s.mu must be held.
+func (s *Supervisor) dependsOnLocked(target monitor.CheckTarget, root string, seen map[string]bool) bool {
+	for _, name := range target.DependsOn {
+		dependency, ok := s.lookupTargetLocked(name)
+		if !ok || seen[dependency.Name] {
+			continue
+		}
+		seen[dependency.Name] = true
+		if dependency.Name == root || s.dependsOnLocked(dependency, root, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostGroups groups the container targets by Docker host. A single host
+// tells nothing apart, so it's no group.
+func (s *Supervisor) hostGroups() []suspectGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byHost := make(map[string]*suspectGroup)
+	total := 0
+	for _, target := range s.targets {
+		if target.ContainerName == "" {
+			continue
+		}
+		total++
+		host := target.DockerHost
+		if host == "" {
+			host = localHostName
+		}
+		group, ok := byHost[host]
+		if !ok {
+			group = &suspectGroup{kind: "host", name: host}
+			byHost[host] = group
+		}
+		group.size++
+		if s.failures[target.Name] > 0 {
+			group.failing++
+		}
+	}
+	return distinctGroups(byHost, total)
+}
+
+// networkGroups groups the container targets by the Docker networks their
+// containers are attached to, inspecting them. Networks every container is
+// on tell nothing apart and aren't groups.
+func (s *Supervisor) networkGroups() []suspectGroup {
+	if s.containers == nil {
+		return nil
+	}
+
+	byNetwork := make(map[string]*suspectGroup)
+	total := 0
+	for _, target := range s.snapshotTargets() {
+		if target.ContainerName == "" || target.SwarmService != "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		_, info, err := s.inspect(ctx, target)
+		cancel()
+		if err != nil {
+			continue
+		}
+		total++
+
+		s.mu.RLock()
+		failing := s.failures[target.Name] > 0
+		s.mu.RUnlock()
+		for network := range info.NetworkSettings.Networks {
+			group, ok := byNetwork[network]
+			if !ok {
+				group = &suspectGroup{kind: "network", name: network}
+				byNetwork[network] = group
+			}
+			group.size++
+			if failing {
+				group.failing++
+			}
+		}
+	}
+	return distinctGroups(byNetwork, total)
+}
+
+// distinctGroups returns the groups that don't hold all total targets,
+// i.e. that tell some targets apart from the rest
+func distinctGroups(byName map[string]*suspectGroup, total int) []suspectGroup {
+	groups := []suspectGroup{}
+	for _, group := range byName {
+		if group.size < total {
+			groups = append(groups, *group)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/scaling"
+)
+
+// What to do when a queue is in a redelivery storm
+const (
+	stormAlert = "alert" // publish queue.redelivery_storm (the default)
+	stormPark  = "park"  // ... and move its messages to a parking-lot queue
+)
+
+// defaultMaxRedeliveryRate is the redeliveries per second above which a
+// queue is in a storm, unless its rule sets another
+const defaultMaxRedeliveryRate = 5.0
+
+// RedeliveryRule declares queues whose redelivery rate is watched
+type RedeliveryRule struct {
+	VHost      string  `yaml:"vhost"`
+	Queue      string  `yaml:"queue"`    // empty means every queue of the vhost
+	MaxRate    float64 `yaml:"max_rate"` // redeliveries per second
+	Action     string  `yaml:"action"`
+	ParkingLot string  `yaml:"parking_lot"` // defaults to <queue>.parking
+}
+
+// queueManagement is the subset of the management API used to watch
+// redeliveries
+type queueManagement interface {
+	Queues(ctx context.Context, vhost string) ([]scaling.QueueStats, error)
+	Park(ctx context.Context, vhost, queue, parkingLot string) error
+}
+
+// queueSample is a queue's redelivery counter when it was last read
+type queueSample struct {
+	redelivered int64
+	at          time.Time
+}
+
+// redeliveryMonitor detects redelivery storms: a poisoned message that
+// makes its worker crash (or NACK it) gets redelivered after every restart,
+// so restarts hide a data problem while the queue loops. It compares the
+// queues' redelivery counters between rounds.
+type redeliveryMonitor struct {
+	rules      []RedeliveryRule
+	management queueManagement
+	publisher  events.Publisher
+	auditLog   *audit.Logger
+	myID       int
+	elector    election.Elector
+
+	samples map[string]queueSample // by vhost/queue
+	storms  map[string]bool        // queues in a storm, by vhost/queue
+}
+
+// newRedeliveryMonitor checks the declared rules. It returns nil when none
+// are declared or the management API isn't configured.
+func newRedeliveryMonitor(rules []RedeliveryRule, publisher events.Publisher, auditLog *audit.Logger,
+	myID int, elector election.Elector) (*redeliveryMonitor, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if err := checkRedeliveryRules(rules); err != nil {
+		return nil, err
+	}
+
+	managementURL := getEnv("RABBITMQ_MANAGEMENT_URL", "")
+	if managementURL == "" {
+		log.Printf("WARNING: Redelivery rules configured but RABBITMQ_MANAGEMENT_URL is not set, redelivery storms won't be detected")
+		return nil, nil
+	}
+	management, err := scaling.NewRabbitMQManagement(managementURL)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Watching redeliveries with %d rule(s)", len(rules))
+	return &redeliveryMonitor{
+		rules:      rules,
+		management: management,
+		publisher:  publisher,
+		auditLog:   auditLog,
+		myID:       myID,
+		elector:    elector,
+		samples:    make(map[string]queueSample),
+		storms:     make(map[string]bool),
+	}, nil
+}
+
+// checkRedeliveryRules fills in the defaults of the rules and checks them
+func checkRedeliveryRules(rules []RedeliveryRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.VHost == "" {
+			rule.VHost = "/"
+		}
+		if rule.MaxRate == 0 {
+			rule.MaxRate = defaultMaxRedeliveryRate
+		}
+		if rule.Action == "" {
+			rule.Action = stormAlert
+		}
+		switch {
+		case rule.MaxRate < 0:
+			return fmt.Errorf("redelivery rule for %s: max_rate must not be negative", rule.describe())
+		case rule.Action != stormAlert && rule.Action != stormPark:
+			return fmt.Errorf("redelivery rule for %s: unknown action %q (expected %s or %s)",
+				rule.describe(), rule.Action, stormAlert, stormPark)
+		case rule.ParkingLot != "" && rule.Queue == "":
+			return fmt.Errorf("redelivery rule for %s: parking_lot needs a queue", rule.describe())
+		}
+	}
+	return nil
+}
+
+// describe names the queues a rule applies to
+func (r RedeliveryRule) describe() string {
+	if r.Queue == "" {
+		return "every queue of vhost " + r.VHost
+	}
+	return fmt.Sprintf("queue %s (vhost %s)", r.Queue, r.VHost)
+}
+
+// run checks the watched queues every interval while this coordinator
+// leads, apart from the checks and one round at a time
+func (m *redeliveryMonitor) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if m.elector.IsLeader() {
+			m.check()
+		}
+	}
+}
+
+// check reads the counters of the watched queues and reports the storms
+// that started or ended since the last round
+func (m *redeliveryMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), recoveryTimeout)
+	defer cancel()
+
+	queues := make(map[string][]scaling.QueueStats)
+	for _, rule := range m.rules {
+		stats, ok := queues[rule.VHost]
+		if !ok {
+			var err error
+			if stats, err = m.management.Queues(ctx, rule.VHost); err != nil {
+				log.Printf("WARNING: Failed to read redeliveries: %v", err)
+				continue
+			}
+			queues[rule.VHost] = stats
+		}
+
+		now := time.Now()
+		for _, queue := range stats {
+			if rule.Queue == "" || rule.Queue == queue.Name {
+				m.observe(ctx, rule, queue, now)
+			}
+		}
+	}
+}
+
+// observe compares a queue's redelivery counter with the previous round's
+func (m *redeliveryMonitor) observe(ctx context.Context, rule RedeliveryRule, queue scaling.QueueStats, now time.Time) {
+	key := rule.VHost + "/" + queue.Name
+	previous, seen := m.samples[key]
+	m.samples[key] = queueSample{redelivered: queue.Redelivered, at: now}
+	// A counter that went back means the queue was declared again
+	if !seen || queue.Redelivered < previous.redelivered {
+		return
+	}
+	elapsed := now.Sub(previous.at).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	rate := float64(queue.Redelivered-previous.redelivered) / elapsed
+	storm := rate >= rule.MaxRate
+	message := fmt.Sprintf("%.1f redeliveries/s (max %.1f), %d messages waiting", rate, rule.MaxRate, queue.Messages)
+	switch {
+	case storm && !m.storms[key]:
+		log.Printf("ERROR: Redelivery storm on queue %s: %s", queue.Name, message)
+		m.publish(events.TypeRedeliveryStorm, queue.Name, message)
+		if rule.Action == stormPark {
+			m.park(ctx, rule, queue.Name, message)
+		}
+	case !storm && m.storms[key]:
+		log.Printf("Redelivery storm on queue %s is over: %s", queue.Name, message)
+		m.publish(events.TypeRedeliveryStormOver, queue.Name, message)
+	}
+	m.storms[key] = storm
+}
+
+// park moves the messages of a queue in a storm to its parking lot, where
+// they can be inspected instead of looping
+func (m *redeliveryMonitor) park(ctx context.Context, rule RedeliveryRule, queue, evidence string) {
+	parkingLot := rule.ParkingLot
+	if parkingLot == "" {
+		parkingLot = queue + ".parking"
+	}
+
+	err := m.management.Park(ctx, rule.VHost, queue, parkingLot)
+	entry := audit.Entry{
+		Action:   audit.ActionPark,
+		Target:   queue,
+		Reason:   "redelivery storm",
+		Evidence: evidence,
+		LeaderID: m.elector.GetLeaderID(),
+		Term:     m.elector.Stats().Term,
+		Outcome:  audit.OutcomeSuccess,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	}
+	m.auditLog.Record(entry)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return
+	}
+
+	log.Printf("Moved the messages of queue %s to %s", queue, parkingLot)
+	m.publish(events.TypeQueueParked, queue, "messages moved to "+parkingLot)
+}
+
+// publish sends a redelivery event about a queue
+func (m *redeliveryMonitor) publish(eventType, queue, message string) {
+	m.publisher.Publish(events.Event{
+		Type:          eventType,
+		CoordinatorID: m.myID,
+		LeaderID:      m.elector.GetLeaderID(),
+		Target:        queue,
+		Message:       message,
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// adaptiveIntervals adapts how often each target is checked to its state:
+// suspects (failing, recovering or recently restarted) are checked every
+// Fast, and targets healthy for StableAfter only every Slow. The zero value
+// disables it: every target is checked at its own interval.
+type adaptiveIntervals struct {
+	Fast          time.Duration
+	Slow          time.Duration
+	StableAfter   time.Duration
+	RestartWindow time.Duration // how long a restarted target stays a suspect
+}
+
+func (a adaptiveIntervals) enabled() bool {
+	return a != adaptiveIntervals{}
+}
+
+// intervalLocked returns how long to wait between checks of a target given
+// its state, stretched for non-critical targets during a load spike (see
+// SetBackpressure). s.mu must be held.
+func (s *Supervisor) intervalLocked(target monitor.CheckTarget, now time.Time) time.Duration {
+	interval := s.adaptiveIntervalLocked(target, now)
+	if s.slowdown > 0 && !target.Critical {
+		interval *= time.Duration(s.slowdown)
+	}
+	return interval
+}
+
+// adaptiveIntervalLocked returns the interval of a target given its state
+// (see adaptiveIntervals). s.mu must be held.
+func (s *Supervisor) adaptiveIntervalLocked(target monitor.CheckTarget, now time.Time) time.Duration {
+	base := target.Interval
+	if base <= 0 {
+		base = checkInterval
+	}
+	if !s.adaptive.enabled() {
+		return base
+	}
+
+	_, recovering := s.recovering[target.Name]
+	if s.failures[target.Name] > 0 || recovering || now.Sub(s.restartedAt[target.Name]) < s.adaptive.RestartWindow {
+		return min(base, s.adaptive.Fast)
+	}
+	if since, ok := s.healthySince[target.Name]; ok && now.Sub(since) >= s.adaptive.StableAfter {
+		return max(base, s.adaptive.Slow)
+	}
+	return base
+}
+
+// scheduleLocked sets and returns when a target is checked next. A target
+// seen for the first time gets a random phase within its interval, so the
+// targets don't all come due on the same tick; after that each check is
+// one interval after the previous slot, keeping the phase, give or take
+// scheduleJitter so targets that end up lined up drift apart again. s.mu
+// must be held.
+func (s *Supervisor) scheduleLocked(target monitor.CheckTarget, now time.Time) time.Time {
+	interval := s.intervalLocked(target, now)
+	if interval <= 0 {
+		s.nextCheck[target.Name] = now
+		return now
+	}
+
+	previous, scheduled := s.nextCheck[target.Name]
+	if !scheduled {
+		next := now.Add(time.Duration(rand.Int63n(int64(interval))))
+		s.nextCheck[target.Name] = next
+		return next
+	}
+
+	// A check that ran late (behind slow checks) or early (a peer saw the
+	// target fail, or its interval changed) starts a new phase
+	next := previous.Add(interval)
+	if next.Before(now) || next.After(now.Add(interval)) {
+		next = now.Add(interval)
+	}
+	if jitter := int64(float64(interval) * scheduleJitter); jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(2*jitter+1) - jitter))
+	}
+	s.nextCheck[target.Name] = next
+	return next
+}
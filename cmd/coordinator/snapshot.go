@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+)
+
+// ExportSnapshot implements admin.Controller
+func (s *Supervisor) ExportSnapshot() admin.Snapshot {
+	return admin.Snapshot{
+		Version:       admin.SnapshotVersion,
+		ExportedAt:    time.Now(),
+		Status:        s.Status(),
+		Election:      s.Election(),
+		Targets:       s.Targets(),
+		Recovery:      s.ExportState(),
+		History:       s.history.All(),
+		RecoveryTimes: s.RecoveryTimes(),
+
+		RecoverySamples: s.mttr.Export(),
+	}
+}
+
+// ImportSnapshot implements admin.Controller. It restores the recovery
+// state, check history and recovery times; state about targets this coordinator doesn't
+// monitor is kept in case they show up later.
+func (s *Supervisor) ImportSnapshot(snapshot admin.Snapshot) error {
+	if snapshot.Version != admin.SnapshotVersion {
+		return fmt.Errorf("%w: version %d, expected %d", admin.ErrInvalidSnapshot, snapshot.Version, admin.SnapshotVersion)
+	}
+
+	s.restoreState(snapshot.Recovery)
+	if snapshot.History != nil {
+		s.history.Replace(snapshot.History)
+	}
+	if snapshot.RecoverySamples != nil {
+		s.mttr.Restore(snapshot.RecoverySamples)
+	}
+
+	unknown := 0
+	for _, target := range snapshot.Targets {
+		if _, err := s.findTarget(target.Name); err != nil {
+			unknown++
+		}
+	}
+	log.Printf("Imported snapshot of coordinator %d taken at %s (%d targets, %d not monitored here)",
+		snapshot.Status.ID, snapshot.ExportedAt.Format(time.RFC3339), len(snapshot.Targets), unknown)
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/statesync"
+)
+
+const stateSyncPort = "12351"
+
+// replicateStatePeriodically pushes the leader's recovery state to the
+// other coordinators every interval while this one leads. It has a loop of
+// its own so a slow peer doesn't hold up the checks.
+func replicateStatePeriodically(supervisor *Supervisor, elector election.Elector, myID, totalReplicas int, peers election.Peers, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if elector.IsLeader() {
+			replicateState(supervisor.ExportState(), myID, totalReplicas, peers)
+		}
+	}
+}
+
+// replicateState pushes a state to every other coordinator at once and
+// waits for all of them, so rounds never overlap
+func replicateState(state statesync.State, myID, totalReplicas int, peers election.Peers) {
+	var wg sync.WaitGroup
+	for id := 1; id <= totalReplicas; id++ {
+		if id == myID {
+			continue
+		}
+		address := net.JoinHostPort(peers.Address(id), stateSyncPort)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := statesync.Send(address, state); err != nil {
+				log.Printf("WARNING: Failed to replicate state: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
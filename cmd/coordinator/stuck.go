@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+)
+
+// conditionStuck is the condition of a target whose container Docker has
+// running while its process doesn't answer (e.g. deadlocked)
+const conditionStuck = "stuck"
+
+// stuck reports whether a failing target looks deadlocked: its health check
+// timed out rather than being refused, yet Docker has its container
+// running. A stuck process may hang in its SIGTERM handler too, so such
+// targets are killed and started instead of restarted.
+func (s *Supervisor) stuck(target monitor.CheckTarget, checkErr error) bool {
+	if target.ContainerName == "" || target.SwarmService != "" || s.containers == nil || !timedOut(checkErr) {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	_, info, err := s.inspect(ctx, target)
+	if err != nil || !info.State.Running || info.State.Paused || info.State.Restarting {
+		return false
+	}
+	return true
+}
+
+// recoveryForStuck returns the action a stuck target is recovered with:
+// kill-start instead of a plain restart. Other actions are kept, since
+// they were chosen for the target.
+func (s *Supervisor) recoveryForStuck(target monitor.CheckTarget, action string, checkErr error) string {
+	if action != recovery.ActionRestart || !s.stuck(target, checkErr) {
+		s.setCondition(target.Name, "")
+		return action
+	}
+
+	s.setCondition(target.Name, conditionStuck)
+	log.Printf("Target %s is stuck: its container is running but it doesn't answer, killing it instead of restarting", target.Name)
+	return recovery.ActionKillStart
+}
+
+// setCondition records what the container inspection made of a failing
+// target; "" clears it
+func (s *Supervisor) setCondition(name, condition string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if condition == "" {
+		delete(s.conditions, name)
+	} else {
+		s.conditions[name] = condition
+	}
+}
+
+// timedOut reports whether a check failed on a timeout
+func timedOut(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// failureCause returns the reason a failed target is recovered for: its
+// check's, unless its container turns out to have been killed out of
+// memory, which a probe alone can't tell from any other crash
+func (s *Supervisor) failureCause(target monitor.CheckTarget, checkErr error) string {
+	cause := monitor.FailureReason(checkErr)
+	if cause == events.ReasonOOM || target.ContainerName == "" || target.SwarmService != "" || s.containers == nil {
+		return cause
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	if _, info, err := s.inspect(ctx, target); err == nil && !info.State.Running && info.State.OOMKilled {
+		return events.ReasonOOM
+	}
+	return cause
+}
@@ -0,0 +1,1225 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/incident"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/statesync"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/tracing"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/vantage"
+)
+
+// Supervisor runs health checks against the monitored targets and recovers
+// the ones that fail. It also implements admin.Controller.
+type Supervisor struct {
+	myID         int
+	nodeID       string
+	advertise    string
+	targets      []monitor.CheckTarget
+	elector      election.Elector
+	recoveries   *recovery.Registry
+	containers   containerResolver // nil without Docker
+	checkers     *monitor.Registry
+	auditLog     *audit.Logger
+	publisher    events.Publisher
+	stream       *stream.Bus
+	alerter      *alert.Alerter
+	heartbeats   *monitor.PushChecker
+	activity     *monitor.ActivityTracker // nil when passive liveness is disabled
+	history      *monitor.History
+	availability *monitor.Availability
+	mttr         *monitor.RecoveryTimes
+	checkLog     *checkLog
+	clocks       *clockSkews
+	incidents    *incident.Log
+
+	mu           sync.RWMutex
+	quarantined  map[string]bool
+	lastError    map[string]string
+	lastChecked  map[string]time.Time
+	nextCheck    map[string]time.Time // see scheduleLocked
+	restartCount map[string]int
+	failures     map[string]int
+	recovering   map[string]time.Time               // restarted targets not healthy again yet
+	peerVerdicts map[string]map[int]vantage.Verdict // target -> coordinator -> latest verdict
+	verifying    map[string]bool                    // recovered targets whose verification is running
+	firstFailure map[string]time.Time               // first failed check of each target's ongoing incident
+	healthySince map[string]time.Time               // first of each healthy target's passing checks in a row
+	restartedAt  map[string]time.Time               // last recovery of each target
+	pending      map[string]pendingRestart          // protected targets waiting for an operator
+	conditions   map[string]string                  // what inspecting the container of each failing target showed
+	completed    map[string]bool                    // one-shot targets deregistered once they completed
+	hostsDown    map[string]hostOutage              // Docker hosts down as a whole (see CheckHosts)
+	suspects     map[string]suspectReport           // last worker report of each target (see ReportSuspect)
+	held         map[string]*heldRestarts           // restarts held by each failing dependency (see dependencies.go)
+
+	// busyUntil is when the current pipeline run is considered over if the
+	// gateway never reports it finished; zero means idle
+	busyUntil   time.Time
+	busyTimeout time.Duration
+
+	// safeMode is set while the leader thinks it's partitioned (see
+	// CheckPartition); nothing is recovered meanwhile
+	safeMode bool
+
+	// slowdown is how many times less often non-critical targets are
+	// checked during a load spike, whose restarts are deferred meanwhile;
+	// zero when there's none (see SetBackpressure)
+	slowdown int
+
+	// massFailure is set while more targets fail at once than the mass
+	// failure threshold (see checkMassFailure)
+	massFailure *massFailure
+
+	// leadership is the last change of leader the elector reported (see
+	// WatchLeadership); nil until there's one
+	leadership *election.LeadershipEvent
+
+	// confirmations is the context of the confirmation webhooks asked while
+	// leading; it's canceled when leadership is lost (see dropPending)
+	confirmations       context.Context
+	cancelConfirmations context.CancelFunc
+
+	adaptive adaptiveIntervals
+
+	// verifyTimeout is how long a recovered target has to pass a check,
+	// unless it sets its own; zero disables verification
+	verifyTimeout time.Duration
+
+	// quorum is how many coordinators, the leader included, must see a
+	// target failing before it's recovered; zero trusts the leader's checks
+	quorum int
+}
+
+// SupervisorDeps is what a Supervisor is built from
+type SupervisorDeps struct {
+	MyID      int
+	NodeID    string
+	Advertise string
+	Targets   []monitor.CheckTarget
+
+	Elector      election.Elector
+	Recoveries   *recovery.Registry
+	Containers   containerResolver
+	Checkers     *monitor.Registry
+	Heartbeats   *monitor.PushChecker
+	Activity     *monitor.ActivityTracker
+	History      *monitor.History
+	Availability *monitor.Availability
+	MTTR         *monitor.RecoveryTimes
+
+	AuditLog  *audit.Logger
+	Publisher events.Publisher
+	Bus       *stream.Bus
+	Alerter   *alert.Alerter
+	Adaptive  adaptiveIntervals
+
+	// Quorum is how many coordinators must see a target failing before
+	// it's recovered; zero trusts the leader's checks
+	Quorum int
+
+	BusyTimeout        time.Duration
+	VerifyTimeout      time.Duration
+	LogSummaryInterval time.Duration
+	ClockSkewWarning   time.Duration
+}
+
+// NewSupervisor creates a supervisor for the given targets
+func NewSupervisor(deps SupervisorDeps) *Supervisor {
+	s := &Supervisor{
+		myID:          deps.MyID,
+		nodeID:        deps.NodeID,
+		advertise:     deps.Advertise,
+		targets:       deps.Targets,
+		elector:       deps.Elector,
+		recoveries:    deps.Recoveries,
+		containers:    deps.Containers,
+		checkers:      deps.Checkers,
+		heartbeats:    deps.Heartbeats,
+		activity:      deps.Activity,
+		history:       deps.History,
+		availability:  deps.Availability,
+		mttr:          deps.MTTR,
+		checkLog:      newCheckLog(deps.LogSummaryInterval),
+		clocks:        newClockSkews(deps.ClockSkewWarning),
+		incidents:     incident.NewLog(),
+		auditLog:      deps.AuditLog,
+		publisher:     deps.Publisher,
+		stream:        deps.Bus,
+		alerter:       deps.Alerter,
+		quarantined:   make(map[string]bool),
+		lastError:     make(map[string]string),
+		lastChecked:   make(map[string]time.Time),
+		nextCheck:     make(map[string]time.Time),
+		restartCount:  make(map[string]int),
+		failures:      make(map[string]int),
+		recovering:    make(map[string]time.Time),
+		peerVerdicts:  make(map[string]map[int]vantage.Verdict),
+		verifying:     make(map[string]bool),
+		firstFailure:  make(map[string]time.Time),
+		healthySince:  make(map[string]time.Time),
+		restartedAt:   make(map[string]time.Time),
+		pending:       make(map[string]pendingRestart),
+		conditions:    make(map[string]string),
+		completed:     make(map[string]bool),
+		hostsDown:     make(map[string]hostOutage),
+		suspects:      make(map[string]suspectReport),
+		held:          make(map[string]*heldRestarts),
+		adaptive:      deps.Adaptive,
+		busyTimeout:   deps.BusyTimeout,
+		verifyTimeout: deps.VerifyTimeout,
+		quorum:        deps.Quorum,
+	}
+	s.confirmations, s.cancelConfirmations = context.WithCancel(context.Background())
+	return s
+}
+
+// RunChecks checks every due target and restarts the unhealthy ones. More
+// than massFailures targets failing at once are diagnosed together (see
+// checkMassFailure); zero disables it.
+func (s *Supervisor) RunChecks(massFailures int) {
+	defer s.announceRootCauses()
+
+	// Every due target is checked before any failure is handled, so a
+	// mass failure is known before a node.down per target goes out
+	type failure struct {
+		target monitor.CheckTarget
+		result monitor.CheckResult
+	}
+	failed := []failure{}
+	for _, target := range dependenciesFirst(s.snapshotTargets()) {
+		if !s.due(target) {
+			continue
+		}
+
+		result := s.check(target)
+		s.checkLog.Result(target.Name, result, s.failingPeers(target.Name))
+		if result.Err != nil {
+			failed = append(failed, failure{target: target, result: result})
+		}
+	}
+	s.checkMassFailure(massFailures)
+
+	for _, f := range failed {
+		target, result := f.target, f.result
+
+		// Only failures start a trace: the spans cover a recovery from the
+		// failed check to the restart and its verification
+		ctx, span := tracing.Start(context.Background(), "recovery", tracing.WithStartTime(result.Timestamp))
+		span.SetAttribute("target", target.Name)
+		_, checkSpan := tracing.Start(ctx, "health_check", tracing.WithStartTime(result.Timestamp))
+		checkSpan.SetAttribute("checker", result.Checker)
+		checkSpan.SetError(result.Err)
+		checkSpan.EndAt(result.Timestamp.Add(result.Duration))
+
+		s.handleFailure(ctx, target, result.Err)
+		span.End()
+	}
+}
+
+// ObserveChecks is what followers run: it checks every due target like
+// RunChecks but never recovers anything, and returns the verdicts so they
+// can be reported to the leader
+func (s *Supervisor) ObserveChecks() []vantage.Verdict {
+	verdicts := []vantage.Verdict{}
+	for _, target := range s.snapshotTargets() {
+		if !s.due(target) {
+			continue
+		}
+
+		result := s.check(target)
+		if result.Err != nil && s.completedOneShot(target, false) {
+			continue
+		}
+		verdict := vantage.Verdict{Target: target.Name, Healthy: result.Healthy, Timestamp: result.Timestamp}
+		if result.Err != nil {
+			verdict.Error = result.Err.Error()
+		}
+		verdicts = append(verdicts, verdict)
+	}
+	return verdicts
+}
+
+// due reports whether a target should be checked now: once its scheduled
+// time (see scheduleLocked) comes, or earlier if another coordinator (or a
+// worker) has seen it fail since the last check.
+func (s *Supervisor) due(target monitor.CheckTarget) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Checks are run on scheduler ticks, so anything due within half a tick
+	// is due now rather than a whole tick late
+	now := time.Now()
+	next, scheduled := s.nextCheck[target.Name]
+	if !scheduled {
+		next = s.scheduleLocked(target, now)
+	}
+	if !now.Before(next.Add(-schedulerTick / 2)) {
+		return true
+	}
+
+	lastChecked := s.lastChecked[target.Name]
+	for _, verdict := range s.peerVerdicts[target.Name] {
+		if !verdict.Healthy && verdict.ReceivedAt.After(lastChecked) && time.Since(verdict.ReceivedAt) < peerVerdictTTL {
+			log.Printf("Coordinator reports %s failing, checking it ahead of its interval", target.Name)
+			return true
+		}
+	}
+	return s.reportedLocked(target.Name)
+}
+
+// check runs the target's health check and records the result
+func (s *Supervisor) check(target monitor.CheckTarget) monitor.CheckResult {
+	result, passive := s.passiveCheck(target)
+	if !passive {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		result = s.checkers.Check(ctx, target)
+		cancel()
+	}
+	s.history.Add(target.Name, result)
+	s.availability.Add(target.Name, result)
+	s.stream.PublishCheck(target.Name, result)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastChecked[target.Name] = result.Timestamp
+	if result.Err != nil {
+		s.lastError[target.Name] = result.Err.Error()
+		s.failures[target.Name]++
+		if _, ok := s.firstFailure[target.Name]; !ok {
+			s.firstFailure[target.Name] = result.Timestamp
+		}
+		delete(s.healthySince, target.Name)
+		s.incidents.Failed(target.Name, result.Timestamp, result.Reason)
+	} else {
+		if _, ok := s.healthySince[target.Name]; !ok {
+			s.healthySince[target.Name] = result.Timestamp
+		}
+		// A restarted target that passes a regular check before (or
+		// without) its verification recovered here
+		if _, ok := s.recovering[target.Name]; ok {
+			s.recordRecoveryLocked(target, result.Timestamp)
+		}
+		delete(s.lastError, target.Name)
+		delete(s.restartCount, target.Name)
+		delete(s.failures, target.Name)
+		delete(s.recovering, target.Name)
+		delete(s.firstFailure, target.Name)
+		delete(s.conditions, target.Name)
+		s.incidents.Healthy(target.Name, result.Timestamp)
+		s.releaseHeldLocked(target.Name)
+		if _, ok := s.pending[target.Name]; ok {
+			log.Printf("Target %s is healthy again, dropping its pending restart", target.Name)
+			delete(s.pending, target.Name)
+		}
+	}
+	s.scheduleLocked(target, result.Timestamp)
+	return result
+}
+
+// passiveCheck passes a target without probing it if it reported activity
+// recently. Suspects (failing, recovering, or seen failing by another
+// coordinator or a worker) are always probed.
+func (s *Supervisor) passiveCheck(target monitor.CheckTarget) (monitor.CheckResult, bool) {
+	if s.activity == nil || !s.activity.Active(target.Name) {
+		return monitor.CheckResult{}, false
+	}
+
+	s.mu.RLock()
+	_, recovering := s.recovering[target.Name]
+	suspect := s.failures[target.Name] > 0 || recovering || s.verifying[target.Name] ||
+		len(s.failingPeersLocked(target.Name)) > 0 || s.reportedLocked(target.Name)
+	s.mu.RUnlock()
+	if suspect {
+		return monitor.CheckResult{}, false
+	}
+	return monitor.CheckResult{Checker: monitor.CheckTypeActivity, Healthy: true, Timestamp: time.Now()}, true
+}
+
+// RecordActivity records that a worker, by target or container name, is
+// processing messages
+func (s *Supervisor) RecordActivity(worker string) {
+	if s.activity == nil {
+		return
+	}
+	if target, err := s.findTarget(worker); err == nil {
+		s.activity.Seen(target.Name)
+	}
+}
+
+// recordRecoveryLocked records how long the target's ongoing incident took
+// to recover, once: the incident is closed afterwards. Incidents that end
+// without a restart aren't recoveries and are never recorded. s.mu must be
+// held.
+func (s *Supervisor) recordRecoveryLocked(target monitor.CheckTarget, healthyAt time.Time) {
+	start, ok := s.firstFailure[target.Name]
+	if !ok {
+		return
+	}
+	delete(s.firstFailure, target.Name)
+	s.mttr.Add(target.Group, healthyAt.Sub(start))
+}
+
+// handleFailure recovers a failed target if decide says it's time
+func (s *Supervisor) handleFailure(ctx context.Context, target monitor.CheckTarget, err error) {
+	if action, cause, now := s.decide(ctx, target, err); now {
+		s.recover(ctx, target, action, "health check failed", cause, err.Error(), "")
+	}
+}
+
+// decide decides, in a "decision" span, whether a failed target is
+// recovered now, and with which action
+func (s *Supervisor) decide(ctx context.Context, target monitor.CheckTarget, err error) (action, cause string, now bool) {
+	s.mu.RLock()
+	failures := s.failures[target.Name]
+	quarantined := s.quarantined[target.Name]
+	restarts := s.restartCount[target.Name]
+	verifying := s.verifying[target.Name]
+	peers := s.failingPeersLocked(target.Name)
+	s.mu.RUnlock()
+
+	_, span := tracing.Start(ctx, "decision")
+	span.SetAttribute("failures", failures)
+	span.SetAttribute("failing_peers", len(peers))
+	span.SetAttribute("failure_threshold", target.FailureThreshold)
+	span.SetAttribute("restarts", restarts)
+	var decision string
+	defer func() {
+		span.SetAttribute("decision", decision)
+		span.End()
+	}()
+
+	if failures < target.FailureThreshold {
+		log.Printf("Target %s failed %d/%d consecutive checks", target.Name, failures, target.FailureThreshold)
+		decision = "below_threshold"
+		return
+	}
+
+	// A partitioned leader can't tell which targets are really down
+	if s.inSafeMode() {
+		s.checkLog.Decision(target.Name, "safe_mode", "In safe mode (probably partitioned), not recovering %s", target.Name)
+		decision = "safe_mode"
+		return
+	}
+
+	// A host that's down was reported once for all its targets, and nothing
+	// on it can be restarted while its daemon doesn't answer
+	outage, hostDown := s.hostDown(target)
+	if hostDown && outage.daemonErr {
+		s.checkLog.Decision(target.Name, "host_down", "Docker host of %s is down, not recovering it", target.Name)
+		decision = "host_down"
+		return
+	}
+
+	// A target whose dependency is failing is most likely failing because
+	// of it: the dependency is the one to recover, and alert about once
+	s.mu.RLock()
+	root := s.failingDependencyLocked(target, map[string]bool{target.Name: true})
+	s.mu.RUnlock()
+	if root != "" {
+		s.checkLog.Decision(target.Name, "dependency_down", "Dependency %s of %s is failing, holding its restart", root, target.Name)
+		s.holdForDependency(target, root)
+		decision = "dependency_down"
+		return
+	}
+
+	// Loaders and clients exit once they're done; that's no failure
+	if s.completedOneShot(target, true) {
+		decision = "completed"
+		return
+	}
+
+	// A paused container isn't dead: someone is probably debugging it
+	if s.paused(target) {
+		decision = "paused"
+		return
+	}
+
+	if quarantined {
+		s.checkLog.Decision(target.Name, "quarantined", "Target %s is quarantined, skipping restart", target.Name)
+		decision = "quarantined"
+		return
+	}
+
+	// A target that was just recovered gets until its verification
+	// deadline to come back before it's recovered again
+	if verifying {
+		s.checkLog.Decision(target.Name, "verifying",
+			"Target %s is still being verified after its recovery, not restarting it again", target.Name)
+		decision = "verifying"
+		return
+	}
+
+	// With quorum confirmation the leader's own failed checks aren't
+	// enough: the leader may be the one that can't reach the target
+	if s.quorum > 0 && 1+len(peers) < s.quorum {
+		s.checkLog.Decision(target.Name, "no_quorum", "Target %s is failing for %d of the %d coordinators needed, not recovering it yet",
+			target.Name, 1+len(peers), s.quorum)
+		decision = "no_quorum"
+		return
+	}
+
+	// During a mass failure its diagnosis stands for the targets' node.down
+	cause = s.failureCause(target, err)
+	if !hostDown && !s.inMassFailure() {
+		s.publishCause(events.TypeNodeDown, target, cause, err.Error())
+	}
+
+	action = recovery.ActionName(target)
+	if action == recovery.ActionNone {
+		decision = "notify_only"
+		return
+	}
+
+	// Restarting a worker during a run (e.g. mid final aggregation)
+	// corrupts its results; the restart happens once the run is over
+	if !target.Critical && s.pipelineBusy() {
+		s.checkLog.Decision(target.Name, "pipeline_busy", "Pipeline run in progress, deferring restart of %s", target.Name)
+		decision = "pipeline_busy"
+		return
+	}
+	if !target.Critical && s.backpressureActive() {
+		s.checkLog.Decision(target.Name, "backpressure", "Broker under a load spike, deferring restart of %s", target.Name)
+		decision = "backpressure"
+		return
+	}
+
+	if busy := s.groupRecovering(target); busy != "" {
+		s.checkLog.Decision(target.Name, "group_limit",
+			"Group %s is at its restart limit (%s still recovering), deferring restart of %s", target.Group, busy, target.Name)
+		decision = "group_limit"
+		return
+	}
+
+	if target.MaxRestarts > 0 && restarts >= target.MaxRestarts {
+		if s.checkLog.Decision(target.Name, "max_restarts", "Target %s reached max restarts (%d), giving up",
+			target.Name, target.MaxRestarts) {
+			s.publish(events.TypeGaveUp, target, fmt.Sprintf("reached max restarts (%d): %v", target.MaxRestarts, err))
+		}
+		decision = "max_restarts"
+		return
+	}
+
+	// Protected and stateful targets are only restarted once an operator
+	// (or their confirmation webhook) approves it
+	if needsConfirmation(target) {
+		s.requestConfirmation(target, action, cause, err)
+		decision = "awaiting_confirmation"
+		return
+	}
+
+	s.checkLog.Decision(target.Name, "recover", "Target %s failed %d checks in a row, recovering it", target.Name, failures)
+	action = s.recoveryForStuck(target, action, err)
+
+	s.mu.Lock()
+	s.restartCount[target.Name]++
+	s.recovering[target.Name] = time.Now()
+	s.mu.Unlock()
+
+	decision = "recover"
+	return action, cause, true
+}
+
+// AddReport records the verdicts a follower reported. Reports from an
+// earlier term were meant for a previous leader and are dropped.
+func (s *Supervisor) AddReport(report vantage.Report) {
+	if report.From == s.myID {
+		return
+	}
+	receivedAt := time.Now()
+	s.clocks.observe(report.From, report.SentAt, receivedAt)
+	if term := s.elector.Stats().Term; report.Term < term {
+		log.Printf("WARNING: Ignoring report from coordinator %d for term %d, the term is %d", report.From, report.Term, term)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, verdict := range report.Verdicts {
+		verdict.ReceivedAt = receivedAt
+		verdicts, ok := s.peerVerdicts[verdict.Target]
+		if !ok {
+			verdicts = make(map[int]vantage.Verdict)
+			s.peerVerdicts[verdict.Target] = verdicts
+		}
+		verdicts[report.From] = verdict
+	}
+}
+
+// ExportState snapshots the recovery state for replication to followers
+func (s *Supervisor) ExportState() statesync.State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := statesync.State{
+		From:          s.myID,
+		Term:          s.elector.Stats().Term,
+		Timestamp:     time.Now(),
+		RestartCounts: make(map[string]int, len(s.restartCount)),
+		Failures:      make(map[string]int, len(s.failures)),
+		Quarantined:   make([]string, 0, len(s.quarantined)),
+		Recovering:    make(map[string]time.Time, len(s.recovering)),
+		BusyUntil:     s.busyUntil,
+	}
+	for name, count := range s.restartCount {
+		state.RestartCounts[name] = count
+	}
+	for name, count := range s.failures {
+		state.Failures[name] = count
+	}
+	for name := range s.quarantined {
+		state.Quarantined = append(state.Quarantined, name)
+	}
+	sort.Strings(state.Quarantined)
+	for name, since := range s.recovering {
+		state.Recovering[name] = since
+	}
+	return state
+}
+
+// ApplyState replaces the recovery state with the leader's snapshot, so it
+// carries over if this coordinator takes over. Snapshots from anyone but
+// the current leader (e.g. a deposed one that hasn't noticed yet) are
+// ignored. The snapshot's timestamps are moved to this coordinator's clock.
+func (s *Supervisor) ApplyState(state statesync.State) {
+	if state.From == s.myID || s.elector.IsLeader() {
+		return
+	}
+	skew := s.clocks.observe(state.From, state.Timestamp, time.Now())
+	if leaderID := s.elector.GetLeaderID(); state.From != leaderID {
+		log.Printf("WARNING: Ignoring state from coordinator %d (term %d), the leader is %d", state.From, state.Term, leaderID)
+		return
+	}
+
+	s.restoreState(toLocalClock(state, skew))
+}
+
+// restoreState replaces the recovery state with a snapshot's
+func (s *Supervisor) restoreState(state statesync.State) {
+	quarantined := make(map[string]bool, len(state.Quarantined))
+	for _, name := range state.Quarantined {
+		quarantined[name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restartCount = orEmpty(state.RestartCounts)
+	s.failures = orEmpty(state.Failures)
+	s.quarantined = quarantined
+	s.recovering = orEmpty(state.Recovering)
+	s.busyUntil = state.BusyUntil
+}
+
+// orEmpty returns m, or an empty map if it's nil
+func orEmpty[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return make(map[string]V)
+	}
+	return m
+}
+
+// failingPeers returns the coordinators whose latest fresh verdict on a
+// target is a failure
+func (s *Supervisor) failingPeers(name string) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.failingPeersLocked(name)
+}
+
+// failingPeersLocked is failingPeers for callers holding mu
+func (s *Supervisor) failingPeersLocked(name string) []int {
+	peers := []int{}
+	for from, verdict := range s.peerVerdicts[name] {
+		if !verdict.Healthy && time.Since(verdict.ReceivedAt) < peerVerdictTTL {
+			peers = append(peers, from)
+		}
+	}
+	sort.Ints(peers)
+	return peers
+}
+
+// recover runs a recovery action on the target, recording the attempt and
+// who approved it, for restarts that needed it. The action runs in a span
+// under the trace in parent, if any.
+func (s *Supervisor) recover(parent context.Context, target monitor.CheckTarget, action, reason, cause, evidence, approver string) error {
+	log.Printf("Attempting to recover %s with action %s (%s)", target.Name, action, cause)
+	s.mu.Lock()
+	s.restartedAt[target.Name] = time.Now()
+	s.mu.Unlock()
+	s.publishCause(events.TypeRestarting, target, cause, reason)
+
+	entry := audit.Entry{
+		Action:        action,
+		Target:        target.Name,
+		ContainerName: target.ContainerName,
+		Reason:        reason,
+		Cause:         cause,
+		Evidence:      evidence,
+		Approver:      approver,
+		LeaderID:      s.elector.GetLeaderID(),
+		Term:          s.elector.Stats().Term,
+		Outcome:       audit.OutcomeSuccess,
+	}
+
+	ctx, span := tracing.Start(parent, "restart")
+	span.SetAttribute("target", target.Name)
+	span.SetAttribute("action", action)
+	span.SetAttribute("reason", reason)
+	ctx, cancel := context.WithTimeout(ctx, recoveryTimeout)
+	defer cancel()
+	if s.elector.IsLeader() {
+		go s.cancelOnLeadershipLoss(ctx, cancel)
+	}
+
+	var err error
+	if action != recovery.ActionNone {
+		err = s.runHook(ctx, target, recovery.HookPreRestart)
+	}
+	if err == nil {
+		target.Recovery = action
+		err = s.recoveries.Recover(ctx, target)
+	}
+	span.SetError(err)
+	span.End()
+
+	if err != nil {
+		log.Printf("ERROR: Failed to recover %s with action %s: %v", target.Name, action, err)
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+		s.publishCause(events.TypeRestartFailed, target, cause, err.Error())
+		s.auditLog.Record(entry)
+		return err
+	}
+
+	log.Printf("SUCCESS: %s recovered with action %s", target.Name, action)
+	s.publishCause(events.TypeRestarted, target, cause, reason)
+
+	timeout := s.verifyTimeoutFor(target)
+	if action == recovery.ActionNone {
+		s.auditLog.Record(entry)
+		return nil
+	}
+	if timeout <= 0 {
+		// Without verification the post-recovery hook runs right away
+		if err := s.runHook(context.Background(), target, recovery.HookPostRecovery); err != nil {
+			entry.Outcome = audit.OutcomeFailure
+			entry.Error = err.Error()
+			s.publishCause(events.TypeRestartFailed, target, cause, err.Error())
+			s.auditLog.Record(entry)
+			return err
+		}
+		s.auditLog.Record(entry)
+		return nil
+	}
+
+	// Verification runs in the background so the other targets keep being
+	// checked; the audit entry is recorded once it's over
+	s.mu.Lock()
+	s.verifying[target.Name] = true
+	s.mu.Unlock()
+	go s.verify(span.Context(), target, timeout, entry)
+	return nil
+}
+
+// verifyTimeoutFor returns how long a recovered target has to pass a check
+func (s *Supervisor) verifyTimeoutFor(target monitor.CheckTarget) time.Duration {
+	if target.VerifyTimeout > 0 {
+		return target.VerifyTimeout
+	}
+	return s.verifyTimeout
+}
+
+// verify checks a recovered target until it's healthy or timeout passes. A
+// target that never comes back is reported like a failed recovery: a
+// node.restart_failed event and a failed audit entry.
+func (s *Supervisor) verify(restart tracing.SpanContext, target monitor.CheckTarget, timeout time.Duration, entry audit.Entry) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.verifying, target.Name)
+		s.mu.Unlock()
+		s.auditLog.Record(entry)
+	}()
+
+	_, span := tracing.Start(context.Background(), "verification", tracing.WithParent(restart))
+	span.SetAttribute("target", target.Name)
+	defer span.End()
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	var lastErr error
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		result := s.checkers.Check(ctx, target)
+		cancel()
+
+		if result.Err == nil {
+			log.Printf("VERIFIED: %s is healthy again %v after its recovery", target.Name, time.Since(start).Round(time.Millisecond))
+			s.mu.Lock()
+			s.recordRecoveryLocked(target, result.Timestamp)
+			s.mu.Unlock()
+			s.publishCause(events.TypeRecovered, target, entry.Cause, entry.Reason)
+			if err := s.runHook(context.Background(), target, recovery.HookPostRecovery); err != nil {
+				span.SetError(err)
+				entry.Outcome = audit.OutcomeFailure
+				entry.Error = err.Error()
+				s.publishCause(events.TypeRestartFailed, target, entry.Cause, err.Error())
+			}
+			return
+		}
+		lastErr = result.Err
+
+		if time.Now().Add(verifyInterval).After(deadline) {
+			break
+		}
+		time.Sleep(verifyInterval)
+	}
+
+	err := fmt.Errorf("not healthy %v after recovery: %w", timeout, lastErr)
+	log.Printf("ERROR: Recovery of %s failed verification: %v", target.Name, err)
+	span.SetError(err)
+	entry.Outcome = audit.OutcomeFailure
+	entry.Error = err.Error()
+	s.publishCause(events.TypeRestartFailed, target, entry.Cause, err.Error())
+}
+
+// cancelOnLeadershipLoss cancels a recovery started as leader if leadership
+// is lost before it finishes (e.g. on split brain), so a deposed leader stops
+// touching containers the new leader now decides about
+func (s *Supervisor) cancelOnLeadershipLoss(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(leadershipPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.elector.IsLeader() {
+				log.Printf("Lost leadership, canceling pending recovery actions")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// groupRecovering returns the name of a group member still recovering when
+// the target's group is at its restart limit, or "" if the target may be
+// restarted. Members that haven't turned healthy within recoveringTimeout
+// stop counting, so one broken worker can't block its group forever.
+func (s *Supervisor) groupRecovering(target monitor.CheckTarget) string {
+	if target.Group == "" || target.GroupRestarts <= 0 {
+		return ""
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recovering := []string{}
+	for _, other := range s.targets {
+		if other.Group != target.Group || other.Name == target.Name {
+			continue
+		}
+		if since, ok := s.recovering[other.Name]; ok && time.Since(since) < recoveringTimeout {
+			recovering = append(recovering, other.Name)
+		}
+	}
+	if len(recovering) < target.GroupRestarts {
+		return ""
+	}
+	return strings.Join(recovering, ", ")
+}
+
+// pipelineBusy reports whether a pipeline run is in progress
+func (s *Supervisor) pipelineBusy() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Now().Before(s.busyUntil)
+}
+
+// publish sends a target-related event
+func (s *Supervisor) publish(eventType string, target monitor.CheckTarget, message string) {
+	s.publishCause(eventType, target, "", message)
+}
+
+// publishCause publishes an event about a target with the machine-readable
+// reason of the restart it's about. Events during an incident of the target
+// are part of it and carry its ID.
+func (s *Supervisor) publishCause(eventType string, target monitor.CheckTarget, cause, message string) {
+	event := events.Event{
+		Type:          eventType,
+		Timestamp:     time.Now().UTC(),
+		CoordinatorID: s.myID,
+		LeaderID:      s.elector.GetLeaderID(),
+		Target:        target.Name,
+		ContainerName: target.ContainerName,
+		Message:       message,
+		Reason:        cause,
+	}
+	event.Incident = s.incidents.Record(event)
+	s.publisher.Publish(event)
+}
+
+// snapshotTargets returns a copy of the targets, which workers can add to
+// by registering while checks run
+func (s *Supervisor) snapshotTargets() []monitor.CheckTarget {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]monitor.CheckTarget(nil), s.targets...)
+}
+
+// findTarget looks a target up by name or container name
+func (s *Supervisor) findTarget(name string) (monitor.CheckTarget, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, target := range s.targets {
+		if target.Name == name || target.ContainerName == name {
+			return target, nil
+		}
+	}
+	return monitor.CheckTarget{}, fmt.Errorf("%w: %s", admin.ErrUnknownTarget, name)
+}
+
+// Status implements admin.Controller
+func (s *Supervisor) Status() admin.Status {
+	status := admin.Status{
+		ID:       s.myID,
+		NodeID:   s.nodeID,
+		IsLeader: s.elector.IsLeader(),
+		LeaderID: s.elector.GetLeaderID(),
+		Targets:  len(s.snapshotTargets()),
+
+		AdvertiseAddress: s.advertise,
+		PipelineBusy:     s.pipelineBusy(),
+		Backpressure:     s.backpressureActive(),
+		MassFailure:      s.massFailureDiagnosis(),
+		SafeMode:         s.inSafeMode(),
+		ClockSkew:        s.clocks.status(),
+	}
+	s.mu.RLock()
+	status.Leadership = s.leadership
+	s.mu.RUnlock()
+	// Followers show the leader's view of the cluster from its heartbeats
+	if carrier, ok := s.elector.(election.ClusterStateCarrier); ok {
+		if cluster, ok := carrier.ClusterState(); ok {
+			inSync := cluster.QuarantineDigest == s.ClusterState().QuarantineDigest
+			status.Cluster, status.QuarantineInSync = &cluster, &inSync
+		}
+	}
+	return status
+}
+
+// WatchLeadership records the elector's changes of leader for the admin
+// API until the elector stops reporting them
+func (s *Supervisor) WatchLeadership() {
+	for change := range s.elector.Subscribe() {
+		change := change
+		s.mu.Lock()
+		s.leadership = &change
+		s.mu.Unlock()
+		if !change.IsLeader {
+			s.dropPending()
+		}
+	}
+}
+
+// ClusterState summarizes the targets for the leader's heartbeats (see
+// election.ClusterState)
+func (s *Supervisor) ClusterState() election.ClusterState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := election.ClusterState{Targets: len(s.targets)}
+	for _, target := range s.targets {
+		if s.failures[target.Name] == 0 {
+			state.HealthyTargets++
+		}
+	}
+	quarantined := make([]string, 0, len(s.quarantined))
+	for name := range s.quarantined {
+		quarantined = append(quarantined, name)
+	}
+	sort.Strings(quarantined)
+	state.Quarantined = len(quarantined)
+	digest := sha256.Sum256([]byte(strings.Join(quarantined, "\n")))
+	state.QuarantineDigest = hex.EncodeToString(digest[:4])
+	return state
+}
+
+// Targets implements admin.Controller
+func (s *Supervisor) Targets() []admin.TargetStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]admin.TargetStatus, 0, len(s.targets))
+	for _, target := range s.targets {
+		_, pending := s.pending[target.Name]
+		statuses = append(statuses, admin.TargetStatus{
+			Name:           target.Name,
+			Group:          target.Group,
+			Address:        net.JoinHostPort(target.Host, target.Port),
+			ContainerName:  target.ContainerName,
+			Quarantined:    s.quarantined[target.Name],
+			LastError:      s.lastError[target.Name],
+			UptimePercent:  monitor.Uptime(s.history.Samples(target.Name)),
+			FailingPeers:   s.failingPeersLocked(target.Name),
+			Protected:      target.Protected,
+			RestartPending: pending,
+			Condition:      s.conditions[target.Name],
+			DependsOn:      target.DependsOn,
+			HeldBy:         s.heldByLocked(target.Name),
+		})
+	}
+	return statuses
+}
+
+// History implements admin.Controller
+func (s *Supervisor) History(name string) (admin.TargetHistory, error) {
+	target, err := s.findTarget(name)
+	if err != nil {
+		return admin.TargetHistory{}, err
+	}
+
+	samples := s.history.Samples(target.Name)
+	history := admin.TargetHistory{
+		Name:          target.Name,
+		UptimePercent: monitor.Uptime(samples),
+		Incidents:     monitor.Incidents(samples),
+		Samples:       samples,
+	}
+	if len(samples) > 0 {
+		history.Since = samples[0].Timestamp
+	}
+	return history, nil
+}
+
+// AvailabilityReport implements admin.Controller
+func (s *Supervisor) AvailabilityReport(from, to time.Time) (admin.AvailabilityReport, error) {
+	targets, err := s.availability.Report(from, to)
+	if err != nil {
+		return admin.AvailabilityReport{}, err
+	}
+	return admin.AvailabilityReport{From: from, To: to, Targets: targets}, nil
+}
+
+// RecoveryTimes implements admin.Controller
+func (s *Supervisor) RecoveryTimes() []monitor.RecoveryStats {
+	return s.mttr.Stats()
+}
+
+// Election implements admin.Controller
+func (s *Supervisor) Election() election.Stats {
+	return s.elector.Stats()
+}
+
+// Incidents implements admin.Controller
+func (s *Supervisor) Incidents() []incident.Incident {
+	return s.incidents.List()
+}
+
+// Restart implements admin.Controller. Only the leader recovers targets, so
+// the other coordinators answer admin.ErrNotLeader (409).
+func (s *Supervisor) Restart(name string) error {
+	if !s.elector.IsLeader() {
+		return admin.ErrNotLeader
+	}
+	return s.restartNow(name, "manual restart", events.ReasonOperatorManual)
+}
+
+// ScheduledRestart restarts a target for the maintenance task named task.
+// Like automatic restarts, it doesn't happen in the middle of a pipeline run
+// or a load spike.
+func (s *Supervisor) ScheduledRestart(name, task string) error {
+	if s.pipelineBusy() {
+		return fmt.Errorf("pipeline run in progress, not restarting %s", name)
+	}
+	if s.backpressureActive() {
+		return fmt.Errorf("broker under a load spike, not restarting %s", name)
+	}
+	return s.restartNow(name, "scheduled restart ("+task+")", events.ReasonScheduled)
+}
+
+// restartNow recovers a target on request, whatever its health
+func (s *Supervisor) restartNow(name, reason, cause string) error {
+	target, err := s.findTarget(name)
+	if err != nil {
+		return err
+	}
+
+	// Requested restarts use the target's own recovery action, except that
+	// "notify only" targets with a container still get a plain restart
+	action := recovery.ActionName(target)
+	if action == recovery.ActionNone && target.ContainerName != "" {
+		action = recovery.ActionRestart
+	}
+	return s.recover(context.Background(), target, action, reason, cause, "", "")
+}
+
+// Quarantine implements admin.Controller. The leader replicates the
+// quarantine to the others, whose own would be overwritten on the next
+// sync, so they answer admin.ErrNotLeader (409).
+func (s *Supervisor) Quarantine(name string, quarantined bool) error {
+	if !s.elector.IsLeader() {
+		return admin.ErrNotLeader
+	}
+	target, err := s.findTarget(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if quarantined {
+		s.quarantined[target.Name] = true
+		log.Printf("Target %s quarantined, automatic restarts disabled", target.Name)
+	} else {
+		delete(s.quarantined, target.Name)
+		log.Printf("Target %s released from quarantine", target.Name)
+	}
+	return nil
+}
+
+// StepDown implements admin.Controller
+func (s *Supervisor) StepDown() error {
+	if !s.elector.IsLeader() {
+		return admin.ErrNotLeader
+	}
+	return s.elector.StepDown()
+}
+
+// Promote implements admin.Controller
+func (s *Supervisor) Promote(id int) error {
+	err := s.elector.Promote(id)
+	if errors.Is(err, election.ErrUnknownCoordinator) {
+		return fmt.Errorf("%w: %d", admin.ErrUnknownCoordinator, id)
+	}
+	return err
+}
+
+// SetPipelineBusy implements admin.Controller. Every coordinator keeps the
+// flag so it survives leader changes; the gateway should notify all of them.
+func (s *Supervisor) SetPipelineBusy(busy bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if busy {
+		s.busyUntil = time.Now().Add(s.busyTimeout)
+		log.Printf("Pipeline run started, deferring non-critical restarts for up to %v", s.busyTimeout)
+	} else {
+		s.busyUntil = time.Time{}
+		log.Printf("Pipeline run finished, resuming restarts")
+	}
+	return nil
+}
+
+// Register implements admin.Controller. A registration replaces any target
+// with the same name, so a worker can re-register after a restart.
+func (s *Supervisor) Register(registration admin.Registration) error {
+	if registration.Name == "" {
+		return fmt.Errorf("%w: missing name", admin.ErrInvalidRegistration)
+	}
+	interval, err := time.ParseDuration(registration.HeartbeatInterval)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("%w: invalid heartbeat_interval %q", admin.ErrInvalidRegistration, registration.HeartbeatInterval)
+	}
+
+	target := monitor.CheckTarget{
+		Name:             registration.Name,
+		Host:             registration.Host,
+		Port:             registration.Port,
+		ContainerName:    registration.ContainerName,
+		DockerHost:       registration.DockerHost,
+		CheckType:        monitor.CheckTypePush,
+		HeartbeatTimeout: missedHeartbeats * interval,
+	}
+	if target.Host == "" {
+		target.Host = target.Name
+	}
+	if target.Port == "" {
+		target.Port = workerHealthPort()
+	}
+	if target.ContainerName == "" {
+		target.Recovery = recovery.ActionNone
+	}
+
+	s.mu.Lock()
+	replaced := false
+	for i := range s.targets {
+		if s.targets[i].Name == target.Name {
+			s.targets[i] = target
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.targets = append(s.targets, target)
+	}
+	s.mu.Unlock()
+
+	s.heartbeats.Beat(target.Name)
+	log.Printf("Worker %s registered (heartbeat every %v)", target.Name, interval)
+	return nil
+}
+
+// Heartbeat implements admin.Controller. Unknown workers get ErrUnknownTarget
+// so they know to register again (e.g. after a coordinator restart).
+func (s *Supervisor) Heartbeat(name string) error {
+	target, err := s.findTarget(name)
+	if err != nil {
+		return err
+	}
+	s.heartbeats.Beat(target.Name)
+	return nil
+}
+
+// Alerts implements admin.Controller
+func (s *Supervisor) Alerts() []alert.Record {
+	return s.alerter.Open()
+}
+
+// AckAlert implements admin.Controller
+func (s *Supervisor) AckAlert(id, by string) error {
+	if by == "" {
+		by = "operator"
+	}
+	return s.alerter.Ack(id, by)
+}
+
+// escalates reports whether unacknowledged alerts about a target are
+// escalated: those of critical and quarantined targets
+func (s *Supervisor) escalates(name string) bool {
+	target, err := s.findTarget(name)
+	if err != nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return target.Critical || s.quarantined[target.Name]
+}
+
+// Subscribe implements admin.Controller
+func (s *Supervisor) Subscribe() *stream.Subscription {
+	return s.stream.Subscribe(0)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker/dockertest"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+)
+
+// fakeElector is an elector whose leadership doesn't change
+type fakeElector struct {
+	leader bool
+}
+
+func (e *fakeElector) Start()                {}
+func (e *fakeElector) IsLeader() bool        { return e.leader }
+func (e *fakeElector) GetLeaderID() int      { return 1 }
+func (e *fakeElector) StepDown() error       { return nil }
+func (e *fakeElector) Promote(int) error     { return nil }
+func (e *fakeElector) Stats() election.Stats { return election.Stats{} }
+func (e *fakeElector) Subscribe() <-chan election.LeadershipEvent {
+	return make(chan election.LeadershipEvent)
+}
+func (e *fakeElector) Unsubscribe(<-chan election.LeadershipEvent) {}
+
+// eventRecorder keeps the types of the events published to it
+type eventRecorder struct {
+	mu    sync.Mutex
+	types []string
+}
+
+func (r *eventRecorder) Publish(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types = append(r.types, event.Type)
+}
+
+func (r *eventRecorder) Close() error { return nil }
+
+func (r *eventRecorder) count(eventType string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, t := range r.types {
+		if t == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+// newTestSupervisor creates a leading supervisor whose targets are checked
+// with Docker's view of their containers and recovered on runtime
+func newTestSupervisor(t *testing.T, runtime *dockertest.Runtime, targets ...monitor.CheckTarget) (*Supervisor, *eventRecorder) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	checkers := monitor.NewRegistry()
+	checkers.Register(monitor.CheckTypeDockerHealth, monitor.NewDockerHealthChecker(func(string) (monitor.Inspector, error) {
+		return runtime, nil
+	}))
+	recoveries := recovery.NewRegistry()
+	recovery.RegisterDocker(recoveries, func(string) (recovery.DockerRuntime, error) { return runtime, nil })
+
+	publisher := &eventRecorder{}
+	supervisor := NewSupervisor(SupervisorDeps{
+		MyID:         1,
+		Targets:      targets,
+		Elector:      &fakeElector{leader: true},
+		Recoveries:   recoveries,
+		Checkers:     checkers,
+		Heartbeats:   monitor.NewPushChecker(),
+		History:      monitor.NewHistory(10),
+		Availability: monitor.NewAvailability(""),
+		MTTR:         monitor.NewRecoveryTimes(10),
+		Publisher:    publisher,
+		Bus:          stream.NewBus(),
+	})
+	return supervisor, publisher
+}
+
+// checkOnce checks a target and handles its failure like RunChecks does,
+// whenever the target is scheduled
+func checkOnce(s *Supervisor, target monitor.CheckTarget) {
+	result := s.check(target)
+	s.checkLog.Result(target.Name, result, s.failingPeers(target.Name))
+	if result.Err != nil {
+		s.handleFailure(context.Background(), target, result.Err)
+	}
+}
+
+func dockerTarget(name string) monitor.CheckTarget {
+	return monitor.CheckTarget{Name: name, ContainerName: name, CheckType: monitor.CheckTypeDockerHealth, FailureThreshold: 2}
+}
+
+func crash(runtime *dockertest.Runtime, name string) {
+	runtime.Update(name, func(c *dockertest.Container) { c.Running = false })
+}
+
+func TestSupervisorRestartsACrashedContainerPastTheThreshold(t *testing.T) {
+	runtime := dockertest.NewRuntime("filter-1")
+	target := dockerTarget("filter-1")
+	supervisor, publisher := newTestSupervisor(t, runtime, target)
+
+	crash(runtime, "filter-1")
+	checkOnce(supervisor, target)
+	if container, _ := runtime.Container("filter-1"); container.Restarts != 0 {
+		t.Fatal("restarted after one failed check, below the threshold of 2")
+	}
+
+	checkOnce(supervisor, target)
+	if container, _ := runtime.Container("filter-1"); !container.Running || container.Restarts != 1 {
+		t.Fatalf("container %+v, want it restarted once", container)
+	}
+	if publisher.count(events.TypeNodeDown) != 1 || publisher.count(events.TypeRestarted) != 1 {
+		t.Errorf("published %v, want a node.down and a node.restarted", publisher.types)
+	}
+
+	// A passing check closes the incident
+	checkOnce(supervisor, target)
+	supervisor.mu.RLock()
+	failures, restarts := supervisor.failures["filter-1"], supervisor.restartCount["filter-1"]
+	supervisor.mu.RUnlock()
+	if failures != 0 || restarts != 0 {
+		t.Errorf("%d failures and %d restarts left after a passing check", failures, restarts)
+	}
+}
+
+func TestSupervisorSkipsQuarantinedTargets(t *testing.T) {
+	runtime := dockertest.NewRuntime("filter-1")
+	target := dockerTarget("filter-1")
+	supervisor, _ := newTestSupervisor(t, runtime, target)
+	if err := supervisor.Quarantine("filter-1", true); err != nil {
+		t.Fatal(err)
+	}
+
+	crash(runtime, "filter-1")
+	for i := 0; i < 3; i++ {
+		checkOnce(supervisor, target)
+	}
+	if container, _ := runtime.Container("filter-1"); container.Restarts != 0 {
+		t.Errorf("a quarantined target was restarted %d times", container.Restarts)
+	}
+}
+
+func TestSupervisorGivesUpAfterMaxRestarts(t *testing.T) {
+	runtime := dockertest.NewRuntime("filter-1")
+	// The container crashes again as soon as it's restarted
+	runtime.OnRestart = func(c *dockertest.Container) { c.Running = false }
+	target := dockerTarget("filter-1")
+	target.FailureThreshold, target.MaxRestarts = 1, 2
+	supervisor, publisher := newTestSupervisor(t, runtime, target)
+
+	crash(runtime, "filter-1")
+	for i := 0; i < 5; i++ {
+		checkOnce(supervisor, target)
+	}
+	if container, _ := runtime.Container("filter-1"); container.Restarts != 2 {
+		t.Errorf("restarted %d times, want 2", container.Restarts)
+	}
+	if publisher.count(events.TypeGaveUp) != 1 {
+		t.Errorf("published %v, want one node.gave_up", publisher.types)
+	}
+}
+
+func TestSupervisorReportsAFailedRestart(t *testing.T) {
+	runtime := dockertest.NewRuntime("filter-1")
+	runtime.Fail("RestartContainer", "filter-1", &docker.APIError{StatusCode: 500, Message: "driver failed"})
+	target := dockerTarget("filter-1")
+	target.FailureThreshold = 1
+	supervisor, publisher := newTestSupervisor(t, runtime, target)
+
+	crash(runtime, "filter-1")
+	checkOnce(supervisor, target)
+	if container, _ := runtime.Container("filter-1"); container.Running {
+		t.Error("the container is running though its restart failed")
+	}
+	if publisher.count(events.TypeRestartFailed) != 1 || publisher.count(events.TypeRestarted) != 0 {
+		t.Errorf("published %v, want a node.restart_failed", publisher.types)
+	}
+
+	// Once the daemon works again the next failed check restarts it
+	runtime.Fail("RestartContainer", "filter-1", nil)
+	checkOnce(supervisor, target)
+	if container, _ := runtime.Container("filter-1"); !container.Running {
+		t.Error("the container wasn't restarted once the daemon recovered")
+	}
+}
+
+func TestSupervisorRecreatesARemovedContainer(t *testing.T) {
+	runtime := dockertest.NewRuntime()
+	target := dockerTarget("filter-1")
+	target.FailureThreshold = 1
+	target.ContainerSpec = &docker.ContainerSpec{Image: "filter:latest"}
+	supervisor, _ := newTestSupervisor(t, runtime, target)
+
+	checkOnce(supervisor, target)
+	if container, ok := runtime.Container("filter-1"); !ok || !container.Running {
+		t.Error("the removed container wasn't recreated from its spec")
+	}
+}
+
+func TestFollowerRefusesManualRestarts(t *testing.T) {
+	runtime := dockertest.NewRuntime("filter-1")
+	supervisor, _ := newTestSupervisor(t, runtime, dockerTarget("filter-1"))
+	supervisor.elector = &fakeElector{}
+
+	if err := supervisor.Restart("filter-1"); !errors.Is(err, admin.ErrNotLeader) {
+		t.Errorf("restart on a follower: %v, want %v", err, admin.ErrNotLeader)
+	}
+	if err := supervisor.Quarantine("filter-1", true); !errors.Is(err, admin.ErrNotLeader) {
+		t.Errorf("quarantine on a follower: %v, want %v", err, admin.ErrNotLeader)
+	}
+	if calls := runtime.Calls(); len(calls) != 0 {
+		t.Errorf("a follower called the runtime: %v", calls)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// suspectReport is a worker reporting that another target failed, e.g.
+// because the consumer of its downstream queue vanished
+type suspectReport struct {
+	From   string
+	Reason string
+	At     time.Time
+}
+
+// ReportSuspect implements admin.Controller: the leader checks a target
+// some worker reports failing right away, ahead of its interval. Only the
+// leader takes reports; it's the one that would recover the target.
+func (s *Supervisor) ReportSuspect(report admin.SuspectReport) error {
+	if !s.elector.IsLeader() {
+		return admin.ErrNotLeader
+	}
+	target, err := s.findTarget(report.Target)
+	if err != nil {
+		return err
+	}
+	if report.From == "" {
+		report.From = "unknown worker"
+	}
+
+	s.mu.Lock()
+	s.suspects[target.Name] = suspectReport{From: report.From, Reason: report.Reason, At: time.Now()}
+	s.mu.Unlock()
+
+	message := fmt.Sprintf("reported failing by %s", report.From)
+	if report.Reason != "" {
+		message += ": " + report.Reason
+	}
+	log.Printf("Target %s %s, checking it now", target.Name, message)
+	s.publish(events.TypeSuspected, target, message)
+	return nil
+}
+
+// reportedLocked reports whether a worker reported a target failing since
+// its last check. s.mu must be held.
+func (s *Supervisor) reportedLocked(name string) bool {
+	report, ok := s.suspects[name]
+	return ok && report.At.After(s.lastChecked[name])
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// configCheck collects the problems found while validating the
+// configuration
+type configCheck struct {
+	problems []string
+}
+
+func (c *configCheck) fail(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// check records err, if any, prefixed with what was being checked
+func (c *configCheck) check(what string, err error) {
+	if err != nil {
+		c.fail("%s: %v", what, err)
+	}
+}
+
+// validateConfig checks the configuration the way startup loads it, but
+// strictly: what startup only warns about (a config file that doesn't
+// parse, invalid labels or static targets, services that can't be
+// resolved) is a problem here too. It also resolves every target and checks
+// them for duplicate names and addresses, and that every Docker daemon
+// answers. It returns the targets resolved and the problems found.
+func validateConfig(myID, totalReplicas int, config *FileConfig, dockerPool *docker.Pool) ([]monitor.CheckTarget, []string) {
+	c := &configCheck{}
+	if totalReplicas < 1 || myID < 1 || myID > totalReplicas {
+		c.fail("MY_ID %d must be between 1 and TOTAL_REPLICAS (%d)", myID, totalReplicas)
+	}
+	if path := getEnv("CONFIG_PATH", ""); path != "" {
+		_, err := loadFileConfig(path)
+		c.check("config file", err)
+	}
+	_, err := selectProfile(config)
+	c.check("profile", err)
+	_, _, err = nodeIdentity(config.Election)
+	c.check("node identity", err)
+	_, err = bindAddress()
+	c.check("bind address", err)
+	_, err = advertiseAddress()
+	c.check("advertise address", err)
+	if getEnvInt("FAILURE_THRESHOLD", 0) < 0 {
+		c.fail("FAILURE_THRESHOLD must not be negative")
+	}
+	if getEnv("QUORUM_CONFIRMATION", "false") == "true" && getEnv("FOLLOWER_CHECKS", "false") != "true" {
+		c.fail("QUORUM_CONFIRMATION needs FOLLOWER_CHECKS")
+	}
+
+	peers, err := peerHosts(config.Election)
+	c.check("election", err)
+	switch backend := getEnv("ELECTION_BACKEND", election.BackendBully); backend {
+	case election.BackendBully:
+		_, err := bullyOptions(config.Election, peers)
+		c.check("election", err)
+	case election.BackendRaft, election.BackendConsul, election.BackendRedis:
+	default:
+		c.fail("invalid ELECTION_BACKEND %q", backend)
+	}
+
+	// Compose services whose labels are invalid are skipped with a warning
+	// at startup
+	if compose, err := loadComposeServices(composePaths()); err != nil {
+		c.check("compose files", err)
+	} else {
+		for name, service := range compose.Services {
+			target := monitor.CheckTarget{Name: name}
+			c.check("service "+name, applyLabels(&target, service.Labels))
+		}
+	}
+	for _, static := range config.Targets {
+		_, err := static.toCheckTarget()
+		c.check("config file", err)
+	}
+
+	targets := coordinatorTargets(myID, totalReplicas, peers)
+	local, _ := dockerPool.Client("")
+	discovered, err := discoverTargets(local, config)
+	c.check("targets", err)
+	targets = append(targets, discovered...)
+	c.check("groups", applyGroupPolicies(targets, config.Groups))
+	c.check("dependencies", checkDependencies(targets))
+	c.checkTargets(targets, config, dockerPool)
+
+	c.checkSections(config)
+	c.checkDocker(dockerPool, config)
+	return targets, c.problems
+}
+
+// checkTargets looks for targets with the same name or address, and for
+// check types, recovery actions and Docker hosts that don't exist
+func (c *configCheck) checkTargets(targets []monitor.CheckTarget, config *FileConfig, dockerPool *docker.Pool) {
+	checkers := newCheckers(dockerPool, monitor.NewPushChecker())
+	recoveries := newRecoveries(dockerPool, config)
+	c.check("plugins", registerPlugins(config.Plugins, checkers, recoveries))
+	if getEnv("GOSSIP_ENABLED", "false") == "true" {
+		checkers.Register(monitor.CheckTypeGossip, monitor.NewGossipChecker(nil))
+	}
+
+	names := make(map[string]bool, len(targets))
+	addresses := make(map[string]string, len(targets))
+	for _, target := range targets {
+		if names[target.Name] {
+			c.fail("target %s is declared more than once", target.Name)
+		}
+		names[target.Name] = true
+
+		if _, ok := checkers.Lookup(target.CheckType); !ok {
+			c.fail("target %s: unknown check type %q", target.Name, target.CheckType)
+		}
+		if _, ok := recoveries.Lookup(target.Recovery); !ok {
+			c.fail("target %s: unknown recovery action %q", target.Name, target.Recovery)
+		}
+		if target.DockerHost != "" {
+			if _, ok := dockerHosts(config)[target.DockerHost]; !ok {
+				c.fail("target %s: unknown Docker host %q", target.Name, target.DockerHost)
+			}
+		}
+
+		// Only the checks that dial the target's address can collide
+		switch target.CheckType {
+		case "", monitor.CheckTypeTCP, monitor.CheckTypeHTTP, monitor.CheckTypeConnect, monitor.CheckTypeGateway:
+		default:
+			continue
+		}
+		address := net.JoinHostPort(target.Host, target.Port)
+		if other, ok := addresses[address]; ok {
+			c.fail("targets %s and %s are both checked at %s", other, target.Name, address)
+			continue
+		}
+		addresses[address] = target.Name
+	}
+}
+
+// checkSections checks the sections of the config file that are only read
+// once the coordinator is running
+func (c *configCheck) checkSections(config *FileConfig) {
+	for _, rule := range config.Autoscale {
+		_, err := rule.toRule()
+		c.check("autoscale", err)
+	}
+	_, _, err := alertPolicy(config.Alerts)
+	c.check("alerts", err)
+	_, err = maintenanceTasks(config.Maintenance)
+	c.check("maintenance", err)
+	_, err = newDesiredState(config.Desired, nil, nil, nil, 0, nil)
+	c.check("desired", err)
+	c.check("redelivery", checkRedeliveryRules(config.Redelivery))
+}
+
+// checkDocker checks that the local Docker daemon and every remote one
+// answer
+func (c *configCheck) checkDocker(dockerPool *docker.Pool, config *FileConfig) {
+	hosts := []string{""}
+	for name := range dockerHosts(config) {
+		hosts = append(hosts, name)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		client, err := dockerPool.Client(host)
+		if err == nil {
+			err = client.Ping(ctx)
+		}
+		cancel()
+		if host == "" {
+			host = localHostName
+		}
+		c.check("Docker host "+host, err)
+	}
+}
+
+// runValidate implements "coordinator validate": it validates the
+// configuration from the environment, prints what it found and fails if
+// there are problems
+func runValidate(stdout io.Writer) error {
+	totalReplicas, err := strconv.Atoi(getEnv("TOTAL_REPLICAS", "3"))
+	if err != nil {
+		return fmt.Errorf("invalid TOTAL_REPLICAS: %w", err)
+	}
+
+	config := &FileConfig{}
+	if path := getEnv("CONFIG_PATH", ""); path != "" {
+		if loaded, err := loadFileConfig(path); err == nil {
+			config = loaded
+		}
+	}
+	// Problems with the identity are reported by validateConfig
+	_, myID, _ := nodeIdentity(config.Election)
+	dockerOptions := dockerOptionsFromEnv()
+	dockerClient, err := docker.NewClientWithOptions(dockerOptions)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Docker client: %w", err)
+	}
+	dockerPool := docker.NewPool(dockerClient, dockerHosts(config), dockerOptions)
+	defer dockerPool.Close()
+
+	targets, problems := validateConfig(myID, totalReplicas, config, dockerPool)
+	for _, problem := range problems {
+		fmt.Fprintf(stdout, "ERROR: %s\n", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+	fmt.Fprintf(stdout, "OK: %d targets\n", len(targets))
+	return nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+)
+
+const usage = `Usage: coordinatorctl [-addr host:port] [-token token] <command> [args]
+
+Commands:
+  status                 Show coordinator status
+  targets                List monitored targets
+  history <name>         Show a target's uptime and incidents
+  election               Show election stats and leadership transitions
+  mttr                   Show recovery time percentiles per target group
+  report [from] [to]     Show each target's availability (times as RFC 3339 or
+                         a duration ago like 24h; default the last 24 hours)
+  restart <name>         Restart a target
+  confirm-restart <name> Confirm the pending restart of a protected target
+  quarantine <name>      Disable automatic restarts for a target
+  unquarantine <name>    Re-enable automatic restarts for a target
+  leader step-down       Make the leader give up leadership
+  leader promote <id>    Make coordinator <id> the leader
+  pipeline busy|idle     Mark a query run as started or finished
+  snapshot export        Print the coordinator's runtime state as JSON
+  snapshot import <file> Restore a snapshot's state into the coordinator
+  events                 Follow health transitions, restarts and leadership changes
+  alerts                 List alerts waiting for acknowledgment
+  ack <id>               Acknowledge an alert, stopping its escalation
+  incidents              List ongoing and recent incidents with what was done about them
+`
+
+func main() {
+	addr := flag.String("addr", getEnv("COORDINATOR_ADDR", "localhost:12347"), "coordinator admin API address")
+	token := flag.String("token", getEnv("ADMIN_TOKEN", ""), "admin API token")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := admin.NewClient(*addr, admin.WithToken(*token))
+	if err := run(client, args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run executes a single command
+func run(client *admin.Client, args []string) error {
+	switch args[0] {
+	case "status":
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("ID:        %d\n", status.ID)
+		if status.NodeID != "" {
+			fmt.Printf("Node:      %s\n", status.NodeID)
+		}
+		if status.AdvertiseAddress != "" {
+			fmt.Printf("Advertise: %s\n", status.AdvertiseAddress)
+		}
+		fmt.Printf("Leader:    %t\n", status.IsLeader)
+		fmt.Printf("Leader ID: %d\n", status.LeaderID)
+		fmt.Printf("Targets:   %d\n", status.Targets)
+		fmt.Printf("Busy:      %t\n", status.PipelineBusy)
+		fmt.Printf("Throttled: %t\n", status.Backpressure)
+		fmt.Printf("Safe mode: %t\n", status.SafeMode)
+		if cluster := status.Cluster; cluster != nil {
+			fmt.Printf("Cluster:   %d/%d targets healthy, %d quarantined (leader %d, term %d, %v ago)\n",
+				cluster.HealthyTargets, cluster.Targets, cluster.Quarantined, cluster.LeaderID, cluster.Term,
+				time.Since(cluster.UpdatedAt).Round(time.Second))
+			if status.QuarantineInSync != nil && !*status.QuarantineInSync {
+				fmt.Printf("           quarantine list differs from the leader's\n")
+			}
+		}
+		if change := status.Leadership; change != nil {
+			fmt.Printf("Leader:    coordinator %d since %s (term %d, %s)\n", change.LeaderID,
+				change.Timestamp.Format(time.RFC3339), change.Term, change.Reason)
+		}
+		if status.MassFailure != "" {
+			fmt.Printf("Failing:   %s\n", status.MassFailure)
+		}
+		for _, clock := range status.ClockSkew {
+			skew, direction := clock.Skew.Round(time.Millisecond), "ahead"
+			if skew < 0 {
+				skew, direction = -skew, "behind"
+			}
+			flag := ""
+			if clock.Significant {
+				flag = " (significant)"
+			}
+			fmt.Printf("Clock of coordinator %d: %v %s%s\n", clock.ID, skew, direction, flag)
+		}
+		return nil
+
+	case "targets":
+		targets, err := client.Targets()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tGROUP\tADDRESS\tCONTAINER\tUPTIME\tQUARANTINED\tCONDITION\tLAST ERROR")
+		for _, t := range targets {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f%%\t%t\t%s\t%s\n", t.Name, t.Group, t.Address, t.ContainerName,
+				t.UptimePercent, t.Quarantined, t.Condition, t.LastError)
+		}
+		return w.Flush()
+
+	case "history":
+		if len(args) != 2 {
+			return fmt.Errorf("history requires a target name")
+		}
+		history, err := client.History(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Target:  %s\n", history.Name)
+		fmt.Printf("Checks:  %d since %s\n", len(history.Samples), history.Since.Format(time.RFC3339))
+		fmt.Printf("Uptime:  %.2f%%\n", history.UptimePercent)
+		fmt.Printf("Incidents:\n")
+		for _, incident := range history.Incidents {
+			state := ""
+			if incident.Ongoing {
+				state = " (ongoing)"
+			}
+			fmt.Printf("  %s  %v%s  %s\n", incident.Start.Format(time.RFC3339), incident.Duration(), state, incident.Error)
+		}
+		return nil
+
+	case "election":
+		stats, err := client.Election()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Term:               %d\n", stats.Term)
+		fmt.Printf("Leader:             %t (leader ID %d)\n", stats.IsLeader, stats.LeaderID)
+		fmt.Printf("Elections:          %d started, %d won, %d lost\n", stats.ElectionsStarted, stats.ElectionsWon, stats.ElectionsLost)
+		fmt.Printf("Leadership changes: %d\n", stats.LeadershipChanges)
+		fmt.Printf("Time as leader:     %v\n", stats.TimeAsLeader.Round(time.Second))
+		fmt.Printf("Transitions:\n")
+		for _, transition := range stats.Transitions {
+			fmt.Printf("  %s  leader=%d term=%d is_leader=%t\n", transition.Timestamp.Format(time.RFC3339),
+				transition.LeaderID, transition.Term, transition.IsLeader)
+		}
+		return nil
+
+	case "report":
+		if len(args) > 3 {
+			return fmt.Errorf("report takes at most a from and a to")
+		}
+		now := time.Now()
+		from, to := now.Add(-24*time.Hour), now
+		var err error
+		if len(args) > 1 {
+			if from, err = reportTime(args[1], now); err != nil {
+				return err
+			}
+		}
+		if len(args) > 2 {
+			if to, err = reportTime(args[2], now); err != nil {
+				return err
+			}
+		}
+		report, err := client.AvailabilityReport(from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("From %s to %s\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TARGET	AVAILABILITY	UP	DOWN	CHECKS	FAILURES	OUTAGES")
+		for _, t := range report.Targets {
+			fmt.Fprintf(w, "%s\t%.3f%%\t%v\t%v\t%d\t%d\t%d\n", t.Target, t.AvailabilityPercent,
+				t.Up.Round(time.Second), t.Down.Round(time.Second), t.Checks, t.Failures, t.Outages)
+		}
+		return w.Flush()
+
+	case "mttr":
+		stats, err := client.RecoveryTimes()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tINCIDENTS\tMEAN\tP50\tP90\tP99\tMAX")
+		for _, g := range stats {
+			fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%v\t%v\t%v\n", g.Group, g.Incidents, g.Mean.Round(time.Second),
+				g.P50.Round(time.Second), g.P90.Round(time.Second), g.P99.Round(time.Second), g.Max.Round(time.Second))
+		}
+		return w.Flush()
+
+	case "alerts":
+		records, err := client.Alerts()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID	TARGET	TYPE	SEVERITY	RAISED	ESCALATIONS	MESSAGE")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", r.ID, r.Target, r.Type, r.Severity,
+				r.RaisedAt.Format(time.RFC3339), r.Escalations, r.Message)
+		}
+		return w.Flush()
+
+	case "incidents":
+		incidents, err := client.Incidents()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID	TARGET	CAUSE	START	DURATION	FAILURES	ACTIONS	RESOLUTION")
+		for _, i := range incidents {
+			resolution := i.Resolution
+			if i.Ongoing {
+				resolution = "ongoing"
+			}
+			actions := make([]string, 0, len(i.Actions))
+			for _, action := range i.Actions {
+				actions = append(actions, action.Event)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%d\t%s\t%s\n", i.ID, i.Target, i.Cause, i.Start.Format(time.RFC3339),
+				i.Duration().Round(time.Second), i.Failures, strings.Join(actions, ","), resolution)
+		}
+		return w.Flush()
+
+	case "ack":
+		if len(args) != 2 {
+			return fmt.Errorf("ack requires an alert ID")
+		}
+		if err := client.AckAlert(args[1], getEnv("USER", "")); err != nil {
+			return err
+		}
+		fmt.Printf("Alert %s acknowledged\n", args[1])
+		return nil
+
+	case "restart", "confirm-restart", "quarantine", "unquarantine":
+		if len(args) != 2 {
+			return fmt.Errorf("%s requires a target name", args[0])
+		}
+		var err error
+		switch args[0] {
+		case "restart":
+			err = client.Restart(args[1])
+		case "confirm-restart":
+			err = client.ConfirmRestart(args[1], getEnv("USER", ""))
+		case "quarantine":
+			err = client.Quarantine(args[1], true)
+		case "unquarantine":
+			err = client.Quarantine(args[1], false)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: ok\n", args[0])
+		return nil
+
+	case "leader":
+		switch {
+		case len(args) == 2 && args[1] == "step-down":
+			if err := client.StepDown(); err != nil {
+				return err
+			}
+			fmt.Println("leader stepped down")
+			return nil
+		case len(args) == 3 && args[1] == "promote":
+			id, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid coordinator ID %q", args[2])
+			}
+			if err := client.Promote(id); err != nil {
+				return err
+			}
+			fmt.Printf("coordinator %d promoted\n", id)
+			return nil
+		}
+		return fmt.Errorf("usage: coordinatorctl leader step-down|promote <id>")
+
+	case "pipeline":
+		if len(args) != 2 || (args[1] != "busy" && args[1] != "idle") {
+			return fmt.Errorf("usage: coordinatorctl pipeline busy|idle")
+		}
+		if err := client.SetPipelineBusy(args[1] == "busy"); err != nil {
+			return err
+		}
+		fmt.Printf("pipeline %s\n", args[1])
+		return nil
+
+	case "events":
+		return client.WatchEvents(context.Background(), func(event admin.StreamEvent) error {
+			fmt.Printf("%s  %-22s %s\n", time.Now().Format(time.RFC3339), event.Name, event.Data)
+			return nil
+		})
+
+	case "snapshot":
+		switch {
+		case len(args) == 2 && args[1] == "export":
+			snapshot, err := client.ExportSnapshot()
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(snapshot)
+		case len(args) == 3 && args[1] == "import":
+			data, err := os.ReadFile(args[2])
+			if err != nil {
+				return err
+			}
+			var snapshot admin.Snapshot
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				return fmt.Errorf("failed to parse snapshot %s: %w", args[2], err)
+			}
+			if err := client.ImportSnapshot(snapshot); err != nil {
+				return err
+			}
+			fmt.Printf("snapshot imported\n")
+			return nil
+		default:
+			return fmt.Errorf("usage: coordinatorctl snapshot export | snapshot import <file>")
+		}
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// reportTime parses a report bound: an RFC 3339 time, or a duration before
+// now
+func reportTime(value string, now time.Time) (time.Time, error) {
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (expected RFC 3339 or a duration like 24h)", value)
+	}
+	return parsed, nil
+}
+
+// getEnv gets an environment variable with a default value
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,233 @@
+// Command electionsim runs failover scenarios against simulated clusters of
+// Bully coordinators (see election.Simulation) and reports which ones hold.
+// Runs use virtual time, so hundreds of seeds take seconds; a failing seed
+// can be replayed with -seed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+)
+
+// scenario scripts faults against a simulation and checks the outcome
+type scenario struct {
+	name string
+	// run gets a random source seeded like the simulation, for scenarios
+	// that script random faults
+	run func(sim *election.Simulation, replicas int, r *rand.Rand) error
+}
+
+// settle is how long a cluster gets to agree on a leader after a fault
+const settle = 30 * time.Second
+
+var scenarios = []scenario{
+	{"startup elects the highest ID", func(sim *election.Simulation, replicas int, _ *rand.Rand) error {
+		sim.Run(settle)
+		return expectLeader(sim, replicas)
+	}},
+	{"leader crash fails over to the next ID", func(sim *election.Simulation, replicas int, _ *rand.Rand) error {
+		sim.Run(settle)
+		sim.Crash(replicas)
+		sim.Run(settle)
+		return expectLeader(sim, replicas-1)
+	}},
+	{"restarted leader takes over again", func(sim *election.Simulation, replicas int, _ *rand.Rand) error {
+		sim.Run(settle)
+		sim.Crash(replicas)
+		sim.Run(settle)
+		sim.Restart(replicas)
+		sim.Run(2 * settle)
+		return expectLeader(sim, replicas)
+	}},
+	{"one leader after a partition heals", func(sim *election.Simulation, replicas int, _ *rand.Rand) error {
+		sim.Run(settle)
+		sim.Partition(ids(1, replicas/2), ids(replicas/2+1, replicas))
+		sim.Run(settle)
+		sim.Heal()
+		sim.Run(2 * settle)
+		// The side that elected a leader during the partition has the
+		// higher term, so its leader may keep leading even if outranked
+		return expectAgreement(sim)
+	}},
+	{"lossy, slow network still converges", func(sim *election.Simulation, replicas int, _ *rand.Rand) error {
+		sim.SetDropRate(0.2)
+		sim.SetDelay(10*time.Millisecond, 500*time.Millisecond)
+		sim.Run(settle)
+		sim.Crash(replicas)
+		sim.Run(settle)
+		sim.SetDropRate(0)
+		sim.Run(settle)
+		// A lost OK can let a lower ID win with a higher term
+		return expectAgreement(sim)
+	}},
+	{"step-down hands leadership over", func(sim *election.Simulation, replicas int, _ *rand.Rand) error {
+		sim.Run(settle)
+		if err := sim.StepDown(replicas); err != nil {
+			return err
+		}
+		sim.Run(5 * time.Second)
+		return expectLeader(sim, replicas-1)
+	}},
+
+	// Properties: each seed scripts its own random faults. Whatever they
+	// are, no two coordinators may lead one term at once, and a single
+	// leader must emerge once they stop.
+	{"property: the highest live ID leads after each fault", func(sim *election.Simulation, replicas int, r *rand.Rand) error {
+		sim.Run(settle)
+		for steps := 3 + r.Intn(6); steps > 0; steps-- {
+			crashOrRestart(sim, replicas, r)
+			sim.Run(settle)
+			up := sim.Up()
+			if err := expectLeader(sim, up[len(up)-1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"property: one leader after rapid crashes and restarts", func(sim *election.Simulation, replicas int, r *rand.Rand) error {
+		sim.Run(settle)
+		for steps := 3 + r.Intn(6); steps > 0; steps-- {
+			crashOrRestart(sim, replicas, r)
+			sim.Run(time.Duration(r.Int63n(int64(settle / 2))))
+		}
+		sim.Run(2 * settle)
+		// Faults that overlap can leave a lower ID leading with a higher
+		// term than the rest
+		return expectAgreement(sim)
+	}},
+	{"property: one leader after random partitions heal", func(sim *election.Simulation, replicas int, r *rand.Rand) error {
+		sim.Run(settle)
+		for steps := 3 + r.Intn(6); steps > 0; steps-- {
+			switch r.Intn(3) {
+			case 0:
+				crashOrRestart(sim, replicas, r)
+			case 1:
+				sim.Partition(randomSplit(replicas, r)...)
+			default:
+				sim.Heal()
+			}
+			sim.Run(time.Duration(r.Int63n(int64(settle))))
+		}
+		sim.Heal()
+		sim.Run(2 * settle)
+		return expectAgreement(sim)
+	}},
+}
+
+func main() {
+	replicas := flag.Int("replicas", 3, "coordinators in the simulated cluster")
+	runs := flag.Int("runs", 100, "seeds to run each scenario with")
+	seed := flag.Int64("seed", 0, "run only this seed")
+	verbose := flag.Bool("v", false, "show the coordinators' logs")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	seeds := make([]int64, 0, *runs)
+	if *seed != 0 {
+		seeds = append(seeds, *seed)
+	} else {
+		for i := 1; i <= *runs; i++ {
+			seeds = append(seeds, int64(i))
+		}
+	}
+
+	failed := 0
+	for _, sc := range scenarios {
+		passed := 0
+		for _, seed := range seeds {
+			if err := runScenario(sc, *replicas, seed); err != nil {
+				fmt.Printf("FAIL %s (seed %d): %v\n", sc.name, seed, err)
+				continue
+			}
+			passed++
+		}
+		fmt.Printf("%-45s %d/%d\n", sc.name, passed, len(seeds))
+		if passed != len(seeds) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runScenario runs one scenario on a fresh cluster
+func runScenario(sc scenario, replicas int, seed int64) error {
+	options := election.DefaultBullyOptions
+	options.Stickiness = 0
+	sim, err := election.NewSimulation(replicas, options, seed)
+	if err != nil {
+		return err
+	}
+	if err := sc.run(sim, replicas, rand.New(rand.NewSource(seed))); err != nil {
+		return fmt.Errorf("%w\n  %s", err, sim)
+	}
+	if violations := sim.Violations(); len(violations) > 0 {
+		return fmt.Errorf("two leaders in one term: %v\n  %s", violations, sim)
+	}
+	return nil
+}
+
+// expectLeader checks that the cluster agrees on the given leader
+func expectLeader(sim *election.Simulation, want int) error {
+	leader, ok := sim.Agreed()
+	if !ok {
+		return fmt.Errorf("no agreed leader (leaders %v)", sim.Leaders())
+	}
+	if leader != want {
+		return fmt.Errorf("leader is %d, want %d", leader, want)
+	}
+	return nil
+}
+
+// expectAgreement checks that the cluster agrees on a single leader
+func expectAgreement(sim *election.Simulation) error {
+	if _, ok := sim.Agreed(); !ok {
+		return fmt.Errorf("no agreed leader (leaders %v)", sim.Leaders())
+	}
+	return nil
+}
+
+// crashOrRestart crashes a random node that's up, keeping at least one up,
+// or restarts it if it's down
+func crashOrRestart(sim *election.Simulation, replicas int, r *rand.Rand) {
+	id := 1 + r.Intn(replicas)
+	up := sim.Up()
+	for _, upID := range up {
+		if upID == id {
+			if len(up) > 1 {
+				sim.Crash(id)
+			}
+			return
+		}
+	}
+	sim.Restart(id)
+}
+
+// randomSplit splits the nodes into two random non-empty groups
+func randomSplit(replicas int, r *rand.Rand) [][]int {
+	shuffled := ids(1, replicas)
+	r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	at := 1 + r.Intn(replicas-1)
+	return [][]int{shuffled[:at], shuffled[at:]}
+}
+
+// ids returns the IDs from first to last
+func ids(first, last int) []int {
+	group := []int{}
+	for id := first; id <= last; id++ {
+		group = append(group, id)
+	}
+	return group
+}
@@ -0,0 +1,306 @@
+// Command scenario checks the coordinator's reactions to worker faults end
+// to end. It starts fake workers, a fake Docker daemon (see dockertest) and
+// a real coordinator binary monitoring them, scripts faults (closing the
+// connection without a PONG, answering garbage, hanging, crashing) and
+// reports whether each fault was detected and recovered from, and whether
+// healthy or briefly flapping workers were left alone.
+//
+//	go build -o coordinator ./cmd/coordinator
+//	go run ./cmd/scenario -coordinator ./coordinator
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/admin"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker/dockertest"
+)
+
+const (
+	failureThreshold = 2
+	// recoveryDeadline is how long a fault may take to be recovered: the
+	// coordinator checks every 5s and needs failureThreshold failures
+	recoveryDeadline = 45 * time.Second
+	// observeWindow is how long workers that must be left alone are watched
+	observeWindow = 30 * time.Second
+)
+
+// scenario scripts a fault on its own worker and checks the outcome
+type scenario struct {
+	name string
+	// fault is the worker mode to switch to; "" leaves the worker healthy
+	fault string
+	// faultFor bounds the fault for flapping workers; zero means until
+	// the worker is restarted
+	faultFor time.Duration
+	// crash also stops the worker's container
+	crash bool
+	// expectRestart is whether the coordinator should restart the worker
+	expectRestart bool
+}
+
+var scenarios = []scenario{
+	{name: "healthy worker is left alone"},
+	{name: "no PONG (connection closed) is restarted", fault: modeClose, expectRestart: true},
+	{name: "garbage answer is restarted", fault: modeGarbage, expectRestart: true},
+	{name: "hanging worker is restarted", fault: modeHang, expectRestart: true},
+	{name: "crashed container is restarted", fault: modeClose, crash: true, expectRestart: true},
+	{name: "single failed check is tolerated", fault: modeClose, faultFor: 3 * time.Second},
+}
+
+// result is the outcome of a scenario
+type result struct {
+	scenario scenario
+	err      error
+	elapsed  time.Duration
+}
+
+func main() {
+	coordinator := flag.String("coordinator", "", "path to the coordinator binary (required)")
+	adminPort := flag.String("admin-port", "22347", "admin API port for the coordinator under test")
+	logPath := flag.String("log", "", "write the coordinator's output to this file")
+	flag.Parse()
+
+	if *coordinator == "" {
+		fmt.Fprintln(os.Stderr, "usage: scenario -coordinator <path> [-admin-port port] [-log file]")
+		os.Exit(2)
+	}
+
+	results, err := run(*coordinator, *adminPort, *logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	fmt.Println()
+	for _, r := range results {
+		status := "PASS"
+		if r.err != nil {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s  %-45s %6.1fs", status, r.scenario.name, r.elapsed.Seconds())
+		if r.err != nil {
+			fmt.Printf("  %v", r.err)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("\n%d/%d scenarios passed\n", len(results)-failed, len(results))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// run sets everything up, runs every scenario at once and returns their results
+func run(coordinator, adminPort, logPath string) ([]result, error) {
+	workers := make(map[string]*worker)
+	names := []string{}
+	for i := range scenarios {
+		name := fmt.Sprintf("scenario-worker-%d", i+1)
+		w, err := startWorker(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start fake worker: %w", err)
+		}
+		workers[name] = w
+		names = append(names, name)
+	}
+
+	// Restarting a worker's container brings it back healthy
+	runtime := dockertest.NewRuntime(names...)
+	runtime.OnRestart = func(container *dockertest.Container) {
+		if w, ok := workers[container.Name]; ok {
+			w.setMode(modeHealthy)
+		}
+	}
+	dockerListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start fake Docker daemon: %w", err)
+	}
+	go http.Serve(dockerListener, dockertest.NewServer(runtime))
+
+	dir, err := os.MkdirTemp("", "scenario")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "coordinator.yml")
+	if err := writeConfig(configPath, names, workers); err != nil {
+		return nil, err
+	}
+
+	stop, err := startCoordinator(coordinator, configPath, dockerListener.Addr().String(), adminPort, logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	client := admin.NewClient(net.JoinHostPort("127.0.0.1", adminPort))
+	if err := waitForLeadership(client); err != nil {
+		return nil, err
+	}
+	log.Printf("Coordinator is leading, running %d scenarios", len(scenarios))
+
+	results := make([]result, len(scenarios))
+	var wg sync.WaitGroup
+	for i, sc := range scenarios {
+		wg.Add(1)
+		go func(i int, sc scenario, w *worker) {
+			defer wg.Done()
+			start := time.Now()
+			err := runScenario(sc, w, runtime)
+			results[i] = result{scenario: sc, err: err, elapsed: time.Since(start)}
+		}(i, sc, workers[names[i]])
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// runScenario injects a scenario's fault and waits for the expected reaction
+func runScenario(sc scenario, w *worker, runtime *dockertest.Runtime) error {
+	if sc.fault != "" {
+		w.setMode(sc.fault)
+	}
+	if sc.crash {
+		runtime.Update(w.name, func(c *dockertest.Container) { c.Running = false })
+	}
+	if sc.faultFor > 0 {
+		time.Sleep(sc.faultFor)
+		w.setMode(modeHealthy)
+	}
+
+	if !sc.expectRestart {
+		time.Sleep(observeWindow)
+		if n := restarts(runtime, w.name); n > 0 {
+			return fmt.Errorf("restarted %d times, expected none", n)
+		}
+		return nil
+	}
+
+	deadline := time.Now().Add(recoveryDeadline)
+	for time.Now().Before(deadline) {
+		if restarts(runtime, w.name) > 0 {
+			if container, _ := runtime.Container(w.name); !container.Running {
+				return fmt.Errorf("restarted but the container isn't running")
+			}
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("not restarted within %v", recoveryDeadline)
+}
+
+// restarts counts the restart calls the coordinator made for a container.
+// A stuck container (running but not answering, like a hanging worker) is
+// killed and started instead, which counts too.
+func restarts(runtime *dockertest.Runtime, name string) int {
+	n := 0
+	for _, call := range runtime.Calls() {
+		if call.Container == name && (call.Method == "RestartContainer" || call.Method == "KillContainer") {
+			n++
+		}
+	}
+	return n
+}
+
+// writeConfig writes a coordinator config file declaring the fake workers
+// as static targets
+func writeConfig(path string, names []string, workers map[string]*worker) error {
+	type target struct {
+		Name             string `yaml:"name"`
+		Host             string `yaml:"host"`
+		Port             int    `yaml:"port"`
+		ContainerName    string `yaml:"container_name"`
+		FailureThreshold int    `yaml:"failure_threshold"`
+	}
+	config := struct {
+		Targets []target `yaml:"targets"`
+	}{}
+	for _, name := range names {
+		config.Targets = append(config.Targets, target{
+			Name:             name,
+			Host:             "127.0.0.1",
+			Port:             workers[name].port(),
+			ContainerName:    name,
+			FailureThreshold: failureThreshold,
+		})
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// startCoordinator runs a single coordinator against the fake Docker
+// daemon and returns a function that stops it
+func startCoordinator(binary, configPath, dockerAddress, adminPort, logPath string) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, binary)
+	cmd.Env = append(os.Environ(),
+		"MY_ID=1",
+		"TOTAL_REPLICAS=1",
+		"BIND_ADDRESS=127.0.0.1",
+		"ADMIN_PORT="+adminPort,
+		"CONFIG_PATH="+configPath,
+		"COMPOSE_PATHS="+filepath.Join(filepath.Dir(configPath), "none.yml"),
+		"DOCKER_HOST=tcp://"+dockerAddress,
+	)
+
+	var output io.Writer = io.Discard
+	if logPath != "" {
+		file, err := os.Create(logPath)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		output = file
+	}
+	cmd.Stdout, cmd.Stderr = output, output
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start coordinator: %w", err)
+	}
+	return func() {
+		cancel()
+		cmd.Wait()
+		if file, ok := output.(*os.File); ok {
+			file.Close()
+		}
+	}, nil
+}
+
+// waitForLeadership waits until the coordinator's admin API reports it leads
+func waitForLeadership(client *admin.Client) error {
+	deadline := time.Now().Add(30 * time.Second)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		status, err := client.Status()
+		if err == nil && status.IsLeader {
+			return nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if lastErr != nil {
+		return fmt.Errorf("coordinator never became leader: %w", lastErr)
+	}
+	return fmt.Errorf("coordinator never became leader")
+}
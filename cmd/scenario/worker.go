@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/healthserver"
+)
+
+// Fault modes of a fake worker
+const (
+	modeHealthy = "healthy" // answers health checks normally
+	modeClose   = "close"   // accepts and closes the connection, no PONG
+	modeGarbage = "garbage" // answers something that isn't PONG
+	modeHang    = "hang"    // accepts and never answers
+)
+
+// hangTimeout is how long a hanging worker holds connections open
+const hangTimeout = time.Minute
+
+// worker is a fake worker: a real healthserver behind a listener that can
+// be scripted to misbehave
+type worker struct {
+	name     string
+	listener net.Listener
+	server   *healthserver.Server
+
+	mu   sync.Mutex
+	mode string
+}
+
+// startWorker starts a healthy fake worker on a local port
+func startWorker(name string) (*worker, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &worker{name: name, mode: modeHealthy, server: healthserver.New("")}
+	w.listener = &faultListener{Listener: listener, worker: w}
+	go w.server.Serve(w.listener)
+	return w, nil
+}
+
+// port returns the worker's health port
+func (w *worker) port() int {
+	return w.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (w *worker) setMode(mode string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.mode = mode
+}
+
+func (w *worker) currentMode() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mode
+}
+
+// faultListener hands the health server only the connections a healthy
+// worker would answer and misbehaves on the rest
+type faultListener struct {
+	net.Listener
+	worker *worker
+}
+
+// Accept implements net.Listener
+func (l *faultListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		switch l.worker.currentMode() {
+		case modeClose:
+			conn.Close()
+		case modeGarbage:
+			conn.Write([]byte("GARBAGE\n"))
+			conn.Close()
+		case modeHang:
+			go func() {
+				time.Sleep(hangTimeout)
+				conn.Close()
+			}()
+		default:
+			return conn, nil
+		}
+	}
+}
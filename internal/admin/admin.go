@@ -0,0 +1,232 @@
+package admin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/incident"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/statesync"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+)
+
+// ErrUnknownTarget is returned when an operation names a target that is not monitored
+var ErrUnknownTarget = errors.New("unknown target")
+
+// ErrNotLeader is returned when an operation requires this node to be the leader
+var ErrNotLeader = errors.New("not the leader")
+
+// ErrUnknownCoordinator is returned when promoting a coordinator that isn't
+// part of the group
+var ErrUnknownCoordinator = errors.New("unknown coordinator")
+
+// ErrNoPendingRestart is returned when confirming the restart of a target
+// that isn't waiting for one
+var ErrNoPendingRestart = errors.New("no restart pending")
+
+// ErrInvalidSnapshot is returned when importing a snapshot this coordinator
+// can't read
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ErrInvalidRegistration is returned when a worker registration is malformed
+var ErrInvalidRegistration = errors.New("invalid registration")
+
+// Status describes the state of a coordinator
+type Status struct {
+	ID       int    `json:"id"`
+	NodeID   string `json:"node_id,omitempty"`
+	IsLeader bool   `json:"is_leader"`
+	LeaderID int    `json:"leader_id"`
+	Targets  int    `json:"targets"`
+
+	// AdvertiseAddress is where the other coordinators dial this one, when
+	// it's not its hostname (ADVERTISE_ADDR)
+	AdvertiseAddress string `json:"advertise_address,omitempty"`
+
+	// PipelineBusy is set while a query run is in progress; non-critical
+	// restarts are deferred until it completes
+	PipelineBusy bool `json:"pipeline_busy"`
+
+	// Backpressure is set while the broker's backlog is over
+	// BACKPRESSURE_BACKLOG: non-critical targets are checked less often and
+	// their restarts deferred
+	Backpressure bool `json:"backpressure,omitempty"`
+
+	// MassFailure is the diagnosis of the mass failure in progress, when
+	// more targets fail at once than MASS_FAILURE_THRESHOLD
+	MassFailure string `json:"mass_failure,omitempty"`
+
+	// Cluster is the leader's summary of the cluster, as its last
+	// heartbeat carried it (Bully election only). QuarantineInSync tells
+	// whether this coordinator's quarantine list matches the leader's.
+	Cluster          *election.ClusterState `json:"cluster,omitempty"`
+	QuarantineInSync *bool                  `json:"quarantine_in_sync,omitempty"`
+
+	// Leadership is the last change of leader this coordinator saw: who,
+	// in which term, why and when
+	Leadership *election.LeadershipEvent `json:"leadership,omitempty"`
+
+	// SafeMode is set while the leader thinks it's partitioned from its
+	// targets and recovers nothing
+	SafeMode bool `json:"safe_mode,omitempty"`
+
+	// ClockSkew is how far the other coordinators' clocks are from this
+	// one's, as estimated from the messages they sent
+	ClockSkew []PeerClock `json:"clock_skew,omitempty"`
+}
+
+// PeerClock is the estimated clock skew of another coordinator
+type PeerClock struct {
+	ID   int           `json:"id"`
+	Skew time.Duration `json:"skew"` // its clock minus ours
+
+	// Significant is set when the skew exceeds the configured warning
+	Significant bool `json:"significant"`
+}
+
+// TargetStatus describes a monitored target
+type TargetStatus struct {
+	Name          string `json:"name"`
+	Group         string `json:"group,omitempty"`
+	Address       string `json:"address"`
+	ContainerName string `json:"container_name"`
+	Quarantined   bool   `json:"quarantined"`
+	LastError     string `json:"last_error,omitempty"`
+
+	// UptimePercent is computed over the target's recorded check history
+	UptimePercent float64 `json:"uptime_percent"`
+
+	// FailingPeers are the other coordinators currently reporting the
+	// target as failing
+	FailingPeers []int `json:"failing_peers,omitempty"`
+
+	// Protected targets are only restarted once an operator confirms it;
+	// RestartPending is set while a restart waits for that
+	Protected      bool `json:"protected,omitempty"`
+	RestartPending bool `json:"restart_pending,omitempty"`
+
+	// Condition is what inspecting the container of a failing target
+	// showed, e.g. "stuck" when it's running but doesn't answer
+	Condition string `json:"condition,omitempty"`
+
+	// DependsOn are the targets it can't work without; HeldBy is the
+	// failing one its restarts wait for
+	DependsOn []string `json:"depends_on,omitempty"`
+	HeldBy    string   `json:"held_by,omitempty"`
+}
+
+// TargetHistory is a target's recent check results and the availability
+// derived from them
+type TargetHistory struct {
+	Name          string             `json:"name"`
+	Since         time.Time          `json:"since"`
+	UptimePercent float64            `json:"uptime_percent"`
+	Incidents     []monitor.Incident `json:"incidents"`
+	Samples       []monitor.Sample   `json:"samples"`
+}
+
+// Registration is a worker announcing itself to the coordinators. Registered
+// workers are checked by the heartbeats they push rather than by polling.
+type Registration struct {
+	Name          string `json:"name"`
+	Host          string `json:"host,omitempty"`
+	Port          string `json:"port,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	DockerHost    string `json:"docker_host,omitempty"`
+
+	// HeartbeatInterval is how often the worker pushes heartbeats, as a Go
+	// duration ("5s"). It's failed after missing three in a row.
+	HeartbeatInterval string `json:"heartbeat_interval"`
+}
+
+// SnapshotVersion is the version of the Snapshot format
+const SnapshotVersion = 1
+
+// Snapshot is a coordinator's whole runtime state, exported for debugging or
+// to migrate it into a fresh replica. Importing restores the recovery state,
+// the check history and the recovery times; status, election stats and targets are only
+// informational, since a replica discovers its own targets and elects its
+// own leader.
+type Snapshot struct {
+	Version       int                         `json:"version"`
+	ExportedAt    time.Time                   `json:"exported_at"`
+	Status        Status                      `json:"status"`
+	Election      election.Stats              `json:"election"`
+	Targets       []TargetStatus              `json:"targets"`
+	Recovery      statesync.State             `json:"recovery"`
+	History       map[string][]monitor.Sample `json:"history"`
+	RecoveryTimes []monitor.RecoveryStats     `json:"recovery_times"`
+
+	// RecoverySamples are the recovery times behind RecoveryTimes, which
+	// an import restores
+	RecoverySamples map[string]monitor.RecoverySamples `json:"recovery_samples,omitempty"`
+}
+
+// Controller is the set of operations exposed through the admin API
+type Controller interface {
+	Status() Status
+	Targets() []TargetStatus
+	History(name string) (TargetHistory, error)
+	RecoveryTimes() []monitor.RecoveryStats
+	ExportSnapshot() Snapshot
+	ImportSnapshot(snapshot Snapshot) error
+	Election() election.Stats
+	Restart(name string) error
+	ConfirmRestart(name, approver string) error
+	Quarantine(name string, quarantined bool) error
+	StepDown() error
+	Promote(id int) error
+	SetPipelineBusy(busy bool) error
+	Register(registration Registration) error
+	Heartbeat(name string) error
+
+	// ReportSuspect takes a worker's report that a target failed; only the
+	// leader takes them
+	ReportSuspect(report SuspectReport) error
+
+	// Alerts returns the alerts waiting for acknowledgment, and AckAlert
+	// acknowledges one, stopping its escalation
+	Alerts() []alert.Record
+	AckAlert(id, by string) error
+
+	// Incidents returns the ongoing incidents and the last ended ones
+	Incidents() []incident.Incident
+
+	// AvailabilityReport returns the targets' availability over [from, to)
+	AvailabilityReport(from, to time.Time) (AvailabilityReport, error)
+
+	// Subscribe returns a subscription to check results and coordinator
+	// events, which the caller must close
+	Subscribe() *stream.Subscription
+}
+
+// SuspectReport is a worker reporting a target it saw failing (e.g. the
+// consumer of its downstream queue vanished), the body of POST
+// /targets/{name}/suspect. Target comes from the path.
+type SuspectReport struct {
+	Target string `json:"target,omitempty"`
+	From   string `json:"from,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConfirmRequest is the optional body of POST /targets/{name}/confirm-restart
+type ConfirmRequest struct {
+	Approver string `json:"approver,omitempty"`
+}
+
+// AckRequest is the optional body of POST /alerts/{id}/ack
+type AckRequest struct {
+	By string `json:"by,omitempty"`
+}
+
+// PromoteRequest is the body of POST /election/promote
+type PromoteRequest struct {
+	ID int `json:"id"`
+}
+
+// errorResponse is the body returned for failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
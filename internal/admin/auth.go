@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures the admin servers and clients
+type Option func(*options)
+
+type options struct {
+	token string
+}
+
+// WithToken sets the bearer token of the API. Servers require it on every
+// operation that changes state (HTTP POSTs, the gRPC restart, quarantine and
+// step-down); clients send it with each request. Empty means no token.
+func WithToken(token string) Option {
+	return func(o *options) { o.token = token }
+}
+
+// newOptions applies options over the defaults
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// validToken reports whether an Authorization value carries the token
+func validToken(token, authorization string) bool {
+	if token == "" {
+		return true
+	}
+	presented, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// requireToken rejects state-changing requests without the token
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !validToken(token, r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing or invalid admin token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the token of a gRPC call that changes state
+func (s *GRPCServer) authorize(ctx context.Context) error {
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	if !validToken(s.token, authorization) {
+		return status.Error(codes.Unauthenticated, "missing or invalid admin token")
+	}
+	return nil
+}
+
+// tokenCredentials sends the token with every gRPC call. The API is served
+// without TLS, so they don't require it.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	tests := []struct {
+		name          string
+		token         string
+		method        string
+		authorization string
+		want          int
+	}{
+		{"reads need no token", "secret", http.MethodGet, "", http.StatusNoContent},
+		{"a POST without the token is refused", "secret", http.MethodPost, "", http.StatusUnauthorized},
+		{"a POST with another token is refused", "secret", http.MethodPost, "Bearer other", http.StatusUnauthorized},
+		{"a POST with the token in another scheme is refused", "secret", http.MethodPost, "Basic secret", http.StatusUnauthorized},
+		{"a POST with the token goes through", "secret", http.MethodPost, "Bearer secret", http.StatusNoContent},
+		{"without a token every POST goes through", "", http.MethodPost, "", http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/targets/filter-1/restart", nil)
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			requireToken(test.token, ok).ServeHTTP(recorder, request)
+			if recorder.Code != test.want {
+				t.Errorf("status %d, want %d", recorder.Code, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/election"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/incident"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+const clientTimeout = 30 * time.Second
+
+// Client talks to a coordinator's admin API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// NewClient creates an admin client for the given address (host:port)
+func NewClient(address string, opts ...Option) *Client {
+	return &Client{
+		baseURL:    "http://" + address,
+		httpClient: &http.Client{Timeout: clientTimeout},
+		token:      newOptions(opts).token,
+	}
+}
+
+// Status returns the coordinator status
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.do(http.MethodGet, "/status", nil, &status)
+	return status, err
+}
+
+// Targets returns the monitored targets
+func (c *Client) Targets() ([]TargetStatus, error) {
+	var targets []TargetStatus
+	err := c.do(http.MethodGet, "/targets", nil, &targets)
+	return targets, err
+}
+
+// History returns a target's check history and uptime
+func (c *Client) History(name string) (TargetHistory, error) {
+	var history TargetHistory
+	err := c.do(http.MethodGet, "/targets/"+url.PathEscape(name)+"/history", nil, &history)
+	return history, err
+}
+
+// RecoveryTimes returns the MTTR of every target group
+func (c *Client) RecoveryTimes() ([]monitor.RecoveryStats, error) {
+	var stats []monitor.RecoveryStats
+	err := c.do(http.MethodGet, "/recovery-times", nil, &stats)
+	return stats, err
+}
+
+// ExportSnapshot returns the coordinator's whole runtime state
+func (c *Client) ExportSnapshot() (Snapshot, error) {
+	var snapshot Snapshot
+	err := c.do(http.MethodGet, "/snapshot", nil, &snapshot)
+	return snapshot, err
+}
+
+// ImportSnapshot restores a snapshot's state into the coordinator
+func (c *Client) ImportSnapshot(snapshot Snapshot) error {
+	return c.do(http.MethodPost, "/snapshot", snapshot, nil)
+}
+
+// Alerts returns the alerts waiting for acknowledgment
+func (c *Client) Alerts() ([]alert.Record, error) {
+	var records []alert.Record
+	err := c.do(http.MethodGet, "/alerts", nil, &records)
+	return records, err
+}
+
+// AckAlert acknowledges an alert on behalf of by
+func (c *Client) AckAlert(id, by string) error {
+	return c.do(http.MethodPost, "/alerts/"+url.PathEscape(id)+"/ack", AckRequest{By: by}, nil)
+}
+
+// Incidents returns the ongoing incidents and the last ended ones
+func (c *Client) Incidents() ([]incident.Incident, error) {
+	var incidents []incident.Incident
+	err := c.do(http.MethodGet, "/incidents", nil, &incidents)
+	return incidents, err
+}
+
+// Election returns the coordinator's election stats and recent leadership
+// transitions
+func (c *Client) Election() (election.Stats, error) {
+	var stats election.Stats
+	err := c.do(http.MethodGet, "/election", nil, &stats)
+	return stats, err
+}
+
+// Restart asks the coordinator to restart a target
+func (c *Client) Restart(name string) error {
+	return c.do(http.MethodPost, "/targets/"+url.PathEscape(name)+"/restart", nil, nil)
+}
+
+// ConfirmRestart confirms the pending restart of a target, recording
+// approver as the one who approved it
+func (c *Client) ConfirmRestart(name, approver string) error {
+	return c.do(http.MethodPost, "/targets/"+url.PathEscape(name)+"/confirm-restart", ConfirmRequest{Approver: approver}, nil)
+}
+
+// Quarantine enables or disables quarantine for a target
+func (c *Client) Quarantine(name string, quarantined bool) error {
+	action := "quarantine"
+	if !quarantined {
+		action = "unquarantine"
+	}
+	return c.do(http.MethodPost, "/targets/"+url.PathEscape(name)+"/"+action, nil, nil)
+}
+
+// ReportSuspect reports to the leader that a target failed
+func (c *Client) ReportSuspect(report SuspectReport) error {
+	return c.do(http.MethodPost, "/targets/"+url.PathEscape(report.Target)+"/suspect", report, nil)
+}
+
+// StepDown asks the coordinator to give up leadership
+func (c *Client) StepDown() error {
+	return c.do(http.MethodPost, "/leader/step-down", nil, nil)
+}
+
+// Promote asks the coordinators to make the given one the leader
+func (c *Client) Promote(id int) error {
+	return c.do(http.MethodPost, "/election/promote", PromoteRequest{ID: id}, nil)
+}
+
+// SetPipelineBusy tells the coordinator whether a query run is in progress
+func (c *Client) SetPipelineBusy(busy bool) error {
+	path := "/pipeline/busy"
+	if !busy {
+		path = "/pipeline/idle"
+	}
+	return c.do(http.MethodPost, path, nil, nil)
+}
+
+// Register announces a worker to the coordinator
+func (c *Client) Register(registration Registration) error {
+	return c.do(http.MethodPost, "/workers", registration, nil)
+}
+
+// Heartbeat pushes a heartbeat for a registered worker
+func (c *Client) Heartbeat(name string) error {
+	return c.do(http.MethodPost, "/workers/"+url.PathEscape(name)+"/heartbeat", nil, nil)
+}
+
+// do performs a request, sending in as JSON (if not nil) and decoding the
+// JSON response into out (if not nil)
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach coordinator at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return fmt.Errorf("coordinator returned status %d", resp.StatusCode)
+		}
+		return fmt.Errorf("coordinator returned status %d: %s", resp.StatusCode, errResp.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+)
+
+// EventHealth is the name of /events messages about a target turning
+// healthy or unhealthy. Coordinator events (restarts, recoveries, leadership
+// changes) are named after their type, e.g. "node.restarting".
+const EventHealth = "health"
+
+// eventsKeepAlive is how often an idle /events stream sends a comment, so
+// proxies don't close it
+const eventsKeepAlive = 15 * time.Second
+
+// HealthEvent is the data of an EventHealth message
+type HealthEvent struct {
+	Target    string    `json:"target"`
+	Healthy   bool      `json:"healthy"`
+	Checker   string    `json:"checker,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StreamEvent is one message of GET /events: its name and JSON data
+type StreamEvent struct {
+	Name string
+	Data json.RawMessage
+}
+
+// handleEvents streams health transitions and coordinator events as
+// server-sent events until the client goes away. A target's first check
+// result is sent as a transition, so clients learn every target's state.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming not supported"})
+		return
+	}
+
+	sub := s.controller.Subscribe()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	healthy := make(map[string]bool)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+
+		case message, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			name, data := streamEvent(message, healthy)
+			if name == "" {
+				continue
+			}
+			body, err := json.Marshal(data)
+			if err != nil {
+				log.Printf("Error encoding event: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, body)
+			flusher.Flush()
+		}
+	}
+}
+
+// streamEvent returns the /events message for a stream message, or an empty
+// name if it isn't news. healthy is the last state sent for each target.
+func streamEvent(message stream.Message, healthy map[string]bool) (string, interface{}) {
+	switch message.Kind {
+	case stream.KindCheck:
+		if was, known := healthy[message.Target]; known && was == message.Check.Healthy {
+			return "", nil
+		}
+		healthy[message.Target] = message.Check.Healthy
+		return EventHealth, healthEvent(message.Target, message.Check)
+	case stream.KindEvent:
+		return message.Event.Type, message.Event
+	}
+	return "", nil
+}
+
+func healthEvent(target string, result monitor.CheckResult) HealthEvent {
+	event := HealthEvent{Target: target, Healthy: result.Healthy, Checker: result.Checker, Reason: result.Reason,
+		Timestamp: result.Timestamp}
+	if result.Err != nil {
+		event.Error = result.Err.Error()
+	}
+	return event
+}
+
+// WatchEvents follows the coordinator's /events stream, calling handle for
+// each event until ctx is done, handle returns an error or the stream ends
+func (c *Client) WatchEvents(ctx context.Context, handle func(StreamEvent) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream has no end, so the client's timeout doesn't apply
+	resp, err := (&http.Client{Transport: c.httpClient.Transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coordinator returned status %d", resp.StatusCode)
+	}
+
+	var event StreamEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event.Name != "" {
+				if err := handle(event); err != nil {
+					return err
+				}
+			}
+			event = StreamEvent{}
+		case strings.HasPrefix(line, "event: "):
+			event.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = json.RawMessage(strings.TrimPrefix(line, "data: "))
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
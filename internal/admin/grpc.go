@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	coordinatorv1 "github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/api/coordinatorv1"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+)
+
+// GRPCServer serves the coordinator.v1.Coordinator gRPC service
+// (api/proto/coordinator/v1) over the same controller as the REST API
+type GRPCServer struct {
+	coordinatorv1.UnimplementedCoordinatorServer
+	controller Controller
+	server     *grpc.Server
+	token      string
+}
+
+// NewGRPCServer creates a gRPC server for the given controller
+func NewGRPCServer(controller Controller, opts ...Option) *GRPCServer {
+	s := &GRPCServer{controller: controller, server: grpc.NewServer(), token: newOptions(opts).token}
+	coordinatorv1.RegisterCoordinatorServer(s.server, s)
+	return s
+}
+
+// ListenAndServe serves the gRPC API on the given address
+func (s *GRPCServer) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	log.Printf("gRPC API listening on %s", address)
+	return s.server.Serve(listener)
+}
+
+// Status implements coordinatorv1.CoordinatorServer
+func (s *GRPCServer) Status(context.Context, *coordinatorv1.StatusRequest) (*coordinatorv1.StatusResponse, error) {
+	current := s.controller.Status()
+	return &coordinatorv1.StatusResponse{
+		Id:           int32(current.ID),
+		IsLeader:     current.IsLeader,
+		LeaderId:     int32(current.LeaderID),
+		Targets:      int32(current.Targets),
+		PipelineBusy: current.PipelineBusy,
+	}, nil
+}
+
+// ListTargets implements coordinatorv1.CoordinatorServer
+func (s *GRPCServer) ListTargets(context.Context, *coordinatorv1.ListTargetsRequest) (*coordinatorv1.ListTargetsResponse, error) {
+	targets := s.controller.Targets()
+	response := &coordinatorv1.ListTargetsResponse{Targets: make([]*coordinatorv1.Target, 0, len(targets))}
+	for _, target := range targets {
+		failingPeers := make([]int32, 0, len(target.FailingPeers))
+		for _, peer := range target.FailingPeers {
+			failingPeers = append(failingPeers, int32(peer))
+		}
+		response.Targets = append(response.Targets, &coordinatorv1.Target{
+			Name:           target.Name,
+			Group:          target.Group,
+			Address:        target.Address,
+			ContainerName:  target.ContainerName,
+			Quarantined:    target.Quarantined,
+			LastError:      target.LastError,
+			UptimePercent:  target.UptimePercent,
+			FailingPeers:   failingPeers,
+			Protected:      target.Protected,
+			RestartPending: target.RestartPending,
+		})
+	}
+	return response, nil
+}
+
+// RestartTarget implements coordinatorv1.CoordinatorServer
+func (s *GRPCServer) RestartTarget(ctx context.Context, request *coordinatorv1.RestartTargetRequest) (*coordinatorv1.RestartTargetResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.controller.Restart(request.GetName()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &coordinatorv1.RestartTargetResponse{}, nil
+}
+
+// Quarantine implements coordinatorv1.CoordinatorServer
+func (s *GRPCServer) Quarantine(ctx context.Context, request *coordinatorv1.QuarantineRequest) (*coordinatorv1.QuarantineResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.controller.Quarantine(request.GetName(), request.GetQuarantined()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &coordinatorv1.QuarantineResponse{}, nil
+}
+
+// StepDown implements coordinatorv1.CoordinatorServer
+func (s *GRPCServer) StepDown(ctx context.Context, _ *coordinatorv1.StepDownRequest) (*coordinatorv1.StepDownResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.controller.StepDown(); err != nil {
+		return nil, grpcError(err)
+	}
+	return &coordinatorv1.StepDownResponse{}, nil
+}
+
+// WatchEvents implements coordinatorv1.CoordinatorServer. Like GET /events,
+// a client that falls behind loses messages rather than slowing checks down.
+func (s *GRPCServer) WatchEvents(request *coordinatorv1.WatchEventsRequest, watcher coordinatorv1.Coordinator_WatchEventsServer) error {
+	kinds := make(map[string]bool, len(request.GetKinds()))
+	for _, kind := range request.GetKinds() {
+		kinds[kind] = true
+	}
+
+	sub := s.controller.Subscribe()
+	defer sub.Close()
+	for {
+		select {
+		case <-watcher.Context().Done():
+			return nil
+		case message, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if len(kinds) > 0 && !kinds[message.Kind] {
+				continue
+			}
+			if err := watcher.Send(watchEventsResponse(message)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchEventsResponse converts a stream message
+func watchEventsResponse(message stream.Message) *coordinatorv1.WatchEventsResponse {
+	response := &coordinatorv1.WatchEventsResponse{Kind: message.Kind, Target: message.Target}
+	switch message.Kind {
+	case stream.KindCheck:
+		check := &coordinatorv1.CheckResult{
+			Checker:    message.Check.Checker,
+			Healthy:    message.Check.Healthy,
+			Timestamp:  timestamppb.New(message.Check.Timestamp),
+			DurationMs: message.Check.Duration.Milliseconds(),
+		}
+		if message.Check.Err != nil {
+			check.Error = message.Check.Err.Error()
+		}
+		response.Message = &coordinatorv1.WatchEventsResponse_Check{Check: check}
+	case stream.KindEvent:
+		event := message.Event
+		response.Message = &coordinatorv1.WatchEventsResponse_Event{Event: &coordinatorv1.Event{
+			Type:          event.Type,
+			Timestamp:     timestamppb.New(event.Timestamp),
+			CoordinatorId: int32(event.CoordinatorID),
+			LeaderId:      int32(event.LeaderID),
+			Target:        event.Target,
+			ContainerName: event.ContainerName,
+			Message:       event.Message,
+		}}
+	}
+	return response
+}
+
+// grpcError maps a controller error to a gRPC status, as writeError does
+// to an HTTP one
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ErrUnknownTarget), errors.Is(err, ErrUnknownCoordinator), errors.Is(err, alert.ErrUnknownAlert):
+		code = codes.NotFound
+	case errors.Is(err, ErrNotLeader), errors.Is(err, ErrNoPendingRestart):
+		code = codes.FailedPrecondition
+	case errors.Is(err, ErrInvalidRegistration), errors.Is(err, ErrInvalidSnapshot):
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	coordinatorv1 "github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/api/coordinatorv1"
+)
+
+// GRPCClient talks to a coordinator's gRPC API, returning the same types as
+// Client
+type GRPCClient struct {
+	conn   *grpc.ClientConn
+	client coordinatorv1.CoordinatorClient
+}
+
+// NewGRPCClient creates a gRPC client for the given address (host:port).
+// The connection is made lazily, on the first call.
+func NewGRPCClient(address string, opts ...Option) (*GRPCClient, error) {
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if token := newOptions(opts).token; token != "" {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+	conn, err := grpc.Dial(address, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", address, err)
+	}
+	return &GRPCClient{conn: conn, client: coordinatorv1.NewCoordinatorClient(conn)}, nil
+}
+
+// Close closes the connection
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// Status returns the coordinator status
+func (c *GRPCClient) Status(ctx context.Context) (Status, error) {
+	response, err := c.client.Status(ctx, &coordinatorv1.StatusRequest{})
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{
+		ID:           int(response.GetId()),
+		IsLeader:     response.GetIsLeader(),
+		LeaderID:     int(response.GetLeaderId()),
+		Targets:      int(response.GetTargets()),
+		PipelineBusy: response.GetPipelineBusy(),
+	}, nil
+}
+
+// Targets returns the monitored targets
+func (c *GRPCClient) Targets(ctx context.Context) ([]TargetStatus, error) {
+	response, err := c.client.ListTargets(ctx, &coordinatorv1.ListTargetsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]TargetStatus, 0, len(response.GetTargets()))
+	for _, target := range response.GetTargets() {
+		var failingPeers []int
+		for _, peer := range target.GetFailingPeers() {
+			failingPeers = append(failingPeers, int(peer))
+		}
+		targets = append(targets, TargetStatus{
+			Name:           target.GetName(),
+			Group:          target.GetGroup(),
+			Address:        target.GetAddress(),
+			ContainerName:  target.GetContainerName(),
+			Quarantined:    target.GetQuarantined(),
+			LastError:      target.GetLastError(),
+			UptimePercent:  target.GetUptimePercent(),
+			FailingPeers:   failingPeers,
+			Protected:      target.GetProtected(),
+			RestartPending: target.GetRestartPending(),
+		})
+	}
+	return targets, nil
+}
+
+// Restart asks the coordinator (must be the leader) to restart a target
+func (c *GRPCClient) Restart(ctx context.Context, name string) error {
+	_, err := c.client.RestartTarget(ctx, &coordinatorv1.RestartTargetRequest{Name: name})
+	return err
+}
+
+// Quarantine stops (or resumes) automatic recovery of a target
+func (c *GRPCClient) Quarantine(ctx context.Context, name string, quarantined bool) error {
+	_, err := c.client.Quarantine(ctx, &coordinatorv1.QuarantineRequest{Name: name, Quarantined: quarantined})
+	return err
+}
+
+// StepDown asks the leader to hand over leadership
+func (c *GRPCClient) StepDown(ctx context.Context) error {
+	_, err := c.client.StepDown(ctx, &coordinatorv1.StepDownRequest{})
+	return err
+}
+
+// WatchEvents streams check results and coordinator events of the given
+// kinds (every kind if none) until ctx is canceled
+func (c *GRPCClient) WatchEvents(ctx context.Context, kinds ...string) (coordinatorv1.Coordinator_WatchEventsClient, error) {
+	return c.client.WatchEvents(ctx, &coordinatorv1.WatchEventsRequest{Kinds: kinds})
+}
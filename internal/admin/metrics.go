@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleMetrics serves the coordinator's metrics in the Prometheus text
+// exposition format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	status := s.controller.Status()
+	stats := s.controller.Election()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics := []struct {
+		name, kind, help string
+		value            float64
+	}{
+		{"coordinator_is_leader", "gauge", "Whether this coordinator is the leader", boolValue(status.IsLeader)},
+		{"coordinator_leader_id", "gauge", "ID of the current leader, -1 if unknown", float64(status.LeaderID)},
+		{"coordinator_targets", "gauge", "Number of monitored targets", float64(status.Targets)},
+		{"coordinator_election_term", "gauge", "Current election term", float64(stats.Term)},
+		{"coordinator_elections_started_total", "counter", "Elections started by this coordinator", float64(stats.ElectionsStarted)},
+		{"coordinator_elections_won_total", "counter", "Elections won by this coordinator", float64(stats.ElectionsWon)},
+		{"coordinator_elections_lost_total", "counter", "Elections lost by this coordinator", float64(stats.ElectionsLost)},
+		{"coordinator_leadership_changes_total", "counter", "Leader changes seen by this coordinator", float64(stats.LeadershipChanges)},
+		{"coordinator_leader_seconds_total", "counter", "Time this coordinator has spent as leader", stats.TimeAsLeader.Seconds()},
+	}
+	if change := status.Leadership; change != nil {
+		metrics = append(metrics, struct {
+			name, kind, help string
+			value            float64
+		}{"coordinator_leader_changed_timestamp_seconds", "gauge", "When this coordinator last saw the leader change",
+			float64(change.Timestamp.Unix())})
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+
+	const mttr = "coordinator_recovery_seconds"
+	fmt.Fprintf(w, "# HELP %s Time from the first failed check to healthy again, per target group\n# TYPE %s summary\n", mttr, mttr)
+	for _, group := range s.controller.RecoveryTimes() {
+		for _, q := range []struct {
+			quantile string
+			value    time.Duration
+		}{{"0.5", group.P50}, {"0.9", group.P90}, {"0.99", group.P99}} {
+			fmt.Fprintf(w, "%s{group=%q,quantile=%q} %g\n", mttr, group.Group, q.quantile, q.value.Seconds())
+		}
+		fmt.Fprintf(w, "%s_sum{group=%q} %g\n", mttr, group.Group, group.Total.Seconds())
+		fmt.Fprintf(w, "%s_count{group=%q} %d\n", mttr, group.Group, group.Incidents)
+	}
+}
+
+// boolValue converts a bool to a 0/1 metric value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// defaultReportRange is the range of an availability report without from
+const defaultReportRange = 24 * time.Hour
+
+// AvailabilityReport is the availability of every target over [From, To)
+type AvailabilityReport struct {
+	From    time.Time                    `json:"from"`
+	To      time.Time                    `json:"to"`
+	Targets []monitor.TargetAvailability `json:"targets"`
+}
+
+// handleAvailabilityReport serves GET /reports/availability?from=&to=&format=
+// with RFC 3339 times (default: the last 24 hours) as JSON or, with
+// format=csv, as a CSV file to download
+func (s *Server) handleAvailabilityReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	to, err := reportTime(query.Get("to"), time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	from, err := reportTime(query.Get("from"), to.Add(-defaultReportRange))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if !from.Before(to) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "from must be before to"})
+		return
+	}
+
+	report, err := s.controller.AvailabilityReport(from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	switch format := query.Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, report)
+	case "csv":
+		writeReportCSV(w, report)
+	default:
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid format %q (expected json or csv)", format)})
+	}
+}
+
+// reportTime parses a report bound, which defaults to fallback
+func reportTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (expected RFC 3339, e.g. 2024-06-01T18:00:00Z)", value)
+	}
+	return parsed, nil
+}
+
+// writeReportCSV writes a report as a CSV attachment, a row per target
+func writeReportCSV(w http.ResponseWriter, report AvailabilityReport) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"availability-%s.csv\"",
+		report.From.UTC().Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"target", "from", "to", "checks", "failures", "up_seconds", "down_seconds",
+		"availability_percent", "outages"})
+	for _, target := range report.Targets {
+		out.Write([]string{
+			target.Target,
+			report.From.UTC().Format(time.RFC3339),
+			report.To.UTC().Format(time.RFC3339),
+			strconv.Itoa(target.Checks),
+			strconv.Itoa(target.Failures),
+			strconv.FormatFloat(target.Up.Seconds(), 'f', 0, 64),
+			strconv.FormatFloat(target.Down.Seconds(), 'f', 0, 64),
+			strconv.FormatFloat(target.AvailabilityPercent, 'f', 3, 64),
+			strconv.Itoa(target.Outages),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("Error writing admin response: %v", err)
+	}
+}
+
+// AvailabilityReport returns the targets' availability over [from, to)
+func (c *Client) AvailabilityReport(from, to time.Time) (AvailabilityReport, error) {
+	query := url.Values{}
+	query.Set("from", from.Format(time.RFC3339))
+	query.Set("to", to.Format(time.RFC3339))
+
+	var report AvailabilityReport
+	err := c.do(http.MethodGet, "/reports/availability?"+query.Encode(), nil, &report)
+	return report, err
+}
@@ -0,0 +1,319 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/alert"
+)
+
+// Server exposes a Controller over HTTP
+//
+//	GET  /status
+//	GET  /targets
+//	GET  /targets/{name}/history
+//	GET  /election
+//	GET  /recovery-times
+//	GET  /snapshot
+//	GET  /events (server-sent events)
+//	GET  /alerts
+//	GET  /incidents
+//	GET  /reports/availability?from=&to=&format=json|csv
+//	POST /snapshot
+//	GET  /metrics
+//	POST /alerts/{id}/ack
+//	POST /targets/{name}/restart
+//	POST /targets/{name}/confirm-restart
+//	POST /targets/{name}/quarantine
+//	POST /targets/{name}/unquarantine
+//	POST /targets/{name}/suspect
+//	POST /leader/step-down (also /election/step-down)
+//	POST /election/promote
+//	POST /pipeline/busy
+//	POST /pipeline/idle
+//	POST /workers
+//	POST /workers/{name}/heartbeat
+//
+// With a token (see WithToken), every POST must carry it as
+// "Authorization: Bearer <token>" or is answered 401.
+type Server struct {
+	controller Controller
+	mux        *http.ServeMux
+	handler    http.Handler
+}
+
+// NewServer creates an admin server backed by the given controller
+func NewServer(controller Controller, opts ...Option) *Server {
+	s := &Server{
+		controller: controller,
+		mux:        http.NewServeMux(),
+	}
+	s.handler = requireToken(newOptions(opts).token, s.mux)
+
+	s.mux.HandleFunc("/status", method(http.MethodGet, s.handleStatus))
+	s.mux.HandleFunc("/targets", method(http.MethodGet, s.handleTargets))
+	s.mux.HandleFunc("/targets/", s.handleTarget)
+	s.mux.HandleFunc("/election", method(http.MethodGet, s.handleElection))
+	s.mux.HandleFunc("/recovery-times", method(http.MethodGet, s.handleRecoveryTimes))
+	s.mux.HandleFunc("/snapshot", s.handleSnapshot)
+	s.mux.HandleFunc("/events", method(http.MethodGet, s.handleEvents))
+	s.mux.HandleFunc("/alerts", method(http.MethodGet, s.handleAlerts))
+	s.mux.HandleFunc("/alerts/", method(http.MethodPost, s.handleAck))
+	s.mux.HandleFunc("/incidents", method(http.MethodGet, s.handleIncidents))
+	s.mux.HandleFunc("/reports/availability", method(http.MethodGet, s.handleAvailabilityReport))
+	s.mux.HandleFunc("/metrics", method(http.MethodGet, s.handleMetrics))
+	s.mux.HandleFunc("/leader/step-down", method(http.MethodPost, s.handleStepDown))
+	s.mux.HandleFunc("/election/step-down", method(http.MethodPost, s.handleStepDown))
+	s.mux.HandleFunc("/election/promote", method(http.MethodPost, s.handlePromote))
+	s.mux.HandleFunc("/pipeline/busy", method(http.MethodPost, s.handlePipeline(true)))
+	s.mux.HandleFunc("/pipeline/idle", method(http.MethodPost, s.handlePipeline(false)))
+	s.mux.HandleFunc("/workers", method(http.MethodPost, s.handleRegister))
+	s.mux.HandleFunc("/workers/", method(http.MethodPost, s.handleHeartbeat))
+
+	return s
+}
+
+// ListenAndServe serves the admin API on the given address
+func (s *Server) ListenAndServe(address string) error {
+	log.Printf("Admin API listening on %s", address)
+	return http.ListenAndServe(address, s.handler)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+func (s *Server) handleTargets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Targets())
+}
+
+func (s *Server) handleElection(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Election())
+}
+
+func (s *Server) handleRecoveryTimes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.RecoveryTimes())
+}
+
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Alerts())
+}
+
+func (s *Server) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.controller.Incidents())
+}
+
+// handleAck acknowledges an alert: POST /alerts/{id}/ack
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/alerts/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "ack" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req AckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if err := s.controller.AckAlert(parts[0], req.By); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSnapshot exports (GET) or imports (POST) the coordinator's state
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.controller.ExportSnapshot())
+	case http.MethodPost:
+		var snapshot Snapshot
+		if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		if err := s.controller.ImportSnapshot(snapshot); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+	}
+}
+
+// handleTarget dispatches /targets/{name}/{action}
+func (s *Server) handleTarget(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/targets/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if parts[1] == "history" {
+		method(http.MethodGet, s.handleHistory(parts[0]))(w, r)
+		return
+	}
+	method(http.MethodPost, s.handleTargetAction(parts[0], parts[1]))(w, r)
+}
+
+// handleHistory serves a target's check history
+func (s *Server) handleHistory(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		history, err := s.controller.History(name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, history)
+	}
+}
+
+// handleTargetAction runs POST /targets/{name}/{action}
+func (s *Server) handleTargetAction(name, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch action {
+		case "restart":
+			err = s.controller.Restart(name)
+		case "confirm-restart":
+			var request ConfirmRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+				return
+			}
+			err = s.controller.ConfirmRestart(name, request.Approver)
+		case "suspect":
+			var report SuspectReport
+			if err := json.NewDecoder(r.Body).Decode(&report); err != nil && err != io.EOF {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+				return
+			}
+			report.Target = name
+			err = s.controller.ReportSuspect(report)
+		case "quarantine":
+			err = s.controller.Quarantine(name, true)
+		case "unquarantine":
+			err = s.controller.Quarantine(name, false)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleStepDown(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.StepDown(); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request) {
+	var request PromoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.controller.Promote(request.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePipeline marks the pipeline busy or idle
+func (s *Server) handlePipeline(busy bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.controller.SetPipelineBusy(busy); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var registration Registration
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		writeError(w, fmt.Errorf("%w: %v", ErrInvalidRegistration, err))
+		return
+	}
+
+	if err := s.controller.Register(registration); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleHeartbeat handles POST /workers/{name}/heartbeat
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/workers/"), "/heartbeat")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := s.controller.Heartbeat(name); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// method restricts a handler to a single HTTP method
+func method(allowed string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != allowed {
+			w.Header().Set("Allow", allowed)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error writing admin response: %v", err)
+	}
+}
+
+// writeError maps a controller error to an HTTP status code
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUnknownTarget), errors.Is(err, ErrUnknownCoordinator), errors.Is(err, alert.ErrUnknownAlert):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrNotLeader), errors.Is(err, ErrNoPendingRestart):
+		status = http.StatusConflict
+	case errors.Is(err, ErrInvalidRegistration), errors.Is(err, ErrInvalidSnapshot):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
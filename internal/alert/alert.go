@@ -0,0 +1,225 @@
+// Package alert notifies operators of coordinator events over webhooks,
+// email and Telegram. Each channel receives the events it's configured for:
+// those at or above its minimum severity, or an explicit list of types.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/stream"
+)
+
+// Severity ranks how urgent an alert is
+type Severity int
+
+// Severities, least urgent first
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+// String returns the severity's name
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "info"
+	}
+}
+
+// ParseSeverity parses a severity name; empty means info
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "", "info":
+		return SeverityInfo, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityInfo, fmt.Errorf("invalid severity %q (expected info, warning or critical)", name)
+}
+
+// severities maps event types to severities; unlisted types are info
+var severities = map[string]Severity{
+	events.TypeNodeDown:        SeverityWarning,
+	events.TypeRestarting:      SeverityWarning,
+	events.TypeRestartPending:  SeverityWarning,
+	events.TypeHookFailed:      SeverityWarning,
+	events.TypePaused:          SeverityWarning,
+	events.TypeBackpressure:    SeverityWarning,
+	events.TypeRestartFailed:   SeverityCritical,
+	events.TypeGaveUp:          SeverityCritical,
+	events.TypeRootCause:       SeverityCritical,
+	events.TypeMassFailure:     SeverityCritical,
+	events.TypeHostDown:        SeverityCritical,
+	events.TypeRedeliveryStorm: SeverityCritical,
+	events.TypeSplitBrain:      SeverityCritical,
+	events.TypePartitioned:     SeverityCritical,
+}
+
+// SeverityOf returns the severity of an event type
+func SeverityOf(eventType string) Severity {
+	return severities[eventType]
+}
+
+// notifyTimeout bounds sending one alert through one channel
+const notifyTimeout = 15 * time.Second
+
+// Alert is an event worth telling operators about
+type Alert struct {
+	Severity Severity
+	Event    events.Event
+
+	// ID is set on alerts that are escalated until acknowledged, and
+	// Escalation counts the escalations so far
+	ID         string
+	Escalation int
+}
+
+// Title is a one-line summary of the alert
+func (a Alert) Title() string {
+	title := fmt.Sprintf("[%s] %s", a.Severity, a.Event.Type)
+	if a.Event.Target != "" {
+		title += ": " + a.Event.Target
+	}
+	if a.Escalation > 0 {
+		title = fmt.Sprintf("ESCALATED (%d) %s", a.Escalation, title)
+	}
+	return title
+}
+
+// Text is the alert's full description
+func (a Alert) Text() string {
+	var b strings.Builder
+	b.WriteString(a.Title())
+	if a.Event.Message != "" {
+		fmt.Fprintf(&b, "\n%s", a.Event.Message)
+	}
+	if a.Event.Incident != "" {
+		fmt.Fprintf(&b, "\nincident %s (coordinatorctl incidents)", a.Event.Incident)
+	}
+	fmt.Fprintf(&b, "\ncoordinator %d (leader %d) at %s", a.Event.CoordinatorID, a.Event.LeaderID,
+		a.Event.Timestamp.Format(time.RFC3339))
+	if a.ID != "" {
+		fmt.Fprintf(&b, "\nacknowledge with: coordinatorctl ack %s", a.ID)
+	}
+	return b.String()
+}
+
+// Notifier delivers alerts through one medium
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Channel is a notifier and the alerts it gets: those naming one of Events,
+// if set, or else those at least as severe as MinSeverity
+type Channel struct {
+	Name        string
+	Notifier    Notifier
+	MinSeverity Severity
+	Events      []string
+}
+
+// Wants reports whether the channel gets an alert
+func (c Channel) Wants(alert Alert) bool {
+	if len(c.Events) > 0 {
+		for _, eventType := range c.Events {
+			if eventType == alert.Event.Type {
+				return true
+			}
+		}
+		return false
+	}
+	return alert.Severity >= c.MinSeverity
+}
+
+// Alerter sends the coordinator's events to its channels, and escalates
+// the unacknowledged alerts of targets that need attention
+type Alerter struct {
+	channels   []Channel
+	byName     map[string]Channel
+	escalation []EscalationStep
+	auditLog   *audit.Logger
+
+	mu     sync.Mutex
+	nextID int
+	open   map[string]*Record // unacknowledged alerts being escalated, by ID
+}
+
+// NewAlerter creates an alerter for the given channels and escalation chain.
+// Acknowledgments are recorded in auditLog, which may be nil.
+func NewAlerter(channels []Channel, escalation []EscalationStep, auditLog *audit.Logger) *Alerter {
+	byName := make(map[string]Channel, len(channels))
+	for _, channel := range channels {
+		byName[channel.Name] = channel
+	}
+	return &Alerter{
+		channels:   channels,
+		byName:     byName,
+		escalation: escalation,
+		auditLog:   auditLog,
+		open:       make(map[string]*Record),
+	}
+}
+
+// Run alerts on every event received on sub until it's closed. Alerts of
+// at least warning severity about targets for which escalates returns true
+// (critical or quarantined targets) are escalated until acknowledged.
+func (a *Alerter) Run(sub *stream.Subscription, escalates func(target string) bool) {
+	ticker := time.NewTicker(escalationTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if message.Kind != stream.KindEvent {
+				continue
+			}
+			alert := Alert{Severity: SeverityOf(message.Event.Type), Event: message.Event}
+			if alert.Event.Type == events.TypeRecovered {
+				a.resolve(alert.Event.Target)
+			} else if len(a.escalation) > 0 && alert.Event.Target != "" && alert.Severity >= SeverityWarning &&
+				escalates(alert.Event.Target) {
+				alert.ID = a.track(alert, time.Now())
+			}
+			a.Send(alert)
+
+		case now := <-ticker.C:
+			a.escalate(now)
+		}
+	}
+}
+
+// Send delivers an alert to every channel that wants it. Failures are
+// logged; one channel failing doesn't keep the alert from the others.
+func (a *Alerter) Send(alert Alert) {
+	for _, channel := range a.channels {
+		if channel.Wants(alert) {
+			a.notify(channel, alert)
+		}
+	}
+}
+
+// notify delivers an alert through one channel
+func (a *Alerter) notify(channel Channel, alert Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := channel.Notifier.Notify(ctx, alert); err != nil {
+		log.Printf("WARNING: Failed to send alert %q to %s: %v", alert.Title(), channel.Name, err)
+	}
+}
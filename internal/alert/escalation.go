@@ -0,0 +1,155 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/audit"
+)
+
+// ErrUnknownAlert is returned when acknowledging an alert that isn't open
+var ErrUnknownAlert = errors.New("unknown alert")
+
+// escalationTick is how often open alerts are checked for escalation
+const escalationTick = 15 * time.Second
+
+// EscalationStep re-sends an unacknowledged alert to Channels once After has
+// passed since it was raised, whatever their severity filters
+type EscalationStep struct {
+	After    time.Duration
+	Channels []string
+}
+
+// Record is an alert waiting for an operator to acknowledge it
+type Record struct {
+	ID          string    `json:"id"`
+	Target      string    `json:"target"`
+	Type        string    `json:"type"`
+	Severity    string    `json:"severity"`
+	Message     string    `json:"message,omitempty"`
+	Incident    string    `json:"incident,omitempty"`
+	RaisedAt    time.Time `json:"raised_at"`
+	Escalations int       `json:"escalations"`
+
+	alert Alert
+}
+
+// track opens an alert for escalation and returns its ID. A target has one
+// open alert at a time: later alerts about it update the open one rather
+// than restarting its escalation.
+func (a *Alerter) track(alert Alert, now time.Time) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, record := range a.open {
+		if record.Target == alert.Event.Target {
+			alert.ID = record.ID
+			record.alert = alert
+			record.Type = alert.Event.Type
+			record.Severity = alert.Severity.String()
+			record.Message = alert.Event.Message
+			record.Incident = alert.Event.Incident
+			return record.ID
+		}
+	}
+
+	a.nextID++
+	alert.ID = fmt.Sprintf("%d", a.nextID)
+	a.open[alert.ID] = &Record{
+		ID:       alert.ID,
+		Target:   alert.Event.Target,
+		Type:     alert.Event.Type,
+		Severity: alert.Severity.String(),
+		Message:  alert.Event.Message,
+		Incident: alert.Event.Incident,
+		RaisedAt: now,
+		alert:    alert,
+	}
+	return alert.ID
+}
+
+// resolve closes the open alert of a target that recovered
+func (a *Alerter) resolve(target string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, record := range a.open {
+		if record.Target == target {
+			delete(a.open, id)
+		}
+	}
+}
+
+// escalate re-sends the open alerts whose next escalation step is due
+func (a *Alerter) escalate(now time.Time) {
+	type due struct {
+		step  EscalationStep
+		alert Alert
+	}
+	var pending []due
+
+	a.mu.Lock()
+	for _, record := range a.open {
+		if record.Escalations >= len(a.escalation) {
+			continue
+		}
+		step := a.escalation[record.Escalations]
+		if now.Sub(record.RaisedAt) < step.After {
+			continue
+		}
+		record.Escalations++
+		alert := record.alert
+		alert.Escalation = record.Escalations
+		pending = append(pending, due{step: step, alert: alert})
+	}
+	a.mu.Unlock()
+
+	for _, p := range pending {
+		log.Printf("Alert %s for %s not acknowledged, escalating to %v", p.alert.ID, p.alert.Event.Target, p.step.Channels)
+		for _, name := range p.step.Channels {
+			if channel, ok := a.byName[name]; ok {
+				a.notify(channel, p.alert)
+			}
+		}
+	}
+}
+
+// Open returns the alerts waiting for acknowledgment, oldest first
+func (a *Alerter) Open() []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	records := make([]Record, 0, len(a.open))
+	for _, record := range a.open {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].RaisedAt.Before(records[j].RaisedAt) })
+	return records
+}
+
+// Ack acknowledges an open alert, stopping its escalation, and records who
+// did it in the audit log
+func (a *Alerter) Ack(id, by string) error {
+	a.mu.Lock()
+	record, ok := a.open[id]
+	delete(a.open, id)
+	a.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAlert, id)
+	}
+
+	log.Printf("Alert %s for %s acknowledged by %s", id, record.Target, by)
+	a.auditLog.Record(audit.Entry{
+		Action:   audit.ActionAcknowledge,
+		Target:   record.Target,
+		Reason:   record.alert.Title(),
+		Evidence: record.Message,
+		Approver: by,
+		LeaderID: record.alert.Event.LeaderID,
+		Outcome:  audit.OutcomeAcknowledged,
+	})
+	return nil
+}
@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook POSTs alerts as JSON to a URL
+type Webhook struct {
+	URL string
+}
+
+// webhookBody is what Webhook POSTs
+type webhookBody struct {
+	Severity string    `json:"severity"`
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Type     string    `json:"type"`
+	Target   string    `json:"target,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Incident string    `json:"incident,omitempty"` // the same for every alert of an incident
+	Time     time.Time `json:"timestamp"`
+}
+
+// Notify implements Notifier
+func (w Webhook) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, w.URL, webhookBody{
+		Severity: alert.Severity.String(),
+		Title:    alert.Title(),
+		Text:     alert.Text(),
+		Type:     alert.Event.Type,
+		Target:   alert.Event.Target,
+		Message:  alert.Event.Message,
+		Incident: alert.Event.Incident,
+		Time:     alert.Event.Timestamp,
+	})
+}
+
+// Email sends alerts through an SMTP server. Username and Password enable
+// PLAIN authentication, which net/smtp only allows over TLS or to localhost.
+type Email struct {
+	Address  string // host:port of the SMTP server
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify implements Notifier
+func (e Email) Notify(ctx context.Context, alert Alert) error {
+	host, _, err := net.SplitHostPort(e.Address)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", e.Address, err)
+	}
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	// The alerts of an incident all reference it, so mail clients thread them
+	var thread string
+	if alert.Event.Incident != "" {
+		reference := "<incident-" + alert.Event.Incident + "@coordinator>"
+		thread = "In-Reply-To: " + reference + "\r\nReferences: " + reference + "\r\n"
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n%sContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		e.From, strings.Join(e.To, ", "), alert.Title(), thread, strings.ReplaceAll(alert.Text(), "\n", "\r\n"))
+
+	// net/smtp takes no context, so the deadline is enforced around it
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(e.Address, auth, e.From, e.To, []byte(message)) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// telegramAPI is the Telegram Bot API base URL
+const telegramAPI = "https://api.telegram.org"
+
+// Telegram sends alerts to a chat through a Telegram bot
+type Telegram struct {
+	Token  string
+	ChatID string
+}
+
+// Notify implements Notifier
+func (t Telegram) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, telegramAPI+"/bot"+t.Token+"/sendMessage", map[string]string{
+		"chat_id": t.ChatID,
+		"text":    alert.Text(),
+	})
+}
+
+// postJSON POSTs body as JSON and fails on non-2xx answers
+func postJSON(ctx context.Context, endpoint string, body interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The URL may hold a token (Telegram), so it's left out of the error
+		return fmt.Errorf("request failed: %v", errorWithoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// errorWithoutURL strips the URL from an HTTP client error
+func errorWithoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// Outcome values recorded for a recovery action
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied" // a restart that needed confirmation was refused
+
+	OutcomeAcknowledged = "acknowledged" // an operator acknowledged an alert
+)
+
+// Actions of entries that don't record a recovery action, which other
+// entries name
+const (
+	ActionAcknowledge = "acknowledge" // an operator acknowledged an alert
+	ActionDeregister  = "deregister"  // a completed one-shot container stopped being monitored
+	ActionPrune       = "prune"       // ... and its container was removed
+	ActionUnpause     = "unpause"     // a paused container was resumed
+	ActionPark        = "park"        // a queue in a redelivery storm was moved to its parking lot
+
+	// Containers the leader started or created to reach a service's
+	// desired replicas
+	ActionStartReplica  = "start_replica"
+	ActionCreateReplica = "create_replica"
+)
+
+// Entry is a single record in the audit log
+type Entry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Action        string    `json:"action"`
+	Target        string    `json:"target"`
+	ContainerName string    `json:"container_name"`
+	Reason        string    `json:"reason"`
+	Cause         string    `json:"cause,omitempty"` // machine-readable reason (see events.Reason*)
+	Evidence      string    `json:"evidence,omitempty"`
+	Approver      string    `json:"approver,omitempty"` // who confirmed or denied a restart that needed it
+	LeaderID      int       `json:"leader_id"`
+	Term          uint64    `json:"term,omitempty"` // election term of that leader
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Logger appends audit entries as JSON lines to a file, and optionally
+// ships them over the event bus too (see Ship)
+type Logger struct {
+	file *os.File // nil for a Logger that only ships entries
+	mu   sync.Mutex
+
+	publisher     events.Publisher
+	coordinatorID int
+}
+
+// NewLogger opens (or creates) the audit log file in append-only mode
+func NewLogger(path string) (*Logger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+
+	log.Printf("Audit log enabled at %s", path)
+
+	return &Logger{file: file}, nil
+}
+
+// NewShippingLogger creates a Logger without a file, whose entries only go
+// out as events once Ship is called
+func NewShippingLogger() *Logger {
+	return &Logger{}
+}
+
+// Record appends an entry to the audit log. A nil Logger discards entries.
+func (l *Logger) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("ERROR: Failed to encode audit entry: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	publisher, coordinatorID := l.publisher, l.coordinatorID
+	if l.file != nil {
+		if _, err := l.file.Write(append(data, '\n')); err != nil {
+			log.Printf("ERROR: Failed to write audit entry: %v", err)
+		}
+	}
+	l.mu.Unlock()
+
+	if publisher != nil {
+		publisher.Publish(events.Event{
+			Type:          events.TypeAuditEntry,
+			Timestamp:     entry.Timestamp,
+			CoordinatorID: coordinatorID,
+			LeaderID:      entry.LeaderID,
+			Target:        entry.Target,
+			ContainerName: entry.ContainerName,
+			Message:       string(data),
+			Reason:        entry.Cause,
+		})
+	}
+}
+
+// Ship publishes every entry recorded from now on as an audit.entry event
+// too, with the JSON line as its message, so consumers of the event bus get
+// the audit trail without reading the file
+func (l *Logger) Ship(publisher events.Publisher, coordinatorID int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.publisher = publisher
+	l.coordinatorID = coordinatorID
+}
+
+// Close closes the underlying audit log file
+func (l *Logger) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// recorder keeps the events published to it
+type recorder struct {
+	events []events.Event
+}
+
+func (r *recorder) Publish(event events.Event) { r.events = append(r.events, event) }
+func (r *recorder) Close() error               { return nil }
+
+func TestShippingLoggerPublishesEntries(t *testing.T) {
+	publisher := &recorder{}
+	logger := NewShippingLogger()
+	logger.Ship(publisher, 2)
+	logger.Record(Entry{Action: "restart", Target: "filter-1", LeaderID: 2, Outcome: OutcomeSuccess})
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("published %d events, want 1", len(publisher.events))
+	}
+	event := publisher.events[0]
+	var entry Entry
+	if err := json.Unmarshal([]byte(event.Message), &entry); err != nil {
+		t.Fatalf("the event doesn't carry the entry: %v", err)
+	}
+	if event.Type != events.TypeAuditEntry || event.CoordinatorID != 2 || entry.Target != "filter-1" {
+		t.Errorf("published %+v", event)
+	}
+}
+
+func TestLoggerWritesAndShipsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewLogger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &recorder{}
+	logger.Ship(publisher, 1)
+	logger.Record(Entry{Action: "restart", Target: "filter-1", Outcome: OutcomeFailure})
+	logger.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"target":"filter-1"`) {
+		t.Errorf("audit log holds %q", data)
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("published %d events, want 1", len(publisher.events))
+	}
+}
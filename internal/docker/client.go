@@ -6,57 +6,177 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/tracing"
 )
 
 const (
 	dockerSocket = "/var/run/docker.sock"
 	dockerAPI    = "http://localhost"
 	timeout      = 10 * time.Second
+
+	// DefaultAPIVersion is the API version used when none is configured.
+	// Podman's Docker-compatible API accepts it as well.
+	DefaultAPIVersion = "1.40"
+)
+
+// Container runtimes served through the Docker-compatible API
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
 )
 
+// Options configures how a Client reaches the container runtime
+type Options struct {
+	Endpoint   string // unix:// or tcp:// endpoint; empty means the runtime's default socket
+	APIVersion string // API version prefix without the "v"; empty means DefaultAPIVersion
+	Runtime    string // RuntimeDocker (default) or RuntimePodman
+	Retry      RetryPolicy
+}
+
 // Client wraps Docker socket connection for container management
 type Client struct {
 	httpClient *http.Client
+	baseURL    string
+	endpoint   string
+	runtime    string
 }
 
 // NewClient creates a new Docker client via Unix socket
 func NewClient() (*Client, error) {
-	// Create HTTP client with Unix socket transport
+	return NewClientWithOptions(Options{})
+}
+
+// NewClientForEndpoint creates a Docker client for a daemon endpoint, either
+// a Unix socket (unix:///var/run/docker.sock) or a TCP address
+// (tcp://host:2375)
+func NewClientForEndpoint(endpoint string) (*Client, error) {
+	return NewClientWithOptions(Options{Endpoint: endpoint})
+}
+
+// DefaultEndpoint returns the default socket of a runtime. Rootless Podman
+// listens under $XDG_RUNTIME_DIR; rootful Podman under /run/podman.
+func DefaultEndpoint(runtime string) string {
+	if runtime != RuntimePodman {
+		return "unix://" + dockerSocket
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return "unix://" + dir + "/podman/podman.sock"
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// NewClientWithOptions creates a client for a Docker or Podman daemon
+func NewClientWithOptions(options Options) (*Client, error) {
+	if options.Runtime == "" {
+		options.Runtime = RuntimeDocker
+	}
+	if options.Runtime != RuntimeDocker && options.Runtime != RuntimePodman {
+		return nil, fmt.Errorf("unsupported container runtime %q", options.Runtime)
+	}
+	if options.Endpoint == "" {
+		options.Endpoint = DefaultEndpoint(options.Runtime)
+	}
+	if options.APIVersion == "" {
+		options.APIVersion = DefaultAPIVersion
+	}
+	if options.Retry == (RetryPolicy{}) {
+		options.Retry = DefaultRetryPolicy
+	}
+
+	endpoint := options.Endpoint
+	transport := &http.Transport{}
+	rootURL := dockerAPI
+
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"):
+		socket := strings.TrimPrefix(endpoint, "unix://")
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.DialTimeout("unix", socket, timeout)
+		}
+	case strings.HasPrefix(endpoint, "tcp://"):
+		rootURL = "http://" + strings.TrimPrefix(endpoint, "tcp://")
+	default:
+		return nil, fmt.Errorf("unsupported Docker endpoint %q (expected unix:// or tcp://)", endpoint)
+	}
+
+	// No client-wide timeout: every call carries its own context deadline,
+	// since a restart legitimately takes as long as the container's stop timeout
 	httpClient := &http.Client{
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return net.DialTimeout("unix", dockerSocket, timeout)
-			},
-		},
-		Timeout: timeout,
+		Transport: newResilientTransport(tracing.Transport(transport), options.Retry),
 	}
 
-	// Verify connection by pinging Docker daemon
-	resp, err := httpClient.Get(dockerAPI + "/v1.40/_ping")
+	// Verify connection by pinging the daemon. The unversioned /_ping is
+	// served by both Docker and Podman's compat API, unlike /libpod/_ping.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rootURL+"/_ping", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Docker daemon via socket %s: %w", dockerSocket, err)
+		return nil, fmt.Errorf("failed to create ping request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s daemon at %s: %w", options.Runtime, endpoint, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Docker daemon returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("%s daemon returned status %d", options.Runtime, resp.StatusCode)
 	}
 
-	log.Println("Successfully connected to Docker daemon via Unix socket")
+	if libpodVersion := resp.Header.Get("Libpod-API-Version"); libpodVersion != "" {
+		log.Printf("Connected to Podman (libpod API %s) at %s", libpodVersion, endpoint)
+		options.Runtime = RuntimePodman
+	} else {
+		log.Printf("Successfully connected to %s daemon at %s", options.Runtime, endpoint)
+	}
 
-	return &Client{httpClient: httpClient}, nil
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    rootURL + "/v" + options.APIVersion,
+		endpoint:   endpoint,
+		runtime:    options.Runtime,
+	}, nil
 }
 
-// RestartContainer restarts a container by its name or ID
-func (c *Client) RestartContainer(containerNameOrID string) error {
+// Ping checks that the daemon answers
+func (c *Client) Ping(ctx context.Context) error {
+	// Docker API: GET /_ping
+	if err := c.doStatus(ctx, http.MethodGet, c.baseURL+"/_ping", nil, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to ping %s daemon at %s: %w", c.runtime, c.endpoint, err)
+	}
+	return nil
+}
+
+// Runtime returns the container runtime behind this client
+func (c *Client) Runtime() string {
+	return c.runtime
+}
+
+// Endpoint returns the daemon endpoint this client talks to
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// RestartContainer restarts a container by its name or ID. stopTimeout is how
+// long Docker waits after SIGTERM before killing the container; zero uses the
+// container's own default.
+func (c *Client) RestartContainer(ctx context.Context, containerNameOrID string, stopTimeout time.Duration) error {
 	log.Printf("Restarting container: %s", containerNameOrID)
 
 	// POST request to restart endpoint
-	// Docker API: POST /containers/{id}/restart
-	url := fmt.Sprintf("%s/v1.40/containers/%s/restart", dockerAPI, containerNameOrID)
+	// Docker API: POST /containers/{id}/restart?t={seconds}
+	url := fmt.Sprintf("%s/containers/%s/restart", c.baseURL, containerNameOrID)
+	if stopTimeout > 0 {
+		url += fmt.Sprintf("?t=%d", int(stopTimeout.Seconds()))
+	}
 
-	req, err := http.NewRequest("POST", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create restart request: %w", err)
 	}
@@ -68,7 +188,7 @@ func (c *Client) RestartContainer(containerNameOrID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Docker API returned status %d for container %s", resp.StatusCode, containerNameOrID)
+		return fmt.Errorf("failed to restart container %s: %w", containerNameOrID, newAPIError(resp))
 	}
 
 	log.Printf("Container %s restarted successfully", containerNameOrID)
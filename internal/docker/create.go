@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ContainerSpec is the subset of a container definition the coordinator can
+// recreate a container from when it has disappeared
+type ContainerSpec struct {
+	Image      string
+	Cmd        []string
+	Entrypoint []string
+	Env        []string
+	Labels     map[string]string
+	Binds      []string
+	Networks   []string
+	Aliases    []string
+}
+
+// CreateContainer creates (but doesn't start) a container named name from
+// spec and returns its ID. The first network is attached at creation; the
+// rest are connected afterwards, as the API only accepts one endpoint on create.
+func (c *Client) CreateContainer(ctx context.Context, name string, spec ContainerSpec) (string, error) {
+	body := map[string]interface{}{
+		"Image":  spec.Image,
+		"Env":    spec.Env,
+		"Labels": spec.Labels,
+		"HostConfig": map[string]interface{}{
+			"Binds": spec.Binds,
+		},
+	}
+	if len(spec.Cmd) > 0 {
+		body["Cmd"] = spec.Cmd
+	}
+	if len(spec.Entrypoint) > 0 {
+		body["Entrypoint"] = spec.Entrypoint
+	}
+	if len(spec.Networks) > 0 {
+		body["NetworkingConfig"] = map[string]interface{}{
+			"EndpointsConfig": map[string]interface{}{
+				spec.Networks[0]: map[string]interface{}{"Aliases": spec.Aliases},
+			},
+		}
+	}
+
+	createBody, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode create request: %w", err)
+	}
+
+	// Docker API: POST /containers/create?name={name}
+	endpoint := fmt.Sprintf("%s/containers/create?name=%s", c.baseURL, url.QueryEscape(name))
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, endpoint, createBody, http.StatusCreated, &created); err != nil {
+		return "", fmt.Errorf("failed to create container %s: %w", name, err)
+	}
+
+	for _, network := range spec.Networks[min(1, len(spec.Networks)):] {
+		if err := c.ConnectNetwork(ctx, network, created.ID, spec.Aliases); err != nil {
+			return created.ID, err
+		}
+	}
+
+	return created.ID, nil
+}
+
+// ConnectNetwork connects a container to a network
+func (c *Client) ConnectNetwork(ctx context.Context, network, containerNameOrID string, aliases []string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"Container":      containerNameOrID,
+		"EndpointConfig": map[string]interface{}{"Aliases": aliases},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode connect request: %w", err)
+	}
+
+	// Docker API: POST /networks/{id}/connect
+	endpoint := fmt.Sprintf("%s/networks/%s/connect", c.baseURL, url.PathEscape(network))
+	if err := c.doJSON(ctx, http.MethodPost, endpoint, body, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("failed to connect container %s to network %s: %w", containerNameOrID, network, err)
+	}
+	return nil
+}
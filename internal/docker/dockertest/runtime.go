@@ -0,0 +1,397 @@
+// Package dockertest is an in-memory container runtime implementing the
+// parts of the Docker client the coordinator depends on (recovery actions,
+// Docker and exec health checks, autoscaling), so their behavior can be
+// exercised without a Docker daemon.
+package dockertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/scaling"
+)
+
+// Runtime stands in for the Docker client wherever the coordinator uses it
+var (
+	_ recovery.DockerRuntime = (*Runtime)(nil)
+	_ monitor.Inspector      = (*Runtime)(nil)
+	_ monitor.Execer         = (*Runtime)(nil)
+	_ scaling.Runtime        = (*Runtime)(nil)
+)
+
+// Call is a runtime method call, recorded in order
+type Call struct {
+	Method    string
+	Container string
+}
+
+// Container is a fake container's state
+type Container struct {
+	ID      string
+	Name    string
+	Labels  map[string]string
+	Running bool
+	Paused  bool
+	// Health is the HEALTHCHECK status, "" for containers without one
+	Health string
+	// Restarts counts restarts, kills and recreations
+	Restarts int
+	// Networks maps network names to the container's IP on them
+	Networks map[string]string
+}
+
+// Runtime is an in-memory container runtime. It's safe for concurrent use.
+type Runtime struct {
+	mu         sync.Mutex
+	containers map[string]*Container // by name
+	nextID     int
+	calls      []Call
+	failures   map[string]error // method or method+" "+container -> error
+
+	// ExecFunc answers Exec calls; the default exits 0 with no output
+	ExecFunc func(container string, cmd []string) docker.ExecResult
+	// OnRestart, if set, runs after a container is restarted, killed and
+	// started or recreated, e.g. to make it healthy again
+	OnRestart func(container *Container)
+}
+
+// NewRuntime creates a runtime with the given running containers
+func NewRuntime(names ...string) *Runtime {
+	r := &Runtime{
+		containers: make(map[string]*Container),
+		failures:   make(map[string]error),
+	}
+	for _, name := range names {
+		r.Add(Container{Name: name, Running: true})
+	}
+	return r
+}
+
+// Add adds (or replaces) a container, returning its ID
+func (r *Runtime) Add(container Container) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addLocked(container)
+}
+
+func (r *Runtime) addLocked(container Container) string {
+	r.nextID++
+	if container.ID == "" {
+		container.ID = fmt.Sprintf("%064x", r.nextID)
+	}
+	r.containers[container.Name] = &container
+	return container.ID
+}
+
+// Container returns a copy of a container's state
+func (r *Runtime) Container(name string) (Container, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, ok := r.containers[name]
+	if !ok {
+		return Container{}, false
+	}
+	return *container, true
+}
+
+// Update changes a container's state, e.g. to crash it or mark it unhealthy
+func (r *Runtime) Update(name string, update func(*Container)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, ok := r.containers[name]
+	if !ok {
+		return notFound(name)
+	}
+	update(container)
+	return nil
+}
+
+// Fail makes method (e.g. "RestartContainer") return err, for every
+// container or, if container isn't empty, only for that one. A nil err
+// clears the failure.
+func (r *Runtime) Fail(method, container string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := strings.TrimSpace(method + " " + container)
+	if err == nil {
+		delete(r.failures, key)
+	} else {
+		r.failures[key] = err
+	}
+}
+
+// Calls returns the calls made so far
+func (r *Runtime) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call{}, r.calls...)
+}
+
+// begin records a call and returns the injected failure and the container,
+// if any. Callers hold mu.
+func (r *Runtime) begin(method, name string) (*Container, error) {
+	r.calls = append(r.calls, Call{Method: method, Container: name})
+	if err := r.failures[method+" "+name]; err != nil {
+		return nil, err
+	}
+	if err := r.failures[method]; err != nil {
+		return nil, err
+	}
+	container := r.find(name)
+	if container == nil {
+		return nil, notFound(name)
+	}
+	return container, nil
+}
+
+// find looks a container up by name or ID (prefix). Callers hold mu.
+func (r *Runtime) find(nameOrID string) *Container {
+	if container, ok := r.containers[strings.TrimPrefix(nameOrID, "/")]; ok {
+		return container
+	}
+	for _, container := range r.containers {
+		if nameOrID != "" && strings.HasPrefix(container.ID, nameOrID) {
+			return container
+		}
+	}
+	return nil
+}
+
+// restarted marks a container running after a restart-like call. Callers
+// hold mu.
+func (r *Runtime) restarted(container *Container) {
+	container.Running = true
+	container.Paused = false
+	container.Restarts++
+	if r.OnRestart != nil {
+		r.OnRestart(container)
+	}
+}
+
+// RestartContainer implements recovery.DockerRuntime
+func (r *Runtime) RestartContainer(ctx context.Context, name string, stopTimeout time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("RestartContainer", name)
+	if err != nil {
+		return err
+	}
+	r.restarted(container)
+	return nil
+}
+
+// StartContainer implements recovery.DockerRuntime and scaling.Runtime
+func (r *Runtime) StartContainer(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("StartContainer", name)
+	if err != nil {
+		return err
+	}
+	if !container.Running {
+		r.restarted(container)
+	}
+	return nil
+}
+
+// StopContainer implements scaling.Runtime
+func (r *Runtime) StopContainer(ctx context.Context, name string, stopTimeout time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("StopContainer", name)
+	if err != nil {
+		return err
+	}
+	container.Running = false
+	return nil
+}
+
+// KillContainer implements recovery.DockerRuntime
+func (r *Runtime) KillContainer(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("KillContainer", name)
+	if err != nil {
+		return err
+	}
+	container.Running = false
+	return nil
+}
+
+// RemoveContainer implements scaling.Runtime
+func (r *Runtime) RemoveContainer(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("RemoveContainer", name)
+	if err != nil {
+		return err
+	}
+	delete(r.containers, container.Name)
+	return nil
+}
+
+// RecreateContainer implements recovery.DockerRuntime
+func (r *Runtime) RecreateContainer(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("RecreateContainer", name)
+	if err != nil {
+		return err
+	}
+	r.nextID++
+	container.ID = fmt.Sprintf("%064x", r.nextID)
+	r.restarted(container)
+	return nil
+}
+
+// CreateContainer implements recovery.DockerRuntime
+func (r *Runtime) CreateContainer(ctx context.Context, name string, spec docker.ContainerSpec) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: "CreateContainer", Container: name})
+	if err := r.failures["CreateContainer"]; err != nil {
+		return "", err
+	}
+	if r.find(name) != nil {
+		return "", &docker.APIError{StatusCode: http.StatusConflict, Message: fmt.Sprintf("container %s already exists", name)}
+	}
+	return r.addLocked(Container{Name: name, Labels: spec.Labels}), nil
+}
+
+// CloneContainer implements scaling.Runtime
+func (r *Runtime) CloneContainer(ctx context.Context, source, name string, labels map[string]string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	original, err := r.begin("CloneContainer", source)
+	if err != nil {
+		return "", err
+	}
+	if r.find(name) != nil {
+		return "", &docker.APIError{StatusCode: http.StatusConflict, Message: fmt.Sprintf("container %s already exists", name)}
+	}
+
+	merged := make(map[string]string, len(original.Labels)+len(labels))
+	for k, v := range original.Labels {
+		if !docker.IsComposeLabel(k) {
+			merged[k] = v
+		}
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return r.addLocked(Container{Name: name, Labels: merged, Health: original.Health}), nil
+}
+
+// ForceUpdateService implements recovery.DockerRuntime; services are
+// modeled as containers of the same name
+func (r *Runtime) ForceUpdateService(ctx context.Context, service string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("ForceUpdateService", service)
+	if err != nil {
+		return err
+	}
+	r.restarted(container)
+	return nil
+}
+
+// Exec implements recovery.DockerRuntime and monitor.Execer
+func (r *Runtime) Exec(ctx context.Context, name string, cmd []string) (docker.ExecResult, error) {
+	r.mu.Lock()
+	container, err := r.begin("Exec", name)
+	execFunc := r.ExecFunc
+	r.mu.Unlock()
+	if err != nil {
+		return docker.ExecResult{}, err
+	}
+	if !container.Running {
+		return docker.ExecResult{}, &docker.APIError{StatusCode: http.StatusConflict, Message: fmt.Sprintf("container %s is not running", name)}
+	}
+	if execFunc == nil {
+		return docker.ExecResult{}, nil
+	}
+	return execFunc(name, cmd), nil
+}
+
+// InspectContainer implements monitor.Inspector
+func (r *Runtime) InspectContainer(ctx context.Context, name string) (docker.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	container, err := r.begin("InspectContainer", name)
+	if err != nil {
+		return docker.ContainerInfo{}, err
+	}
+
+	info := docker.ContainerInfo{ID: container.ID, Name: "/" + container.Name}
+	info.State.Running = container.Running
+	info.State.Paused = container.Paused
+	info.State.Status = status(container)
+	if container.Health != "" {
+		info.State.Health = &docker.Health{Status: container.Health}
+	}
+	info.NetworkSettings.Networks = make(map[string]docker.NetworkEndpoint)
+	for network, ip := range container.Networks {
+		info.NetworkSettings.Networks[network] = docker.NetworkEndpoint{IPAddress: ip}
+	}
+	return info, nil
+}
+
+// ListContainers implements scaling.Runtime
+func (r *Runtime) ListContainers(ctx context.Context, labels map[string]string) ([]docker.ContainerSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: "ListContainers"})
+	if err := r.failures["ListContainers"]; err != nil {
+		return nil, err
+	}
+
+	summaries := []docker.ContainerSummary{}
+	for _, container := range r.containers {
+		if matches(container.Labels, labels) {
+			summaries = append(summaries, docker.ContainerSummary{
+				ID:     container.ID,
+				Names:  []string{"/" + container.Name},
+				State:  status(container),
+				Labels: container.Labels,
+			})
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Names[0] < summaries[j].Names[0] })
+	return summaries, nil
+}
+
+// status is the container's state as Docker names it
+func status(container *Container) string {
+	switch {
+	case container.Paused:
+		return "paused"
+	case container.Running:
+		return "running"
+	default:
+		return "exited"
+	}
+}
+
+// matches reports whether labels contain every filter label; an empty
+// filter value only requires the label to be present
+func matches(labels, filter map[string]string) bool {
+	for k, v := range filter {
+		if value, ok := labels[k]; !ok || (v != "" && value != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// notFound is the error Docker returns for a missing container
+func notFound(name string) error {
+	return &docker.APIError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("No such container: %s", name)}
+}
@@ -0,0 +1,114 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+)
+
+// containerPath matches /v{version}/containers/{name}[/{action}]
+var containerPath = regexp.MustCompile(`^/v[0-9.]+/containers/([^/]+)(?:/([a-z]+))?$`)
+
+// Server serves the subset of the Docker Engine API backed by a Runtime
+// that monitoring and restart-based recovery use: ping, list, inspect,
+// start, stop, restart, kill and remove. Pointing DOCKER_HOST at it (as
+// tcp://host:port) runs a real coordinator against fake containers.
+type Server struct {
+	runtime *Runtime
+}
+
+// NewServer creates a Docker API server for runtime
+func NewServer(runtime *Runtime) *Server {
+	return &Server{runtime: runtime}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/_ping") {
+		w.Write([]byte("OK"))
+		return
+	}
+
+	ctx := r.Context()
+	if strings.HasSuffix(r.URL.Path, "/containers/json") && r.Method == http.MethodGet {
+		containers, err := s.runtime.ListContainers(ctx, labelFilters(r))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, containers)
+		return
+	}
+
+	match := containerPath.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		writeError(w, &docker.APIError{StatusCode: http.StatusNotImplemented, Message: "not supported by dockertest: " + r.URL.Path})
+		return
+	}
+	name, action := match[1], match[2]
+
+	var err error
+	switch {
+	case r.Method == http.MethodGet && action == "json":
+		var info docker.ContainerInfo
+		if info, err = s.runtime.InspectContainer(ctx, name); err == nil {
+			writeJSON(w, info)
+			return
+		}
+	case r.Method == http.MethodPost && action == "restart":
+		err = s.runtime.RestartContainer(ctx, name, 0)
+	case r.Method == http.MethodPost && action == "start":
+		err = s.runtime.StartContainer(ctx, name)
+	case r.Method == http.MethodPost && action == "stop":
+		err = s.runtime.StopContainer(ctx, name, 0)
+	case r.Method == http.MethodPost && action == "kill":
+		err = s.runtime.KillContainer(ctx, name)
+	case r.Method == http.MethodDelete && action == "":
+		err = s.runtime.RemoveContainer(ctx, name)
+	default:
+		err = &docker.APIError{StatusCode: http.StatusNotImplemented, Message: "not supported by dockertest: " + r.Method + " " + r.URL.Path}
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// labelFilters extracts the label filters of a list request
+func labelFilters(r *http.Request) map[string]string {
+	var filters struct {
+		Label []string `json:"label"`
+	}
+	json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+
+	labels := make(map[string]string, len(filters.Label))
+	for _, filter := range filters.Label {
+		key, value, _ := strings.Cut(filter, "=")
+		labels[key] = value
+	}
+	return labels
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers with the status of an APIError (500 for anything
+// else) and Docker's {"message": "..."} body
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *docker.APIError
+	if errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+}
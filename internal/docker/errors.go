@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error classes for Docker API failures, matched with errors.Is
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrServerError = errors.New("server error")
+)
+
+// APIError is a non-2xx response from the Docker API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Docker API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Docker API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is lets errors.Is match APIError against the error classes
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// newAPIError builds an APIError from a response, reading the
+// {"message": "..."} body Docker returns on errors
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err == nil && json.Unmarshal(data, &body) == nil {
+		apiErr.Message = body.Message
+	}
+	return apiErr
+}
@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxExecOutput caps how much command output is kept from an exec
+const maxExecOutput = 4096
+
+// ExecResult is the outcome of a command run inside a container
+type ExecResult struct {
+	ExitCode int
+	Output   string
+}
+
+// Exec runs a command inside a running container and waits for it to finish.
+// Stdout and stderr are combined and truncated to maxExecOutput bytes.
+func (c *Client) Exec(ctx context.Context, containerNameOrID string, cmd []string) (ExecResult, error) {
+	// Docker API: POST /containers/{id}/exec
+	createBody, err := json.Marshal(map[string]interface{}{
+		"Cmd":          cmd,
+		"AttachStdout": true,
+		"AttachStderr": true,
+	})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to encode exec request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/containers/%s/exec", c.baseURL, containerNameOrID)
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, url, createBody, http.StatusCreated, &created); err != nil {
+		return ExecResult{}, fmt.Errorf("failed to create exec in container %s: %w", containerNameOrID, err)
+	}
+
+	// Docker API: POST /exec/{id}/start (attached, returns multiplexed stream)
+	url = fmt.Sprintf("%s/exec/%s/start", c.baseURL, created.ID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader([]byte(`{"Detach":false,"Tty":false}`)))
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to create exec start request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to start exec in container %s: %w", containerNameOrID, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError(resp)
+		resp.Body.Close()
+		return ExecResult{}, fmt.Errorf("failed to start exec in container %s: %w", containerNameOrID, apiErr)
+	}
+	output, err := demuxOutput(resp.Body, maxExecOutput)
+	resp.Body.Close()
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to read exec output from container %s: %w", containerNameOrID, err)
+	}
+
+	// Docker API: GET /exec/{id}/json
+	url = fmt.Sprintf("%s/exec/%s/json", c.baseURL, created.ID)
+	var inspect struct {
+		Running  bool `json:"Running"`
+		ExitCode int  `json:"ExitCode"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, url, nil, http.StatusOK, &inspect); err != nil {
+		return ExecResult{}, fmt.Errorf("failed to inspect exec in container %s: %w", containerNameOrID, err)
+	}
+	if inspect.Running {
+		return ExecResult{}, fmt.Errorf("exec in container %s still running after output closed", containerNameOrID)
+	}
+
+	return ExecResult{ExitCode: inspect.ExitCode, Output: output}, nil
+}
+
+// doJSON performs a request with an optional JSON body and decodes the JSON response
+func (c *Client) doJSON(ctx context.Context, method, url string, body []byte, expectedStatus int, out interface{}) error {
+	resp, err := c.doRequest(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return newAPIError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// doRequest sends a request with an optional JSON body
+func (c *Client) doRequest(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// demuxOutput reads a Docker multiplexed stdout/stderr stream, keeping at most
+// limit bytes of payload. Each frame is an 8-byte header (stream type, 3 zero
+// bytes, big-endian uint32 size) followed by the payload.
+func demuxOutput(r io.Reader, limit int) (string, error) {
+	var output bytes.Buffer
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return output.String(), nil
+			}
+			return output.String(), err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		remaining := int64(limit - output.Len())
+		if remaining > size {
+			remaining = size
+		}
+		if remaining > 0 {
+			if _, err := io.CopyN(&output, r, remaining); err != nil {
+				return output.String(), err
+			}
+		}
+		if _, err := io.CopyN(io.Discard, r, size-remaining); err != nil {
+			return output.String(), err
+		}
+	}
+}
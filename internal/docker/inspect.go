@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Health status values reported by Docker HEALTHCHECK
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// HealthLog is a single HEALTHCHECK probe result
+type HealthLog struct {
+	ExitCode int    `json:"ExitCode"`
+	Output   string `json:"Output"`
+}
+
+// Health is the HEALTHCHECK state of a container
+type Health struct {
+	Status        string      `json:"Status"`
+	FailingStreak int         `json:"FailingStreak"`
+	Log           []HealthLog `json:"Log"`
+}
+
+// ContainerState is the subset of the container state the coordinator uses
+type ContainerState struct {
+	Status     string  `json:"Status"`
+	Running    bool    `json:"Running"`
+	Paused     bool    `json:"Paused"`
+	Restarting bool    `json:"Restarting"`
+	OOMKilled  bool    `json:"OOMKilled"`
+	ExitCode   int     `json:"ExitCode"`
+	StartedAt  string  `json:"StartedAt"`
+	FinishedAt string  `json:"FinishedAt"`
+	Health     *Health `json:"Health"`
+
+	// Podman before 4.x reports the health under this key instead
+	Healthcheck *Health `json:"Healthcheck"`
+}
+
+// Container states and restart policies the coordinator tells apart
+const (
+	StatusExited    = "exited"
+	RestartPolicyNo = "no"
+)
+
+// NetworkEndpoint is a container's attachment to a network
+type NetworkEndpoint struct {
+	IPAddress         string `json:"IPAddress"`
+	GlobalIPv6Address string `json:"GlobalIPv6Address"`
+}
+
+// ContainerInfo is the subset of the inspect response the coordinator uses
+type ContainerInfo struct {
+	ID         string         `json:"Id"`
+	Name       string         `json:"Name"`
+	State      ContainerState `json:"State"`
+	HostConfig struct {
+		RestartPolicy struct {
+			Name string `json:"Name"`
+		} `json:"RestartPolicy"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		Networks map[string]NetworkEndpoint `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// NetworkAddress returns the container's IP on a network, IPv4 if it has
+// one, or "" if it isn't attached to it
+func (info ContainerInfo) NetworkAddress(network string) string {
+	endpoint, ok := info.NetworkSettings.Networks[network]
+	if !ok {
+		return ""
+	}
+	if endpoint.IPAddress != "" {
+		return endpoint.IPAddress
+	}
+	return endpoint.GlobalIPv6Address
+}
+
+// Completed reports whether the container ran to completion: it exited with
+// code 0 and has no restart policy, so nothing is meant to start it again
+// (e.g. a loader or client that finished its job)
+func (info ContainerInfo) Completed() bool {
+	policy := info.HostConfig.RestartPolicy.Name
+	return info.State.Status == StatusExited && info.State.ExitCode == 0 &&
+		(policy == "" || policy == RestartPolicyNo)
+}
+
+// InspectContainer returns low-level information about a container
+func (c *Client) InspectContainer(ctx context.Context, containerNameOrID string) (ContainerInfo, error) {
+	// Docker API: GET /containers/{id}/json
+	url := fmt.Sprintf("%s/containers/%s/json", c.baseURL, containerNameOrID)
+
+	var info ContainerInfo
+	if err := c.doJSON(ctx, http.MethodGet, url, nil, http.StatusOK, &info); err != nil {
+		return ContainerInfo{}, fmt.Errorf("failed to inspect container %s: %w", containerNameOrID, err)
+	}
+
+	if info.State.Health == nil {
+		info.State.Health = info.State.Healthcheck
+	}
+	return info, nil
+}
@@ -0,0 +1,239 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StartContainer starts a stopped container
+func (c *Client) StartContainer(ctx context.Context, containerNameOrID string) error {
+	// Docker API: POST /containers/{id}/start (304 if already started)
+	endpoint := fmt.Sprintf("%s/containers/%s/start", c.baseURL, containerNameOrID)
+	if err := c.doStatus(ctx, http.MethodPost, endpoint, nil, http.StatusNoContent, http.StatusNotModified); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", containerNameOrID, err)
+	}
+	return nil
+}
+
+// KillContainer sends SIGKILL to a container
+func (c *Client) KillContainer(ctx context.Context, containerNameOrID string) error {
+	// Docker API: POST /containers/{id}/kill (409 if not running)
+	endpoint := fmt.Sprintf("%s/containers/%s/kill", c.baseURL, containerNameOrID)
+	if err := c.doStatus(ctx, http.MethodPost, endpoint, nil, http.StatusNoContent, http.StatusConflict); err != nil {
+		return fmt.Errorf("failed to kill container %s: %w", containerNameOrID, err)
+	}
+	return nil
+}
+
+// UnpauseContainer resumes a paused container
+func (c *Client) UnpauseContainer(ctx context.Context, containerNameOrID string) error {
+	// Docker API: POST /containers/{id}/unpause
+	endpoint := fmt.Sprintf("%s/containers/%s/unpause", c.baseURL, containerNameOrID)
+	if err := c.doStatus(ctx, http.MethodPost, endpoint, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to unpause container %s: %w", containerNameOrID, err)
+	}
+	return nil
+}
+
+// RemoveContainer force-removes a container
+func (c *Client) RemoveContainer(ctx context.Context, containerNameOrID string) error {
+	// Docker API: DELETE /containers/{id}?force=true
+	endpoint := fmt.Sprintf("%s/containers/%s?force=true", c.baseURL, containerNameOrID)
+	if err := c.doStatus(ctx, http.MethodDelete, endpoint, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", containerNameOrID, err)
+	}
+	return nil
+}
+
+// RecreateContainer removes a container and creates a new one with the same
+// name, image, config, host config and networks, then starts it. Useful when
+// the container's writable layer or runtime state is what's broken.
+func (c *Client) RecreateContainer(ctx context.Context, containerNameOrID string) error {
+	bp, err := c.blueprint(ctx, containerNameOrID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container %s for recreate: %w", containerNameOrID, err)
+	}
+
+	if err := c.RemoveContainer(ctx, containerNameOrID); err != nil {
+		return err
+	}
+
+	id, err := c.createFromBlueprint(ctx, bp.name, bp)
+	if err != nil {
+		return err
+	}
+	return c.StartContainer(ctx, id)
+}
+
+// CloneContainer creates (but doesn't start) a new container named name with
+// the same image, config and networks as source, plus the given labels. The
+// clone doesn't inherit source's name as a network alias, nor its compose
+// labels: it's not a replica of the compose service, which would count it
+// and discover it as one.
+func (c *Client) CloneContainer(ctx context.Context, source, name string, labels map[string]string) (string, error) {
+	bp, err := c.blueprint(ctx, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container %s for clone: %w", source, err)
+	}
+
+	var sourceLabels map[string]string
+	if raw, ok := bp.config["Labels"]; ok {
+		json.Unmarshal(raw, &sourceLabels)
+	}
+	merged := make(map[string]string, len(sourceLabels)+len(labels))
+	for k, v := range sourceLabels {
+		if !IsComposeLabel(k) {
+			merged[k] = v
+		}
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	clone := *bp
+	clone.config = make(map[string]json.RawMessage, len(bp.config))
+	for k, v := range bp.config {
+		clone.config[k] = v
+	}
+	clone.config["Hostname"], _ = json.Marshal(name)
+	clone.config["Labels"], _ = json.Marshal(merged)
+
+	clone.networks = make(map[string]endpointSettings, len(bp.networks))
+	for network, settings := range bp.networks {
+		aliases := []string{name}
+		for _, alias := range settings.Aliases {
+			if alias != bp.name && !strings.HasPrefix(bp.id, alias) {
+				aliases = append(aliases, alias)
+			}
+		}
+		settings.Aliases = aliases
+		clone.networks[network] = settings
+	}
+
+	return c.createFromBlueprint(ctx, name, &clone)
+}
+
+// endpointSettings are the user-provided settings of a network attachment
+type endpointSettings struct {
+	Aliases    []string          `json:"Aliases"`
+	Links      []string          `json:"Links"`
+	DriverOpts map[string]string `json:"DriverOpts"`
+}
+
+// blueprint is what's needed to create a container like an existing one
+type blueprint struct {
+	id         string
+	name       string
+	config     map[string]json.RawMessage
+	hostConfig json.RawMessage
+	networks   map[string]endpointSettings
+}
+
+// blueprint inspects a container, keeping its config raw so every field survives
+func (c *Client) blueprint(ctx context.Context, containerNameOrID string) (*blueprint, error) {
+	// Docker API: GET /containers/{id}/json
+	endpoint := fmt.Sprintf("%s/containers/%s/json", c.baseURL, containerNameOrID)
+	var inspect struct {
+		ID              string                     `json:"Id"`
+		Name            string                     `json:"Name"`
+		Config          map[string]json.RawMessage `json:"Config"`
+		HostConfig      json.RawMessage            `json:"HostConfig"`
+		NetworkSettings struct {
+			// Only the user-provided endpoint settings are decoded; runtime
+			// fields such as IPAddress or EndpointID would pin the new
+			// container to stale values
+			Networks map[string]endpointSettings `json:"Networks"`
+		} `json:"NetworkSettings"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, http.StatusOK, &inspect); err != nil {
+		return nil, err
+	}
+
+	return &blueprint{
+		id:         inspect.ID,
+		name:       strings.TrimPrefix(inspect.Name, "/"),
+		config:     inspect.Config,
+		hostConfig: inspect.HostConfig,
+		networks:   inspect.NetworkSettings.Networks,
+	}, nil
+}
+
+// createFromBlueprint creates a container named name from bp and returns its
+// ID. API 1.40 only accepts one endpoint on create, so the first network
+// (in name order) is attached at creation and the rest are connected after.
+func (c *Client) createFromBlueprint(ctx context.Context, name string, bp *blueprint) (string, error) {
+	networks := make([]string, 0, len(bp.networks))
+	for network := range bp.networks {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+
+	body := make(map[string]interface{}, len(bp.config)+2)
+	for key, value := range bp.config {
+		body[key] = value
+	}
+	body["HostConfig"] = bp.hostConfig
+	if len(networks) > 0 {
+		body["NetworkingConfig"] = map[string]interface{}{
+			"EndpointsConfig": map[string]interface{}{networks[0]: bp.networks[networks[0]]},
+		}
+	}
+
+	createBody, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode create request: %w", err)
+	}
+
+	// Docker API: POST /containers/create?name={name}
+	endpoint := fmt.Sprintf("%s/containers/create?name=%s", c.baseURL, url.QueryEscape(name))
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, endpoint, createBody, http.StatusCreated, &created); err != nil {
+		return "", fmt.Errorf("failed to create container %s: %w", name, err)
+	}
+
+	for _, network := range networks[min(1, len(networks)):] {
+		if err := c.ConnectNetwork(ctx, network, created.ID, bp.networks[network].Aliases); err != nil {
+			return created.ID, err
+		}
+	}
+
+	return created.ID, nil
+}
+
+// StopContainer stops a container, waiting up to stopTimeout before killing
+// it; zero uses the container's own default
+func (c *Client) StopContainer(ctx context.Context, containerNameOrID string, stopTimeout time.Duration) error {
+	// Docker API: POST /containers/{id}/stop?t={seconds} (304 if already stopped)
+	endpoint := fmt.Sprintf("%s/containers/%s/stop", c.baseURL, containerNameOrID)
+	if stopTimeout > 0 {
+		endpoint += fmt.Sprintf("?t=%d", int(stopTimeout.Seconds()))
+	}
+	if err := c.doStatus(ctx, http.MethodPost, endpoint, nil, http.StatusNoContent, http.StatusNotModified); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", containerNameOrID, err)
+	}
+	return nil
+}
+
+// doStatus performs a request and accepts any of the given status codes
+func (c *Client) doStatus(ctx context.Context, method, endpoint string, body []byte, acceptedStatus ...int) error {
+	resp, err := c.doRequest(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, status := range acceptedStatus {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return newAPIError(resp)
+}
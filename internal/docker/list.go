@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Labels set by Docker Compose on the containers it creates
+const (
+	LabelComposeProject = "com.docker.compose.project"
+	LabelComposeService = "com.docker.compose.service"
+	LabelComposeNumber  = "com.docker.compose.container-number"
+)
+
+// composeLabelPrefix starts every label Docker Compose sets
+const composeLabelPrefix = "com.docker.compose."
+
+// IsComposeLabel reports whether a label is one Docker Compose sets, which
+// ties the container to a compose service
+func IsComposeLabel(key string) bool {
+	return strings.HasPrefix(key, composeLabelPrefix)
+}
+
+// ContainerSummary is the subset of the container list response the coordinator uses
+type ContainerSummary struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// Name returns the container's primary name without the leading slash
+func (s ContainerSummary) Name() string {
+	if len(s.Names) == 0 {
+		return s.ID
+	}
+	return strings.TrimPrefix(s.Names[0], "/")
+}
+
+// ListContainers returns all containers (running or not) that carry every
+// given label. A label with an empty value matches any value.
+func (c *Client) ListContainers(ctx context.Context, labels map[string]string) ([]ContainerSummary, error) {
+	labelFilters := []string{}
+	for key, value := range labels {
+		if value == "" {
+			labelFilters = append(labelFilters, key)
+		} else {
+			labelFilters = append(labelFilters, key+"="+value)
+		}
+	}
+
+	filters, err := json.Marshal(map[string][]string{"label": labelFilters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container filters: %w", err)
+	}
+
+	// Docker API: GET /containers/json
+	query := url.Values{}
+	query.Set("all", "true")
+	query.Set("filters", string(filters))
+	endpoint := fmt.Sprintf("%s/containers/json?%s", c.baseURL, query.Encode())
+
+	var containers []ContainerSummary
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, http.StatusOK, &containers); err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return containers, nil
+}
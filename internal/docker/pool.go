@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Pool holds one Docker client per host so containers can be managed on
+// whichever machine they run. The empty host name is the local daemon.
+type Pool struct {
+	local     *Client
+	options   Options
+	endpoints map[string]string
+	clients   map[string]*Client
+	mu        sync.Mutex
+}
+
+// NewPool creates a pool around the local client and the endpoints of the
+// remote hosts (host name -> unix:// or tcp:// endpoint). Remote clients are
+// connected lazily on first use, with options apart from the endpoint.
+func NewPool(local *Client, endpoints map[string]string, options Options) *Pool {
+	return &Pool{
+		local:     local,
+		options:   options,
+		endpoints: endpoints,
+		clients:   make(map[string]*Client),
+	}
+}
+
+// Client returns the client for a host, connecting to it if needed
+func (p *Pool) Client(host string) (*Client, error) {
+	if host == "" {
+		return p.local, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[host]; ok {
+		return client, nil
+	}
+
+	endpoint, ok := p.endpoints[host]
+	if !ok {
+		return nil, fmt.Errorf("unknown Docker host %q", host)
+	}
+
+	options := p.options
+	options.Endpoint = endpoint
+	client, err := NewClientWithOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker host %s: %w", host, err)
+	}
+
+	log.Printf("Connected to Docker host %s at %s", host, endpoint)
+	p.clients[host] = client
+	return client, nil
+}
+
+// Close closes every client in the pool
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, client := range p.clients {
+		client.Close()
+	}
+	return p.local.Close()
+}
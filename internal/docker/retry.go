@@ -0,0 +1,184 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while the circuit breaker is refusing calls
+var ErrCircuitOpen = errors.New("Docker API circuit breaker is open")
+
+// RetryPolicy configures retries and the circuit breaker around Docker API calls
+type RetryPolicy struct {
+	MaxAttempts      int           // Attempts per call, including the first; <= 1 disables retries
+	InitialBackoff   time.Duration // Wait before the first retry; doubles on each attempt
+	MaxBackoff       time.Duration // Upper bound for the wait between attempts
+	BreakerThreshold int           // Consecutive failed calls that open the breaker; zero disables it
+	BreakerCooldown  time.Duration // How long the breaker stays open before allowing a trial call
+}
+
+// DefaultRetryPolicy is used when Options.Retry is the zero value
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      3,
+	InitialBackoff:   200 * time.Millisecond,
+	MaxBackoff:       2 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
+// resilientTransport retries failed calls with exponential backoff (see
+// retryable), and stops issuing calls for a while after too many
+// consecutive failures so a misbehaving daemon isn't hammered
+type resilientTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+
+	mu       sync.Mutex
+	failures int
+	// openUntil is when the open breaker lets a trial call through; zero
+	// while it's closed
+	openUntil time.Time
+	// trying is set while the trial call of the half-open breaker is in
+	// flight
+	trying bool
+}
+
+// newResilientTransport wraps next with policy
+func newResilientTransport(next http.RoundTripper, policy RetryPolicy) *resilientTransport {
+	return &resilientTransport{next: next, policy: policy}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trial, err := t.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := t.policy.InitialBackoff
+	attempts := t.policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err = t.next.RoundTrip(attemptReq)
+		if !retryable(req, resp, err) || attempt >= attempts || (req.Body != nil && req.GetBody == nil) {
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		log.Printf("Docker API %s %s failed (attempt %d/%d), retrying in %v", req.Method, req.URL.Path, attempt, attempts, backoff)
+
+		select {
+		case <-req.Context().Done():
+			t.record(true, trial, false)
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if t.policy.MaxBackoff > 0 && backoff > t.policy.MaxBackoff {
+			backoff = t.policy.MaxBackoff
+		}
+	}
+
+	// A cancelled context says nothing about the daemon's health
+	t.record(req.Context().Err() != nil, trial, !failed(resp, err))
+	return resp, err
+}
+
+// allow fails fast while the breaker is open. Once the cooldown is over
+// the breaker is half-open: one trial call goes through, reported by
+// allow, and the others keep failing fast until it succeeds.
+func (t *resilientTransport) allow() (trial bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case t.openUntil.IsZero():
+		return false, nil
+	case time.Now().Before(t.openUntil):
+		return false, fmt.Errorf("%w until %s", ErrCircuitOpen, t.openUntil.Format(time.RFC3339))
+	case t.trying:
+		return false, fmt.Errorf("%w, a trial call is in flight", ErrCircuitOpen)
+	}
+	t.trying = true
+	return true, nil
+}
+
+// record updates the breaker with the outcome of a call. An abandoned call
+// (its context cancelled) doesn't count either way; if it was the trial,
+// the next call is.
+func (t *resilientTransport) record(abandoned, trial, success bool) {
+	if t.policy.BreakerThreshold <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if trial {
+		t.trying = false
+	}
+	switch {
+	case abandoned:
+	case success:
+		if trial {
+			log.Printf("Docker API trial call succeeded, resuming calls")
+		}
+		t.failures = 0
+		t.openUntil = time.Time{}
+	case trial:
+		t.openUntil = time.Now().Add(t.policy.BreakerCooldown)
+		log.Printf("WARNING: Docker API trial call failed, pausing calls for another %v", t.policy.BreakerCooldown)
+	default:
+		t.failures++
+		if t.failures >= t.policy.BreakerThreshold {
+			t.openUntil = time.Now().Add(t.policy.BreakerCooldown)
+			t.failures = 0
+			log.Printf("WARNING: Docker API failed %d times in a row, pausing calls for %v", t.policy.BreakerThreshold, t.policy.BreakerCooldown)
+		}
+	}
+}
+
+// failed reports whether a call failed: no response, or a 5xx
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryable reports whether a failed call is worth retrying. GET and HEAD
+// can run twice; anything else (a restart, a kill, a create) only when it
+// never reached the daemon, i.e. the connection couldn't be made, as the
+// daemon may have acted on it even if the answer was lost or a 5xx.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if !failed(resp, err) {
+		return false
+	}
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
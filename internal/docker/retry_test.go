@@ -0,0 +1,146 @@
+package docker
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport answers every call with the result of answer and counts them
+type fakeTransport struct {
+	mu     sync.Mutex
+	calls  int
+	answer func(req *http.Request) (*http.Response, error)
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	return f.answer(req)
+}
+
+func (f *fakeTransport) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func status(code int) func(*http.Request) (*http.Response, error) {
+	return func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+}
+
+var (
+	errDial  = &net.OpError{Op: "dial", Net: "unix", Err: errors.New("connection refused")}
+	errReset = &net.OpError{Op: "read", Net: "unix", Err: errors.New("connection reset by peer")}
+)
+
+func fail(err error) func(*http.Request) (*http.Response, error) {
+	return func(*http.Request) (*http.Response, error) { return nil, err }
+}
+
+func TestRetryOnlyIdempotentOrUnsentCalls(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		answer func(*http.Request) (*http.Response, error)
+		calls  int
+	}{
+		{"a GET answered 5xx is retried", http.MethodGet, status(http.StatusInternalServerError), 3},
+		{"a GET whose connection broke is retried", http.MethodGet, fail(errReset), 3},
+		{"a POST answered 5xx isn't retried", http.MethodPost, status(http.StatusInternalServerError), 1},
+		{"a POST whose connection broke isn't retried", http.MethodPost, fail(errReset), 1},
+		{"a POST that couldn't connect is retried", http.MethodPost, fail(errDial), 3},
+		{"a POST that succeeded isn't retried", http.MethodPost, status(http.StatusNoContent), 1},
+		{"a GET answered 404 isn't retried", http.MethodGet, status(http.StatusNotFound), 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := &fakeTransport{answer: test.answer}
+			transport := newResilientTransport(fake, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+			req, _ := http.NewRequest(test.method, "http://docker/containers/worker-1/restart", nil)
+			if resp, err := transport.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+			if calls := fake.count(); calls != test.calls {
+				t.Errorf("%d calls, want %d", calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestBreakerLetsOneTrialCallThrough(t *testing.T) {
+	fake := &fakeTransport{answer: fail(errDial)}
+	transport := newResilientTransport(fake, RetryPolicy{MaxAttempts: 1, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+	call := func() error {
+		req, _ := http.NewRequest(http.MethodGet, "http://docker/_ping", nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	call()
+	call()
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after 2 failures: %v, want the breaker open", err)
+	}
+
+	// Half-open: while the trial call hangs, the others fail fast
+	release := make(chan struct{})
+	fake.answer = func(*http.Request) (*http.Response, error) {
+		<-release
+		return status(http.StatusOK)(nil)
+	}
+	time.Sleep(60 * time.Millisecond)
+	trial := make(chan error)
+	go func() { trial <- call() }()
+	for fake.count() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("during the trial call: %v, want the breaker open", err)
+	}
+	close(release)
+	if err := <-trial; err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+
+	// The trial succeeded, so the breaker is closed again
+	if err := call(); err != nil {
+		t.Errorf("after the trial succeeded: %v", err)
+	}
+	if calls := fake.count(); calls != 4 {
+		t.Errorf("%d calls reached the daemon, want 4", calls)
+	}
+}
+
+func TestBreakerReopensWhenTheTrialFails(t *testing.T) {
+	fake := &fakeTransport{answer: fail(errDial)}
+	transport := newResilientTransport(fake, RetryPolicy{MaxAttempts: 1, BreakerThreshold: 1, BreakerCooldown: 50 * time.Millisecond})
+	call := func() error {
+		req, _ := http.NewRequest(http.MethodGet, "http://docker/_ping", nil)
+		_, err := transport.RoundTrip(req)
+		return err
+	}
+
+	call()
+	time.Sleep(60 * time.Millisecond)
+	if err := call(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("trial call: %v, want the daemon's error", err)
+	}
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("after the trial failed: %v, want the breaker open", err)
+	}
+	if calls := fake.count(); calls != 2 {
+		t.Errorf("%d calls reached the daemon, want 2", calls)
+	}
+}
@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ForceUpdateService forces a Swarm service to redeploy its tasks, the API
+// equivalent of `docker service update --force`. Needed under Swarm because
+// tasks get rescheduled into new containers, so restarting by container name
+// stops working.
+func (c *Client) ForceUpdateService(ctx context.Context, serviceNameOrID string) error {
+	// Docker API: GET /services/{id} (Spec kept raw so every field survives)
+	endpoint := fmt.Sprintf("%s/services/%s", c.baseURL, url.PathEscape(serviceNameOrID))
+	var service struct {
+		ID      string `json:"ID"`
+		Version struct {
+			Index uint64 `json:"Index"`
+		} `json:"Version"`
+		Spec map[string]json.RawMessage `json:"Spec"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, endpoint, nil, http.StatusOK, &service); err != nil {
+		return fmt.Errorf("failed to inspect service %s: %w", serviceNameOrID, err)
+	}
+
+	var taskTemplate map[string]json.RawMessage
+	if raw, ok := service.Spec["TaskTemplate"]; ok {
+		if err := json.Unmarshal(raw, &taskTemplate); err != nil {
+			return fmt.Errorf("failed to decode task template of service %s: %w", serviceNameOrID, err)
+		}
+	}
+	if taskTemplate == nil {
+		taskTemplate = make(map[string]json.RawMessage)
+	}
+
+	// Bumping ForceUpdate is what makes Swarm replace the tasks
+	var forceUpdate uint64
+	if raw, ok := taskTemplate["ForceUpdate"]; ok {
+		json.Unmarshal(raw, &forceUpdate)
+	}
+	taskTemplate["ForceUpdate"] = json.RawMessage(fmt.Sprintf("%d", forceUpdate+1))
+
+	rawTemplate, err := json.Marshal(taskTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to encode task template: %w", err)
+	}
+	service.Spec["TaskTemplate"] = rawTemplate
+
+	body, err := json.Marshal(service.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to encode service spec: %w", err)
+	}
+
+	// Docker API: POST /services/{id}/update?version={index}
+	endpoint = fmt.Sprintf("%s/services/%s/update?version=%d", c.baseURL, service.ID, service.Version.Index)
+	if err := c.doJSON(ctx, http.MethodPost, endpoint, body, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("failed to force update service %s: %w", serviceNameOrID, err)
+	}
+	return nil
+}
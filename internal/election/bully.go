@@ -2,328 +2,454 @@ package election
 
 import (
 	"fmt"
-	"io"
 	"log"
-	"net"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/supervise"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/connlimit"
 )
 
 const (
-	electionPort       = "12340"
-	timeout            = 2 * time.Second
-	heartbeatInterval  = 2 * time.Second
-	electionTimeout    = 6 * time.Second
-	
-	// Protocol messages
+	defaultElectionPort      = "12340"
+	defaultMessageTimeout    = 2 * time.Second
+	defaultHeartbeatInterval = 2 * time.Second
+	defaultElectionTimeout   = 6 * time.Second
+	defaultMissedHeartbeats  = 3
+	defaultStickiness        = 30 * time.Second
+
+	// maxMessageLength bounds a single election message
+	maxMessageLength = 256
+
+	// Protocol messages (newline-terminated, see pkg/framing)
 	msgElection = "ELECTION"
 	msgOK       = "OK"
 	msgLeader   = "LEADER"
+	msgResign   = "RESIGN"
+	msgPromote  = "PROMOTE"
 )
 
-// Coordinator represents a coordinator node in the election
+// BullyOptions tune the Bully election
+type BullyOptions struct {
+	// Port is the TCP port election messages are exchanged on, the same
+	// on every coordinator
+	Port string
+	// BindAddress is the local address the election server listens on;
+	// empty means every interface
+	BindAddress string
+	// MessageTimeout bounds connecting to a peer and waiting for its OK
+	MessageTimeout time.Duration
+	// HeartbeatInterval is how often the leader sends LEADER heartbeats
+	HeartbeatInterval time.Duration
+	// ElectionTimeout is how long followers wait without heartbeats before
+	// starting an election. It must be longer than HeartbeatInterval.
+	ElectionTimeout time.Duration
+	// MissedHeartbeats is how many heartbeats in a row a follower must miss
+	// (on top of the election timeout elapsing) before starting an election,
+	// so one slow heartbeat doesn't trigger one
+	MissedHeartbeats int
+	// Stickiness is how long a new leader keeps leadership even against a
+	// higher rank, so latency spikes don't make leadership ping-pong; only a
+	// higher term takes it away sooner. Zero disables it.
+	Stickiness time.Duration
+	// StartupTimeout is how long Start waits for a majority of the
+	// coordinators to be reachable before the first election. Zero starts
+	// it right away.
+	StartupTimeout time.Duration
+	// Peers resolves the other coordinators' hostnames
+	Peers Peers
+	// Transport carries election messages; nil means TCP on BindAddress
+	// and Port, dialing Peers
+	Transport Transport
+	// Priorities rank coordinators by ID; a higher priority wins elections
+	// regardless of ID, and the ID breaks ties. Unlisted coordinators have
+	// priority 0. Every coordinator must be given the same priorities.
+	Priorities map[int]int
+	// Limits bound the connections the TCP transport accepts
+	Limits connlimit.Limits
+	// NodeID is this coordinator's stable identity (see LoadNodeID),
+	// announced to the peers in the handshake. Empty announces none.
+	NodeID string
+	// AdvertiseAddress is the host peers should dial to reach this
+	// coordinator, when it's not its hostname (NAT, several networks). It's
+	// announced in the handshake; peers keep it in Peers.Advertised.
+	AdvertiseAddress string
+}
+
+// outranks reports whether coordinator a wins elections over coordinator b
+func (o BullyOptions) outranks(a, b int) bool {
+	if o.Priorities[a] != o.Priorities[b] {
+		return o.Priorities[a] > o.Priorities[b]
+	}
+	return a > b
+}
+
+// DefaultBullyOptions are the options used by NewCoordinator
+var DefaultBullyOptions = BullyOptions{
+	Port:              defaultElectionPort,
+	MessageTimeout:    defaultMessageTimeout,
+	HeartbeatInterval: defaultHeartbeatInterval,
+	ElectionTimeout:   defaultElectionTimeout,
+	MissedHeartbeats:  defaultMissedHeartbeats,
+	Stickiness:        defaultStickiness,
+	StartupTimeout:    defaultStartupTimeout,
+	Peers:             DefaultPeers,
+}
+
+// Validate checks that the options are usable
+func (o BullyOptions) Validate() error {
+	if o.Port == "" {
+		return fmt.Errorf("election port is required")
+	}
+	if o.MessageTimeout <= 0 || o.HeartbeatInterval <= 0 || o.ElectionTimeout <= 0 {
+		return fmt.Errorf("election timeouts and heartbeat interval must be positive")
+	}
+	if o.ElectionTimeout <= o.HeartbeatInterval {
+		return fmt.Errorf("election timeout (%v) must be longer than the heartbeat interval (%v)",
+			o.ElectionTimeout, o.HeartbeatInterval)
+	}
+	if o.MissedHeartbeats < 0 || o.Stickiness < 0 || o.StartupTimeout < 0 {
+		return fmt.Errorf("missed heartbeats, stickiness and startup timeout can't be negative")
+	}
+	if o.NodeID != "" && !ValidNodeID(o.NodeID) {
+		return fmt.Errorf("invalid node identity %q (expected a UUID)", o.NodeID)
+	}
+	if o.AdvertiseAddress != "" && !ValidHost(o.AdvertiseAddress) {
+		return fmt.Errorf("invalid advertise address %q (expected an IP address or hostname, without port)", o.AdvertiseAddress)
+	}
+	return nil
+}
+
+// fanOutTimeout bounds a whole round of ELECTION messages: connecting and
+// waiting for the OK
+func (o BullyOptions) fanOutTimeout() time.Duration {
+	return 2 * o.MessageTimeout
+}
+
+// listenWindow is how long a node that just started waits for a leader's
+// heartbeat before its first election: a heartbeat interval, plus a
+// message timeout for the heartbeat to arrive
+func (o BullyOptions) listenWindow() time.Duration {
+	return o.HeartbeatInterval + o.MessageTimeout
+}
+
+// stepDownDuration is how long a leader that stepped down stays out of elections
+func (o BullyOptions) stepDownDuration() time.Duration {
+	return 3 * o.ElectionTimeout
+}
+
+// outboxSize is how many messages may wait to be sent to one peer; once
+// full, new ones are dropped rather than piling up behind a dead peer
+const outboxSize = 8
+
+// Coordinator runs the Bully election. Every transition happens on one
+// goroutine that owns a bullyState; connections, ticks and election rounds
+// only feed it events, so nothing accumulates however many messages arrive.
 type Coordinator struct {
-	myID              int
-	totalReplicas     int
-	isLeader          bool
-	leaderID          int
-	mu                sync.RWMutex
-	leaderChan        chan bool
-	lastHeartbeat     time.Time
-	heartbeatMu       sync.RWMutex
-	stopHeartbeat     chan bool
+	myID          int
+	totalReplicas int
+	options       BullyOptions
+	state         *bullyState
+	events        chan interface{}
+	outboxes      map[int]chan string
+
+	// mu guards current, the state as last published by the event loop
+	mu      sync.RWMutex
+	current view
+
+	// capsMu guards what each peer speaks and the identity it announced
+	// (see capabilities.go)
+	capsMu      sync.Mutex
+	peerCaps    map[int]Capabilities
+	peerNodeIDs map[int]string
+
+	*statsRecorder
+	*leadershipNotifier
 }
 
 // NewCoordinator creates a new coordinator for Bully election
 func NewCoordinator(myID, totalReplicas int) *Coordinator {
-	return &Coordinator{
-		myID:          myID,
-		totalReplicas: totalReplicas,
-		isLeader:      false,
-		leaderID:      -1,
-		leaderChan:    make(chan bool, 10),
-		lastHeartbeat: time.Now(),
-		stopHeartbeat: make(chan bool, 1),
-	}
+	c, _ := NewCoordinatorWithOptions(myID, totalReplicas, DefaultBullyOptions)
+	return c
 }
 
-// Start begins the election process and TCP server
-func (c *Coordinator) Start() {
-	log.Printf("Starting Bully election: MY_ID=%d, TOTAL_REPLICAS=%d", c.myID, c.totalReplicas)
-	
-	// Start TCP server to receive election messages
-	go c.startServer()
-	
-	// Start election timeout monitor
-	go c.monitorElectionTimeout()
-	
-	// Wait a bit for all coordinators to start their servers
-	time.Sleep(2 * time.Second)
-	
-	// Start initial election
-	go c.startElection()
-}
+// NewCoordinatorWithOptions creates a new coordinator for Bully election
+// with custom options
+func NewCoordinatorWithOptions(myID, totalReplicas int, options BullyOptions) (*Coordinator, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if options.MissedHeartbeats == 0 {
+		options.MissedHeartbeats = 1
+	}
+	if options.Transport == nil {
+		options.Transport = NewTCPTransport(options.BindAddress, options.Port, options.Peers, options.MessageTimeout, options.Limits)
+	}
 
-// startServer starts TCP server to receive election messages
-func (c *Coordinator) startServer() {
-	listener, err := net.Listen("tcp", "0.0.0.0:"+electionPort)
-	if err != nil {
-		log.Fatalf("Failed to start election server: %v", err)
+	c := &Coordinator{
+		statsRecorder:      newStatsRecorder(),
+		leadershipNotifier: newLeadershipNotifier(),
+		options:            options,
+		myID:               myID,
+		totalReplicas:      totalReplicas,
+		state:              newBullyState(myID, totalReplicas, options, time.Now()),
+		events:             make(chan interface{}, 64),
+		outboxes:           make(map[int]chan string),
+		current:            view{leaderID: -1},
+		peerCaps:           make(map[int]Capabilities),
+		peerNodeIDs:        make(map[int]string),
 	}
-	defer listener.Close()
-	
-	log.Printf("Election server listening on port %s", electionPort)
-	
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			log.Printf("Error accepting connection: %v", err)
-			continue
+	for id := 1; id <= totalReplicas; id++ {
+		if id != myID {
+			c.outboxes[id] = make(chan string, outboxSize)
 		}
-		
-		go c.handleConnection(conn)
 	}
+	return c, nil
 }
 
-// handleConnection handles incoming election messages
-func (c *Coordinator) handleConnection(conn net.Conn) {
-	defer conn.Close()
-	
-	buffer := make([]byte, 1024)
-	n, err := conn.Read(buffer)
-	if err != nil {
-		if err != io.EOF {
-			log.Printf("Error reading message: %v", err)
-		}
-		return
+// Start begins the election process and TCP server
+func (c *Coordinator) Start() {
+	switch {
+	case c.options.AdvertiseAddress != "":
+		log.Printf("Starting Bully election: MY_ID=%d, NODE_ID=%s, TOTAL_REPLICAS=%d, advertising %s", c.myID,
+			valueOrNone(c.options.NodeID), c.totalReplicas, c.options.AdvertiseAddress)
+	case c.options.NodeID != "":
+		log.Printf("Starting Bully election: MY_ID=%d, NODE_ID=%s, TOTAL_REPLICAS=%d", c.myID, c.options.NodeID, c.totalReplicas)
+	default:
+		log.Printf("Starting Bully election: MY_ID=%d, TOTAL_REPLICAS=%d", c.myID, c.totalReplicas)
 	}
-	
-	message := string(buffer[:n])
-	
-	switch message {
-	case msgElection:
-		// Someone with lower ID is asking for election
-		log.Printf("Received ELECTION message, responding with OK")
-		conn.Write([]byte(msgOK))
-		
-		c.mu.RLock()
-		isLeader := c.isLeader
-		c.mu.RUnlock()
-		
-		// If I'm the leader, immediately send LEADER message to reaffirm authority
-		if isLeader {
-			log.Printf("I'm the leader, sending LEADER message to reaffirm")
-			// Send LEADER message to all nodes
-			go c.broadcastLeadership()
-		} else {
-			// Start our own election if we're not already leader
-			go c.startElection()
+
+	// Start the server receiving election messages
+	go supervise.Run("Election server", func() error {
+		return c.options.Transport.Listen(c.handleMessage)
+	})
+
+	for id, outbox := range c.outboxes {
+		go c.deliver(id, outbox)
+	}
+	go c.run()
+
+	// Start the initial election once enough coordinators are up
+	go func() {
+		c.awaitPeers()
+		c.events <- startEvent{}
+	}()
+}
+
+// run is the event loop: it hands every event to the state machine and
+// carries out the resulting actions
+func (c *Coordinator) run() {
+	ticker := time.NewTicker(c.options.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		var ev interface{}
+		select {
+		case ev = <-c.events:
+		case <-ticker.C:
+			ev = tickEvent{}
 		}
-		
-	case msgOK:
-		// Someone with higher ID responded, they will handle it
-		log.Printf("Received OK message, higher ID node will handle election")
-		
-	case msgLeader:
-		// New leader announcement (heartbeat)
-		log.Printf("Received LEADER heartbeat")
-		
-		// Reset heartbeat timer
-		c.heartbeatMu.Lock()
-		c.lastHeartbeat = time.Now()
-		c.heartbeatMu.Unlock()
-		
-		c.mu.Lock()
-		wasLeader := c.isLeader
-		// Update leader ID if we don't know who the leader is
-		if c.leaderID == -1 {
-			c.leaderID = c.myID + 1 // Assume it's from a higher ID
+
+		out := c.state.handle(time.Now(), ev)
+		switch ev := ev.(type) {
+		case messageEvent:
+			ev.reply <- out.reply
+		case stepDownEvent:
+			ev.done <- out.err
+		case takeOverEvent:
+			ev.done <- out.err
+		case clusterStateEvent:
+			ev.done <- out.err
 		}
-		c.isLeader = false
-		c.mu.Unlock()
-		
-		if wasLeader {
-			log.Printf("Lost leadership")
-			c.leaderChan <- false
+
+		for _, action := range out.actions {
+			c.apply(action)
 		}
-		
-	default:
-		log.Printf("Unknown message: %s", message)
+		c.publish(c.state.view())
 	}
 }
 
-// startElection initiates the Bully election algorithm
-func (c *Coordinator) startElection() {
-	log.Printf("Starting election process")
-	
-	// Send ELECTION to all nodes with higher IDs
-	receivedOK := false
-	
-	for id := c.myID + 1; id <= c.totalReplicas; id++ {
-		if c.sendMessage(id, msgElection) {
-			receivedOK = true
-		}
-	}
-	
-	if receivedOK {
-		// Higher ID node responded, they will handle leadership
-		log.Printf("Higher ID node responded, waiting for leader announcement")
-		// Don't do anything - the heartbeat monitor will detect if no leader emerges
-	} else {
-		// No higher ID responded, become leader
-		c.becomeLeader()
+// apply carries out an action of the state machine without blocking the
+// event loop
+func (c *Coordinator) apply(action interface{}) {
+	switch action := action.(type) {
+	case broadcastAction:
+		c.broadcast(action.message)
+	case electionAction:
+		c.electionStarted()
+		go func() {
+			c.events <- roundDoneEvent{round: action.round, gotOK: c.sendElections()}
+		}()
+	case electionEndedAction:
+		c.electionEnded(action.won)
+	case splitBrainAction:
+		c.splitBrain()
 	}
 }
 
-// becomeLeader makes this node the leader
-func (c *Coordinator) becomeLeader() {
+// publish makes a new view visible to IsLeader and GetLeaderID, records
+// it and notifies leadership changes
+func (c *Coordinator) publish(next view) {
 	c.mu.Lock()
-	wasLeader := c.isLeader
-	c.isLeader = true
-	c.leaderID = c.myID
+	previous := c.current
+	c.current = next
 	c.mu.Unlock()
-	
-	log.Printf("*** I AM THE LEADER (ID=%d) ***", c.myID)
-	
-	// Announce leadership to all other nodes
-	c.broadcastLeadership()
-	
-	// Start heartbeat loop
-	go c.sendHeartbeats()
-	
-	// Notify main loop if we just became leader
-	if !wasLeader {
-		c.leaderChan <- true
+
+	if next == previous {
+		return
 	}
+	c.transition(next.isLeader, next.leaderID, next.term)
+	c.notify(next.isLeader, next.leaderID, next.term, next.reason)
 }
 
-// broadcastLeadership sends LEADER message to all other nodes
-func (c *Coordinator) broadcastLeadership() {
-	for id := 1; id <= c.totalReplicas; id++ {
-		if id != c.myID {
-			c.sendMessage(id, msgLeader)
-		}
+// handleMessage hands a received message to the event loop and returns
+// its answer
+func (c *Coordinator) handleMessage(message string) string {
+	// The handshake is answered here: it's not part of the election
+	if strings.HasPrefix(message, msgHello+" ") {
+		return c.handleHello(message)
+	}
+	c.observePeer(message)
+
+	reply := make(chan string, 1)
+	select {
+	case c.events <- messageEvent{message: message, reply: reply}:
+	case <-time.After(c.options.MessageTimeout):
+		log.Printf("Election loop busy, dropping message: %s", message)
+		return ""
 	}
+	return <-reply
 }
 
-// sendHeartbeats periodically sends LEADER messages while this node is the leader
-func (c *Coordinator) sendHeartbeats() {
-	ticker := time.NewTicker(heartbeatInterval)
-	defer ticker.Stop()
-	
-	log.Printf("Starting heartbeat broadcasts (every %v)", heartbeatInterval)
-	
-	for {
+// broadcast queues a message for every other coordinator
+func (c *Coordinator) broadcast(message string) {
+	for id, outbox := range c.outboxes {
 		select {
-		case <-ticker.C:
-			c.mu.RLock()
-			isLeader := c.isLeader
-			c.mu.RUnlock()
-			
-			if !isLeader {
-				log.Printf("No longer leader, stopping heartbeats")
-				return
-			}
-			
-			// Send heartbeat to all followers
-			for id := 1; id <= c.totalReplicas; id++ {
-				if id != c.myID {
-					c.sendMessage(id, msgLeader)
-				}
-			}
-			
-		case <-c.stopHeartbeat:
-			log.Printf("Heartbeat stopped")
-			return
+		case outbox <- message:
+		default:
+			log.Printf("Outbox of coordinator %d is full, dropping %s", id, message)
 		}
 	}
 }
 
-// monitorElectionTimeout monitors if we haven't received heartbeats and starts election
-func (c *Coordinator) monitorElectionTimeout() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-	
-	for range ticker.C {
-		c.mu.RLock()
-		isLeader := c.isLeader
-		c.mu.RUnlock()
-		
-		// Only followers check for election timeout
-		if !isLeader {
-			c.heartbeatMu.RLock()
-			timeSinceLastHeartbeat := time.Since(c.lastHeartbeat)
-			c.heartbeatMu.RUnlock()
-			
-			if timeSinceLastHeartbeat > electionTimeout {
-				log.Printf("Election timeout: no heartbeat for %v, starting election", timeSinceLastHeartbeat)
-				
-				// Reset heartbeat timer to avoid multiple elections
-				c.heartbeatMu.Lock()
-				c.lastHeartbeat = time.Now()
-				c.heartbeatMu.Unlock()
-				
-				// Reset leader ID
-				c.mu.Lock()
-				c.leaderID = -1
-				c.mu.Unlock()
-				
-				go c.startElection()
+// deliver sends the messages queued for one peer, one at a time, so a dead
+// peer holds up only its own messages
+func (c *Coordinator) deliver(id int, outbox <-chan string) {
+	for message := range outbox {
+		c.sendMessage(id, message)
+	}
+}
+
+// sendElections sends ELECTION to every node that outranks this one at
+// once and reports whether any answered OK. It returns on the first OK, or
+// once every node failed or the fan-out timeout passed, so dead peers don't
+// add up.
+func (c *Coordinator) sendElections() bool {
+	higher := 0
+	results := make(chan bool, c.totalReplicas)
+	for id := 1; id <= c.totalReplicas; id++ {
+		if id == c.myID || !c.options.outranks(id, c.myID) {
+			continue
+		}
+		higher++
+		go func(id int) {
+			results <- c.sendMessage(id, msgElection)
+		}(id)
+	}
+	if higher == 0 {
+		return false
+	}
+
+	deadline := time.NewTimer(c.options.fanOutTimeout())
+	defer deadline.Stop()
+	for i := 0; i < higher; i++ {
+		select {
+		case ok := <-results:
+			if ok {
+				return true
 			}
+		case <-deadline.C:
+			log.Printf("No OK within %v", c.options.fanOutTimeout())
+			return false
 		}
 	}
+	return false
 }
 
-// sendMessage sends a message to a specific coordinator
+// sendMessage sends a message to a specific coordinator, in a form it
+// understands. ELECTION and PROMOTE succeed only if it answers OK.
 func (c *Coordinator) sendMessage(targetID int, message string) bool {
-	hostname := fmt.Sprintf("coordinator-%d", targetID)
-	address := net.JoinHostPort(hostname, electionPort)
-	
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	capabilities, err := c.peerCapabilities(targetID)
 	if err != nil {
 		// Node is down or unreachable
 		return false
 	}
-	defer conn.Close()
-	
-	_, err = conn.Write([]byte(message))
+
+	expectReply := message == msgElection || message == msgPromote
+	answer, err := c.options.Transport.Send(targetID, downgrade(message, capabilities), expectReply)
 	if err != nil {
+		// Node is down or unreachable; it may come back as another version
+		c.forgetPeer(targetID)
 		return false
 	}
-	
-	// For ELECTION messages, wait for OK response
-	if message == msgElection {
-		conn.SetReadDeadline(time.Now().Add(timeout))
-		buffer := make([]byte, 1024)
-		n, err := conn.Read(buffer)
-		if err != nil {
-			return false
-		}
-		
-		response := string(buffer[:n])
-		return response == msgOK
+	return !expectReply || answer == msgOK
+}
+
+// StepDown gives up leadership and stays out of elections for a while so
+// another coordinator takes over
+func (c *Coordinator) StepDown() error {
+	done := make(chan error, 1)
+	c.events <- stepDownEvent{done: done}
+	return <-done
+}
+
+// Promote implements Elector. Promoting this node makes it take over at
+// once; promoting another one asks it to over the election protocol. The
+// new leader takes a higher term, so the current one yields to it.
+func (c *Coordinator) Promote(id int) error {
+	if id < 1 || id > c.totalReplicas {
+		return fmt.Errorf("%w: %d", ErrUnknownCoordinator, id)
 	}
-	
-	return true
+	if id == c.myID {
+		done := make(chan error, 1)
+		c.events <- takeOverEvent{done: done}
+		return <-done
+	}
+	if !c.sendMessage(id, msgPromote) {
+		return fmt.Errorf("coordinator %d did not accept the promotion", id)
+	}
+	log.Printf("Asked coordinator %d to take over leadership", id)
+	return nil
 }
 
-// IsLeader returns whether this node is currently the leader
-func (c *Coordinator) IsLeader() bool {
+// SetClusterState implements ClusterStateCarrier. Only the leader's state
+// is sent.
+func (c *Coordinator) SetClusterState(state ClusterState) {
+	done := make(chan error, 1)
+	c.events <- clusterStateEvent{state: state, done: done}
+	if err := <-done; err != nil {
+		log.Printf("Not sending cluster state: %v", err)
+	}
+}
+
+// ClusterState implements ClusterStateCarrier
+func (c *Coordinator) ClusterState() (ClusterState, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.isLeader
+	return c.current.cluster, c.current.hasCluster
 }
 
-// LeaderChan returns the channel that signals leadership changes
-func (c *Coordinator) LeaderChan() <-chan bool {
-	return c.leaderChan
+// IsLeader returns whether this node is currently the leader
+func (c *Coordinator) IsLeader() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current.isLeader
 }
 
 // GetLeaderID returns the current leader ID
 func (c *Coordinator) GetLeaderID() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.leaderID
+	return c.current.leaderID
 }
-
-
@@ -0,0 +1,184 @@
+package election
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// memoryOptions are fast election options for coordinators on a
+// MemoryNetwork
+func memoryOptions(network *MemoryNetwork, id int) BullyOptions {
+	options := DefaultBullyOptions
+	options.Transport = network.Transport(id)
+	options.MessageTimeout = 50 * time.Millisecond
+	options.HeartbeatInterval = 50 * time.Millisecond
+	options.ElectionTimeout = 150 * time.Millisecond
+	options.Stickiness = 0
+	options.StartupTimeout = time.Second
+	return options
+}
+
+// startMemoryCluster starts replicas coordinators on a fresh MemoryNetwork
+func startMemoryCluster(t *testing.T, replicas int) (*MemoryNetwork, []*Coordinator) {
+	t.Helper()
+	quietLogs(t)
+	network := NewMemoryNetwork()
+	coordinators := make([]*Coordinator, 0, replicas)
+	for id := 1; id <= replicas; id++ {
+		coordinator, err := NewCoordinatorWithOptions(id, replicas, memoryOptions(network, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		coordinators = append(coordinators, coordinator)
+	}
+	for _, coordinator := range coordinators {
+		coordinator.Start()
+	}
+	return network, coordinators
+}
+
+// awaitAgreement waits up to timeout for every coordinator that's up to
+// follow the same leader, which is up and leads, and returns it
+func awaitAgreement(network *MemoryNetwork, coordinators []*Coordinator, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		leader, err := agreedLeader(network, coordinators)
+		if err == nil {
+			return leader, nil
+		}
+		if time.Now().After(deadline) {
+			return -1, err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// agreedLeader returns the leader every coordinator that's up follows
+func agreedLeader(network *MemoryNetwork, coordinators []*Coordinator) (int, error) {
+	network.mu.RLock()
+	defer network.mu.RUnlock()
+
+	leader := -1
+	for _, coordinator := range coordinators {
+		if network.down[coordinator.myID] {
+			continue
+		}
+		id := coordinator.GetLeaderID()
+		if leader == -1 {
+			leader = id
+		}
+		if id == -1 || id != leader {
+			return -1, fmt.Errorf("coordinator %d follows %d, another follows %d", coordinator.myID, id, leader)
+		}
+	}
+	if network.down[leader] {
+		return -1, fmt.Errorf("coordinators follow %d, which is down", leader)
+	}
+	for _, coordinator := range coordinators {
+		if !network.down[coordinator.myID] && coordinator.IsLeader() != (coordinator.myID == leader) {
+			return -1, fmt.Errorf("coordinator %d follows %d but leads: %v", coordinator.myID, leader, coordinator.IsLeader())
+		}
+	}
+	return leader, nil
+}
+
+func TestCoordinatorsElectTheHighestID(t *testing.T) {
+	network, coordinators := startMemoryCluster(t, 3)
+	leader, err := awaitAgreement(network, coordinators, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader != 3 {
+		t.Fatalf("leader is %d, want 3", leader)
+	}
+	if term := coordinators[2].Stats().Term; term%3 != 0 {
+		t.Errorf("coordinator 3 leads term %d, which isn't one of its own", term)
+	}
+}
+
+func TestCoordinatorsFailOver(t *testing.T) {
+	network, coordinators := startMemoryCluster(t, 3)
+	if _, err := awaitAgreement(network, coordinators, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	network.SetDown(3, true)
+	leader, err := awaitAgreement(network, coordinators, 5*time.Second)
+	if err != nil {
+		t.Fatalf("after the leader went down: %v", err)
+	}
+	if leader != 2 {
+		t.Fatalf("leader after 3 went down is %d, want 2", leader)
+	}
+
+	// Coordinator 3 kept leading on its own; back on the network, the
+	// higher term of 2 or its own rank settles it
+	network.SetDown(3, false)
+	if _, err := awaitAgreement(network, coordinators, 5*time.Second); err != nil {
+		t.Fatalf("after 3 came back: %v", err)
+	}
+}
+
+func TestCoordinatorStepDownAndPromote(t *testing.T) {
+	network, coordinators := startMemoryCluster(t, 3)
+	if _, err := awaitAgreement(network, coordinators, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := coordinators[2].StepDown(); err != nil {
+		t.Fatal(err)
+	}
+	leader, err := awaitAgreement(network, coordinators, 5*time.Second)
+	if err != nil {
+		t.Fatalf("after stepping down: %v", err)
+	}
+	if leader != 2 {
+		t.Fatalf("leader after 3 stepped down is %d, want 2", leader)
+	}
+
+	if err := coordinators[1].Promote(1); err != nil {
+		t.Fatal(err)
+	}
+	leader, err = awaitAgreement(network, coordinators, 5*time.Second)
+	if err != nil {
+		t.Fatalf("after promoting 1: %v", err)
+	}
+	if leader != 1 {
+		t.Fatalf("leader after promoting 1 is %d, want 1", leader)
+	}
+	if err := coordinators[0].Promote(4); err == nil {
+		t.Error("promoting an unknown coordinator succeeded")
+	}
+}
+
+// TestCoordinatorDoesNotLeakGoroutines floods a follower with ELECTION
+// messages, each of which used to start its own election goroutine, and
+// checks that once the elections are over the goroutines are gone
+func TestCoordinatorDoesNotLeakGoroutines(t *testing.T) {
+	network, coordinators := startMemoryCluster(t, 3)
+	if _, err := awaitAgreement(network, coordinators, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	count := func() int {
+		time.Sleep(4 * coordinators[0].options.fanOutTimeout())
+		runtime.GC()
+		return runtime.NumGoroutine()
+	}
+	before := count()
+
+	for i := 0; i < 200; i++ {
+		if answer := coordinators[0].handleMessage(msgElection); answer != msgOK {
+			t.Fatalf("ELECTION answered %q, want OK", answer)
+		}
+	}
+	if _, err := awaitAgreement(network, coordinators, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Heartbeat deliveries come and go; a few goroutines either way is noise
+	if after := count(); after > before+5 {
+		t.Errorf("%d goroutines after 200 ELECTION messages, %d before", after, before)
+	}
+}
@@ -0,0 +1,488 @@
+package election
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bullyState is the Bully election as a state machine: every input (a
+// message, a tick, the end of an election round, an operator command) is
+// an event, and handling one only updates the state and returns what to do
+// next. It never blocks, starts goroutines or reads the clock, so all of a
+// Coordinator's transitions happen in order on a single goroutine and can
+// be replayed deterministically.
+type bullyState struct {
+	myID          int
+	totalReplicas int
+	options       BullyOptions
+
+	isLeader         bool
+	leaderID         int
+	reason           string // why the leader last changed (see Reason*)
+	leaderSince      time.Time
+	steppedDownUntil time.Time
+
+	// term is the highest leadership term seen; each new leader takes the
+	// next one of its own (see nextTerm) and sends it in its heartbeats so
+	// two leaders can tell which of them is stale
+	term uint64
+
+	// lastHeartbeat is when the last LEADER heartbeat arrived; missed counts
+	// the ticks in a row without one
+	lastHeartbeat  time.Time
+	heardSinceTick bool
+	missed         int
+
+	// listenUntil is when a node that just started gives up waiting for a
+	// leader's heartbeat and runs its first election; zero once it did
+	// either (see start)
+	listenUntil time.Time
+
+	// electing is set while an election round (ELECTION messages waiting
+	// for an OK) is in flight; round numbers them so late results of an
+	// older round are ignored
+	electing bool
+	round    uint64
+
+	// cluster is the state the leader's heartbeats carry: set by the
+	// supervisor on the leader, received on followers
+	cluster    ClusterState
+	hasCluster bool
+}
+
+func newBullyState(myID, totalReplicas int, options BullyOptions, now time.Time) *bullyState {
+	return &bullyState{
+		myID:          myID,
+		totalReplicas: totalReplicas,
+		options:       options,
+		leaderID:      -1,
+		lastHeartbeat: now,
+	}
+}
+
+// Events
+type (
+	// messageEvent is a message received from another coordinator
+	messageEvent struct {
+		message string
+		reply   chan<- string // answer to write back, "" for none
+	}
+	// tickEvent fires every heartbeat interval
+	tickEvent struct{}
+	// startEvent starts the node: its first election comes after it
+	// listens for a leader
+	startEvent struct{}
+	// roundDoneEvent ends an election round: whether any node answered OK
+	roundDoneEvent struct {
+		round uint64
+		gotOK bool
+	}
+	// stepDownEvent and takeOverEvent are operator commands
+	stepDownEvent struct {
+		done chan<- error
+	}
+	takeOverEvent struct {
+		done chan<- error
+	}
+	// clusterStateEvent sets the cluster state the heartbeats carry
+	clusterStateEvent struct {
+		state ClusterState
+		done  chan<- error
+	}
+)
+
+// Actions
+type (
+	// broadcastAction sends a message to every other coordinator
+	broadcastAction struct {
+		message string
+	}
+	// electionAction sends ELECTION to every node that outranks this one
+	// and reports a roundDoneEvent
+	electionAction struct {
+		round uint64
+	}
+	// electionEndedAction reports the outcome of an election round
+	electionEndedAction struct {
+		won bool
+	}
+	// splitBrainAction reports another coordinator claiming leadership
+	// while this one leads
+	splitBrainAction struct{}
+)
+
+// outcome is the result of handling an event
+type outcome struct {
+	reply   string // for a messageEvent
+	err     error  // for a command
+	actions []interface{}
+}
+
+// view is the part of the state other goroutines may read
+type view struct {
+	isLeader   bool
+	leaderID   int
+	reason     string
+	term       uint64
+	cluster    ClusterState
+	hasCluster bool
+}
+
+func (s *bullyState) view() view {
+	return view{isLeader: s.isLeader, leaderID: s.leaderID, reason: s.reason, term: s.term,
+		cluster: s.cluster, hasCluster: s.hasCluster}
+}
+
+// handle applies an event at the given time
+func (s *bullyState) handle(now time.Time, ev interface{}) outcome {
+	switch ev := ev.(type) {
+	case messageEvent:
+		return s.handleMessage(now, ev.message)
+	case tickEvent:
+		return s.handleTick(now)
+	case startEvent:
+		return s.start(now)
+	case roundDoneEvent:
+		return s.handleRoundDone(now, ev)
+	case stepDownEvent:
+		return s.stepDown(now)
+	case takeOverEvent:
+		return s.takeOver(now)
+	case clusterStateEvent:
+		return s.setClusterState(now, ev.state)
+	default:
+		panic(fmt.Sprintf("election: unknown event %T", ev))
+	}
+}
+
+// handleMessage handles a protocol message
+func (s *bullyState) handleMessage(now time.Time, message string) outcome {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		log.Printf("Received empty message")
+		return outcome{}
+	}
+
+	switch fields[0] {
+	case msgElection:
+		// While stepped down we behave as if absent so a lower ID can win
+		if s.steppedDown(now) {
+			log.Printf("Received ELECTION message while stepped down, ignoring")
+			return outcome{}
+		}
+
+		// Someone with lower rank is asking for election
+		log.Printf("Received ELECTION message, responding with OK")
+		if s.isLeader {
+			// Reaffirm authority instead of electing again
+			log.Printf("I'm the leader, sending LEADER message to reaffirm")
+			return outcome{reply: msgOK, actions: []interface{}{s.leaderBroadcast()}}
+		}
+		out := s.startElection(now)
+		out.reply = msgOK
+		return out
+
+	case msgOK:
+		// Someone with higher ID responded, they will handle it
+		log.Printf("Received OK message, higher ID node will handle election")
+		return outcome{}
+
+	case msgLeader:
+		// New leader announcement (heartbeat)
+		log.Printf("Received LEADER heartbeat")
+		return s.handleLeader(now, fields[1:])
+
+	case msgResign:
+		return s.handleResign(now, fields[1:])
+
+	case msgPromote:
+		// An operator chose this node as the leader
+		out := s.takeOver(now)
+		out.reply = msgOK
+		return out
+
+	default:
+		log.Printf("Unknown message: %s", message)
+		return outcome{}
+	}
+}
+
+// handleResign processes a RESIGN from a leader that stepped down, "RESIGN
+// <id>" (older coordinators send a bare "RESIGN"). Only the leader this node
+// follows can resign it; one from anybody else is stale and ignored.
+func (s *bullyState) handleResign(now time.Time, args []string) outcome {
+	senderID := -1
+	if len(args) > 0 {
+		id, err := strconv.Atoi(args[0])
+		if err != nil || len(args) > 1 {
+			log.Printf("Malformed RESIGN message: %v", args)
+			return outcome{}
+		}
+		senderID = id
+	}
+	switch {
+	case s.isLeader:
+		log.Printf("Received RESIGN while leading, ignoring")
+		return outcome{}
+	case senderID != -1 && senderID != s.leaderID:
+		log.Printf("Received RESIGN from coordinator %d, which isn't the leader (%d), ignoring", senderID, s.leaderID)
+		return outcome{}
+	}
+
+	// The leader stepped down: elect a new one now rather than after the
+	// election timeout
+	log.Printf("Leader resigned, starting election")
+	s.leaderID = -1
+	s.reason = ReasonSteppedDown
+	return s.startElection(now)
+}
+
+// handleLeader processes a LEADER heartbeat, "LEADER <id> <term>
+// [<cluster state>]" (older coordinators send a bare "LEADER"). A leader
+// only yields to a heartbeat with a higher term or, once its stickiness
+// window is over, from a coordinator that outranks it; otherwise it keeps
+// leading and reasserts itself so the other leader steps down instead.
+func (s *bullyState) handleLeader(now time.Time, args []string) outcome {
+	senderID, term := -1, uint64(0)
+	var cluster *ClusterState
+	if len(args) == 2 || len(args) == 3 {
+		id, idErr := strconv.Atoi(args[0])
+		t, termErr := strconv.ParseUint(args[1], 10, 64)
+		if idErr != nil || termErr != nil {
+			log.Printf("Malformed LEADER message: %v", args)
+			return outcome{}
+		}
+		senderID, term = id, t
+	}
+	if len(args) == 3 {
+		state, err := parseClusterState(args[2])
+		if err != nil {
+			log.Printf("WARNING: Ignoring the cluster state of a LEADER message: %v", err)
+		} else {
+			state.Term, state.LeaderID, state.UpdatedAt = term, senderID, now
+			cluster = &state
+		}
+	}
+
+	sticky := now.Sub(s.leaderSince) < s.options.Stickiness
+	if s.isLeader && senderID != -1 && term <= s.term && (!s.options.outranks(senderID, s.myID) || sticky) {
+		log.Printf("SPLIT BRAIN: coordinator %d also claims leadership (term %d, mine %d), reasserting",
+			senderID, term, s.term)
+		return outcome{actions: []interface{}{s.leaderBroadcast(), splitBrainAction{}}}
+	}
+	var out outcome
+	if s.isLeader {
+		log.Printf("SPLIT BRAIN: coordinator %d also claims leadership (term %d, mine %d), stepping down",
+			senderID, term, s.term)
+		log.Printf("Lost leadership")
+		out.actions = []interface{}{splitBrainAction{}}
+		s.reason = ReasonSuperseded
+	} else if senderID != s.leaderID {
+		s.reason = ReasonElected
+	}
+
+	s.term = max(s.term, term)
+	if senderID != -1 {
+		s.leaderID = senderID
+	} else if s.leaderID == -1 {
+		s.leaderID = s.myID + 1 // Assume it's from a higher ID (older coordinators don't send it)
+	}
+	s.isLeader = false
+	s.lastHeartbeat = now
+	s.heardSinceTick = true
+	if cluster != nil {
+		s.cluster, s.hasCluster = *cluster, true
+	}
+
+	// A node that just started takes over from a leader it outranks, now
+	// that it knows which term to beat
+	if !s.listenUntil.IsZero() {
+		s.listenUntil = time.Time{}
+		if senderID != -1 && s.options.outranks(s.myID, senderID) {
+			log.Printf("Coordinator %d leads term %d and I outrank it, starting election", senderID, term)
+			election := s.startElection(now)
+			out.actions = append(out.actions, election.actions...)
+		}
+	}
+	return out
+}
+
+// handleTick sends the leader's heartbeat, or on followers counts the
+// heartbeats missed in a row and starts an election once both enough were
+// missed and the election timeout elapsed
+func (s *bullyState) handleTick(now time.Time) outcome {
+	if !s.listenUntil.IsZero() {
+		if now.Before(s.listenUntil) {
+			return outcome{}
+		}
+		log.Printf("No leader heard since starting")
+		return s.startElection(now)
+	}
+	if s.isLeader {
+		s.missed = 0
+		return outcome{actions: []interface{}{s.leaderBroadcast()}}
+	}
+
+	if s.heardSinceTick {
+		s.heardSinceTick = false
+		s.missed = 0
+		return outcome{}
+	}
+	s.missed++
+
+	silence := now.Sub(s.lastHeartbeat)
+	if s.missed < s.options.MissedHeartbeats || silence <= s.options.ElectionTimeout || s.electing {
+		return outcome{}
+	}
+	log.Printf("Election timeout: missed %d heartbeats (none for %v), starting election", s.missed, silence)
+
+	// Restart the timeout window so one silence starts one election
+	s.lastHeartbeat = now
+	s.missed = 0
+	s.leaderID = -1
+	s.reason = ReasonLeaderLost
+	return s.startElection(now)
+}
+
+// start begins a node's part in the election. Its term starts over at
+// zero, so rather than electing itself right away with a term a running
+// leader may already have used, it first listens for a heartbeat for
+// listenWindow: hearing one teaches it the term, and it then takes over
+// only if it outranks that leader.
+func (s *bullyState) start(now time.Time) outcome {
+	if s.totalReplicas <= 1 {
+		return s.startElection(now)
+	}
+	s.listenUntil = now.Add(s.options.listenWindow())
+	log.Printf("Listening for a leader for %v before the first election", s.options.listenWindow())
+	return outcome{}
+}
+
+// startElection starts an election round unless one is already running
+func (s *bullyState) startElection(now time.Time) outcome {
+	s.listenUntil = time.Time{}
+	if s.steppedDown(now) {
+		log.Printf("Stepped down, not starting election")
+		return outcome{}
+	}
+	if s.electing {
+		return outcome{}
+	}
+
+	log.Printf("Starting election process")
+	s.electing = true
+	s.round++
+	return outcome{actions: []interface{}{electionAction{round: s.round}}}
+}
+
+// handleRoundDone becomes leader if no node that outranks this one answered
+func (s *bullyState) handleRoundDone(now time.Time, ev roundDoneEvent) outcome {
+	if !s.electing || ev.round != s.round {
+		return outcome{}
+	}
+	s.electing = false
+
+	ended := electionEndedAction{won: !ev.gotOK}
+	if ev.gotOK {
+		// The heartbeat timeout covers a higher node that never announces itself
+		log.Printf("Higher ID node responded, waiting for leader announcement")
+		return outcome{actions: []interface{}{ended}}
+	}
+	out := s.becomeLeader(now, ReasonElected)
+	out.actions = append([]interface{}{ended}, out.actions...)
+	return out
+}
+
+// becomeLeader makes this node the leader and announces it
+func (s *bullyState) becomeLeader(now time.Time, reason string) outcome {
+	if !s.isLeader {
+		s.term = s.nextTerm()
+		s.leaderSince = now
+		s.reason = reason
+		// The previous leader's state isn't this one's to pass on
+		s.hasCluster = false
+	}
+	s.isLeader = true
+	s.leaderID = s.myID
+	s.listenUntil = time.Time{}
+	// A round still in flight no longer matters
+	s.electing = false
+
+	log.Printf("*** I AM THE LEADER (ID=%d, term %d) ***", s.myID, s.term)
+	return outcome{actions: []interface{}{s.leaderBroadcast()}}
+}
+
+// nextTerm returns the term this node takes when it becomes leader: the
+// first one above every term it has seen that belongs to it. Terms are dealt
+// round-robin by ID (term mod replicas), so two coordinators never lead the
+// same term, even if one restarted and forgot the terms it saw, or a
+// partition or lost messages kept them from hearing each other.
+func (s *bullyState) nextTerm() uint64 {
+	replicas := uint64(s.totalReplicas)
+	next := s.term + 1
+	if replicas <= 1 {
+		return next
+	}
+	own := uint64(s.myID) % replicas
+	return next + (own+replicas-next%replicas)%replicas
+}
+
+// stepDown gives up leadership and stays out of elections for a while so
+// another coordinator takes over
+func (s *bullyState) stepDown(now time.Time) outcome {
+	if !s.isLeader {
+		return outcome{err: fmt.Errorf("coordinator %d is not the leader", s.myID)}
+	}
+	s.isLeader = false
+	s.leaderID = -1
+	s.reason = ReasonSteppedDown
+	s.steppedDownUntil = now.Add(s.options.stepDownDuration())
+
+	// Give the other coordinators a fresh timeout window before they notice
+	s.lastHeartbeat = now
+	s.missed = 0
+
+	log.Printf("Stepping down from leadership for %v", s.options.stepDownDuration())
+	// Tell the others so they elect a new leader right away
+	return outcome{actions: []interface{}{broadcastAction{message: fmt.Sprintf("%s %d", msgResign, s.myID)}}}
+}
+
+// takeOver makes this node the leader regardless of rank, lifting any
+// step-down
+func (s *bullyState) takeOver(now time.Time) outcome {
+	s.steppedDownUntil = time.Time{}
+	if s.isLeader {
+		return outcome{}
+	}
+	log.Printf("Promoted to leader by an operator")
+	return s.becomeLeader(now, ReasonPromoted)
+}
+
+// steppedDown reports whether this node is currently refusing leadership
+func (s *bullyState) steppedDown(now time.Time) bool {
+	return now.Before(s.steppedDownUntil)
+}
+
+// setClusterState sets the cluster state the heartbeats carry while this
+// node leads
+func (s *bullyState) setClusterState(now time.Time, state ClusterState) outcome {
+	if !s.isLeader {
+		return outcome{err: fmt.Errorf("coordinator %d is not the leader", s.myID)}
+	}
+	state.Term, state.LeaderID, state.UpdatedAt = s.term, s.myID, now
+	s.cluster, s.hasCluster = state, true
+	return outcome{}
+}
+
+// leaderBroadcast is the LEADER heartbeat carrying this node's ID and term,
+// and the cluster state once the supervisor set it
+func (s *bullyState) leaderBroadcast() broadcastAction {
+	message := fmt.Sprintf("%s %d %d", msgLeader, s.myID, s.term)
+	if s.hasCluster {
+		message += " " + s.cluster.encode()
+	}
+	return broadcastAction{message: message}
+}
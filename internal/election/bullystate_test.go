@@ -0,0 +1,264 @@
+package election
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestBullyStateTransitions checks single transitions of the state machine:
+// each case puts a coordinator in a state, hands it one event and checks
+// the answer, the actions and where it ends up
+func TestBullyStateTransitions(t *testing.T) {
+	quietLogs(t)
+	epoch := time.Unix(0, 0).UTC()
+	options := DefaultBullyOptions
+	options.Stickiness = 0
+
+	// States to start from; each returns the time the event arrives
+	follower := func(s *bullyState) time.Time {
+		s.leaderID, s.term, s.lastHeartbeat = 3, 3, epoch
+		return epoch.Add(time.Second)
+	}
+	leader := func(s *bullyState) time.Time {
+		s.isLeader, s.leaderID, s.term, s.leaderSince = true, s.myID, uint64(s.myID), epoch
+		return epoch.Add(time.Second)
+	}
+	leaderless := func(s *bullyState) time.Time {
+		// Silent for longer than the election timeout, with enough missed
+		// heartbeats
+		s.leaderID, s.term, s.lastHeartbeat = 3, 3, epoch
+		s.missed = options.MissedHeartbeats
+		return epoch.Add(options.ElectionTimeout + time.Second)
+	}
+	electing := func(s *bullyState) time.Time {
+		s.electing, s.round, s.term = true, 4, 3
+		return epoch.Add(time.Second)
+	}
+	steppedDown := func(s *bullyState) time.Time {
+		s.leaderID, s.steppedDownUntil = 3, epoch.Add(time.Minute)
+		return epoch.Add(time.Second)
+	}
+	listening := func(s *bullyState) time.Time {
+		s.listenUntil = epoch.Add(options.listenWindow())
+		return epoch
+	}
+
+	tests := []struct {
+		name  string
+		id    int
+		setup func(s *bullyState) time.Time
+		event interface{}
+
+		reply      string
+		actions    []interface{}
+		isLeader   bool
+		leaderID   int
+		term       uint64
+		inElection bool // electing is still set
+	}{
+		{
+			name: "ELECTION makes a follower answer OK and run its own election",
+			id:   2, setup: follower, event: messageEvent{message: "ELECTION"},
+			reply: msgOK, actions: []interface{}{electionAction{round: 1}},
+			leaderID: 3, term: 3, inElection: true,
+		},
+		{
+			name: "ELECTION makes the leader answer OK and reassert itself",
+			id:   3, setup: leader, event: messageEvent{message: "ELECTION"},
+			reply: msgOK, actions: []interface{}{broadcastAction{message: "LEADER 3 3"}},
+			isLeader: true, leaderID: 3, term: 3,
+		},
+		{
+			name: "a stepped-down node ignores ELECTION",
+			id:   3, setup: steppedDown, event: messageEvent{message: "ELECTION"},
+			leaderID: 3,
+		},
+		{
+			name: "a follower follows a new leader's heartbeat and term",
+			id:   1, setup: follower, event: messageEvent{message: "LEADER 2 5"},
+			leaderID: 2, term: 5,
+		},
+		{
+			name: "a leader yields to a higher term",
+			id:   2, setup: leader, event: messageEvent{message: "LEADER 1 4"},
+			actions:  []interface{}{splitBrainAction{}},
+			leaderID: 1, term: 4,
+		},
+		{
+			name: "a leader yields to a higher ID in the same term",
+			id:   2, setup: leader, event: messageEvent{message: "LEADER 3 2"},
+			actions:  []interface{}{splitBrainAction{}},
+			leaderID: 3, term: 2,
+		},
+		{
+			name: "a leader reasserts itself against a lower ID and term",
+			id:   3, setup: leader, event: messageEvent{message: "LEADER 1 1"},
+			actions:  []interface{}{broadcastAction{message: "LEADER 3 3"}, splitBrainAction{}},
+			isLeader: true, leaderID: 3, term: 3,
+		},
+		{
+			name: "a malformed heartbeat is ignored",
+			id:   1, setup: follower, event: messageEvent{message: "LEADER x 7"},
+			leaderID: 3, term: 3,
+		},
+		{
+			name: "RESIGN from its leader makes a follower elect a new leader",
+			id:   2, setup: follower, event: messageEvent{message: "RESIGN 3"},
+			actions:  []interface{}{electionAction{round: 1}},
+			leaderID: -1, term: 3, inElection: true,
+		},
+		{
+			name: "a follower ignores RESIGN from a coordinator that isn't its leader",
+			id:   2, setup: follower, event: messageEvent{message: "RESIGN 1"},
+			leaderID: 3, term: 3,
+		},
+		{
+			name: "a bare RESIGN from an older leader makes a follower elect a new leader",
+			id:   2, setup: follower, event: messageEvent{message: "RESIGN"},
+			actions:  []interface{}{electionAction{round: 1}},
+			leaderID: -1, term: 3, inElection: true,
+		},
+		{
+			name: "a malformed RESIGN is ignored",
+			id:   2, setup: follower, event: messageEvent{message: "RESIGN x"},
+			leaderID: 3, term: 3,
+		},
+		{
+			name: "the leader ignores RESIGN from a stale leader",
+			id:   3, setup: leader, event: messageEvent{message: "RESIGN 2"},
+			isLeader: true, leaderID: 3, term: 3,
+		},
+		{
+			name: "the leader ignores a bare RESIGN",
+			id:   3, setup: leader, event: messageEvent{message: "RESIGN"},
+			isLeader: true, leaderID: 3, term: 3,
+		},
+		{
+			name: "PROMOTE makes a follower lead with its next own term",
+			id:   1, setup: follower, event: messageEvent{message: "PROMOTE"},
+			reply: msgOK, actions: []interface{}{broadcastAction{message: "LEADER 1 4"}},
+			isLeader: true, leaderID: 1, term: 4,
+		},
+		{
+			name: "an unknown message is ignored",
+			id:   1, setup: follower, event: messageEvent{message: "HELLOX 1 2"},
+			leaderID: 3, term: 3,
+		},
+		{
+			name: "a round nobody outranking answered is won",
+			id:   2, setup: electing, event: roundDoneEvent{round: 4},
+			actions:  []interface{}{electionEndedAction{won: true}, broadcastAction{message: "LEADER 2 5"}},
+			isLeader: true, leaderID: 2, term: 5,
+		},
+		{
+			name: "a round a higher ID answered is lost",
+			id:   2, setup: electing, event: roundDoneEvent{round: 4, gotOK: true},
+			actions:  []interface{}{electionEndedAction{won: false}},
+			leaderID: -1, term: 3,
+		},
+		{
+			name: "the result of an older round is ignored",
+			id:   2, setup: electing, event: roundDoneEvent{round: 3},
+			leaderID: -1, term: 3, inElection: true,
+		},
+		{
+			name: "the leader sends a heartbeat every tick",
+			id:   3, setup: leader, event: tickEvent{},
+			actions:  []interface{}{broadcastAction{message: "LEADER 3 3"}},
+			isLeader: true, leaderID: 3, term: 3,
+		},
+		{
+			name: "a follower that heard its leader lately waits",
+			id:   1, setup: follower, event: tickEvent{},
+			leaderID: 3, term: 3,
+		},
+		{
+			name: "a follower that stopped hearing its leader starts an election",
+			id:   1, setup: leaderless, event: tickEvent{},
+			actions:  []interface{}{electionAction{round: 1}},
+			leaderID: -1, term: 3, inElection: true,
+		},
+		{
+			name: "a node that just started listens for a leader first",
+			id:   1, setup: func(s *bullyState) time.Time { return epoch }, event: startEvent{},
+			leaderID: -1,
+		},
+		{
+			name: "a node that heard no leader while listening starts an election",
+			id:   1, setup: func(s *bullyState) time.Time { listening(s); return epoch.Add(options.listenWindow()) },
+			event:   tickEvent{},
+			actions: []interface{}{electionAction{round: 1}}, leaderID: -1, inElection: true,
+		},
+		{
+			name: "a listening node that outranks the leader it hears takes over",
+			id:   3, setup: listening, event: messageEvent{message: "LEADER 2 5"},
+			actions:  []interface{}{electionAction{round: 1}},
+			leaderID: 2, term: 5, inElection: true,
+		},
+		{
+			name: "a listening node outranked by the leader it hears follows it",
+			id:   1, setup: listening, event: messageEvent{message: "LEADER 2 5"},
+			leaderID: 2, term: 5,
+		},
+		{
+			name: "stepping down resigns",
+			id:   3, setup: leader, event: stepDownEvent{},
+			actions:  []interface{}{broadcastAction{message: "RESIGN 3"}},
+			leaderID: -1, term: 3,
+		},
+		{
+			name: "an operator's take-over skips the election",
+			id:   1, setup: steppedDown, event: takeOverEvent{},
+			actions:  []interface{}{broadcastAction{message: "LEADER 1 1"}},
+			isLeader: true, leaderID: 1, term: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newBullyState(test.id, 3, options, epoch)
+			now := test.setup(s)
+			out := s.handle(now, test.event)
+
+			if out.reply != test.reply {
+				t.Errorf("reply = %q, want %q", out.reply, test.reply)
+			}
+			if !reflect.DeepEqual(out.actions, test.actions) && (len(out.actions) > 0 || len(test.actions) > 0) {
+				t.Errorf("actions = %+v, want %+v", out.actions, test.actions)
+			}
+			got := fmt.Sprintf("leader=%v leaderID=%d term=%d electing=%v", s.isLeader, s.leaderID, s.term, s.electing)
+			want := fmt.Sprintf("leader=%v leaderID=%d term=%d electing=%v", test.isLeader, test.leaderID, test.term, test.inElection)
+			if got != want {
+				t.Errorf("state %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestNextTerm checks that terms are dealt round-robin by ID, above every
+// term seen
+func TestNextTerm(t *testing.T) {
+	tests := []struct {
+		id, replicas int
+		seen, want   uint64
+	}{
+		{id: 1, replicas: 3, seen: 0, want: 1},
+		{id: 2, replicas: 3, seen: 0, want: 2},
+		{id: 3, replicas: 3, seen: 0, want: 3},
+		{id: 1, replicas: 3, seen: 1, want: 4},
+		{id: 2, replicas: 3, seen: 4, want: 5},
+		{id: 3, replicas: 3, seen: 4, want: 6},
+		{id: 2, replicas: 3, seen: 5, want: 8},
+		{id: 5, replicas: 5, seen: 7, want: 10},
+		{id: 1, replicas: 1, seen: 7, want: 8},
+	}
+	for _, test := range tests {
+		s := newBullyState(test.id, test.replicas, DefaultBullyOptions, time.Unix(0, 0))
+		s.term = test.seen
+		if got := s.nextTerm(); got != test.want {
+			t.Errorf("coordinator %d of %d after term %d: next term %d, want %d",
+				test.id, test.replicas, test.seen, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package election
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Capabilities are the protocol features a coordinator speaks. They're
+// exchanged in a HELLO handshake so coordinators of different versions can
+// run side by side during a rolling upgrade: each message goes out in the
+// richest form both ends understand.
+type Capabilities uint32
+
+const (
+	// CapTerms: LEADER carries the sender's ID and term
+	CapTerms Capabilities = 1 << iota
+	// CapClusterState: LEADER may carry the cluster state (see ClusterState)
+	CapClusterState
+	// CapBatching: several messages can share a connection
+	CapBatching
+	// CapResignSender: RESIGN carries the sender's ID
+	CapResignSender
+
+	// LocalCapabilities are the ones this version speaks
+	LocalCapabilities = CapTerms | CapClusterState | CapBatching | CapResignSender
+
+	// legacyCapabilities are assumed for coordinators from before the
+	// handshake: the plain protocol, a bare LEADER
+	legacyCapabilities Capabilities = 0
+)
+
+// msgHello is the handshake, "HELLO <id> <capabilities in hex> [<node
+// identity> [<advertised address>]]", answered with the receiver's own; "-"
+// stands for a field the sender has no value for. Fields after those are
+// ignored, so later versions can add some.
+const msgHello = "HELLO"
+
+// helloNone stands for a HELLO field without a value
+const helloNone = "-"
+
+var capabilityNames = []struct {
+	capability Capabilities
+	name       string
+}{
+	{CapTerms, "terms"},
+	{CapClusterState, "cluster_state"},
+	{CapBatching, "batching"},
+	{CapResignSender, "resign_sender"},
+}
+
+// String lists the capabilities by name, e.g. "terms,batching"
+func (c Capabilities) String() string {
+	names := []string{}
+	for _, named := range capabilityNames {
+		if c&named.capability != 0 {
+			names = append(names, named.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// hello is one side of the handshake
+type hello struct {
+	id           int
+	capabilities Capabilities
+	nodeID       string // "" if the peer has none
+	address      string // advertised address; "" if the peer has none
+}
+
+// helloMessage is this coordinator's side of the handshake
+func (c *Coordinator) helloMessage() string {
+	fields := []string{msgHello, strconv.Itoa(c.myID), strconv.FormatUint(uint64(LocalCapabilities), 16)}
+	switch {
+	case c.options.AdvertiseAddress != "":
+		fields = append(fields, valueOrNone(c.options.NodeID), c.options.AdvertiseAddress)
+	case c.options.NodeID != "":
+		fields = append(fields, c.options.NodeID)
+	}
+	return strings.Join(fields, " ")
+}
+
+// valueOrNone returns value, or helloNone if it's empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return helloNone
+	}
+	return value
+}
+
+// parseHello parses a handshake message. Capabilities this version doesn't
+// know are dropped, as it can't use them anyway.
+func parseHello(message string) (hello, error) {
+	fields := strings.Fields(message)
+	if len(fields) < 3 || fields[0] != msgHello {
+		return hello{}, fmt.Errorf("malformed HELLO message %q", message)
+	}
+	id, idErr := strconv.Atoi(fields[1])
+	capabilities, capsErr := strconv.ParseUint(fields[2], 16, 32)
+	if idErr != nil || capsErr != nil {
+		return hello{}, fmt.Errorf("malformed HELLO message %q", message)
+	}
+	parsed := hello{id: id, capabilities: Capabilities(capabilities) & LocalCapabilities}
+	if len(fields) > 3 && fields[3] != helloNone {
+		if !ValidNodeID(fields[3]) {
+			return hello{}, fmt.Errorf("malformed HELLO message %q", message)
+		}
+		parsed.nodeID = fields[3]
+	}
+	if len(fields) > 4 && fields[4] != helloNone {
+		if !ValidHost(fields[4]) {
+			return hello{}, fmt.Errorf("malformed HELLO message %q", message)
+		}
+		parsed.address = fields[4]
+	}
+	return parsed, nil
+}
+
+// downgrade rewrites a message for a peer with the given capabilities
+func downgrade(message string, capabilities Capabilities) string {
+	fields := strings.Fields(message)
+	if len(fields) > 0 && fields[0] == msgResign && capabilities&CapResignSender == 0 {
+		return msgResign
+	}
+	if len(fields) == 0 || fields[0] != msgLeader {
+		return message
+	}
+	switch {
+	case capabilities&CapTerms == 0:
+		return msgLeader
+	case capabilities&CapClusterState == 0 && len(fields) > 3:
+		return strings.Join(fields[:3], " ")
+	}
+	return message
+}
+
+// observedCapabilities returns what a message shows its sender speaks
+func observedCapabilities(message string) Capabilities {
+	fields := strings.Fields(message)
+	if len(fields) == 0 || fields[0] != msgLeader {
+		return 0
+	}
+	switch len(fields) {
+	case 3:
+		return CapTerms
+	case 4:
+		return CapTerms | CapClusterState
+	}
+	return 0
+}
+
+// peerCapabilities returns what a peer speaks, shaking hands with it the
+// first time. A peer that takes the connection but doesn't answer HELLO
+// predates the handshake.
+func (c *Coordinator) peerCapabilities(id int) (Capabilities, error) {
+	c.capsMu.Lock()
+	capabilities, known := c.peerCaps[id]
+	c.capsMu.Unlock()
+	if known {
+		return capabilities, nil
+	}
+
+	answer, err := c.options.Transport.Send(id, c.helloMessage(), true)
+	var opErr *net.OpError
+	switch {
+	case err == nil && answer != "":
+		peer, err := parseHello(answer)
+		if err != nil || peer.id != id {
+			log.Printf("WARNING: Coordinator %d answered HELLO with %q, assuming the plain protocol", id, answer)
+			peer.capabilities = legacyCapabilities
+		} else {
+			c.setPeerNodeID(id, peer.nodeID)
+			c.setPeerAddress(id, peer.address)
+		}
+		capabilities = peer.capabilities
+	case err != nil && errors.As(err, &opErr) && opErr.Op == "dial":
+		return 0, err
+	default:
+		capabilities = legacyCapabilities
+	}
+	c.setPeerCapabilities(id, capabilities)
+	return capabilities, nil
+}
+
+// setPeerCapabilities records what a peer speaks
+func (c *Coordinator) setPeerCapabilities(id int, capabilities Capabilities) {
+	c.capsMu.Lock()
+	previous, known := c.peerCaps[id]
+	c.peerCaps[id] = capabilities
+	c.capsMu.Unlock()
+
+	if !known || previous != capabilities {
+		log.Printf("Coordinator %d speaks protocol capabilities: %s", id, capabilities)
+	}
+}
+
+// observePeer widens a peer's known capabilities with what a message it
+// sent shows, so peers from before the handshake still get the richest
+// messages they understand
+func (c *Coordinator) observePeer(message string) {
+	observed := observedCapabilities(message)
+	if observed == 0 {
+		return
+	}
+	id, err := strconv.Atoi(strings.Fields(message)[1])
+	if err != nil || id == c.myID || id < 1 || id > c.totalReplicas {
+		return
+	}
+	c.capsMu.Lock()
+	capabilities := c.peerCaps[id]
+	c.capsMu.Unlock()
+	if capabilities|observed != capabilities {
+		c.setPeerCapabilities(id, capabilities|observed)
+	}
+}
+
+// forgetPeer drops what a peer speaks after a failed send: it may come
+// back as another version
+func (c *Coordinator) forgetPeer(id int) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	delete(c.peerCaps, id)
+}
+
+// handleHello answers a peer's handshake with this coordinator's own
+func (c *Coordinator) handleHello(message string) string {
+	peer, err := parseHello(message)
+	if err == nil && peer.id == c.myID && peer.nodeID != "" && peer.nodeID != c.options.NodeID {
+		log.Printf("WARNING: Node %s claims this coordinator's ID %d", peer.nodeID, c.myID)
+	}
+	if err != nil || peer.id == c.myID || peer.id < 1 || peer.id > c.totalReplicas {
+		log.Printf("Ignoring HELLO message: %q", message)
+		return ""
+	}
+	c.setPeerCapabilities(peer.id, peer.capabilities)
+	c.setPeerNodeID(peer.id, peer.nodeID)
+	c.setPeerAddress(peer.id, peer.address)
+	return c.helloMessage()
+}
+
+// PeerCapabilities returns what each peer is known to speak
+func (c *Coordinator) PeerCapabilities() map[int]Capabilities {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	peers := make(map[int]Capabilities, len(c.peerCaps))
+	for id, capabilities := range c.peerCaps {
+		peers[id] = capabilities
+	}
+	return peers
+}
+
+// setPeerAddress records the address a peer advertised, so it's dialed
+// there from then on. It's kept when sends fail: a peer that comes back
+// elsewhere announces its new address when it starts.
+func (c *Coordinator) setPeerAddress(id int, address string) {
+	if address == "" || c.options.Peers.Advertised == nil {
+		return
+	}
+	if c.options.Peers.Advertised.Set(id, address) {
+		log.Printf("Coordinator %d advertises address %s", id, address)
+	}
+}
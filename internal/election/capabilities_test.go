@@ -0,0 +1,25 @@
+package election
+
+import "testing"
+
+// TestDowngrade checks that each message goes out in the richest form a
+// peer understands
+func TestDowngrade(t *testing.T) {
+	tests := []struct {
+		message      string
+		capabilities Capabilities
+		want         string
+	}{
+		{"LEADER 3 6 t=1,h=1,q=0,qd=0", LocalCapabilities, "LEADER 3 6 t=1,h=1,q=0,qd=0"},
+		{"LEADER 3 6 t=1,h=1,q=0,qd=0", CapTerms, "LEADER 3 6"},
+		{"LEADER 3 6", legacyCapabilities, "LEADER"},
+		{"RESIGN 3", LocalCapabilities, "RESIGN 3"},
+		{"RESIGN 3", CapTerms | CapClusterState | CapBatching, "RESIGN"},
+		{"ELECTION", legacyCapabilities, "ELECTION"},
+	}
+	for _, test := range tests {
+		if got := downgrade(test.message, test.capabilities); got != test.want {
+			t.Errorf("downgrade(%q, %s) = %q, want %q", test.message, test.capabilities, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package election
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClusterState is a summary of the cluster the Bully leader piggybacks on
+// its LEADER heartbeats, so followers keep a warm copy of it without asking
+type ClusterState struct {
+	Term           uint64 `json:"term"`
+	LeaderID       int    `json:"leader_id"`
+	Targets        int    `json:"targets"`
+	HealthyTargets int    `json:"healthy_targets"`
+	Quarantined    int    `json:"quarantined"`
+
+	// QuarantineDigest identifies the set of quarantined targets, so a
+	// follower can tell whether its replicated copy matches the leader's
+	QuarantineDigest string `json:"quarantine_digest,omitempty"`
+
+	// UpdatedAt is when the leader set it, or when the follower received it
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ClusterStateCarrier is implemented by electors whose heartbeats carry a
+// ClusterState (the Bully Coordinator)
+type ClusterStateCarrier interface {
+	// SetClusterState sets what the leader's next heartbeats carry
+	SetClusterState(state ClusterState)
+	// ClusterState returns the last state set (on the leader) or received
+	// (on followers), if any
+	ClusterState() (ClusterState, bool)
+}
+
+// encode returns the state as the last field of a LEADER message, e.g.
+// "t=14,h=12,q=2,qd=5f3a09c1". Term and leader already travel in the
+// message.
+func (c ClusterState) encode() string {
+	return fmt.Sprintf("t=%d,h=%d,q=%d,qd=%s", c.Targets, c.HealthyTargets, c.Quarantined, c.QuarantineDigest)
+}
+
+// parseClusterState parses the last field of a LEADER message. Unknown keys
+// are skipped, so newer leaders can add some.
+func parseClusterState(field string) (ClusterState, error) {
+	var state ClusterState
+	for _, pair := range strings.Split(field, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return ClusterState{}, fmt.Errorf("malformed cluster state %q", field)
+		}
+		var err error
+		switch key {
+		case "t":
+			state.Targets, err = strconv.Atoi(value)
+		case "h":
+			state.HealthyTargets, err = strconv.Atoi(value)
+		case "q":
+			state.Quarantined, err = strconv.Atoi(value)
+		case "qd":
+			state.QuarantineDigest = value
+		}
+		if err != nil {
+			return ClusterState{}, fmt.Errorf("malformed cluster state %q", field)
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,315 @@
+package election
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/framing"
+)
+
+// The coordinator under test is the highest of three; the other two don't
+// exist, so it leads alone
+const (
+	wireReplicas = 3
+	wireID       = 3
+	wireNodeID   = "00000000-0000-4000-8000-000000000003"
+
+	wireTimeout = 200 * time.Millisecond
+
+	// closeBound is how long after the client's last byte the coordinator
+	// has to close the connection: a message waiting for the event loop,
+	// then the idle timeout, plus slack
+	closeBound = 3*wireTimeout + 500*time.Millisecond
+
+	// recoverBound is how long the coordinator has to lead again after the
+	// inputs, which may have made it follow a made-up leader
+	recoverBound = 30 * wireTimeout
+)
+
+// wireHello is the coordinator's answer to a handshake
+var wireHello = fmt.Sprintf("HELLO %d %x %s 127.0.0.1", wireID, uint32(LocalCapabilities), wireNodeID)
+
+// validAnswers are the only answers the protocol allows
+var validAnswers = map[string]bool{msgOK: true, wireHello: true}
+
+// wireInput is what a client sends on one connection
+type wireInput struct {
+	name    string
+	payload string
+	// closeWrite half-closes the connection after the payload; otherwise
+	// the client just goes quiet
+	closeWrite bool
+	// want are the exact answers expected, when they're known
+	want []string
+}
+
+var conformance = []wireInput{
+	{"ELECTION is answered OK", "ELECTION\n", false, []string{"OK"}},
+	{"OK gets no answer", "OK\n", false, []string{}},
+	{"heartbeat from a lower ID gets no answer", "LEADER 1 0\n", false, []string{}},
+	{"batched messages are answered in order", "ELECTION\nOK\nELECTION\n", false, []string{"OK", "OK"}},
+	{"CRLF terminators", "ELECTION\r\n", false, []string{"OK"}},
+	{"last message cut by EOF", "ELECTION", true, []string{"OK"}},
+	{"truncated message and silence", "ELECT", false, []string{}},
+	{"truncated message and EOF", "LEA", true, []string{}},
+	{"empty line", "\n", false, []string{}},
+	{"HELLO is answered with the capabilities", "HELLO 1 7\n", false, []string{wireHello}},
+	{"HELLO with unknown capabilities", "HELLO 2 ffffffff\n", false, []string{wireHello}},
+	{"HELLO then ELECTION", "HELLO 1 0\nELECTION\n", false, []string{wireHello, "OK"}},
+	{"HELLO with a node identity", "HELLO 1 7 00000000-0000-4000-8000-000000000001\n", false, []string{wireHello}},
+	{"HELLO with fields from later versions", "HELLO 2 7 00000000-0000-4000-8000-000000000002 x y\n", false, []string{wireHello}},
+	{"HELLO with a malformed node identity", "HELLO 1 7 not-a-uuid\n", false, []string{}},
+	{"HELLO with an advertised address", "HELLO 1 7 - coordinator-1.example\n", false, []string{wireHello}},
+	{"HELLO with an advertised address with port", "HELLO 1 7 - 10.0.0.1:12345\n", false, []string{}},
+	{"HELLO from itself gets no answer", "HELLO 3 7\n", false, []string{}},
+	{"HELLO from another node with its ID", "HELLO 3 7 00000000-0000-4000-8000-000000000004\n", false, []string{}},
+	{"malformed HELLO", "HELLO 1\n", false, []string{}},
+	{"HELLO with non-hex capabilities", "HELLO 1 xyz\n", false, []string{}},
+	{"unknown command", "HELLOX 1 2\n", false, []string{}},
+	{"LEADER without fields", "LEADER\n", false, []string{}},
+	{"non-numeric fields", "LEADER x y z\n", false, []string{}},
+	{"overflowing numbers", "LEADER 99999999999999999999 18446744073709551616\n", false, []string{}},
+	{"negative numbers", "LEADER -1 -5\n", false, []string{}},
+	{"malformed cluster state", "LEADER 1 0 t=,h=x,q,,qd=\n", false, []string{}},
+	{"over-long message", strings.Repeat("A", 1000) + "\n", false, []string{}},
+	{"binary garbage", "\x00\xff\xfe\x01\n", false, []string{}},
+	{"idle connection", "", false, []string{}},
+	{"immediate EOF", "", true, []string{}},
+}
+
+// TestConformance sends each conformance input, on its own connection, to a
+// real coordinator listening on localhost
+func TestConformance(t *testing.T) {
+	coordinator, address := wireCoordinator(t)
+	sendAll(t, address, conformance)
+	if err := awaitLeadership(coordinator, recoverBound); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestRandomInputs sends the random input of each seed. Whatever they did
+// to its state, the coordinator must still run its election: with nobody
+// else around, it leads again.
+func TestRandomInputs(t *testing.T) {
+	coordinator, address := wireCoordinator(t)
+	seeds := int64(300)
+	if testing.Short() {
+		seeds = 50
+	}
+
+	inputs := make([]wireInput, 0, seeds)
+	for seed := int64(1); seed <= seeds; seed++ {
+		inputs = append(inputs, randomInput(seed))
+	}
+	sendAll(t, address, inputs)
+	if err := awaitLeadership(coordinator, recoverBound); err != nil {
+		t.Errorf("after the random inputs: %v", err)
+	}
+}
+
+// sendAll checks every input, a few connections at once: most wait for the
+// coordinator to close an idle connection
+func sendAll(t *testing.T, address string, inputs []wireInput) {
+	var wg sync.WaitGroup
+	queue := make(chan wireInput)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for in := range queue {
+				if err := checkWire(address, in); err != nil {
+					t.Errorf("%s %q: %v", in.name, in.payload, err)
+				}
+			}
+		}()
+	}
+	for _, in := range inputs {
+		queue <- in
+	}
+	close(queue)
+	wg.Wait()
+}
+
+var (
+	wireOnce        sync.Once
+	wireCoord       *Coordinator
+	wireAddress     string
+	wireStartFailed error
+)
+
+// wireCoordinator returns the coordinator under test, shared by the tests
+// of this file, and the address it listens on. It's started on first use
+// and waited on until it leads.
+func wireCoordinator(t *testing.T) (*Coordinator, string) {
+	t.Helper()
+	quietLogs(t)
+	wireOnce.Do(func() {
+		port, err := freePort()
+		if err != nil {
+			wireStartFailed = err
+			return
+		}
+		options := DefaultBullyOptions
+		options.Port = port
+		options.BindAddress = "127.0.0.1"
+		options.MessageTimeout = wireTimeout
+		options.HeartbeatInterval = wireTimeout
+		options.ElectionTimeout = 3 * wireTimeout
+		options.Stickiness = 0
+		options.StartupTimeout = 0
+		options.NodeID = wireNodeID
+		options.AdvertiseAddress = "127.0.0.1"
+		options.Peers = Peers{
+			Template:  "electiontest-{id}.invalid",
+			Overrides: map[int]string{wireID: "127.0.0.1"},
+		}
+		wireCoord, wireStartFailed = NewCoordinatorWithOptions(wireID, wireReplicas, options)
+		if wireStartFailed != nil {
+			return
+		}
+		wireCoord.Start()
+		wireAddress = net.JoinHostPort("127.0.0.1", port)
+		wireStartFailed = awaitLeadership(wireCoord, recoverBound)
+	})
+	if wireStartFailed != nil {
+		t.Fatalf("starting the coordinator: %v", wireStartFailed)
+	}
+	return wireCoord, wireAddress
+}
+
+// freePort returns a localhost port nothing is listening on
+func freePort() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	return port, err
+}
+
+// awaitLeadership waits up to timeout for the coordinator to lead
+func awaitLeadership(coordinator *Coordinator, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for !coordinator.IsLeader() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not the leader after %v (leader %d)", timeout, coordinator.GetLeaderID())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
+
+// randomInput builds the input of a seed: a few messages made of protocol
+// words, numbers and junk, then mutated
+func randomInput(seed int64) wireInput {
+	r := rand.New(rand.NewSource(seed))
+	commands := []string{"ELECTION", "OK", "LEADER", "RESIGN", "PROMOTE", "HELLO", "LEADERX", ""}
+	fields := []func() string{
+		func() string { return strconv.Itoa(r.Intn(10) - 2) },
+		func() string { return strconv.FormatUint(r.Uint64(), 10) },
+		func() string { return "99999999999999999999999" },
+		func() string {
+			return fmt.Sprintf("t=%d,h=%d,q=%d,qd=%x", r.Intn(50), r.Intn(50), r.Intn(5), r.Uint32())
+		},
+		func() string { return randomBytes(r, r.Intn(8)) },
+	}
+
+	messages := make([]string, 1+r.Intn(4))
+	for i := range messages {
+		message := []string{commands[r.Intn(len(commands))]}
+		for n := r.Intn(5); n > 0; n-- {
+			message = append(message, fields[r.Intn(len(fields))]())
+		}
+		messages[i] = strings.Join(message, " ")
+	}
+	payload := []byte(strings.Join(messages, "\n") + "\n")
+
+	switch r.Intn(6) {
+	case 0: // flip some bytes
+		for n := 1 + r.Intn(3); n > 0; n-- {
+			payload[r.Intn(len(payload))] ^= byte(1 + r.Intn(255))
+		}
+	case 1: // truncate
+		payload = payload[:r.Intn(len(payload))]
+	case 2: // stray carriage returns and NULs
+		at := r.Intn(len(payload))
+		payload = append(payload[:at], append([]byte{"\r\x00"[r.Intn(2)]}, payload[at:]...)...)
+	case 3: // too long
+		payload = append([]byte(strings.Repeat("LEADER ", 50+r.Intn(100))), payload...)
+	}
+	return wireInput{name: fmt.Sprintf("seed %d", seed), payload: string(payload), closeWrite: r.Intn(2) == 0}
+}
+
+// randomBytes returns n random bytes
+func randomBytes(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+	r.Read(b)
+	return string(b)
+}
+
+// checkWire sends an input on its own connection and checks the answers
+// and that the coordinator closes the connection in time
+func checkWire(address string, in wireInput) error {
+	conn, err := net.DialTimeout("tcp", address, wireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, in.payload); err != nil && !isReset(err) {
+		return fmt.Errorf("failed to send: %w", err)
+	}
+	if in.closeWrite {
+		conn.(*net.TCPConn).CloseWrite()
+	}
+	answers, err := readAnswers(conn)
+	if err != nil {
+		return err
+	}
+	if in.want != nil && strings.Join(answers, "\n") != strings.Join(in.want, "\n") {
+		return fmt.Errorf("got answers %q, want %q", answers, in.want)
+	}
+	return nil
+}
+
+// readAnswers reads the answers on a connection until the coordinator
+// closes it, which it must do within closeBound, and checks that each is
+// one the protocol allows
+func readAnswers(conn net.Conn) ([]string, error) {
+	conn.SetReadDeadline(time.Now().Add(closeBound))
+	answers := []string{}
+	reader := framing.NewReader(conn, maxMessageLength)
+	for {
+		answer, err := reader.ReadMessage()
+		if err == io.EOF || isReset(err) {
+			return answers, nil
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return answers, fmt.Errorf("connection still open %v after the last byte (answers %q)", closeBound, answers)
+		}
+		if err != nil {
+			return answers, fmt.Errorf("unreadable answer: %w", err)
+		}
+		if !validAnswers[answer] {
+			return answers, fmt.Errorf("invalid answer %q", answer)
+		}
+		answers = append(answers, answer)
+	}
+}
+
+// isReset reports whether the coordinator reset the connection, as it does
+// when it closes one with input left unread
+func isReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,170 @@
+package election
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsulLock is a Lock on a Consul KV key, held through a session with a TTL
+type ConsulLock struct {
+	baseURL string
+	key     string
+	ttl     time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	session string
+}
+
+// NewConsulLock creates a lock on key in the Consul agent at address
+// (http://consul:8500)
+func NewConsulLock(address, key string, ttl time.Duration) *ConsulLock {
+	return &ConsulLock{
+		baseURL: strings.TrimSuffix(address, "/"),
+		key:     strings.TrimPrefix(key, "/"),
+		ttl:     ttl,
+		client:  &http.Client{},
+	}
+}
+
+// Acquire implements Lock
+func (l *ConsulLock) Acquire(ctx context.Context, holder string) (bool, error) {
+	session, err := l.ensureSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Consul API: PUT /v1/kv/{key}?acquire={session}; true if we hold it
+	var acquired bool
+	err = l.do(ctx, http.MethodPut, "/v1/kv/"+l.key+"?acquire="+session, []byte(holder), &acquired)
+	return acquired, err
+}
+
+// ensureSession creates the session, or renews it if it exists. A session
+// Consul no longer knows (expired or the agent restarted) is recreated.
+func (l *ConsulLock) ensureSession(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session != "" {
+		// Consul API: PUT /v1/session/renew/{id} (404 once expired)
+		err := l.do(ctx, http.MethodPut, "/v1/session/renew/"+l.session, nil, nil)
+		if err == nil {
+			return l.session, nil
+		}
+		if !isConsulNotFound(err) {
+			return "", err
+		}
+		l.session = ""
+	}
+
+	// Consul API: PUT /v1/session/create. Behavior "delete" frees the key
+	// as soon as the session expires.
+	body, err := json.Marshal(map[string]string{
+		"Name":      "coordinator-leader",
+		"TTL":       l.ttl.String(),
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	})
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		ID string `json:"ID"`
+	}
+	if err := l.do(ctx, http.MethodPut, "/v1/session/create", body, &created); err != nil {
+		return "", err
+	}
+	l.session = created.ID
+	return l.session, nil
+}
+
+// Holder implements Lock
+func (l *ConsulLock) Holder(ctx context.Context) (string, error) {
+	// Consul API: GET /v1/kv/{key} (404 if the key doesn't exist)
+	var entries []struct {
+		Value   string `json:"Value"`
+		Session string `json:"Session"`
+	}
+	err := l.do(ctx, http.MethodGet, "/v1/kv/"+l.key, nil, &entries)
+	if err != nil {
+		if isConsulNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(entries) == 0 || entries[0].Session == "" {
+		return "", nil
+	}
+
+	value, err := base64.StdEncoding.DecodeString(entries[0].Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode lock holder: %w", err)
+	}
+	return string(value), nil
+}
+
+// Release implements Lock
+func (l *ConsulLock) Release(ctx context.Context, holder string) error {
+	l.mu.Lock()
+	session := l.session
+	l.mu.Unlock()
+	if session == "" {
+		return nil
+	}
+
+	// Consul API: PUT /v1/kv/{key}?release={session}
+	return l.do(ctx, http.MethodPut, "/v1/kv/"+l.key+"?release="+session, nil, nil)
+}
+
+// do performs a Consul API request and decodes the JSON response into out
+func (l *ConsulLock) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, l.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create Consul request: %w", err)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Consul: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &consulError{status: resp.StatusCode, message: strings.TrimSpace(string(message))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// consulError is a non-200 response from Consul
+type consulError struct {
+	status  int
+	message string
+}
+
+func (e *consulError) Error() string {
+	return fmt.Sprintf("Consul returned status %d: %s", e.status, e.message)
+}
+
+// isConsulNotFound reports whether err is a 404 from Consul
+func isConsulNotFound(err error) bool {
+	var consulErr *consulError
+	return errors.As(err, &consulErr) && consulErr.status == http.StatusNotFound
+}
@@ -0,0 +1,32 @@
+package election
+
+import "errors"
+
+// Elector elects a leader among the coordinator replicas. Coordinator (Bully),
+// RaftElector and LockElector implement it.
+type Elector interface {
+	Start()
+	IsLeader() bool
+	// Subscribe returns a channel with the changes of leader seen by this
+	// coordinator, starting with the current leader if it's known. Every
+	// subscriber gets every change; one that falls behind loses the oldest.
+	Subscribe() <-chan LeadershipEvent
+	// Unsubscribe stops the events of a subscription and closes its channel
+	Unsubscribe(events <-chan LeadershipEvent)
+	GetLeaderID() int
+	StepDown() error
+	Promote(id int) error
+	Stats() Stats
+}
+
+// ErrUnknownCoordinator is returned when promoting a coordinator that
+// isn't part of the group
+var ErrUnknownCoordinator = errors.New("unknown coordinator")
+
+// Election backends selectable with ELECTION_BACKEND
+const (
+	BackendBully  = "bully"
+	BackendRaft   = "raft"
+	BackendConsul = "consul"
+	BackendRedis  = "redis"
+)
@@ -0,0 +1,142 @@
+package election
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestFailoverScenarios runs the failover scenarios of cmd/electionsim, each
+// with a few seeds on clusters of 3 and 5 coordinators. Whatever a scenario
+// expects of the leader, no term may ever have two.
+func TestFailoverScenarios(t *testing.T) {
+	quietLogs(t)
+	seeds := int64(50)
+	if testing.Short() {
+		seeds = 10
+	}
+
+	tests := []struct {
+		name string
+		// run scripts the faults and checks the outcome
+		run func(sim *Simulation, replicas int) error
+	}{
+		{"startup elects the highest ID", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			return agreedOn(sim, replicas)
+		}},
+		{"leader crash fails over to the next ID", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			sim.Crash(replicas)
+			sim.Run(settle)
+			return agreedOn(sim, replicas-1)
+		}},
+		{"restarted leader takes over again", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			sim.Crash(replicas)
+			sim.Run(settle)
+			sim.Restart(replicas)
+			sim.Run(2 * settle)
+			return agreedOn(sim, replicas)
+		}},
+		{"a crashed follower doesn't disturb the leader", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			sim.Crash(1)
+			sim.Run(settle)
+			sim.Restart(1)
+			sim.Run(settle)
+			return agreedOn(sim, replicas)
+		}},
+		{"step-down hands leadership over", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			if err := sim.StepDown(replicas); err != nil {
+				return err
+			}
+			sim.Run(5 * time.Second)
+			return agreedOn(sim, replicas-1)
+		}},
+		{"step-down is refused by a follower", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			if err := sim.StepDown(1); err == nil {
+				return fmt.Errorf("a follower stepped down")
+			}
+			sim.Run(settle)
+			return agreedOn(sim, replicas)
+		}},
+		{"promotion makes a lower ID lead", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			sim.Promote(1)
+			sim.Run(settle)
+			return agreedOn(sim, 1)
+		}},
+		{"lossy, slow network still converges", func(sim *Simulation, replicas int) error {
+			sim.SetDropRate(0.2)
+			sim.SetDelay(10*time.Millisecond, 500*time.Millisecond)
+			sim.Run(settle)
+			sim.Crash(replicas)
+			sim.Run(settle)
+			sim.SetDropRate(0)
+			sim.Run(settle)
+			// A lost OK can let a lower ID win with a higher term
+			return agreed(sim)
+		}},
+		{"no messages, no agreement", func(sim *Simulation, replicas int) error {
+			sim.SetDropRate(1)
+			sim.Run(settle)
+			if leader, ok := sim.Agreed(); ok {
+				return fmt.Errorf("agreed on %d without any message delivered", leader)
+			}
+			// Each node elects itself, each with a term of its own
+			if leaders := sim.Leaders(); len(leaders) != replicas {
+				return fmt.Errorf("leaders %v, want every coordinator", leaders)
+			}
+			sim.SetDropRate(0)
+			sim.Run(2 * settle)
+			return agreed(sim)
+		}},
+		{"an isolated leader is replaced and yields once back", func(sim *Simulation, replicas int) error {
+			sim.Run(settle)
+			sim.Partition([]int{replicas})
+			sim.Run(settle)
+			if leaders := sim.Leaders(); len(leaders) != 2 || leaders[0] != replicas-1 {
+				return fmt.Errorf("leaders while isolated %v, want %d and %d", leaders, replicas-1, replicas)
+			}
+			sim.Heal()
+			sim.Run(2 * settle)
+			return agreed(sim)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, replicas := range scenarioReplicas {
+				for seed := int64(1); seed <= seeds; seed++ {
+					sim := newTestSimulation(t, replicas, seed)
+					if err := test.run(sim, replicas); err != nil {
+						t.Errorf("%d replicas, seed %d: %v\n  %s", replicas, seed, err, sim)
+					}
+					expectNoViolations(t, sim, replicas, seed)
+				}
+			}
+		})
+	}
+}
+
+// agreedOn checks that the cluster agrees on the given leader
+func agreedOn(sim *Simulation, want int) error {
+	leader, ok := sim.Agreed()
+	if !ok {
+		return fmt.Errorf("no agreed leader (leaders %v)", sim.Leaders())
+	}
+	if leader != want {
+		return fmt.Errorf("leader is %d, want %d", leader, want)
+	}
+	return nil
+}
+
+// agreed checks that the cluster agrees on a single leader
+func agreed(sim *Simulation) error {
+	if _, ok := sim.Agreed(); !ok {
+		return fmt.Errorf("no agreed leader (leaders %v)", sim.Leaders())
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package election
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// FuzzHandleMessage feeds arbitrary bytes to the election protocol twice:
+// line by line to a bare state machine, which must only ever answer OK and
+// keep its invariants, and over a real connection to TCPTransport's
+// handleConnection in front of a running coordinator, which must answer
+// only what the protocol allows and close the connection once the client
+// is done. The seed corpus is the conformance table and the random inputs
+// of TestRandomInputs.
+func FuzzHandleMessage(f *testing.F) {
+	for _, in := range conformance {
+		f.Add(in.payload)
+	}
+	for seed := int64(1); seed <= 50; seed++ {
+		f.Add(randomInput(seed).payload)
+	}
+
+	f.Fuzz(func(t *testing.T, payload string) {
+		checkStateMachine(t, payload)
+		checkConnection(t, payload)
+	})
+}
+
+// checkStateMachine hands each line of payload to a fresh state machine,
+// with ticks in between
+func checkStateMachine(t *testing.T, payload string) {
+	quietLogs(t)
+	now := time.Unix(0, 0).UTC()
+	state := newBullyState(wireID, wireReplicas, DefaultBullyOptions, now)
+	state.handle(now, startEvent{})
+
+	for _, message := range strings.Split(payload, "\n") {
+		out := state.handle(now, messageEvent{message: strings.TrimSuffix(message, "\r")})
+		if out.reply != "" && out.reply != msgOK {
+			t.Fatalf("message %q answered %q", message, out.reply)
+		}
+		now = now.Add(DefaultBullyOptions.HeartbeatInterval)
+		state.handle(now, tickEvent{})
+
+		if state.isLeader && state.leaderID != state.myID {
+			t.Fatalf("after %q: leading, but the leader is %d", message, state.leaderID)
+		}
+		// A leader only ever holds a term of its own (see nextTerm)
+		if state.isLeader && state.term%wireReplicas != wireID%wireReplicas {
+			t.Fatalf("after %q: leading term %d, which isn't this coordinator's", message, state.term)
+		}
+	}
+}
+
+// checkConnection sends payload on a connection served by handleConnection
+// and half-closes it
+func checkConnection(t *testing.T, payload string) {
+	coordinator, _ := wireCoordinator(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	transport := NewTCPTransport("127.0.0.1", "0", Peers{}, wireTimeout, coordinator.options.Limits)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		transport.handleConnection(conn, coordinator.handleMessage)
+	}()
+
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), wireTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, payload); err != nil && !isReset(err) {
+		t.Fatalf("failed to send: %v", err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+
+	answers, err := readAnswers(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages := strings.Count(payload, "\n") + 1; len(answers) > messages {
+		t.Fatalf("%d answers to at most %d messages: %q", len(answers), messages, answers)
+	}
+}
@@ -0,0 +1,93 @@
+package election
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// nodeIDPattern is the form of a node identity: a UUID
+var nodeIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// NewNodeID returns a random node identity, a version 4 UUID
+func NewNodeID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ValidNodeID reports whether id is a node identity
+func ValidNodeID(id string) bool {
+	return nodeIDPattern.MatchString(id)
+}
+
+// LoadNodeID returns the node identity saved at path, creating and saving
+// one the first time. Kept on a volume, it survives the container being
+// recreated, unlike everything else about the replica.
+func LoadNodeID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.ToLower(strings.TrimSpace(string(data)))
+		if !ValidNodeID(id) {
+			return "", fmt.Errorf("invalid node identity %q in %s", id, path)
+		}
+		return id, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read node identity: %w", err)
+	}
+
+	id := NewNodeID()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to save node identity: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to save node identity: %w", err)
+	}
+	log.Printf("Created node identity %s in %s", id, path)
+	return id, nil
+}
+
+// setPeerNodeID records the identity a peer announced in its HELLO,
+// reporting when the replica behind an ID changes
+func (c *Coordinator) setPeerNodeID(id int, nodeID string) {
+	if nodeID == "" {
+		return
+	}
+	c.capsMu.Lock()
+	previous := c.peerNodeIDs[id]
+	c.peerNodeIDs[id] = nodeID
+	c.capsMu.Unlock()
+
+	switch previous {
+	case nodeID:
+	case "":
+		log.Printf("Coordinator %d is node %s", id, nodeID)
+	default:
+		log.Printf("Coordinator %d is now node %s (was node %s): the replica was recreated or another one took its ID",
+			id, nodeID, previous)
+	}
+}
+
+// NodeID returns this coordinator's node identity; "" if it has none
+func (c *Coordinator) NodeID() string {
+	return c.options.NodeID
+}
+
+// PeerNodeIDs returns the identity each peer announced
+func (c *Coordinator) PeerNodeIDs() map[int]string {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	peers := make(map[int]string, len(c.peerNodeIDs))
+	for id, nodeID := range c.peerNodeIDs {
+		peers[id] = nodeID
+	}
+	return peers
+}
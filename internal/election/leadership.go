@@ -0,0 +1,103 @@
+package election
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Why leadership changed, as seen by one coordinator
+const (
+	ReasonElected     = "elected"      // an election (or the lock changing hands) made a leader
+	ReasonPromoted    = "promoted"     // an operator made this coordinator the leader
+	ReasonSteppedDown = "stepped_down" // the leader gave leadership up
+	ReasonSuperseded  = "superseded"   // another leader with a higher term or rank took over
+	ReasonLeaderLost  = "leader_lost"  // the leader stopped sending heartbeats (or renewing its lease)
+)
+
+// leadershipBuffer is how many events wait for a slow subscriber before
+// the oldest are dropped
+const leadershipBuffer = 16
+
+// LeadershipEvent is a change of leader as seen by one coordinator: it
+// became or stopped being the leader, or learned of another one
+type LeadershipEvent struct {
+	IsLeader  bool      `json:"is_leader"`
+	LeaderID  int       `json:"leader_id"` // -1 while there's none
+	Term      uint64    `json:"term,omitempty"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// leadershipNotifier fans an elector's leadership events out to its
+// subscribers without ever blocking it: when a subscriber falls behind, its
+// oldest events are dropped, so the latest state always gets through.
+// Reports that don't change the leader are ignored. Electors embed it.
+type leadershipNotifier struct {
+	mu          sync.Mutex
+	last        LeadershipEvent // zero Timestamp until the first change
+	subscribers map[<-chan LeadershipEvent]chan LeadershipEvent
+}
+
+func newLeadershipNotifier() *leadershipNotifier {
+	return &leadershipNotifier{
+		last:        LeadershipEvent{LeaderID: -1},
+		subscribers: make(map[<-chan LeadershipEvent]chan LeadershipEvent),
+	}
+}
+
+// notify reports the current leader
+func (n *leadershipNotifier) notify(isLeader bool, leaderID int, term uint64, reason string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if isLeader == n.last.IsLeader && leaderID == n.last.LeaderID {
+		return
+	}
+	event := LeadershipEvent{IsLeader: isLeader, LeaderID: leaderID, Term: term, Reason: reason, Timestamp: time.Now()}
+	n.last = event
+	for _, events := range n.subscribers {
+		deliver(events, event)
+	}
+}
+
+// deliver sends an event to a subscriber, dropping its oldest event if its
+// buffer is full. The notifier's lock must be held, so it's the only sender.
+func deliver(events chan LeadershipEvent, event LeadershipEvent) {
+	for {
+		select {
+		case events <- event:
+			return
+		default:
+		}
+		select {
+		case dropped := <-events:
+			log.Printf("WARNING: Leadership events not consumed, dropping the one from %v", dropped.Timestamp.Format(time.RFC3339))
+		default:
+		}
+	}
+}
+
+// Subscribe implements Elector
+func (n *leadershipNotifier) Subscribe() <-chan LeadershipEvent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	events := make(chan LeadershipEvent, leadershipBuffer)
+	if !n.last.Timestamp.IsZero() {
+		events <- n.last
+	}
+	n.subscribers[events] = events
+	return events
+}
+
+// Unsubscribe implements Elector
+func (n *leadershipNotifier) Unsubscribe(events <-chan LeadershipEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if subscriber, ok := n.subscribers[events]; ok {
+		delete(n.subscribers, events)
+		close(subscriber)
+	}
+}
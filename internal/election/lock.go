@@ -0,0 +1,183 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Lock is a distributed lock with a lease, held in an external store
+type Lock interface {
+	// Acquire takes the lock for holder if it's free, or extends the lease
+	// if holder already has it. It reports whether holder has the lock.
+	Acquire(ctx context.Context, holder string) (bool, error)
+	// Holder returns the current holder, or "" if the lock is free
+	Holder(ctx context.Context) (string, error)
+	// Release frees the lock if holder has it
+	Release(ctx context.Context, holder string) error
+}
+
+// LockElector makes the coordinator holding an external lock the leader,
+// for deployments that already run Consul or Redis. The lease is renewed
+// every third of its TTL; a leader that can't renew it stops acting as
+// leader before the lease can expire and pass to someone else.
+type LockElector struct {
+	myID int
+	lock Lock
+	ttl  time.Duration
+
+	mu               sync.RWMutex
+	isLeader         bool
+	leaderID         int
+	steppedDownUntil time.Time
+
+	*statsRecorder
+	*leadershipNotifier
+}
+
+// NewLockElector creates an elector on top of lock. ttl must match the
+// lease the lock was configured with.
+func NewLockElector(myID int, lock Lock, ttl time.Duration) *LockElector {
+	return &LockElector{
+		myID:     myID,
+		lock:     lock,
+		ttl:      ttl,
+		leaderID: -1,
+
+		statsRecorder:      newStatsRecorder(),
+		leadershipNotifier: newLeadershipNotifier(),
+	}
+}
+
+// Start implements Elector
+func (e *LockElector) Start() {
+	log.Printf("Starting lock-based election: MY_ID=%d, lease %v", e.myID, e.ttl)
+	go func() {
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			e.tick()
+			<-ticker.C
+		}
+	}()
+}
+
+// tick acquires or renews the lock and refreshes the known leader
+func (e *LockElector) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+
+	holder := strconv.Itoa(e.myID)
+	held := false
+	if !e.isSteppedDown() {
+		var err error
+		held, err = e.lock.Acquire(ctx, holder)
+		if err != nil {
+			log.Printf("ERROR: Failed to acquire leadership lock: %v", err)
+		}
+	}
+
+	leaderID := -1
+	if held {
+		leaderID = e.myID
+	} else if current, err := e.lock.Holder(ctx); err != nil {
+		log.Printf("ERROR: Failed to read leadership lock: %v", err)
+	} else if id, err := strconv.Atoi(current); err == nil {
+		leaderID = id
+	}
+
+	e.mu.Lock()
+	previous := e.leaderID
+	e.leaderID = leaderID
+	e.mu.Unlock()
+	e.setLeader(held)
+
+	reason := ReasonElected
+	if leaderID == -1 {
+		reason = ReasonLeaderLost
+	}
+	e.notify(held, leaderID, 0, reason)
+
+	// There's no election as such: the lock changing hands counts as one,
+	// won if this node took it
+	if leaderID != previous && leaderID != -1 {
+		e.electionStarted()
+		e.electionEnded(leaderID == e.myID)
+	}
+	e.transition(held, leaderID, 0)
+}
+
+// setLeader records whether this node holds the lock, logging changes
+func (e *LockElector) setLeader(held bool) {
+	e.mu.Lock()
+	changed := e.isLeader != held
+	e.isLeader = held
+	e.mu.Unlock()
+
+	if changed {
+		if held {
+			log.Printf("*** Acquired leadership lock ***")
+		} else {
+			log.Printf("Leadership lock lost")
+		}
+	}
+}
+
+// isSteppedDown reports whether this node is currently refusing leadership
+func (e *LockElector) isSteppedDown() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return time.Now().Before(e.steppedDownUntil)
+}
+
+// IsLeader implements Elector
+func (e *LockElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isLeader
+}
+
+// GetLeaderID implements Elector
+func (e *LockElector) GetLeaderID() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leaderID
+}
+
+// Promote implements Elector. The lock goes to whoever grabs it first, so
+// leadership can't be handed to a specific coordinator.
+func (e *LockElector) Promote(id int) error {
+	if id == e.GetLeaderID() {
+		return nil
+	}
+	return fmt.Errorf("lock-based election can't promote a specific coordinator, step the leader down instead")
+}
+
+// StepDown implements Elector: it releases the lock and doesn't try to take
+// it again for a while so another coordinator does
+func (e *LockElector) StepDown() error {
+	if !e.IsLeader() {
+		return fmt.Errorf("coordinator %d is not the leader", e.myID)
+	}
+
+	e.mu.Lock()
+	e.steppedDownUntil = time.Now().Add(3 * e.ttl)
+	e.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+	if err := e.lock.Release(ctx, strconv.Itoa(e.myID)); err != nil {
+		log.Printf("WARNING: Failed to release leadership lock, it expires in %v: %v", e.ttl, err)
+	}
+
+	log.Printf("Stepping down from leadership for %v", 3*e.ttl)
+	e.mu.Lock()
+	e.leaderID = -1
+	e.mu.Unlock()
+	e.setLeader(false)
+	e.notify(false, -1, 0, ReasonSteppedDown)
+	return nil
+}
@@ -0,0 +1,116 @@
+package election
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultPeerHostTemplate names replicas like the compose file does
+const DefaultPeerHostTemplate = "coordinator-{id}"
+
+// Peers resolves coordinator IDs to the hostnames the other replicas reach
+// them at
+type Peers struct {
+	// Template is the hostname with "{id}" standing for the replica ID
+	Template string
+	// Overrides are hostnames for specific replicas that don't follow the
+	// template
+	Overrides map[int]string
+	// Resolve, when set, maps a replica hostname to the address to dial
+	// instead (e.g. its IP on a preferred network), or "" to dial the
+	// hostname itself
+	Resolve func(host string) string
+	// Advertised, when set, holds the addresses replicas announced in the
+	// election handshake, which are dialed before anything else
+	Advertised *AdvertisedAddresses
+}
+
+// DefaultPeers are the compose hostnames, coordinator-1..N
+var DefaultPeers = Peers{Template: DefaultPeerHostTemplate}
+
+// Host returns the hostname of a replica
+func (p Peers) Host(id int) string {
+	if host, ok := p.Overrides[id]; ok {
+		return host
+	}
+	template := p.Template
+	if template == "" {
+		template = DefaultPeerHostTemplate
+	}
+	return strings.ReplaceAll(template, "{id}", strconv.Itoa(id))
+}
+
+// Address returns the host to dial to reach a replica: the address it
+// advertised, else its hostname or what Resolve maps it to
+func (p Peers) Address(id int) string {
+	if address := p.Advertised.Get(id); address != "" {
+		return address
+	}
+	host := p.Host(id)
+	if p.Resolve != nil {
+		if address := p.Resolve(host); address != "" {
+			return address
+		}
+	}
+	return host
+}
+
+// hostnamePattern is the form of a DNS name
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// ValidHost reports whether host is an IP address or a hostname, without a
+// port
+func ValidHost(host string) bool {
+	return net.ParseIP(host) != nil || (len(host) <= 253 && hostnamePattern.MatchString(host))
+}
+
+// AdvertisedAddresses are the addresses replicas asked to be dialed at,
+// which may differ from their hostnames behind NAT or with several networks
+type AdvertisedAddresses struct {
+	mu        sync.RWMutex
+	addresses map[int]string
+}
+
+// NewAdvertisedAddresses creates an empty set of advertised addresses
+func NewAdvertisedAddresses() *AdvertisedAddresses {
+	return &AdvertisedAddresses{addresses: make(map[int]string)}
+}
+
+// Get returns the address a replica advertised; "" if none (or a is nil)
+func (a *AdvertisedAddresses) Get(id int) string {
+	if a == nil {
+		return ""
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.addresses[id]
+}
+
+// Set records the address a replica advertised and reports whether it
+// changed
+func (a *AdvertisedAddresses) Set(id int, address string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.addresses[id] == address {
+		return false
+	}
+	a.addresses[id] = address
+	return true
+}
+
+// All returns the advertised address of every replica that announced one
+func (a *AdvertisedAddresses) All() map[int]string {
+	addresses := make(map[int]string)
+	if a == nil {
+		return addresses
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for id, address := range a.addresses {
+		addresses[id] = address
+	}
+	return addresses
+}
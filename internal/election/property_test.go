@@ -0,0 +1,125 @@
+package election
+
+import (
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+)
+
+// settle is how long a simulated cluster gets to agree on a leader after a
+// fault
+const settle = 30 * time.Second
+
+// TestAtMostOneLeaderPerTerm scripts random crashes, restarts, partitions
+// and message loss, a different sequence per seed, and checks that no two
+// coordinators ever led the same term, at once or one after the other
+func TestAtMostOneLeaderPerTerm(t *testing.T) {
+	quietLogs(t)
+	seeds := int64(200)
+	if testing.Short() {
+		seeds = 20
+	}
+
+	properties := []struct {
+		name  string
+		fault func(sim *Simulation, replicas int, r *rand.Rand)
+	}{
+		{"crashes and restarts", func(sim *Simulation, replicas int, r *rand.Rand) {
+			crashOrRestart(sim, replicas, r)
+		}},
+		{"partitions", func(sim *Simulation, replicas int, r *rand.Rand) {
+			switch r.Intn(3) {
+			case 0:
+				crashOrRestart(sim, replicas, r)
+			case 1:
+				sim.Partition(randomSplit(replicas, r)...)
+			default:
+				sim.Heal()
+			}
+		}},
+		{"lossy network", func(sim *Simulation, replicas int, r *rand.Rand) {
+			sim.SetDropRate(r.Float64() * 0.3)
+			sim.SetDelay(time.Duration(r.Int63n(int64(50*time.Millisecond))), time.Duration(r.Int63n(int64(time.Second))))
+			crashOrRestart(sim, replicas, r)
+		}},
+	}
+	for _, property := range properties {
+		for _, replicas := range []int{3, 5} {
+			for seed := int64(1); seed <= seeds; seed++ {
+				sim := newTestSimulation(t, replicas, seed)
+				r := rand.New(rand.NewSource(seed))
+				sim.Run(settle)
+				for steps := 3 + r.Intn(6); steps > 0; steps-- {
+					property.fault(sim, replicas, r)
+					sim.Run(time.Duration(r.Int63n(int64(settle))))
+				}
+				sim.Heal()
+				sim.SetDropRate(0)
+				sim.Run(2 * settle)
+
+				if violations := sim.Violations(); len(violations) > 0 {
+					t.Errorf("%s, %d replicas, seed %d: %v\n  %s", property.name, replicas, seed, violations, sim)
+				}
+				if _, ok := sim.Agreed(); !ok {
+					t.Errorf("%s, %d replicas, seed %d: no agreed leader (leaders %v)\n  %s",
+						property.name, replicas, seed, sim.Leaders(), sim)
+				}
+			}
+		}
+	}
+}
+
+// newTestSimulation creates a simulation with the default options, without
+// stickiness so outranked leaders hand over right away
+func newTestSimulation(t *testing.T, replicas int, seed int64) *Simulation {
+	t.Helper()
+	options := DefaultBullyOptions
+	options.Stickiness = 0
+	sim, err := NewSimulation(replicas, options, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sim
+}
+
+// quietLogs discards the coordinators' logs for the rest of the test
+func quietLogs(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+// crashOrRestart crashes a random node that's up, keeping at least one up,
+// or restarts it if it's down
+func crashOrRestart(sim *Simulation, replicas int, r *rand.Rand) {
+	id := 1 + r.Intn(replicas)
+	up := sim.Up()
+	for _, upID := range up {
+		if upID == id {
+			if len(up) > 1 {
+				sim.Crash(id)
+			}
+			return
+		}
+	}
+	sim.Restart(id)
+}
+
+// randomSplit splits the nodes into two random non-empty groups
+func randomSplit(replicas int, r *rand.Rand) [][]int {
+	shuffled := ids(1, replicas)
+	r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	at := 1 + r.Intn(replicas-1)
+	return [][]int{shuffled[:at], shuffled[at:]}
+}
+
+// ids returns the IDs from first to last
+func ids(first, last int) []int {
+	group := []int{}
+	for id := first; id <= last; id++ {
+		group = append(group, id)
+	}
+	return group
+}
@@ -0,0 +1,249 @@
+package election
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+)
+
+const (
+	raftPort          = "12350"
+	raftTransportPool = 3
+	raftTimeout       = 10 * time.Second
+
+	// raftSnapshotsRetained is how many snapshots are kept in the data
+	// directory; they're empty, since the log carries no commands
+	raftSnapshotsRetained = 2
+)
+
+// RaftElector elects the leader with an embedded Raft group. Only
+// leadership is used: the replicated log carries no commands. Raft's
+// majority quorum rules out the split-brain windows of Bully, but only if
+// every replica remembers its term and vote across restarts, so the log and
+// stable stores live in a data directory. Without one they're kept in
+// memory: a restarted replica could then vote a second time in a term it
+// already voted in, and two leaders could be elected for it.
+type RaftElector struct {
+	myID  int
+	peers Peers
+	raft  *raft.Raft
+	*statsRecorder
+	*leadershipNotifier
+}
+
+// NewRaftElector creates the Raft node for this replica, listening on
+// bindAddress (empty means every interface) and advertising
+// advertiseAddress (empty means its hostname). Its state is kept in dataDir,
+// or in memory if it's empty (see RaftElector). Every replica bootstraps
+// the same static configuration (replicas 1..N, named by peers).
+func NewRaftElector(myID, totalReplicas int, peers Peers, bindAddress, advertiseAddress, dataDir string) (*RaftElector, error) {
+	leaderChan := make(chan bool, 10)
+	config := raft.DefaultConfig()
+	config.LocalID = raft.ServerID(strconv.Itoa(myID))
+	config.NotifyCh = leaderChan
+	config.Logger = hclog.New(&hclog.LoggerOptions{
+		Name:   "raft",
+		Level:  hclog.Warn,
+		Output: log.Writer(),
+	})
+
+	if advertiseAddress == "" {
+		advertiseAddress = peers.Address(myID)
+	}
+	advertise, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(advertiseAddress, raftPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve raft address: %w", err)
+	}
+	transport, err := raft.NewTCPTransport(net.JoinHostPort(bindAddress, raftPort), advertise, raftTransportPool, raftTimeout, io.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start raft transport: %w", err)
+	}
+
+	logs, stable, snapshots, err := raftStores(dataDir)
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+	r, err := raft.NewRaft(config, noopFSM{}, logs, stable, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start raft: %w", err)
+	}
+
+	servers := make([]raft.Server, 0, totalReplicas)
+	for i := 1; i <= totalReplicas; i++ {
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(strconv.Itoa(i)),
+			Address: raft.ServerAddress(raftAddress(peers, i)),
+		})
+	}
+	if err := r.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil && err != raft.ErrCantBootstrap {
+		return nil, fmt.Errorf("failed to bootstrap raft: %w", err)
+	}
+
+	e := &RaftElector{myID: myID, peers: peers, raft: r,
+		statsRecorder: newStatsRecorder(), leadershipNotifier: newLeadershipNotifier()}
+	observations := make(chan raft.Observation, 16)
+	r.RegisterObserver(raft.NewObserver(observations, false, nil))
+	go e.observe(observations)
+	go e.watchLeadership(leaderChan)
+	return e, nil
+}
+
+// raftStores returns the log, stable and snapshot stores kept in dataDir,
+// or in memory if it's empty
+func raftStores(dataDir string) (raft.LogStore, raft.StableStore, raft.SnapshotStore, error) {
+	if dataDir == "" {
+		log.Printf("WARNING: No data directory for Raft, keeping its state in memory: a restarted replica may vote twice in a term")
+		store := raft.NewInmemStore()
+		return store, store, raft.NewInmemSnapshotStore(), nil
+	}
+
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create raft data directory: %w", err)
+	}
+	store, err := newBoltStore(dataDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	snapshots, err := raft.NewFileSnapshotStore(dataDir, raftSnapshotsRetained, io.Discard)
+	if err != nil {
+		store.Close()
+		return nil, nil, nil, fmt.Errorf("failed to open raft snapshot store: %w", err)
+	}
+	log.Printf("Keeping Raft state in %s", dataDir)
+	return store, store, snapshots, nil
+}
+
+// watchLeadership turns Raft's notifications of this node gaining or losing
+// leadership into leadership events
+func (e *RaftElector) watchLeadership(leaderChan <-chan bool) {
+	for isLeader := range leaderChan {
+		if isLeader {
+			e.notify(true, e.myID, e.term(), ReasonElected)
+		} else {
+			e.notifyLeader()
+		}
+	}
+}
+
+// notifyLeader reports the leader Raft currently knows of
+func (e *RaftElector) notifyLeader() {
+	leaderID := e.GetLeaderID()
+	reason := ReasonElected
+	if leaderID == -1 {
+		reason = ReasonLeaderLost
+	}
+	e.notify(e.IsLeader(), leaderID, e.term(), reason)
+}
+
+// observe feeds the election stats from Raft's state changes. A candidate
+// that becomes leader won its election; one that falls back to follower
+// lost it.
+func (e *RaftElector) observe(observations <-chan raft.Observation) {
+	candidate := false
+	for observation := range observations {
+		switch data := observation.Data.(type) {
+		case raft.RaftState:
+			switch {
+			case data == raft.Candidate:
+				if !candidate {
+					e.electionStarted()
+				}
+				candidate = true
+			case candidate:
+				e.electionEnded(data == raft.Leader)
+				candidate = false
+			}
+		case raft.LeaderObservation:
+			e.transition(e.IsLeader(), e.GetLeaderID(), e.term())
+			e.notifyLeader()
+		}
+	}
+}
+
+// term returns the current Raft term
+func (e *RaftElector) term() uint64 {
+	term, _ := strconv.ParseUint(e.raft.Stats()["term"], 10, 64)
+	return term
+}
+
+// raftAddress returns the Raft transport address of a replica
+func raftAddress(peers Peers, id int) string {
+	return net.JoinHostPort(peers.Address(id), raftPort)
+}
+
+// Start implements Elector. Raft runs from creation, so it only logs.
+func (e *RaftElector) Start() {
+	log.Printf("Starting Raft election: MY_ID=%d", e.myID)
+}
+
+// IsLeader implements Elector
+func (e *RaftElector) IsLeader() bool {
+	return e.raft.State() == raft.Leader
+}
+
+// GetLeaderID implements Elector. It returns -1 while there's no leader.
+func (e *RaftElector) GetLeaderID() int {
+	_, id := e.raft.LeaderWithID()
+	leaderID, err := strconv.Atoi(string(id))
+	if err != nil {
+		return -1
+	}
+	return leaderID
+}
+
+// StepDown implements Elector by transferring leadership to another replica
+func (e *RaftElector) StepDown() error {
+	if !e.IsLeader() {
+		return fmt.Errorf("not the leader")
+	}
+	log.Printf("Transferring Raft leadership")
+	return e.raft.LeadershipTransfer().Error()
+}
+
+// Promote implements Elector by transferring leadership to the given
+// replica. Only the leader can transfer it.
+func (e *RaftElector) Promote(id int) error {
+	configuration := e.raft.GetConfiguration()
+	if err := configuration.Error(); err != nil {
+		return err
+	}
+	serverID := raft.ServerID(strconv.Itoa(id))
+	known := false
+	for _, server := range configuration.Configuration().Servers {
+		if server.ID == serverID {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: %d", ErrUnknownCoordinator, id)
+	}
+
+	if !e.IsLeader() {
+		return fmt.Errorf("not the leader, send the promotion to coordinator %d", e.GetLeaderID())
+	}
+	if id == e.myID {
+		return nil
+	}
+	log.Printf("Transferring Raft leadership to coordinator %d", id)
+	return e.raft.LeadershipTransferToServer(serverID, raft.ServerAddress(raftAddress(e.peers, id))).Error()
+}
+
+// noopFSM is the state machine of a Raft group used only for leadership
+type noopFSM struct{}
+
+func (noopFSM) Apply(*raft.Log) interface{}          { return nil }
+func (noopFSM) Snapshot() (raft.FSMSnapshot, error)  { return noopSnapshot{}, nil }
+func (noopFSM) Restore(snapshot io.ReadCloser) error { return snapshot.Close() }
+
+type noopSnapshot struct{}
+
+func (noopSnapshot) Persist(sink raft.SnapshotSink) error { return sink.Close() }
+func (noopSnapshot) Release()                             {}
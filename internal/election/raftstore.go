@@ -0,0 +1,174 @@
+package election
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/raft"
+	bolt "go.etcd.io/bbolt"
+)
+
+// raftStoreFile is the file, under the data directory, holding the Raft log
+// and stable state
+const raftStoreFile = "raft.db"
+
+var (
+	logsBucket   = []byte("logs")
+	stableBucket = []byte("stable")
+)
+
+// errKeyNotFound is what Raft expects from a StableStore for a key never
+// set; it compares the message, not the value
+var errKeyNotFound = errors.New("not found")
+
+// boltStore is a raft.LogStore and raft.StableStore kept in a bbolt file, so
+// the current term, the vote and the log survive a restart: a replica that
+// forgot its vote could vote twice in the same term and let two leaders be
+// elected.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// newBoltStore opens (or creates) the store in dir
+func newBoltStore(dir string) (*boltStore, error) {
+	db, err := bolt.Open(filepath.Join(dir, raftStoreFile), 0600, &bolt.Options{Timeout: raftTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft store in %s: %w", dir, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(logsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(stableBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize raft store in %s: %w", dir, err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+// Close closes the underlying file
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// FirstIndex implements raft.LogStore
+func (s *boltStore) FirstIndex() (uint64, error) {
+	var index uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if key, _ := tx.Bucket(logsBucket).Cursor().First(); key != nil {
+			index = binary.BigEndian.Uint64(key)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// LastIndex implements raft.LogStore
+func (s *boltStore) LastIndex() (uint64, error) {
+	var index uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if key, _ := tx.Bucket(logsBucket).Cursor().Last(); key != nil {
+			index = binary.BigEndian.Uint64(key)
+		}
+		return nil
+	})
+	return index, err
+}
+
+// GetLog implements raft.LogStore
+func (s *boltStore) GetLog(index uint64, entry *raft.Log) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(logsBucket).Get(uint64Key(index))
+		if value == nil {
+			return raft.ErrLogNotFound
+		}
+		return json.Unmarshal(value, entry)
+	})
+}
+
+// StoreLog implements raft.LogStore
+func (s *boltStore) StoreLog(entry *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{entry})
+}
+
+// StoreLogs implements raft.LogStore
+func (s *boltStore) StoreLogs(entries []*raft.Log) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(logsBucket)
+		for _, entry := range entries {
+			value, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(uint64Key(entry.Index), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRange implements raft.LogStore
+func (s *boltStore) DeleteRange(min, max uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(logsBucket).Cursor()
+		for key, _ := cursor.Seek(uint64Key(min)); key != nil; key, _ = cursor.Next() {
+			if binary.BigEndian.Uint64(key) > max {
+				break
+			}
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Set implements raft.StableStore
+func (s *boltStore) Set(key, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stableBucket).Put(key, value)
+	})
+}
+
+// Get implements raft.StableStore
+func (s *boltStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		stored := tx.Bucket(stableBucket).Get(key)
+		if stored == nil {
+			return errKeyNotFound
+		}
+		// The stored slice is only valid during the transaction
+		value = append([]byte(nil), stored...)
+		return nil
+	})
+	return value, err
+}
+
+// SetUint64 implements raft.StableStore
+func (s *boltStore) SetUint64(key []byte, value uint64) error {
+	return s.Set(key, uint64Key(value))
+}
+
+// GetUint64 implements raft.StableStore
+func (s *boltStore) GetUint64(key []byte) (uint64, error) {
+	value, err := s.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// uint64Key encodes n so that keys sort in numeric order
+func uint64Key(n uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n)
+	return key
+}
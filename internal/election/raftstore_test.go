@@ -0,0 +1,82 @@
+package election
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestBoltStoreKeepsVoteAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newBoltStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetUint64([]byte("CurrentTerm")); err == nil || err.Error() != "not found" {
+		t.Fatalf("GetUint64 of a missing key = %v, want \"not found\"", err)
+	}
+	if err := store.SetUint64([]byte("CurrentTerm"), 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set([]byte("LastVoteCand"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = newBoltStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if term, err := store.GetUint64([]byte("CurrentTerm")); err != nil || term != 7 {
+		t.Errorf("CurrentTerm after reopening = %d, %v; want 7", term, err)
+	}
+	if vote, err := store.Get([]byte("LastVoteCand")); err != nil || string(vote) != "2" {
+		t.Errorf("LastVoteCand after reopening = %q, %v; want \"2\"", vote, err)
+	}
+}
+
+func TestBoltStoreLogs(t *testing.T) {
+	store, err := newBoltStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if first, _ := store.FirstIndex(); first != 0 {
+		t.Errorf("FirstIndex of an empty log = %d, want 0", first)
+	}
+	entries := []*raft.Log{}
+	for i := uint64(1); i <= 5; i++ {
+		entries = append(entries, &raft.Log{Index: i, Term: 1, Type: raft.LogNoop, Data: []byte{byte(i)}})
+	}
+	if err := store.StoreLogs(entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteRange(1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  func() (uint64, error)
+		want uint64
+	}{
+		{"first", store.FirstIndex, 3},
+		{"last", store.LastIndex, 5},
+	}
+	for _, test := range tests {
+		if got, err := test.got(); err != nil || got != test.want {
+			t.Errorf("%s index = %d, %v; want %d", test.name, got, err, test.want)
+		}
+	}
+
+	var entry raft.Log
+	if err := store.GetLog(4, &entry); err != nil || entry.Term != 1 || entry.Data[0] != 4 {
+		t.Errorf("GetLog(4) = %+v, %v", entry, err)
+	}
+	if err := store.GetLog(2, &entry); !errors.Is(err, raft.ErrLogNotFound) {
+		t.Errorf("GetLog of a deleted entry = %v, want %v", err, raft.ErrLogNotFound)
+	}
+}
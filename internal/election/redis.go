@@ -0,0 +1,150 @@
+package election
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+)
+
+const redisDialTimeout = 2 * time.Second
+
+// Lua scripts run atomically by Redis, so checking the holder and changing
+// the key can't interleave with another coordinator
+const (
+	redisAcquireScript = `
+local holder = redis.call('get', KEYS[1])
+if holder == ARGV[1] then
+	redis.call('pexpire', KEYS[1], ARGV[2])
+	return 1
+end
+if holder == false then
+	redis.call('set', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`
+
+	redisReleaseScript = `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0`
+)
+
+// RedisLock is a Lock on a Redis key that expires after its TTL. It speaks
+// just enough RESP for the few commands it needs.
+type RedisLock struct {
+	address  string
+	password string
+	key      string
+	ttl      time.Duration
+}
+
+// NewRedisLock creates a lock on key in the Redis server at rawURL
+// (redis://[:password@]host:6379)
+func NewRedisLock(rawURL, key string, ttl time.Duration) (*RedisLock, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "redis" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q (expected redis://host:port)", rawURL)
+	}
+
+	l := &RedisLock{address: parsed.Host, key: key, ttl: ttl}
+	if parsed.Port() == "" {
+		l.address = net.JoinHostPort(parsed.Hostname(), "6379")
+	}
+	if parsed.User != nil {
+		l.password, _ = parsed.User.Password()
+	}
+	return l, nil
+}
+
+// Acquire implements Lock
+func (l *RedisLock) Acquire(ctx context.Context, holder string) (bool, error) {
+	reply, err := l.command(ctx, "EVAL", redisAcquireScript, "1", l.key, holder, strconv.FormatInt(l.ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == "1", nil
+}
+
+// Holder implements Lock
+func (l *RedisLock) Holder(ctx context.Context) (string, error) {
+	return l.command(ctx, "GET", l.key)
+}
+
+// Release implements Lock
+func (l *RedisLock) Release(ctx context.Context, holder string) error {
+	_, err := l.command(ctx, "EVAL", redisReleaseScript, "1", l.key, holder)
+	return err
+}
+
+// command runs one command on a fresh connection, authenticating first if
+// a password is set. Nil replies are returned as "".
+func (l *RedisLock) command(ctx context.Context, args ...string) (string, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", l.address, redisDialTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to Redis at %s: %w", l.address, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+
+	if l.password != "" {
+		if _, err := redisRoundTrip(conn, reader, "AUTH", l.password); err != nil {
+			return "", err
+		}
+	}
+	return redisRoundTrip(conn, reader, args...)
+}
+
+// redisRoundTrip writes a command as a RESP array and reads its reply
+func redisRoundTrip(conn net.Conn, reader *bufio.Reader, args ...string) (string, error) {
+	var request strings.Builder
+	fmt.Fprintf(&request, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&request, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(request.String())); err != nil {
+		return "", fmt.Errorf("failed to send Redis %s: %w", args[0], err)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read Redis %s reply: %w", args[0], err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("empty Redis %s reply", args[0])
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("Redis %s failed: %s", args[0], line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("malformed Redis %s reply %q", args[0], line)
+		}
+		if size < 0 {
+			return "", nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return "", fmt.Errorf("failed to read Redis %s reply: %w", args[0], err)
+		}
+		return string(data[:size]), nil
+	}
+	return "", fmt.Errorf("unsupported Redis %s reply %q", args[0], line)
+}
@@ -0,0 +1,412 @@
+package election
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Simulation runs the Bully state machines of a whole cluster on a virtual
+// clock and network, in a single goroutine. Given the same seed and script
+// it always produces the same run, so failover scenarios (crashes,
+// partitions, lossy or slow links) can be replayed and checked in CI
+// without real time passing.
+type Simulation struct {
+	options BullyOptions
+	random  *rand.Rand
+	now     time.Time
+	nodes   []*simNode // by ID - 1
+	queue   simQueue
+	seq     uint64
+
+	// Network faults
+	dropRate           float64
+	minDelay, maxDelay time.Duration
+	partition          map[int]int // node -> group; nodes in different groups can't talk
+
+	// leadersByTerm records every node that led each term, to check that
+	// no term ever had two leaders
+	leadersByTerm map[uint64]map[int]bool
+	delivered     int
+	dropped       int
+}
+
+// simNode is one simulated coordinator
+type simNode struct {
+	id    int
+	state *bullyState
+	up    bool
+	// incarnation changes on every crash, so events scheduled for a
+	// previous life of the node are ignored
+	incarnation int
+	// rounds are the election rounds in flight: round -> pending answers
+	rounds map[uint64]int
+}
+
+// NewSimulation creates a simulated cluster of totalReplicas coordinators,
+// all up, that start their first election within the first heartbeat
+// interval. Options must be valid; the transport is ignored.
+func NewSimulation(totalReplicas int, options BullyOptions, seed int64) (*Simulation, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if options.MissedHeartbeats == 0 {
+		options.MissedHeartbeats = 1
+	}
+
+	s := &Simulation{
+		options:       options,
+		random:        rand.New(rand.NewSource(seed)),
+		now:           time.Unix(0, 0).UTC(),
+		partition:     make(map[int]int),
+		leadersByTerm: make(map[uint64]map[int]bool),
+	}
+	for id := 1; id <= totalReplicas; id++ {
+		s.nodes = append(s.nodes, &simNode{id: id})
+	}
+	// Every node must know the cluster size when it boots
+	for _, node := range s.nodes {
+		s.boot(node)
+	}
+	return s, nil
+}
+
+// boot starts a node with fresh state, as a restarted container would
+func (s *Simulation) boot(node *simNode) {
+	node.state = newBullyState(node.id, len(s.nodes), s.options, s.now)
+	node.up = true
+	node.incarnation++
+	node.rounds = make(map[uint64]int)
+
+	// Nodes don't tick in lockstep
+	phase := time.Duration(s.random.Int63n(int64(s.options.HeartbeatInterval)))
+	s.after(node, phase, func() { s.handle(node, startEvent{}) })
+	s.after(node, phase, func() { s.tick(node) })
+}
+
+// tick delivers a tick and schedules the next one
+func (s *Simulation) tick(node *simNode) {
+	s.handle(node, tickEvent{})
+	s.after(node, s.options.HeartbeatInterval, func() { s.tick(node) })
+}
+
+// Now returns the virtual time
+func (s *Simulation) Now() time.Time {
+	return s.now
+}
+
+// Run advances the virtual clock by d, processing everything due
+func (s *Simulation) Run(d time.Duration) {
+	end := s.now.Add(d)
+	for s.queue.Len() > 0 && !s.queue[0].at.After(end) {
+		item := heap.Pop(&s.queue).(*simItem)
+		s.now = item.at
+		if item.node == nil || (item.node.up && item.node.incarnation == item.incarnation) {
+			item.fn()
+		}
+	}
+	s.now = end
+}
+
+// Crash stops a node; it loses its state
+func (s *Simulation) Crash(id int) {
+	node := s.node(id)
+	node.up = false
+	node.incarnation++
+}
+
+// Restart brings a crashed node back with fresh state
+func (s *Simulation) Restart(id int) {
+	if node := s.node(id); !node.up {
+		s.boot(node)
+	}
+}
+
+// StepDown makes a node step down, as the admin API would
+func (s *Simulation) StepDown(id int) error {
+	return s.handle(s.node(id), stepDownEvent{}).err
+}
+
+// Promote makes a node take over, as the admin API would
+func (s *Simulation) Promote(id int) {
+	s.handle(s.node(id), takeOverEvent{})
+}
+
+// Partition splits the network into groups; nodes in different groups
+// can't reach each other. Nodes not listed form a group of their own.
+func (s *Simulation) Partition(groups ...[]int) {
+	s.partition = make(map[int]int)
+	for i, group := range groups {
+		for _, id := range group {
+			s.partition[id] = i + 1
+		}
+	}
+}
+
+// Heal removes every partition
+func (s *Simulation) Heal() {
+	s.partition = make(map[int]int)
+}
+
+// SetDropRate makes each message be lost with the given probability
+func (s *Simulation) SetDropRate(rate float64) {
+	s.dropRate = rate
+}
+
+// SetDelay makes each message take between min and max to arrive
+func (s *Simulation) SetDelay(min, max time.Duration) {
+	s.minDelay, s.maxDelay = min, max
+}
+
+// Up returns the IDs of the nodes that are up, in order
+func (s *Simulation) Up() []int {
+	up := []int{}
+	for _, node := range s.nodes {
+		if node.up {
+			up = append(up, node.id)
+		}
+	}
+	return up
+}
+
+// Leaders returns the up nodes that consider themselves the leader
+func (s *Simulation) Leaders() []int {
+	leaders := []int{}
+	for _, node := range s.nodes {
+		if node.up && node.state.isLeader {
+			leaders = append(leaders, node.id)
+		}
+	}
+	return leaders
+}
+
+// Agreed returns the leader if exactly one up node leads and every other up
+// node follows it
+func (s *Simulation) Agreed() (int, bool) {
+	leaders := s.Leaders()
+	if len(leaders) != 1 {
+		return -1, false
+	}
+	for _, node := range s.nodes {
+		if node.up && node.state.leaderID != leaders[0] {
+			return -1, false
+		}
+	}
+	return leaders[0], true
+}
+
+// Violations returns the terms that had more than one leader, which the
+// protocol must never allow
+func (s *Simulation) Violations() []string {
+	return termViolations(s.leadersByTerm)
+}
+
+// termViolations describes the terms with more than one leader
+func termViolations(leadersByTerm map[uint64]map[int]bool) []string {
+	terms := make([]uint64, 0, len(leadersByTerm))
+	for term, leaders := range leadersByTerm {
+		if len(leaders) > 1 {
+			terms = append(terms, term)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i] < terms[j] })
+
+	violations := []string{}
+	for _, term := range terms {
+		ids := []string{}
+		for id := range leadersByTerm[term] {
+			ids = append(ids, fmt.Sprint(id))
+		}
+		sort.Strings(ids)
+		violations = append(violations, fmt.Sprintf("term %d had leaders %s", term, strings.Join(ids, ", ")))
+	}
+	return violations
+}
+
+// String summarizes the cluster, e.g. for failed assertions
+func (s *Simulation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "t=%v delivered=%d dropped=%d", s.now.Sub(time.Unix(0, 0).UTC()), s.delivered, s.dropped)
+	for _, node := range s.nodes {
+		if !node.up {
+			fmt.Fprintf(&b, " [%d down]", node.id)
+			continue
+		}
+		fmt.Fprintf(&b, " [%d leader=%d term=%d]", node.id, node.state.leaderID, node.state.term)
+	}
+	return b.String()
+}
+
+// handle feeds an event to a node and carries out the actions
+func (s *Simulation) handle(node *simNode, ev interface{}) outcome {
+	out := node.state.handle(s.now, ev)
+	if node.state.isLeader {
+		if s.leadersByTerm[node.state.term] == nil {
+			s.leadersByTerm[node.state.term] = make(map[int]bool)
+		}
+		s.leadersByTerm[node.state.term][node.id] = true
+	}
+
+	for _, action := range out.actions {
+		switch action := action.(type) {
+		case broadcastAction:
+			for _, peer := range s.nodes {
+				if peer != node {
+					s.send(node, peer, action.message, nil)
+				}
+			}
+		case electionAction:
+			s.startRound(node, action.round)
+		}
+	}
+	return out
+}
+
+// startRound sends ELECTION to every node that outranks this one and ends
+// the round on the first OK, once all answered, or at the fan-out timeout
+func (s *Simulation) startRound(node *simNode, round uint64) {
+	pending := 0
+	for _, peer := range s.nodes {
+		if peer == node || !s.options.outranks(peer.id, node.id) {
+			continue
+		}
+		pending++
+		s.send(node, peer, msgElection, func(answer string, ok bool) {
+			if _, open := node.rounds[round]; !open {
+				return
+			}
+			node.rounds[round]--
+			if ok && answer == msgOK {
+				delete(node.rounds, round)
+				s.handle(node, roundDoneEvent{round: round, gotOK: true})
+			} else if node.rounds[round] == 0 {
+				delete(node.rounds, round)
+				s.handle(node, roundDoneEvent{round: round})
+			}
+		})
+	}
+	if pending == 0 {
+		s.handle(node, roundDoneEvent{round: round})
+		return
+	}
+
+	node.rounds[round] = pending
+	s.after(node, s.options.fanOutTimeout(), func() {
+		if _, open := node.rounds[round]; open {
+			delete(node.rounds, round)
+			s.handle(node, roundDoneEvent{round: round})
+		}
+	})
+}
+
+// send delivers a message after the network delay unless it's lost. If
+// answered is set, the sender gets the answer back (over the same faulty
+// network) or a failure once the message timeout passes.
+func (s *Simulation) send(from, to *simNode, message string, answered func(answer string, ok bool)) {
+	if answered != nil {
+		timedOut := false
+		replied := false
+		s.after(from, s.options.MessageTimeout, func() {
+			if !replied {
+				timedOut = true
+				answered("", false)
+			}
+		})
+		inner := answered
+		answered = func(answer string, ok bool) {
+			if !timedOut && !replied {
+				replied = true
+				inner(answer, ok)
+			}
+		}
+	}
+
+	if !s.reachable(from, to) {
+		s.dropped++
+		return
+	}
+	s.at(s.now.Add(s.delay()), func() {
+		if !to.up || !s.reachable(from, to) {
+			s.dropped++
+			return
+		}
+		s.delivered++
+		out := s.handle(to, messageEvent{message: message})
+		if answered == nil || out.reply == "" {
+			return
+		}
+		s.at(s.now.Add(s.delay()), func() {
+			if from.up && s.reachable(to, from) {
+				answered(out.reply, true)
+			}
+		})
+	})
+}
+
+// reachable reports whether a message from one node to another gets
+// through: both are up, on the same side of any partition, and it isn't
+// randomly dropped
+func (s *Simulation) reachable(from, to *simNode) bool {
+	if !from.up || !to.up || s.partition[from.id] != s.partition[to.id] {
+		return false
+	}
+	return s.dropRate <= 0 || s.random.Float64() >= s.dropRate
+}
+
+// delay draws a message delay
+func (s *Simulation) delay() time.Duration {
+	if s.maxDelay <= s.minDelay {
+		return s.minDelay
+	}
+	return s.minDelay + time.Duration(s.random.Int63n(int64(s.maxDelay-s.minDelay)))
+}
+
+func (s *Simulation) node(id int) *simNode {
+	if id < 1 || id > len(s.nodes) {
+		panic(fmt.Sprintf("election: simulation has no coordinator %d", id))
+	}
+	return s.nodes[id-1]
+}
+
+// after schedules fn for a node, skipped if the node crashes meanwhile
+func (s *Simulation) after(node *simNode, d time.Duration, fn func()) {
+	s.seq++
+	heap.Push(&s.queue, &simItem{at: s.now.Add(d), seq: s.seq, node: node, incarnation: node.incarnation, fn: fn})
+}
+
+// at schedules fn regardless of crashes
+func (s *Simulation) at(t time.Time, fn func()) {
+	s.seq++
+	heap.Push(&s.queue, &simItem{at: t, seq: s.seq, fn: fn})
+}
+
+// simItem is a scheduled event; seq keeps same-time events in order
+type simItem struct {
+	at          time.Time
+	seq         uint64
+	node        *simNode
+	incarnation int
+	fn          func()
+}
+
+// simQueue is a min-heap of scheduled events
+type simQueue []*simItem
+
+func (q simQueue) Len() int { return len(q) }
+func (q simQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q simQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *simQueue) Push(x interface{}) { *q = append(*q, x.(*simItem)) }
+func (q *simQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,139 @@
+package election
+
+import (
+	"testing"
+	"time"
+)
+
+// Each scenario runs on clusters of 3 and 5 coordinators with a few seeds;
+// a seed fixes every message delay and tick phase, so a failure replays
+// exactly
+var (
+	scenarioReplicas = []int{3, 5}
+	scenarioSeeds    = int64(50)
+)
+
+func TestSimulationLeaderCrash(t *testing.T) {
+	quietLogs(t)
+	for _, replicas := range scenarioReplicas {
+		for seed := int64(1); seed <= scenarioSeeds; seed++ {
+			sim := newTestSimulation(t, replicas, seed)
+			sim.Run(settle)
+			expectLeader(t, sim, replicas, seed, replicas)
+
+			sim.Crash(replicas)
+			sim.Run(settle)
+			expectLeader(t, sim, replicas, seed, replicas-1)
+
+			// The next one down takes over in turn
+			sim.Crash(replicas - 1)
+			sim.Run(settle)
+			expectLeader(t, sim, replicas, seed, replicas-2)
+
+			// A restarted leader hears the current one, outranks it and
+			// takes over
+			sim.Restart(replicas)
+			sim.Run(2 * settle)
+			expectLeader(t, sim, replicas, seed, replicas)
+			expectNoViolations(t, sim, replicas, seed)
+		}
+	}
+}
+
+func TestSimulationPartitionHeal(t *testing.T) {
+	quietLogs(t)
+	for _, replicas := range scenarioReplicas {
+		for seed := int64(1); seed <= scenarioSeeds; seed++ {
+			sim := newTestSimulation(t, replicas, seed)
+			sim.Run(settle)
+			expectLeader(t, sim, replicas, seed, replicas)
+
+			// The side without the leader elects its own highest ID; the
+			// leader keeps leading its side
+			sim.Partition(ids(1, replicas/2), ids(replicas/2+1, replicas))
+			sim.Run(settle)
+			leaders := sim.Leaders()
+			if len(leaders) != 2 || leaders[0] != replicas/2 || leaders[1] != replicas {
+				t.Errorf("%d replicas, seed %d: leaders during the partition %v, want [%d %d]\n  %s",
+					replicas, seed, leaders, replicas/2, replicas, sim)
+			}
+
+			// Once healed, one of them yields to the other's term or rank
+			sim.Heal()
+			sim.Run(2 * settle)
+			if _, ok := sim.Agreed(); !ok {
+				t.Errorf("%d replicas, seed %d: no agreed leader after healing (leaders %v)\n  %s",
+					replicas, seed, sim.Leaders(), sim)
+			}
+			expectNoViolations(t, sim, replicas, seed)
+		}
+	}
+}
+
+func TestSimulationSimultaneousStart(t *testing.T) {
+	quietLogs(t)
+	for _, replicas := range scenarioReplicas {
+		for seed := int64(1); seed <= scenarioSeeds; seed++ {
+			// Every node boots within one heartbeat interval of the others,
+			// inside each other's listen window, with no leader to hear
+			sim := newTestSimulation(t, replicas, seed)
+			sim.Run(settle)
+			expectLeader(t, sim, replicas, seed, replicas)
+
+			// The same after the whole cluster goes down and comes back
+			for id := 1; id <= replicas; id++ {
+				sim.Crash(id)
+			}
+			for id := 1; id <= replicas; id++ {
+				sim.Restart(id)
+			}
+			sim.Run(settle)
+			expectLeader(t, sim, replicas, seed, replicas)
+
+			// Lower IDs may start elections, but only the highest ever wins
+			for term, leaders := range sim.leadersByTerm {
+				for id := range leaders {
+					if id != replicas {
+						t.Errorf("%d replicas, seed %d: coordinator %d led term %d", replicas, seed, id, term)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	quietLogs(t)
+	run := func() string {
+		sim := newTestSimulation(t, 5, 42)
+		sim.SetDropRate(0.1)
+		sim.SetDelay(0, 200*time.Millisecond)
+		sim.Run(settle)
+		sim.Crash(5)
+		sim.Partition([]int{1, 2}, []int{3, 4})
+		sim.Run(settle)
+		sim.Heal()
+		sim.Restart(5)
+		sim.Run(settle)
+		return sim.String()
+	}
+	if first, second := run(), run(); first != second {
+		t.Errorf("the same seed ran differently:\n  %s\n  %s", first, second)
+	}
+}
+
+// expectLeader checks that the cluster agrees on the given leader
+func expectLeader(t *testing.T, sim *Simulation, replicas int, seed int64, want int) {
+	t.Helper()
+	if err := agreedOn(sim, want); err != nil {
+		t.Errorf("%d replicas, seed %d: %v\n  %s", replicas, seed, err, sim)
+	}
+}
+
+// expectNoViolations checks that no term had two leaders
+func expectNoViolations(t *testing.T, sim *Simulation, replicas int, seed int64) {
+	t.Helper()
+	if violations := sim.Violations(); len(violations) > 0 {
+		t.Errorf("%d replicas, seed %d: %v\n  %s", replicas, seed, violations, sim)
+	}
+}
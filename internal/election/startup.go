@@ -0,0 +1,61 @@
+package election
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	defaultStartupTimeout = 10 * time.Second
+
+	// Backoff between rounds of startup probes
+	startupProbeBackoff    = 100 * time.Millisecond
+	maxStartupProbeBackoff = 2 * time.Second
+)
+
+// Prober is implemented by transports that can tell whether a coordinator
+// is listening without sending it an election message
+type Prober interface {
+	Probe(id int) error
+}
+
+// awaitPeers waits until a majority of the coordinators, this one included,
+// are listening for election messages, so the first election doesn't run
+// while the others are still starting. Peers are probed with backoff until
+// StartupTimeout passes; after that the election starts anyway. Every peer
+// found is greeted with the handshake, so both sides learn each other's
+// capabilities, identity and advertised address before the election.
+func (c *Coordinator) awaitPeers() {
+	prober, ok := c.options.Transport.(Prober)
+	if !ok || c.options.StartupTimeout <= 0 || c.totalReplicas <= 1 {
+		return
+	}
+
+	started := time.Now()
+	deadline := started.Add(c.options.StartupTimeout)
+	quorum := c.totalReplicas/2 + 1
+	reachable := map[int]bool{c.myID: true}
+	backoff := startupProbeBackoff
+	for {
+		for id := 1; id <= c.totalReplicas; id++ {
+			if !reachable[id] && prober.Probe(id) == nil {
+				reachable[id] = true
+				c.peerCapabilities(id)
+			}
+		}
+		if len(reachable) >= quorum {
+			log.Printf("%d of %d coordinators reachable after %v, starting election", len(reachable), c.totalReplicas,
+				time.Since(started).Round(time.Millisecond))
+			return
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			log.Printf("WARNING: Only %d of %d coordinators reachable after %v, starting election anyway",
+				len(reachable), c.totalReplicas, c.options.StartupTimeout)
+			return
+		}
+		time.Sleep(min(backoff, remaining))
+		backoff = min(2*backoff, maxStartupProbeBackoff)
+	}
+}
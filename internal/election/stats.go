@@ -0,0 +1,120 @@
+package election
+
+import (
+	"sync"
+	"time"
+)
+
+// maxTransitions is how many leadership transitions are kept in Stats
+const maxTransitions = 50
+
+// Transition is a change of leader as seen by one coordinator
+type Transition struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsLeader  bool      `json:"is_leader"`
+	LeaderID  int       `json:"leader_id"`
+	Term      uint64    `json:"term,omitempty"`
+}
+
+// Stats are election counters for debugging failover behavior
+type Stats struct {
+	ElectionsStarted  int           `json:"elections_started"`
+	ElectionsWon      int           `json:"elections_won"`
+	ElectionsLost     int           `json:"elections_lost"`
+	LeadershipChanges int           `json:"leadership_changes"`
+	Term              uint64        `json:"term"`
+	IsLeader          bool          `json:"is_leader"`
+	LeaderID          int           `json:"leader_id"`
+	TimeAsLeader      time.Duration `json:"time_as_leader"`
+
+	// SplitBrains counts the times another coordinator claimed leadership
+	// while this one led
+	SplitBrains int `json:"split_brains"`
+
+	// Transitions are the most recent leader changes, oldest first
+	Transitions []Transition `json:"transitions"`
+}
+
+// statsRecorder keeps an elector's Stats. Electors embed it and report what
+// happens; it's safe for concurrent use.
+type statsRecorder struct {
+	statsMu     sync.Mutex
+	stats       Stats
+	leaderSince time.Time
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{stats: Stats{LeaderID: -1}}
+}
+
+// electionStarted counts an election this node started
+func (r *statsRecorder) electionStarted() {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	r.stats.ElectionsStarted++
+}
+
+// electionEnded counts the outcome of an election this node started
+func (r *statsRecorder) electionEnded(won bool) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	if won {
+		r.stats.ElectionsWon++
+	} else {
+		r.stats.ElectionsLost++
+	}
+}
+
+// splitBrain counts another coordinator claiming leadership while this one
+// leads
+func (r *statsRecorder) splitBrain() {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	r.stats.SplitBrains++
+}
+
+// transition records the current leader, ignoring reports that don't
+// change anything
+func (r *statsRecorder) transition(isLeader bool, leaderID int, term uint64) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	if term > r.stats.Term {
+		r.stats.Term = term
+	}
+	if isLeader == r.stats.IsLeader && leaderID == r.stats.LeaderID {
+		return
+	}
+
+	now := time.Now()
+	if r.stats.IsLeader && !isLeader {
+		r.stats.TimeAsLeader += now.Sub(r.leaderSince)
+	}
+	if isLeader && !r.stats.IsLeader {
+		r.leaderSince = now
+	}
+
+	// Losing track of the leader (-1) is recorded but isn't a new leader
+	if leaderID != -1 {
+		r.stats.LeadershipChanges++
+	}
+	r.stats.IsLeader = isLeader
+	r.stats.LeaderID = leaderID
+	r.stats.Transitions = append(r.stats.Transitions, Transition{Timestamp: now, IsLeader: isLeader, LeaderID: leaderID, Term: term})
+	if len(r.stats.Transitions) > maxTransitions {
+		r.stats.Transitions = r.stats.Transitions[len(r.stats.Transitions)-maxTransitions:]
+	}
+}
+
+// Stats implements Elector
+func (r *statsRecorder) Stats() Stats {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	stats := r.stats
+	stats.Transitions = append([]Transition{}, r.stats.Transitions...)
+	if stats.IsLeader {
+		stats.TimeAsLeader += time.Since(r.leaderSince)
+	}
+	return stats
+}
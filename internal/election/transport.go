@@ -0,0 +1,204 @@
+package election
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/connlimit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/framing"
+)
+
+// Transport carries Bully election messages between coordinators. The
+// default is TCPTransport; MemoryNetwork connects coordinators in the same
+// process, for tests and simulations.
+type Transport interface {
+	// Listen hands every message received to handle and sends back the
+	// answer it returns, unless it's empty. It blocks while serving.
+	Listen(handle func(message string) string) error
+	// Send sends a message to coordinator id and, if expectReply is set,
+	// waits for its answer
+	Send(id int, message string, expectReply bool) (string, error)
+}
+
+// TCPTransport sends each message over its own TCP connection, framed by
+// pkg/framing
+type TCPTransport struct {
+	bindAddress string
+	port        string
+	peers       Peers
+	timeout     time.Duration
+	limits      connlimit.Limits
+}
+
+// NewTCPTransport creates a transport listening on bindAddress:port and
+// dialing the same port on peers. timeout bounds each exchange, and limits
+// the connections accepted.
+func NewTCPTransport(bindAddress, port string, peers Peers, timeout time.Duration, limits connlimit.Limits) *TCPTransport {
+	return &TCPTransport{bindAddress: bindAddress, port: port, peers: peers, timeout: timeout, limits: limits}
+}
+
+// Listen implements Transport
+func (t *TCPTransport) Listen(handle func(message string) string) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(t.bindAddress, t.port))
+	if err != nil {
+		return fmt.Errorf("failed to start election server: %w", err)
+	}
+	listener = connlimit.Wrap(listener, "Election server", t.limits)
+	defer listener.Close()
+
+	log.Printf("Election server listening on port %s", t.port)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("Error accepting connection: %v", err)
+			continue
+		}
+
+		go t.handleConnection(conn, handle)
+	}
+}
+
+// handleConnection reads election messages, writing back each one's answer,
+// if any, until the peer closes the connection or leaves it idle for the
+// message timeout. Peers may batch several messages on one connection.
+func (t *TCPTransport) handleConnection(conn net.Conn, handle func(message string) string) {
+	defer conn.Close()
+
+	reader := framing.NewReader(conn, maxMessageLength)
+	for read := 0; ; read++ {
+		conn.SetDeadline(time.Now().Add(t.timeout))
+		message, err := reader.ReadMessage()
+		if err != nil {
+			// Once a message arrived, the peer going quiet is the usual end
+			var netErr net.Error
+			idle := read > 0 && errors.As(err, &netErr) && netErr.Timeout()
+			if err != io.EOF && !idle {
+				log.Printf("Error reading message: %v", err)
+			}
+			return
+		}
+
+		if answer := handle(message); answer != "" {
+			if err := framing.WriteMessage(conn, answer); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Probe implements Prober: it only connects to the peer's election port
+func (t *TCPTransport) Probe(id int) error {
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(t.peers.Address(id), t.port), t.timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Send implements Transport
+func (t *TCPTransport) Send(id int, message string, expectReply bool) (string, error) {
+	address := net.JoinHostPort(t.peers.Address(id), t.port)
+
+	conn, err := dialer.Dial("tcp", address, t.timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(t.timeout))
+	if err := framing.WriteMessage(conn, message); err != nil {
+		return "", err
+	}
+	if !expectReply {
+		return "", nil
+	}
+	return framing.NewReader(conn, maxMessageLength).ReadMessage()
+}
+
+// MemoryNetwork connects coordinators in the same process. Messages are
+// handed straight to the receiver's handler; coordinators can be taken down
+// to simulate crashes.
+type MemoryNetwork struct {
+	mu       sync.RWMutex
+	handlers map[int]func(message string) string
+	down     map[int]bool
+}
+
+// NewMemoryNetwork creates an empty in-memory network
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		handlers: make(map[int]func(message string) string),
+		down:     make(map[int]bool),
+	}
+}
+
+// Transport returns the transport of coordinator id on this network
+func (n *MemoryNetwork) Transport(id int) Transport {
+	return &memoryTransport{network: n, id: id}
+}
+
+// SetDown makes coordinator id unreachable (or reachable again); it
+// doesn't receive messages and its own messages are lost
+func (n *MemoryNetwork) SetDown(id int, down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.down[id] = down
+}
+
+// deliver hands a message from one coordinator to another
+func (n *MemoryNetwork) deliver(from, to int, message string) (string, error) {
+	handle, err := n.reach(from, to)
+	if err != nil {
+		return "", err
+	}
+	return handle(message), nil
+}
+
+// reach returns the handler of coordinator to, if from can reach it
+func (n *MemoryNetwork) reach(from, to int) (func(message string) string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	handle, listening := n.handlers[to]
+	if !listening || n.down[from] || n.down[to] {
+		return nil, fmt.Errorf("coordinator %d unreachable", to)
+	}
+	return handle, nil
+}
+
+// memoryTransport is one coordinator's end of a MemoryNetwork
+type memoryTransport struct {
+	network *MemoryNetwork
+	id      int
+}
+
+// Listen implements Transport. It registers the handler and blocks forever,
+// like a real server.
+func (t *memoryTransport) Listen(handle func(message string) string) error {
+	t.network.mu.Lock()
+	t.network.handlers[t.id] = handle
+	t.network.mu.Unlock()
+	select {}
+}
+
+// Probe implements Prober
+func (t *memoryTransport) Probe(id int) error {
+	_, err := t.network.reach(t.id, id)
+	return err
+}
+
+// Send implements Transport
+func (t *memoryTransport) Send(id int, message string, expectReply bool) (string, error) {
+	answer, err := t.network.deliver(t.id, id, message)
+	if err != nil || !expectReply {
+		return "", err
+	}
+	return answer, nil
+}
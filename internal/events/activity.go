@@ -0,0 +1,30 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// ActivityExchangeName is the fanout exchange workers report activity to
+// while they process messages. A worker that reports activity is known to
+// be alive, so the coordinators don't need to probe it.
+const ActivityExchangeName = "coordinator.activity"
+
+// ActivityMessage is a worker reporting it's processing messages. Worker is
+// its target or container name.
+type ActivityMessage struct {
+	Worker string `json:"worker"`
+}
+
+// ListenActivity consumes activity reports from RabbitMQ in the background,
+// calling handle for each one, like ListenControl
+func ListenActivity(url string, handle func(ActivityMessage)) {
+	listenFanout(url, ActivityExchangeName, "activity", func(body []byte) {
+		var message ActivityMessage
+		if err := json.Unmarshal(body, &message); err != nil || message.Worker == "" {
+			log.Printf("WARNING: Ignoring malformed activity message: %s", body)
+			return
+		}
+		handle(message)
+	})
+}
@@ -0,0 +1,227 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// ExchangeName is the topic exchange coordinator events are published to
+	ExchangeName   = "coordinator.events"
+	publishTimeout = 5 * time.Second
+
+	// queueSize is how many events wait to be published while RabbitMQ is
+	// slow or unreachable; newer events are dropped once it fills up
+	queueSize = 256
+
+	// Reconnection attempts back off exponentially between these bounds
+	initialReconnectBackoff = time.Second
+	maxReconnectBackoff     = 30 * time.Second
+
+	// closeTimeout bounds how long Close waits for the queued events
+	closeTimeout = 5 * time.Second
+)
+
+// AMQPPublisher publishes events to a RabbitMQ topic exchange.
+// The routing key of each message is the event type. Events are queued and
+// published from a background goroutine, which also reconnects when the
+// connection is lost, so a slow or unreachable broker never blocks the
+// caller.
+type AMQPPublisher struct {
+	url     string
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	mu      sync.Mutex // guards conn and channel
+
+	queue     chan Event
+	done      chan struct{} // closed by Close
+	stopped   chan struct{} // closed when the publishing goroutine returns
+	closeOnce sync.Once
+	dropped   atomic.Int64
+}
+
+// NewAMQPPublisher connects to RabbitMQ, declares the events exchange and
+// starts publishing in the background. If RabbitMQ can't be reached yet,
+// events are queued and the connection is retried like a lost one; only a
+// malformed URL is an error.
+func NewAMQPPublisher(url string) (*AMQPPublisher, error) {
+	if _, err := amqp.ParseURI(url); err != nil {
+		return nil, fmt.Errorf("invalid RabbitMQ URL: %w", err)
+	}
+	p := &AMQPPublisher{
+		url:     url,
+		queue:   make(chan Event, queueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := p.connect(); err != nil {
+		log.Printf("WARNING: %v, queueing events until it's reachable", err)
+	}
+
+	go p.run()
+	log.Printf("Publishing coordinator events to exchange %s", ExchangeName)
+	return p, nil
+}
+
+// connect (re)establishes the connection and channel
+func (p *AMQPPublisher) connect() error {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+
+	err = channel.ExchangeDeclare(ExchangeName, "topic", true, false, false, false, nil)
+	if err != nil {
+		channel.Close()
+		conn.Close()
+		return fmt.Errorf("failed to declare exchange %s: %w", ExchangeName, err)
+	}
+
+	p.mu.Lock()
+	p.conn = conn
+	p.channel = channel
+	p.mu.Unlock()
+	return nil
+}
+
+// Publish queues an event for publishing. It never blocks: if the queue is
+// full (the broker has been unreachable for a while) the event is dropped.
+func (p *AMQPPublisher) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	select {
+	case <-p.done:
+	case p.queue <- event:
+	default:
+		// Logged once per burst of drops, not per event
+		if dropped := p.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			log.Printf("WARNING: Event queue full, dropped %d event(s) so far (latest %s)", dropped, event.Type)
+		}
+	}
+}
+
+// Dropped returns how many events were dropped because the queue was full
+func (p *AMQPPublisher) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// run publishes the queued events until Close, reconnecting with backoff
+// whenever publishing fails. An event is retried until it's published, so
+// the order of events is kept.
+func (p *AMQPPublisher) run() {
+	defer close(p.stopped)
+
+	for {
+		select {
+		case <-p.done:
+			p.drain()
+			return
+		case event := <-p.queue:
+			backoff := initialReconnectBackoff
+			for {
+				err := p.publish(event)
+				if err == nil {
+					break
+				}
+				log.Printf("ERROR: Failed to publish event %s: %v (retrying in %v)", event.Type, err, backoff)
+				select {
+				case <-p.done:
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, maxReconnectBackoff)
+			}
+		}
+	}
+}
+
+// drain publishes the events still queued when Close is called, giving up
+// on the first failure
+func (p *AMQPPublisher) drain() {
+	for {
+		select {
+		case event := <-p.queue:
+			if err := p.publish(event); err != nil {
+				log.Printf("WARNING: Failed to publish event %s while closing: %v", event.Type, err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// publish sends an event to the exchange, reconnecting first if the
+// connection was lost. Events that can't be encoded are logged and
+// skipped.
+func (p *AMQPPublisher) publish(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to encode event %s: %v", event.Type, err)
+		return nil
+	}
+
+	p.mu.Lock()
+	disconnected := p.conn == nil || p.conn.IsClosed()
+	p.mu.Unlock()
+	if disconnected {
+		if err := p.connect(); err != nil {
+			return err
+		}
+		log.Printf("Reconnected to RabbitMQ, publishing events again")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err = p.channel.PublishWithContext(ctx, ExchangeName, event.Type, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    event.Timestamp,
+		Body:         body,
+	})
+	if err != nil {
+		// The channel may be closed while the connection isn't: start over
+		p.conn.Close()
+	}
+	return err
+}
+
+// Close stops publishing, after trying to publish the queued events for a
+// while, and closes the channel and connection
+func (p *AMQPPublisher) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	select {
+	case <-p.stopped:
+	case <-time.After(closeTimeout):
+		log.Printf("WARNING: Gave up publishing the queued events after %v", closeTimeout)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.channel != nil {
+		p.channel.Close()
+	}
+	if p.conn != nil {
+		return p.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"io"
+	"log"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAMQPPublisherStartsWithoutABroker(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	publisher, err := NewAMQPPublisher("amqp://guest:guest@" + address + "/")
+	if err != nil {
+		t.Fatalf("an unreachable broker failed the publisher: %v", err)
+	}
+	publisher.Publish(Event{Type: "node.down"})
+
+	closed := make(chan error)
+	go func() { closed <- publisher.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(2 * closeTimeout):
+		t.Fatal("Close hung on the unreachable broker")
+	}
+	if publisher.Dropped() != 0 {
+		t.Errorf("dropped %d events, want them queued", publisher.Dropped())
+	}
+}
+
+func TestAMQPPublisherRejectsAMalformedURL(t *testing.T) {
+	if _, err := NewAMQPPublisher("http://rabbitmq:5672"); err == nil {
+		t.Error("a URL that isn't amqp:// was accepted")
+	}
+}
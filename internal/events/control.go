@@ -0,0 +1,92 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// ControlExchangeName is the fanout exchange other services (e.g. the
+	// gateway) send control messages to. Every coordinator gets a copy.
+	ControlExchangeName = "coordinator.control"
+	controlRetryDelay   = 5 * time.Second
+)
+
+// Control message types
+const (
+	TypePipelineBusy = "pipeline.busy"
+	TypePipelineIdle = "pipeline.idle"
+)
+
+// ControlMessage is a message sent to the coordinators
+type ControlMessage struct {
+	Type string `json:"type"`
+}
+
+// ListenControl consumes control messages from RabbitMQ in the background,
+// calling handle for each one. Each coordinator binds its own exclusive
+// queue; the connection is re-established whenever it drops.
+func ListenControl(url string, handle func(ControlMessage)) {
+	listenFanout(url, ControlExchangeName, "control", func(body []byte) {
+		var message ControlMessage
+		if err := json.Unmarshal(body, &message); err != nil {
+			log.Printf("WARNING: Ignoring malformed control message: %v", err)
+			return
+		}
+		handle(message)
+	})
+}
+
+// listenFanout consumes a fanout exchange in the background through an
+// exclusive queue, reconnecting whenever the connection drops
+func listenFanout(url, exchange, what string, handle func(body []byte)) {
+	go func() {
+		for {
+			if err := consumeFanout(url, exchange, what, handle); err != nil {
+				log.Printf("WARNING: Listener for %s messages stopped: %v", what, err)
+			}
+			time.Sleep(controlRetryDelay)
+		}
+	}()
+}
+
+// consumeFanout consumes an exchange until the connection is lost
+func consumeFanout(url, exchange, what string, handle func(body []byte)) error {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+	defer channel.Close()
+
+	if err := channel.ExchangeDeclare(exchange, "fanout", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
+	}
+	queue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare %s queue: %w", what, err)
+	}
+	if err := channel.QueueBind(queue.Name, "", exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind %s queue: %w", what, err)
+	}
+
+	deliveries, err := channel.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume %s queue: %w", what, err)
+	}
+
+	log.Printf("Listening for %s messages on exchange %s", what, exchange)
+	for delivery := range deliveries {
+		handle(delivery.Body)
+	}
+	return fmt.Errorf("%s channel closed", what)
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"time"
+)
+
+// Event types published by the coordinator
+const (
+	TypeNodeDown       = "node.down"
+	TypeRestarting     = "node.restarting"
+	TypeRestarted      = "node.restarted"
+	TypeRestartFailed  = "node.restart_failed"
+	TypeRecovered      = "node.recovered"
+	TypeRestartPending = "node.restart_pending"
+	TypeHookFailed     = "node.hook_failed"
+	TypeGaveUp         = "node.gave_up"
+	TypeCompleted      = "node.completed"
+	TypeAdded          = "node.added"
+	TypeRemoved        = "node.removed"
+	TypePaused         = "node.paused"
+	TypeUnpaused       = "node.unpaused"
+	TypeSuspected      = "node.suspected"
+	TypeRootCause      = "node.root_cause"
+	TypeScaledUp       = "node.scaled_up"
+	TypeScaledDown     = "node.scaled_down"
+	TypeReplicaStarted = "service.replica_started"
+	TypeReplicaCreated = "service.replica_created"
+	TypeExtraReplicas  = "service.extra_replicas"
+	TypeHostDown       = "host.down"
+	TypeHostUp         = "host.up"
+
+	TypeRedeliveryStorm     = "queue.redelivery_storm"
+	TypeRedeliveryStormOver = "queue.redelivery_storm_over"
+	TypeQueueParked         = "queue.parked"
+
+	TypeMassFailure     = "cluster.mass_failure"
+	TypeMassFailureOver = "cluster.mass_failure_over"
+
+	TypeBackpressure     = "pipeline.backpressure"
+	TypeBackpressureOver = "pipeline.backpressure_over"
+
+	TypeAuditEntry = "audit.entry" // an audit log entry, shipped with AUDIT_EVENTS
+
+	TypeLeadershipChange = "leadership.change"
+	TypeSplitBrain       = "leadership.split_brain"
+	TypePartitioned      = "leadership.partitioned"
+	TypePartitionHealed  = "leadership.partition_healed"
+)
+
+// Event is a coordinator event published to interested consumers
+type Event struct {
+	Type          string    `json:"type"`
+	Timestamp     time.Time `json:"timestamp"`
+	CoordinatorID int       `json:"coordinator_id"`
+	LeaderID      int       `json:"leader_id"`
+	Target        string    `json:"target,omitempty"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Message       string    `json:"message,omitempty"`
+	Reason        string    `json:"reason,omitempty"`   // see reasons.go
+	Incident      string    `json:"incident,omitempty"` // ID of the target's ongoing incident
+}
+
+// Publisher publishes coordinator events
+type Publisher interface {
+	Publish(event Event)
+	Close() error
+}
+
+// NopPublisher discards all events
+type NopPublisher struct{}
+
+// Publish discards the event
+func (NopPublisher) Publish(Event) {}
+
+// Close does nothing
+func (NopPublisher) Close() error { return nil }
+
+// MultiPublisher publishes every event to each of its publishers
+type MultiPublisher []Publisher
+
+// Publish sends the event to every publisher
+func (m MultiPublisher) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	for _, publisher := range m {
+		publisher.Publish(event)
+	}
+}
+
+// Close closes every publisher, returning the first error
+func (m MultiPublisher) Close() error {
+	var first error
+	for _, publisher := range m {
+		if err := publisher.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
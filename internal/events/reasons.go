@@ -0,0 +1,15 @@
+package events
+
+// Machine-readable reasons of restart events, so consumers can aggregate
+// restarts by cause (Message stays a human-readable description)
+const (
+	ReasonPingTimeout     = "ping-timeout"     // the check got no answer in time
+	ReasonPingRefused     = "ping-refused"     // nothing listens on the checked port
+	ReasonBadResponse     = "bad-response"     // the target answered, but wrongly
+	ReasonOOM             = "oom"              // the kernel killed the container out of memory
+	ReasonDockerUnhealthy = "docker-unhealthy" // the container's HEALTHCHECK fails
+	ReasonQueueStalled    = "queue-stalled"    // the target's queue stopped moving
+	ReasonOperatorManual  = "operator-manual"  // an operator asked for the restart
+	ReasonScheduled       = "scheduled"        // a maintenance task restarted it
+	ReasonCheckFailed     = "check-failed"     // the check failed for any other reason
+)
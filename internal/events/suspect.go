@@ -0,0 +1,31 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// SuspectExchangeName is the fanout exchange workers report the failures
+// of their peers to, e.g. when the consumer of their downstream queue
+// vanishes. The leader checks the target reported right away.
+const SuspectExchangeName = "coordinator.suspects"
+
+// SuspectMessage is a worker (From) reporting that Target failed
+type SuspectMessage struct {
+	Target string `json:"target"`
+	From   string `json:"from,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ListenSuspects consumes failure reports from RabbitMQ in the background,
+// calling handle for each one, like ListenControl
+func ListenSuspects(url string, handle func(SuspectMessage)) {
+	listenFanout(url, SuspectExchangeName, "suspect", func(body []byte) {
+		var message SuspectMessage
+		if err := json.Unmarshal(body, &message); err != nil || message.Target == "" {
+			log.Printf("WARNING: Ignoring malformed suspect message: %s", body)
+			return
+		}
+		handle(message)
+	})
+}
@@ -0,0 +1,523 @@
+// Package gossip implements a SWIM-style failure detector. Every member
+// probes one random peer per protocol period, asks others to probe it
+// indirectly when it doesn't answer, and spreads suspicion and confirmation
+// by piggybacking membership updates on its probes. Detection load stays
+// constant per member instead of concentrating on the leader.
+package gossip
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is what the group believes about a member
+type State int
+
+// Member states
+const (
+	Alive State = iota
+	Suspect
+	Dead
+)
+
+// String returns the state name
+func (s State) String() string {
+	switch s {
+	case Alive:
+		return "alive"
+	case Suspect:
+		return "suspect"
+	case Dead:
+		return "dead"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Message types
+const (
+	msgPing    = "ping"
+	msgAck     = "ack"
+	msgPingReq = "ping-req"
+)
+
+const (
+	maxPacketSize        = 65507
+	maxUpdatesPerMessage = 10
+)
+
+// Config configures a Detector
+type Config struct {
+	Name             string            // this member's name
+	BindAddress      string            // UDP address to listen on
+	Peers            map[string]string // other members: name -> UDP address
+	ProbeInterval    time.Duration     // protocol period
+	ProbeTimeout     time.Duration     // wait for a direct ack
+	SuspicionTimeout time.Duration     // suspect -> dead if not refuted in time
+	IndirectProbes   int               // members asked to probe on our behalf
+}
+
+// DefaultConfig returns the timing used unless overridden
+func DefaultConfig() Config {
+	return Config{
+		ProbeInterval:    time.Second,
+		ProbeTimeout:     300 * time.Millisecond,
+		SuspicionTimeout: 5 * time.Second,
+		IndirectProbes:   3,
+	}
+}
+
+// Member is a member of the group as seen by this detector
+type Member struct {
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	State       State     `json:"state"`
+	Incarnation uint64    `json:"incarnation"`
+	Since       time.Time `json:"since"`
+}
+
+// update is a piggybacked membership change
+type update struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	State       State  `json:"state"`
+	Incarnation uint64 `json:"incarnation"`
+}
+
+// message is a SWIM protocol datagram
+type message struct {
+	Type          string   `json:"type"`
+	Seq           uint64   `json:"seq"`
+	From          string   `json:"from"`
+	Target        string   `json:"target,omitempty"`
+	TargetAddress string   `json:"target_address,omitempty"`
+	Updates       []update `json:"updates,omitempty"`
+}
+
+// broadcast is an update queued for dissemination
+type broadcast struct {
+	update    update
+	transmits int
+}
+
+// Detector is a SWIM member
+type Detector struct {
+	config Config
+	conn   net.PacketConn
+
+	mu          sync.Mutex
+	members     map[string]*Member
+	incarnation uint64
+	seq         uint64
+	acks        map[uint64]chan struct{}
+	broadcasts  []*broadcast
+	probeOrder  []string
+	stop        chan struct{}
+}
+
+// New creates a detector and binds its socket. Peers start alive.
+func New(config Config) (*Detector, error) {
+	defaults := DefaultConfig()
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = defaults.ProbeInterval
+	}
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = defaults.ProbeTimeout
+	}
+	if config.SuspicionTimeout <= 0 {
+		config.SuspicionTimeout = defaults.SuspicionTimeout
+	}
+	if config.IndirectProbes <= 0 {
+		config.IndirectProbes = defaults.IndirectProbes
+	}
+
+	conn, err := net.ListenPacket("udp", config.BindAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gossip on %s: %w", config.BindAddress, err)
+	}
+
+	d := &Detector{
+		config: config,
+		conn:   conn,
+		// A restarted member must outrank what the group remembers about its
+		// previous life, so incarnations start from the clock
+		incarnation: uint64(time.Now().UnixNano()),
+		members:     make(map[string]*Member),
+		acks:        make(map[uint64]chan struct{}),
+		stop:        make(chan struct{}),
+	}
+	now := time.Now()
+	for name, address := range config.Peers {
+		if name != config.Name {
+			d.members[name] = &Member{Name: name, Address: address, State: Alive, Since: now}
+		}
+	}
+	return d, nil
+}
+
+// Start runs the protocol in the background
+func (d *Detector) Start() {
+	log.Printf("Gossip failure detector %s listening on %s with %d peers", d.config.Name, d.config.BindAddress, len(d.members))
+	go d.receiveLoop()
+	go d.probeLoop()
+}
+
+// Close stops the detector
+func (d *Detector) Close() error {
+	close(d.stop)
+	return d.conn.Close()
+}
+
+// State returns what the group believes about a member
+func (d *Detector) State(name string) (State, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	member, ok := d.members[name]
+	if !ok {
+		return Dead, false
+	}
+	return member.State, true
+}
+
+// Knows reports whether name is a member of the group
+func (d *Detector) Knows(name string) bool {
+	_, ok := d.State(name)
+	return ok
+}
+
+// Verdict returns an error if a member is confirmed dead or unknown.
+// Suspects count as alive until confirmed.
+func (d *Detector) Verdict(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	member, ok := d.members[name]
+	switch {
+	case !ok:
+		return fmt.Errorf("%s is not a gossip member", name)
+	case member.State == Dead:
+		return fmt.Errorf("%s confirmed dead by gossip since %s", name, member.Since.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Members returns the members sorted by name
+func (d *Detector) Members() []Member {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	members := make([]Member, 0, len(d.members))
+	for _, member := range d.members {
+		members = append(members, *member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members
+}
+
+// probeLoop probes one member per protocol period and expires suspicions
+func (d *Detector) probeLoop() {
+	ticker := time.NewTicker(d.config.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.expireSuspects()
+			if target, ok := d.nextTarget(); ok {
+				d.probe(target)
+			}
+		}
+	}
+}
+
+// nextTarget returns the next member to probe. Members are probed in a
+// shuffled round-robin order, which bounds the time to first detection.
+// Dead members keep being probed so a recovered one is noticed.
+func (d *Detector) nextTarget() (Member, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for len(d.probeOrder) > 0 {
+		name := d.probeOrder[0]
+		d.probeOrder = d.probeOrder[1:]
+		if member, ok := d.members[name]; ok {
+			return *member, true
+		}
+	}
+
+	for name := range d.members {
+		d.probeOrder = append(d.probeOrder, name)
+	}
+	if len(d.probeOrder) == 0 {
+		return Member{}, false
+	}
+	rand.Shuffle(len(d.probeOrder), func(i, j int) {
+		d.probeOrder[i], d.probeOrder[j] = d.probeOrder[j], d.probeOrder[i]
+	})
+	member := *d.members[d.probeOrder[0]]
+	d.probeOrder = d.probeOrder[1:]
+	return member, true
+}
+
+// probe pings a member directly, then indirectly through others, and
+// suspects it if neither gets an ack within the protocol period
+func (d *Detector) probe(target Member) {
+	if d.ping(target.Address, d.config.ProbeTimeout) {
+		return
+	}
+
+	helpers := d.randomMembers(d.config.IndirectProbes, target.Name)
+	seq, acked := d.expectAck()
+	defer d.forgetAck(seq)
+	for _, helper := range helpers {
+		d.send(helper.Address, message{Type: msgPingReq, Seq: seq, Target: target.Name, TargetAddress: target.Address})
+	}
+
+	select {
+	case <-acked:
+		return
+	case <-time.After(d.config.ProbeInterval - d.config.ProbeTimeout):
+	}
+
+	if target.State == Alive {
+		d.apply(update{Name: target.Name, Address: target.Address, State: Suspect, Incarnation: target.Incarnation})
+	}
+}
+
+// ping sends a ping and waits for its ack
+func (d *Detector) ping(address string, timeout time.Duration) bool {
+	seq, acked := d.expectAck()
+	defer d.forgetAck(seq)
+
+	d.send(address, message{Type: msgPing, Seq: seq})
+	select {
+	case <-acked:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// expectAck registers a sequence number to wait an ack for
+func (d *Detector) expectAck() (uint64, chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seq++
+	acked := make(chan struct{}, 1)
+	d.acks[d.seq] = acked
+	return d.seq, acked
+}
+
+// forgetAck stops waiting for an ack
+func (d *Detector) forgetAck(seq uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.acks, seq)
+}
+
+// randomMembers returns up to n random non-dead members other than exclude
+func (d *Detector) randomMembers(n int, exclude string) []Member {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	candidates := []Member{}
+	for name, member := range d.members {
+		if name != exclude && member.State != Dead {
+			candidates = append(candidates, *member)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	return candidates[:min(n, len(candidates))]
+}
+
+// expireSuspects confirms as dead the suspects that didn't refute in time
+func (d *Detector) expireSuspects() {
+	d.mu.Lock()
+	expired := []update{}
+	for _, member := range d.members {
+		if member.State == Suspect && time.Since(member.Since) >= d.config.SuspicionTimeout {
+			expired = append(expired, update{Name: member.Name, Address: member.Address, State: Dead, Incarnation: member.Incarnation})
+		}
+	}
+	d.mu.Unlock()
+
+	for _, u := range expired {
+		d.apply(u)
+	}
+}
+
+// receiveLoop handles incoming datagrams
+func (d *Detector) receiveLoop() {
+	buffer := make([]byte, maxPacketSize)
+	for {
+		n, from, err := d.conn.ReadFrom(buffer)
+		if err != nil {
+			select {
+			case <-d.stop:
+				return
+			default:
+			}
+			log.Printf("Error reading gossip message: %v", err)
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal(buffer[:n], &msg); err != nil {
+			log.Printf("WARNING: Ignoring malformed gossip message from %s: %v", from, err)
+			continue
+		}
+		d.handle(msg, from)
+	}
+}
+
+// handle processes one message
+func (d *Detector) handle(msg message, from net.Addr) {
+	for _, u := range msg.Updates {
+		d.apply(u)
+	}
+
+	// A member we consider dead is talking to us: make sure it hears the
+	// verdict so it can refute it, in case the original broadcast was lost
+	d.mu.Lock()
+	if member, ok := d.members[msg.From]; ok && member.State == Dead {
+		d.queue(update{Name: member.Name, Address: member.Address, State: Dead, Incarnation: member.Incarnation})
+	}
+	d.mu.Unlock()
+
+	switch msg.Type {
+	case msgPing:
+		d.send(from.String(), message{Type: msgAck, Seq: msg.Seq})
+
+	case msgAck:
+		d.mu.Lock()
+		acked, ok := d.acks[msg.Seq]
+		d.mu.Unlock()
+		if ok {
+			select {
+			case acked <- struct{}{}:
+			default:
+			}
+		}
+
+	case msgPingReq:
+		// Probe the target on the requester's behalf and relay the ack
+		go func() {
+			if d.ping(msg.TargetAddress, d.config.ProbeTimeout) {
+				d.send(from.String(), message{Type: msgAck, Seq: msg.Seq})
+			}
+		}()
+	}
+}
+
+// apply merges a membership update using SWIM's precedence rules: higher
+// incarnations win, and at equal incarnation dead > suspect > alive. A
+// member suspected or declared dead refutes it with a new incarnation.
+func (d *Detector) apply(u update) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if u.Name == d.config.Name {
+		if u.State != Alive && u.Incarnation >= d.incarnation {
+			d.incarnation = u.Incarnation + 1
+			log.Printf("Gossip: refuting %s suspicion about myself (incarnation %d)", u.State, d.incarnation)
+			d.queue(d.self())
+		}
+		return
+	}
+
+	member, ok := d.members[u.Name]
+	if !ok {
+		if u.Address == "" {
+			return
+		}
+		member = &Member{Name: u.Name, Address: u.Address, State: u.State, Incarnation: u.Incarnation, Since: time.Now()}
+		d.members[u.Name] = member
+		log.Printf("Gossip: %s joined as %s", u.Name, u.State)
+		d.queue(u)
+		return
+	}
+
+	newer := u.Incarnation > member.Incarnation ||
+		(u.Incarnation == member.Incarnation && u.State > member.State)
+	if !newer {
+		return
+	}
+
+	if member.State != u.State {
+		log.Printf("Gossip: %s is %s (was %s)", u.Name, u.State, member.State)
+		member.Since = time.Now()
+	}
+	member.State = u.State
+	member.Incarnation = u.Incarnation
+	if u.Address != "" {
+		member.Address = u.Address
+	}
+	d.queue(u)
+}
+
+// self returns the update announcing this member alive. Caller must hold mu.
+func (d *Detector) self() update {
+	return update{Name: d.config.Name, State: Alive, Incarnation: d.incarnation}
+}
+
+// queue schedules an update for dissemination. Caller must hold mu.
+func (d *Detector) queue(u update) {
+	for i, b := range d.broadcasts {
+		if b.update.Name == u.Name {
+			d.broadcasts = append(d.broadcasts[:i], d.broadcasts[i+1:]...)
+			break
+		}
+	}
+	d.broadcasts = append(d.broadcasts, &broadcast{update: u})
+}
+
+// piggyback takes the updates to attach to an outgoing message. Each update
+// is sent about 3*log2(N) times, enough to reach every member with high
+// probability. Caller must hold mu.
+func (d *Detector) piggyback() []update {
+	limit := 3 * int(math.Ceil(math.Log2(float64(len(d.members)+2))))
+
+	// Always announce ourselves so a restarted member is recognized by its
+	// newer incarnation
+	updates := []update{d.self()}
+	kept := d.broadcasts[:0]
+	for _, b := range d.broadcasts {
+		if len(updates) < maxUpdatesPerMessage {
+			updates = append(updates, b.update)
+			b.transmits++
+		}
+		if b.transmits < limit {
+			kept = append(kept, b)
+		}
+	}
+	d.broadcasts = kept
+	return updates
+}
+
+// send sends a message with piggybacked updates
+func (d *Detector) send(address string, msg message) {
+	d.mu.Lock()
+	msg.From = d.config.Name
+	msg.Updates = d.piggyback()
+	d.mu.Unlock()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("ERROR: Failed to encode gossip message: %v", err)
+		return
+	}
+
+	udpAddress, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return
+	}
+	d.conn.WriteTo(data, udpAddress)
+}
@@ -0,0 +1,195 @@
+// Package incident groups a target's downtime into incidents: its
+// consecutive failed checks, what the coordinator did about them and how it
+// ended, under one ID that the events (and so the alerts) about it carry.
+package incident
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// Resolutions of incidents that ended
+const (
+	ResolutionRecovered = "recovered" // healthy again after the coordinator recovered it
+	ResolutionCleared   = "cleared"   // healthy again on its own
+	ResolutionRemoved   = "removed"   // no longer monitored
+)
+
+// maxEnded is how many ended incidents are kept
+const maxEnded = 200
+
+// Action is an event published about a target during one of its incidents
+type Action struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Reason  string    `json:"reason,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Incident is a target's downtime, from its first failed check until it's
+// healthy again
+type Incident struct {
+	ID         string    `json:"id"`
+	Target     string    `json:"target"`
+	Cause      string    `json:"cause,omitempty"` // reason of the first failed check (see events.Reason*)
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"` // zero while ongoing
+	Ongoing    bool      `json:"ongoing"`
+	Failures   int       `json:"failures"`
+	Actions    []Action  `json:"actions"`
+	Resolution string    `json:"resolution,omitempty"`
+
+	recovered bool // the coordinator recovered the target during it
+}
+
+// Duration returns how long the incident lasted (so far)
+func (i Incident) Duration() time.Duration {
+	if i.Ongoing {
+		return time.Since(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// Log keeps the ongoing incidents and the last ended ones. A failed check
+// only starts a tentative incident: it's opened, and gets its ID, with the
+// first event about the target, so failures tolerated below the target's
+// threshold don't become incidents.
+type Log struct {
+	mu      sync.Mutex
+	nextID  int
+	failing map[string]*Incident // by target
+	latest  map[string]*Incident // last opened incident of each target
+	ended   []*Incident          // oldest first
+}
+
+// NewLog creates an empty incident log
+func NewLog() *Log {
+	return &Log{failing: make(map[string]*Incident), latest: make(map[string]*Incident)}
+}
+
+// Failed records a failed check of target
+func (l *Log) Failed(target string, at time.Time, cause string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	incident, ok := l.failing[target]
+	if !ok {
+		incident = &Incident{Target: target, Cause: cause, Start: at, Ongoing: true, Actions: []Action{}}
+		l.failing[target] = incident
+	}
+	incident.Failures++
+}
+
+// Record adds an event to the incident of its target, opening it if it's
+// tentative, and returns the incident's ID; "" if the target has none. A
+// recovered event belongs to the target's last incident even if it already
+// ended: a regular check may see the target healthy before its
+// verification does.
+func (l *Log) Record(event events.Event) string {
+	if event.Target == "" {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	incident, ok := l.failing[event.Target]
+	if !ok && event.Type == events.TypeRecovered {
+		incident, ok = l.latest[event.Target]
+	}
+	if !ok {
+		return ""
+	}
+
+	if incident.ID == "" {
+		l.nextID++
+		incident.ID = fmt.Sprintf("%d-%d", incident.Start.Unix(), l.nextID)
+		l.latest[event.Target] = incident
+	}
+	if event.Type == events.TypeRestarting {
+		incident.recovered = true
+	}
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	incident.Actions = append(incident.Actions, Action{
+		Time:    timestamp,
+		Event:   event.Type,
+		Reason:  event.Reason,
+		Message: event.Message,
+	})
+	return incident.ID
+}
+
+// Healthy ends the incident of a target that passed a check
+func (l *Log) Healthy(target string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	resolution := ResolutionCleared
+	if incident, ok := l.failing[target]; ok && incident.recovered {
+		resolution = ResolutionRecovered
+	}
+	l.endLocked(target, at, resolution)
+}
+
+// Removed ends the incident of a target no longer monitored
+func (l *Log) Removed(target string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.endLocked(target, at, ResolutionRemoved)
+	delete(l.latest, target)
+}
+
+// endLocked ends a target's incident, dropping it if it was tentative.
+// l.mu must be held.
+func (l *Log) endLocked(target string, at time.Time, resolution string) {
+	incident, ok := l.failing[target]
+	if !ok {
+		return
+	}
+	delete(l.failing, target)
+	if incident.ID == "" {
+		return
+	}
+
+	incident.End = at
+	incident.Ongoing = false
+	incident.Resolution = resolution
+	l.ended = append(l.ended, incident)
+	if len(l.ended) > maxEnded {
+		l.ended = l.ended[len(l.ended)-maxEnded:]
+	}
+}
+
+// List returns the ongoing incidents and then the ended ones, most recent
+// first
+func (l *Log) List() []Incident {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ongoing := make([]Incident, 0, len(l.failing))
+	for _, incident := range l.failing {
+		if incident.ID != "" {
+			ongoing = append(ongoing, copyIncident(incident))
+		}
+	}
+	sort.Slice(ongoing, func(i, j int) bool { return ongoing[i].Start.After(ongoing[j].Start) })
+
+	incidents := ongoing
+	for i := len(l.ended) - 1; i >= 0; i-- {
+		incidents = append(incidents, copyIncident(l.ended[i]))
+	}
+	return incidents
+}
+
+// copyIncident copies an incident so callers don't share its actions
+func copyIncident(incident *Incident) Incident {
+	copied := *incident
+	copied.Actions = append([]Action{}, incident.Actions...)
+	return copied
+}
@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// CheckTypeActivity is the checker reported for results inferred from a
+// target's recent activity instead of a probe
+const CheckTypeActivity = "activity"
+
+// ActivityTracker records when targets were last seen doing work (e.g.
+// processing messages), a passive sign of liveness
+type ActivityTracker struct {
+	window   time.Duration
+	mu       sync.RWMutex
+	lastSeen map[string]time.Time
+}
+
+// NewActivityTracker creates an activity tracker that considers targets
+// active for window after they were last seen
+func NewActivityTracker(window time.Duration) *ActivityTracker {
+	return &ActivityTracker{window: window, lastSeen: make(map[string]time.Time)}
+}
+
+// Seen records activity from a target
+func (at *ActivityTracker) Seen(name string) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.lastSeen[name] = time.Now()
+}
+
+// Active reports whether a target was seen within the window
+func (at *ActivityTracker) Active(name string) bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	seen, ok := at.lastSeen[name]
+	return ok && time.Since(seen) <= at.window
+}
@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// availabilityBucket is how much time each persisted sample covers
+	availabilityBucket = time.Minute
+
+	// maxAvailabilityGap is the longest time between two results of a target
+	// that's still attributed to the first one; longer gaps (the coordinator
+	// was down) count as neither up nor down
+	maxAvailabilityGap = 15 * time.Minute
+
+	// maxMemoryBuckets bounds the buckets kept when there's no file to
+	// persist them to
+	maxMemoryBuckets = 100000
+)
+
+// AvailabilityBucket is how a target did during one bucket of time
+type AvailabilityBucket struct {
+	Target   string        `json:"target"`
+	Start    time.Time     `json:"start"`
+	Checks   int           `json:"checks"`
+	Failures int           `json:"failures"`
+	Up       time.Duration `json:"up"`
+	Down     time.Duration `json:"down"`
+	Outages  int           `json:"outages"` // times the target went from healthy to failing
+}
+
+// TargetAvailability is a target's availability over a time range
+type TargetAvailability struct {
+	Target              string        `json:"target"`
+	Checks              int           `json:"checks"`
+	Failures            int           `json:"failures"`
+	Up                  time.Duration `json:"up"`
+	Down                time.Duration `json:"down"`
+	AvailabilityPercent float64       `json:"availability_percent"`
+	Outages             int           `json:"outages"`
+}
+
+// Availability keeps per-target availability samples for the long run,
+// unlike History: a bucket per target and minute with the time the target
+// spent healthy and failing, appended as JSON lines to a file if it has
+// one. Each result's state is held until the next result.
+type Availability struct {
+	path string
+
+	mu      sync.Mutex
+	last    map[string]Sample              // last result of each target
+	open    map[string]*AvailabilityBucket // current bucket of each target
+	pending []AvailabilityBucket           // closed buckets not saved yet
+}
+
+// NewAvailability creates an availability store persisted to path; empty
+// keeps the samples in memory only
+func NewAvailability(path string) *Availability {
+	return &Availability{
+		path: path,
+		last: make(map[string]Sample),
+		open: make(map[string]*AvailabilityBucket),
+	}
+}
+
+// Add records a check result of a target
+func (a *Availability) Add(name string, result CheckResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	start := result.Timestamp.Truncate(availabilityBucket)
+	bucket, ok := a.open[name]
+	if !ok || !bucket.Start.Equal(start) {
+		if ok {
+			a.closeLocked(*bucket)
+		}
+		bucket = &AvailabilityBucket{Target: name, Start: start}
+		a.open[name] = bucket
+	}
+
+	bucket.Checks++
+	previous, seen := a.last[name]
+	if !result.Healthy {
+		bucket.Failures++
+		if !seen || previous.Healthy {
+			bucket.Outages++
+		}
+	}
+	if gap := result.Timestamp.Sub(previous.Timestamp); seen && gap > 0 && gap <= maxAvailabilityGap {
+		if previous.Healthy {
+			bucket.Up += gap
+		} else {
+			bucket.Down += gap
+		}
+	}
+	a.last[name] = Sample{Timestamp: result.Timestamp, Healthy: result.Healthy}
+}
+
+// closeLocked queues a bucket that won't change anymore. a.mu must be held.
+func (a *Availability) closeLocked(bucket AvailabilityBucket) {
+	a.pending = append(a.pending, bucket)
+	if a.path == "" && len(a.pending) > maxMemoryBuckets {
+		a.pending = a.pending[len(a.pending)-maxMemoryBuckets:]
+	}
+}
+
+// Save appends the closed buckets to the file. With all, the open buckets
+// are saved and closed too, as on shutdown.
+func (a *Availability) Save(all bool) error {
+	if a.path == "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	if all {
+		for name, bucket := range a.open {
+			a.closeLocked(*bucket)
+			delete(a.open, name)
+		}
+	}
+	buckets := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	err := appendBuckets(a.path, buckets)
+	if err != nil {
+		// Kept for the next attempt
+		a.mu.Lock()
+		a.pending = append(buckets, a.pending...)
+		a.mu.Unlock()
+	}
+	return err
+}
+
+// appendBuckets appends buckets to the file at path as JSON lines
+func appendBuckets(path string, buckets []AvailabilityBucket) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open availability file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, bucket := range buckets {
+		if err := encoder.Encode(bucket); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to save availability: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save availability: %w", err)
+	}
+	return file.Close()
+}
+
+// Report returns the availability of every target with buckets starting
+// within [from, to), sorted by target
+func (a *Availability) Report(from, to time.Time) ([]TargetAvailability, error) {
+	totals := make(map[string]*TargetAvailability)
+	add := func(bucket AvailabilityBucket) {
+		if bucket.Start.Before(from.Truncate(availabilityBucket)) || !bucket.Start.Before(to) {
+			return
+		}
+		total, ok := totals[bucket.Target]
+		if !ok {
+			total = &TargetAvailability{Target: bucket.Target}
+			totals[bucket.Target] = total
+		}
+		total.Checks += bucket.Checks
+		total.Failures += bucket.Failures
+		total.Up += bucket.Up
+		total.Down += bucket.Down
+		total.Outages += bucket.Outages
+	}
+
+	if a.path != "" {
+		if err := readBuckets(a.path, add); err != nil {
+			return nil, err
+		}
+	}
+	a.mu.Lock()
+	for _, bucket := range a.pending {
+		add(bucket)
+	}
+	for _, bucket := range a.open {
+		add(*bucket)
+	}
+	a.mu.Unlock()
+
+	report := make([]TargetAvailability, 0, len(totals))
+	for _, total := range totals {
+		total.AvailabilityPercent = 100
+		if observed := total.Up + total.Down; observed > 0 {
+			total.AvailabilityPercent = 100 * float64(total.Up) / float64(observed)
+		} else if total.Failures > 0 {
+			total.AvailabilityPercent = 0
+		}
+		report = append(report, *total)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Target < report[j].Target })
+	return report, nil
+}
+
+// readBuckets calls add with every bucket saved at path. A missing file has
+// none; lines that don't parse (cut short by a crash) are skipped.
+func readBuckets(path string, add func(AvailabilityBucket)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read availability file: %w", err)
+	}
+	defer file.Close()
+
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var bucket AvailabilityBucket
+		if err := json.Unmarshal(scanner.Bytes(), &bucket); err != nil {
+			skipped++
+			continue
+		}
+		add(bucket)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read availability file: %w", err)
+	}
+	if skipped > 0 {
+		log.Printf("WARNING: Skipped %d unreadable line(s) of %s", skipped, path)
+	}
+	return nil
+}
@@ -1,10 +1,19 @@
 package monitor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
 )
 
 const (
@@ -12,69 +21,234 @@ const (
 	pongMessage = "PONG"
 	dialTimeout = 2 * time.Second
 	readTimeout = 2 * time.Second
+
+	// Version 2 of the protocol is newline-terminated and tagged, so servers
+	// can tell it apart from the legacy 4-byte exchange. Legacy servers read
+	// the "PING" prefix and answer a plain "PONG".
+	pingV2Message = "PING/2\n"
+	pongV2Message = "PONG/2\n"
+	maxPongLength = 64
 )
 
-// HealthChecker verifies the health of TCP endpoints
-type HealthChecker struct{}
+// Health protocol versions
+const (
+	ProtocolLegacy = 1
+	ProtocolV2     = 2
+)
+
+// CheckTypeTCP is the registry name of the TCP PING/PONG checker
+const CheckTypeTCP = "tcp"
+
+// HealthChecker verifies the health of TCP endpoints using the PING/PONG
+// protocol. It negotiates the protocol version per address: "PING/2" first,
+// falling back to the legacy "PING" for servers that don't answer it, and
+// remembers what each address speaks.
+type HealthChecker struct {
+	mu       sync.Mutex
+	versions map[string]int
+}
 
 // NewHealthChecker creates a new health checker
 func NewHealthChecker() *HealthChecker {
-	return &HealthChecker{}
+	return &HealthChecker{versions: make(map[string]int)}
 }
 
 // IsAlive checks if a host is responding to health checks
 // Protocol: Connect -> Send "PING" -> Expect "PONG"
 func (hc *HealthChecker) IsAlive(host string, port string) bool {
+	if err := hc.Ping(context.Background(), host, port); err != nil {
+		log.Printf("%v", err)
+		return false
+	}
+	return true
+}
+
+// Check implements Checker
+func (hc *HealthChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+	err := hc.Ping(ctx, target.Host, target.Port)
+	return newCheckResult(CheckTypeTCP, start, err)
+}
+
+// Ping performs a single PING/PONG exchange and returns the reason it failed, if any
+func (hc *HealthChecker) Ping(ctx context.Context, host string, port string) error {
 	address := net.JoinHostPort(host, port)
-	
+
+	if hc.Version(address) == ProtocolLegacy {
+		return hc.exchange(ctx, address, pingMessage, pongMessage)
+	}
+
+	err := hc.exchange(ctx, address, pingV2Message, pongV2Message)
+	var legacy *legacyResponseError
+	switch {
+	case err == nil:
+		hc.setVersion(address, ProtocolV2)
+		return nil
+	case errors.As(err, &legacy):
+		hc.setVersion(address, ProtocolLegacy)
+		return nil
+	case isDialError(err) || ctx.Err() != nil:
+		return err
+	}
+
+	// Connected but got no usable answer: the server may be a strict legacy
+	// one that only accepts exactly "PING"
+	if legacyErr := hc.exchange(ctx, address, pingMessage, pongMessage); legacyErr != nil {
+		return err
+	}
+	hc.setVersion(address, ProtocolLegacy)
+	return nil
+}
+
+// Version returns the protocol version last negotiated with an address, or
+// ProtocolV2 if none was negotiated yet
+func (hc *HealthChecker) Version(address string) int {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if version, ok := hc.versions[address]; ok {
+		return version
+	}
+	return ProtocolV2
+}
+
+// setVersion records the protocol version an address speaks
+func (hc *HealthChecker) setVersion(address string, version int) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if hc.versions == nil {
+		hc.versions = make(map[string]int)
+	}
+	if hc.versions[address] != version {
+		log.Printf("%s speaks health protocol version %d", address, version)
+	}
+	hc.versions[address] = version
+}
+
+// isDialError reports whether err is a failure to connect, where retrying
+// with another version is pointless
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// legacyResponseError is returned when a "PING/2" gets a legacy "PONG"
+type legacyResponseError struct{}
+
+func (*legacyResponseError) Error() string { return "legacy PONG response" }
+
+// exchange sends ping and expects pong back on a fresh connection
+func (hc *HealthChecker) exchange(ctx context.Context, address, ping, pong string) error {
 	// Connect with timeout
-	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	conn, err := dialer.DialContext(ctx, "tcp", address, dialTimeout)
 	if err != nil {
-		log.Printf("Failed to connect to %s: %v", address, err)
-		return false
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
 	defer conn.Close()
 
-	// Set read deadline
-	if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
-		log.Printf("Failed to set read deadline for %s: %v", address, err)
-		return false
+	// Set read deadline, never past the context deadline
+	deadline := time.Now().Add(readTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set read deadline for %s: %w", address, err)
 	}
 
 	// Send PING
-	_, err = conn.Write([]byte(pingMessage))
+	_, err = conn.Write([]byte(ping))
 	if err != nil {
-		log.Printf("Failed to send PING to %s: %v", address, err)
-		return false
+		return fmt.Errorf("failed to send PING to %s: %w", address, err)
 	}
 
-	// Read response
-	buffer := make([]byte, len(pongMessage))
-	n, err := conn.Read(buffer)
-	if err != nil {
-		log.Printf("Failed to read response from %s: %v", address, err)
-		return false
+	// Read response. Legacy servers answer exactly "PONG" without a newline,
+	// so the answer ends at the newline, at len(pong) bytes or when the
+	// server closes the connection
+	buffer := make([]byte, 0, maxPongLength)
+	chunk := make([]byte, maxPongLength)
+	for len(buffer) < len(pong) && !strings.Contains(string(buffer), "\n") {
+		n, err := conn.Read(chunk)
+		buffer = append(buffer, chunk[:n]...)
+		if err != nil {
+			if err == io.EOF && len(buffer) > 0 {
+				break
+			}
+			return fmt.Errorf("failed to read response from %s: %w", address, err)
+		}
 	}
 
-	response := string(buffer[:n])
-	if response != pongMessage {
-		log.Printf("Unexpected response from %s: got '%s', expected '%s'", address, response, pongMessage)
-		return false
+	response := string(buffer)
+	if response == pong {
+		return nil
 	}
-
-	return true
+	if pong != pongMessage && response == pongMessage {
+		return &legacyResponseError{}
+	}
+	return WithReason(events.ReasonBadResponse, fmt.Errorf("unexpected response from %s: got '%s', expected '%s'", address,
+		strings.TrimSpace(response), strings.TrimSpace(pong)))
 }
 
 // CheckTarget represents a target to monitor
 type CheckTarget struct {
-	Name          string
-	Host          string
-	Port          string
-	ContainerName string
+	Name             string
+	Host             string
+	Port             string
+	ContainerName    string
+	Group            string        // Policy group (filters, joiners, ...); empty means none
+	GroupRestarts    int           // Max targets of the group recovering at once; zero means unlimited
+	DockerHost       string        // Docker host running the container; empty means local
+	CheckType        string        // Registered checker name; empty means CheckTypeTCP
+	Path             string        // Request path for HTTP checks
+	ExecCommand      []string      // Command for exec checks
+	ClientPort       string        // Port clients connect to, for gateway checks
+	Hello            string        // Line sent to the client port by gateway checks; empty means "HELLO"
+	HelloReply       string        // Prefix expected in the answer to the hello; empty accepts any answer
+	Interval         time.Duration // Minimum time between checks; zero means every round
+	FailureThreshold int           // Consecutive failed checks before recovering; zero means the first one
+	HeartbeatTimeout time.Duration // Longest silence allowed between pushed heartbeats (push checks)
+	MaxRestarts      int           // Consecutive restarts before giving up; zero means unlimited
+	StopTimeout      time.Duration // Grace period between SIGTERM and SIGKILL on restart; zero means the container's default
+	RestartDeadline  time.Duration // Escalate a hanging restart to kill+start after this long; zero disables it
+	DrainTimeout     time.Duration // Wait this long for the worker to drain before a restart; zero skips draining
+	VerifyTimeout    time.Duration // Wait this long for the target to pass a check after a recovery; zero means the coordinator's default
+	Critical         bool          // Restarted right away even while a pipeline run is in progress
+	Protected        bool          // Never restarted automatically: failures wait for an operator to confirm the restart
+	ConfirmWebhook   string        // URL asked to approve each restart; setting it requires confirmation like Protected
+	ConfirmTimeout   time.Duration // How long a restart waits for confirmation; zero waits indefinitely
+	ConfirmDefault   string        // What happens when confirmation times out: "skip" (default) or "restart"
+	PreRestart       *Hook         // Run before the recovery action (e.g. flush state, leave the load balancer)
+	PostRecovery     *Hook         // Run once the target is verified healthy again (e.g. re-register, warm caches)
+	Recovery         string        // Recovery action name; empty means restart
+	RecoveryCommand  []string      // Command for the exec recovery action
+	WebhookURL       string        // URL for the webhook recovery action
+	Unit             string        // Unit for the systemd recovery action
+	SSHHost          string        // Host for SSH-based recovery; empty means Host
+	SwarmService     string        // Swarm service for the swarm recovery action
+	OneShotCleanup   string        // What happens once the container runs to completion (see docker.ContainerInfo.Completed); empty means restart it
+	PausedAction     string        // What happens while the container is paused: "alert" (default), "ignore" or "unpause"
+	DependsOn        []string      // Targets (by name or container name) it can't work without; its restarts wait while they fail
+
+	// PluginParams are passed to plugin checkers and actions (see
+	// internal/plugin)
+	PluginParams map[string]string
+
+	// ContainerSpec, when set, is used to recreate the container if it no
+	// longer exists
+	ContainerSpec *docker.ContainerSpec
+}
+
+// Hook is a command run in the target's container, or a webhook, run around
+// its recovery
+type Hook struct {
+	Command []string
+	Webhook string
+
+	// Blocking makes a failing hook fail the recovery: a pre-restart hook
+	// cancels the restart, a post-recovery hook marks the recovery failed.
+	// Non-blocking failures are only reported.
+	Blocking bool
 }
 
 // String returns a string representation of the target
 func (t *CheckTarget) String() string {
 	return fmt.Sprintf("%s (%s:%s -> container: %s)", t.Name, t.Host, t.Port, t.ContainerName)
 }
-
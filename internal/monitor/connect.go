@@ -0,0 +1,37 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+)
+
+// CheckTypeConnect is the registry name of the plain TCP connect checker
+const CheckTypeConnect = "connect"
+
+// ConnectChecker considers a target healthy when a TCP connection can be
+// established. Meant for third-party services (databases, brokers) that
+// don't speak the PING/PONG protocol.
+type ConnectChecker struct{}
+
+// NewConnectChecker creates a new connect checker
+func NewConnectChecker() *ConnectChecker {
+	return &ConnectChecker{}
+}
+
+// Check implements Checker
+func (cc *ConnectChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+	address := net.JoinHostPort(target.Host, target.Port)
+
+	conn, err := dialer.DialContext(ctx, "tcp", address, dialTimeout)
+	if err != nil {
+		return newCheckResult(CheckTypeConnect, start, fmt.Errorf("failed to connect to %s: %w", address, err))
+	}
+	conn.Close()
+
+	return newCheckResult(CheckTypeConnect, start, nil)
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// CheckTypeDockerHealth is the registry name of the Docker HEALTHCHECK checker
+const CheckTypeDockerHealth = "docker"
+
+// Inspector returns container state from the container runtime
+type Inspector interface {
+	InspectContainer(ctx context.Context, containerNameOrID string) (docker.ContainerInfo, error)
+}
+
+// InspectorResolver returns the Inspector for the Docker host a target runs on
+type InspectorResolver func(dockerHost string) (Inspector, error)
+
+// DockerHealthChecker uses the HEALTHCHECK status Docker already tracks for
+// the target's container. "unhealthy" is a failure; "starting" is given the
+// benefit of the doubt. Containers without a HEALTHCHECK are healthy while
+// they are running.
+type DockerHealthChecker struct {
+	resolve InspectorResolver
+}
+
+// NewDockerHealthChecker creates a new Docker HEALTHCHECK checker
+func NewDockerHealthChecker(resolve InspectorResolver) *DockerHealthChecker {
+	return &DockerHealthChecker{resolve: resolve}
+}
+
+// Check implements Checker
+func (dc *DockerHealthChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+
+	inspector, err := dc.resolve(target.DockerHost)
+	if err != nil {
+		return newCheckResult(CheckTypeDockerHealth, start, err)
+	}
+
+	info, err := inspector.InspectContainer(ctx, target.ContainerName)
+	if err != nil {
+		return newCheckResult(CheckTypeDockerHealth, start, err)
+	}
+
+	if !info.State.Running {
+		err := fmt.Errorf("container %s is not running (status %s)", target.ContainerName, info.State.Status)
+		if info.State.OOMKilled {
+			err = WithReason(events.ReasonOOM, fmt.Errorf("%w, killed out of memory", err))
+		}
+		return newCheckResult(CheckTypeDockerHealth, start, err)
+	}
+
+	health := info.State.Health
+	if health == nil || health.Status != docker.HealthUnhealthy {
+		return newCheckResult(CheckTypeDockerHealth, start, nil)
+	}
+
+	err = fmt.Errorf("container %s is unhealthy (failing streak %d)", target.ContainerName, health.FailingStreak)
+	if len(health.Log) > 0 {
+		last := health.Log[len(health.Log)-1]
+		err = fmt.Errorf("%w: last probe exited with code %d: %s", err, last.ExitCode, strings.TrimSpace(last.Output))
+	}
+	return newCheckResult(CheckTypeDockerHealth, start, WithReason(events.ReasonDockerUnhealthy, err))
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+)
+
+const (
+	drainMessage   = "DRAIN"
+	drainedMessage = "DRAINED"
+)
+
+// Drain asks a worker to finish its in-flight work and stop consuming, over
+// the same port as PING/PONG. The worker answers "DRAINED" once it's safe to
+// stop it; Drain waits up to timeout for that answer.
+func (hc *HealthChecker) Drain(ctx context.Context, host string, port string, timeout time.Duration) error {
+	address := net.JoinHostPort(host, port)
+
+	conn, err := dialer.DialContext(ctx, "tcp", address, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set deadline for %s: %w", address, err)
+	}
+
+	if _, err := conn.Write([]byte(drainMessage)); err != nil {
+		return fmt.Errorf("failed to send DRAIN to %s: %w", address, err)
+	}
+
+	buffer := make([]byte, len(drainedMessage))
+	n, err := io.ReadFull(conn, buffer)
+	if err != nil {
+		return fmt.Errorf("%s did not drain: %w", address, err)
+	}
+
+	if response := string(buffer[:n]); response != drainedMessage {
+		return fmt.Errorf("unexpected drain response from %s: got '%s', expected '%s'", address, response, drainedMessage)
+	}
+	return nil
+}
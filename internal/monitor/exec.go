@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+)
+
+// CheckTypeExec is the registry name of the exec checker
+const CheckTypeExec = "exec"
+
+// DefaultExecCommand is run when a target doesn't configure its own command
+var DefaultExecCommand = []string{"/healthcheck"}
+
+// Execer runs commands inside containers
+type Execer interface {
+	Exec(ctx context.Context, containerNameOrID string, cmd []string) (docker.ExecResult, error)
+}
+
+// ExecerResolver returns the Execer for the Docker host a target runs on
+type ExecerResolver func(dockerHost string) (Execer, error)
+
+// ExecChecker runs a command inside the target's container and considers it
+// healthy when the command exits with status 0. Useful for workers that
+// expose no port at all.
+type ExecChecker struct {
+	resolve ExecerResolver
+}
+
+// NewExecChecker creates a new exec checker
+func NewExecChecker(resolve ExecerResolver) *ExecChecker {
+	return &ExecChecker{resolve: resolve}
+}
+
+// Check implements Checker
+func (ec *ExecChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+
+	cmd := target.ExecCommand
+	if len(cmd) == 0 {
+		cmd = DefaultExecCommand
+	}
+
+	execer, err := ec.resolve(target.DockerHost)
+	if err != nil {
+		return newCheckResult(CheckTypeExec, start, err)
+	}
+
+	result, err := execer.Exec(ctx, target.ContainerName, cmd)
+	if err != nil {
+		return newCheckResult(CheckTypeExec, start, err)
+	}
+
+	if result.ExitCode != 0 {
+		output := strings.TrimSpace(result.Output)
+		return newCheckResult(CheckTypeExec, start,
+			fmt.Errorf("%s in %s exited with code %d: %s", strings.Join(cmd, " "), target.ContainerName, result.ExitCode, output))
+	}
+
+	return newCheckResult(CheckTypeExec, start, nil)
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+)
+
+const (
+	// CheckTypeGateway is the registry name of the gateway checker
+	CheckTypeGateway = "gateway"
+
+	// defaultHello is sent to the client port of targets that don't set
+	// their own
+	defaultHello = "HELLO"
+)
+
+// GatewayChecker checks a gateway end to end: a gateway has been seen
+// answering PING while no longer serving clients. It runs the PING/PONG
+// check on the health port, then opens a connection to the client port like
+// a client would, sends the target's hello line and expects an answer line
+// starting with HelloReply (any line if it's empty).
+type GatewayChecker struct {
+	health *HealthChecker
+}
+
+// NewGatewayChecker creates a gateway checker that pings the health port
+// with health
+func NewGatewayChecker(health *HealthChecker) *GatewayChecker {
+	return &GatewayChecker{health: health}
+}
+
+// Check implements Checker
+func (gc *GatewayChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+	if err := gc.health.Ping(ctx, target.Host, target.Port); err != nil {
+		return newCheckResult(CheckTypeGateway, start, err)
+	}
+	if target.ClientPort == "" {
+		return newCheckResult(CheckTypeGateway, start, errors.New("gateway check without a client port"))
+	}
+	return newCheckResult(CheckTypeGateway, start, hello(ctx, target))
+}
+
+// hello sends the hello line to the target's client port and checks the
+// answer
+func hello(ctx context.Context, target CheckTarget) error {
+	address := net.JoinHostPort(target.Host, target.ClientPort)
+	conn, err := dialer.DialContext(ctx, "tcp", address, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to client port %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(readTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set deadline for %s: %w", address, err)
+	}
+
+	message := target.Hello
+	if message == "" {
+		message = defaultHello
+	}
+	if _, err := conn.Write([]byte(message + "\n")); err != nil {
+		return fmt.Errorf("failed to send hello to %s: %w", address, err)
+	}
+
+	// A half-alive gateway accepts the connection but never answers
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if err != nil && answer == "" {
+		return fmt.Errorf("gateway at %s accepted the connection but didn't answer the hello: %w", address, err)
+	}
+	if !strings.HasPrefix(answer, target.HelloReply) {
+		return WithReason(events.ReasonBadResponse,
+			fmt.Errorf("unexpected hello answer from %s: got '%s', expected '%s...'", address, answer, target.HelloReply))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package monitor
+
+import (
+	"context"
+	"time"
+)
+
+// CheckTypeGossip is the registry name of the gossip membership checker
+const CheckTypeGossip = "gossip"
+
+// Membership is a failure detector that reaches its own verdicts about
+// members, such as the gossip detector. Verdict returns nil while a member
+// is considered alive.
+type Membership interface {
+	Verdict(name string) error
+}
+
+// GossipChecker reads a target's health from a failure detector instead of
+// probing it, so the leader only acts on what the group already agreed on.
+// Members are looked up by the target's host.
+type GossipChecker struct {
+	membership Membership
+}
+
+// NewGossipChecker creates a checker backed by a failure detector
+func NewGossipChecker(membership Membership) *GossipChecker {
+	return &GossipChecker{membership: membership}
+}
+
+// Check implements Checker
+func (gc *GossipChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+	return newCheckResult(CheckTypeGossip, start, gc.membership.Verdict(target.Host))
+}
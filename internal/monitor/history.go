@@ -0,0 +1,187 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Sample is a check result as kept in the history
+type Sample struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Healthy   bool          `json:"healthy"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Incident is a run of consecutive failed checks
+type Incident struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"` // first healthy check after the incident, or the last failure if ongoing
+	Ongoing bool      `json:"ongoing"`
+	Error   string    `json:"error"` // error of the first failed check
+}
+
+// Duration returns how long the incident lasted (so far)
+func (i Incident) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// History keeps the last results of every target in fixed-size ring buffers
+type History struct {
+	size    int
+	mu      sync.RWMutex
+	samples map[string][]Sample // per target, oldest first, at most size long
+}
+
+// NewHistory creates a history keeping up to size results per target
+func NewHistory(size int) *History {
+	return &History{size: size, samples: make(map[string][]Sample)}
+}
+
+// Add records a check result for a target, evicting its oldest one if full
+func (h *History) Add(name string, result CheckResult) {
+	sample := Sample{Timestamp: result.Timestamp, Healthy: result.Healthy, Duration: result.Duration}
+	if result.Err != nil {
+		sample.Error = result.Err.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	samples := append(h.samples[name], sample)
+	if len(samples) > h.size {
+		samples = samples[len(samples)-h.size:]
+	}
+	h.samples[name] = samples
+}
+
+// Samples returns a copy of a target's results, oldest first
+func (h *History) Samples(name string) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]Sample(nil), h.samples[name]...)
+}
+
+// Uptime returns the share of the recorded time a target was healthy, as a
+// percentage, weighting each result by the time until the next one. It's
+// 100 when there's nothing recorded.
+func Uptime(samples []Sample) float64 {
+	if len(samples) < 2 {
+		if len(samples) == 1 && !samples[0].Healthy {
+			return 0
+		}
+		return 100
+	}
+
+	var up, total time.Duration
+	for i := 0; i < len(samples)-1; i++ {
+		span := samples[i+1].Timestamp.Sub(samples[i].Timestamp)
+		total += span
+		if samples[i].Healthy {
+			up += span
+		}
+	}
+	if total <= 0 {
+		return 100
+	}
+	return 100 * float64(up) / float64(total)
+}
+
+// Incidents returns the runs of failed checks in the samples, oldest first
+func Incidents(samples []Sample) []Incident {
+	incidents := []Incident{}
+	var current *Incident
+	for _, sample := range samples {
+		switch {
+		case !sample.Healthy && current == nil:
+			current = &Incident{Start: sample.Timestamp, End: sample.Timestamp, Ongoing: true, Error: sample.Error}
+		case !sample.Healthy:
+			current.End = sample.Timestamp
+		case current != nil:
+			current.End = sample.Timestamp
+			current.Ongoing = false
+			incidents = append(incidents, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		incidents = append(incidents, *current)
+	}
+	return incidents
+}
+
+// Save writes the history to a JSON file, replacing it atomically
+func (h *History) Save(path string) error {
+	h.mu.RLock()
+	data, err := json.Marshal(h.samples)
+	h.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode health history: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".history-*")
+	if err != nil {
+		return fmt.Errorf("failed to save health history: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save health history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save health history: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load replaces the history with the one saved at path. A missing file
+// leaves it empty.
+func (h *History) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read health history: %w", err)
+	}
+
+	samples := make(map[string][]Sample)
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return fmt.Errorf("failed to parse health history %s: %w", path, err)
+	}
+	h.Replace(samples)
+	return nil
+}
+
+// All returns a copy of every target's results
+func (h *History) All() map[string][]Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	all := make(map[string][]Sample, len(h.samples))
+	for name, samples := range h.samples {
+		all[name] = append([]Sample(nil), samples...)
+	}
+	return all
+}
+
+// Replace replaces the whole history, keeping the last results of each
+// target if there are more than fit
+func (h *History) Replace(samples map[string][]Sample) {
+	replaced := make(map[string][]Sample, len(samples))
+	for name, s := range samples {
+		if len(s) > h.size {
+			s = s[len(s)-h.size:]
+		}
+		replaced[name] = append([]Sample(nil), s...)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = replaced
+}
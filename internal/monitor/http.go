@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+)
+
+const (
+	// CheckTypeHTTP is the registry name of the HTTP checker
+	CheckTypeHTTP = "http"
+
+	defaultHTTPPath = "/health"
+	httpTimeout     = 2 * time.Second
+)
+
+// HTTPChecker considers a target healthy when GET on its health path returns 2xx
+type HTTPChecker struct {
+	client *http.Client
+}
+
+// NewHTTPChecker creates a new HTTP checker
+func NewHTTPChecker() *HTTPChecker {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address, httpTimeout)
+	}
+	return &HTTPChecker{client: &http.Client{Timeout: httpTimeout, Transport: transport}}
+}
+
+// Check implements Checker
+func (hc *HTTPChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+
+	path := target.Path
+	if path == "" {
+		path = defaultHTTPPath
+	}
+	url := "http://" + net.JoinHostPort(target.Host, target.Port) + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return newCheckResult(CheckTypeHTTP, start, fmt.Errorf("failed to create request for %s: %w", url, err))
+	}
+
+	resp, err := hc.client.Do(req)
+	if err != nil {
+		return newCheckResult(CheckTypeHTTP, start, fmt.Errorf("failed to reach %s: %w", url, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newCheckResult(CheckTypeHTTP, start,
+			WithReason(events.ReasonBadResponse, fmt.Errorf("%s returned status %d", url, resp.StatusCode)))
+	}
+
+	return newCheckResult(CheckTypeHTTP, start, nil)
+}
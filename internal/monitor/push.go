@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// CheckTypePush is the registry name of the push heartbeat checker
+const CheckTypePush = "push"
+
+// PushChecker judges targets by the heartbeats they push to the coordinator
+// instead of probing them. A target is healthy while its last heartbeat is
+// no older than its HeartbeatTimeout.
+type PushChecker struct {
+	mu       sync.RWMutex
+	lastBeat map[string]time.Time
+}
+
+// NewPushChecker creates a new push checker
+func NewPushChecker() *PushChecker {
+	return &PushChecker{lastBeat: make(map[string]time.Time)}
+}
+
+// Beat records a heartbeat from a target
+func (pc *PushChecker) Beat(name string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.lastBeat[name] = time.Now()
+}
+
+// Check implements Checker
+func (pc *PushChecker) Check(ctx context.Context, target CheckTarget) CheckResult {
+	start := time.Now()
+
+	pc.mu.RLock()
+	lastBeat, ok := pc.lastBeat[target.Name]
+	pc.mu.RUnlock()
+
+	if !ok {
+		return newCheckResult(CheckTypePush, start,
+			WithReason(events.ReasonPingTimeout, fmt.Errorf("no heartbeat received from %s", target.Name)))
+	}
+	if silence := start.Sub(lastBeat); silence > target.HeartbeatTimeout {
+		return newCheckResult(CheckTypePush, start,
+			WithReason(events.ReasonPingTimeout, fmt.Errorf("no heartbeat from %s for %v", target.Name, silence.Round(time.Second))))
+	}
+	return newCheckResult(CheckTypePush, start, nil)
+}
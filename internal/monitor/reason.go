@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// reasonError is a check error that knows its reason
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string { return e.err.Error() }
+func (e *reasonError) Unwrap() error { return e.err }
+
+// WithReason marks a check error with one of the events.Reason* reasons,
+// for failures its error alone doesn't tell apart
+func WithReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reasonError{reason: reason, err: err}
+}
+
+// FailureReason returns the reason of a failed check: the one it was marked
+// with, or else what the network error says (timed out or refused)
+func FailureReason(err error) string {
+	var marked *reasonError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &marked):
+		return marked.reason
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return events.ReasonPingRefused
+	case errors.Is(err, context.DeadlineExceeded):
+		return events.ReasonPingTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return events.ReasonPingTimeout
+	}
+	return events.ReasonCheckFailed
+}
@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UngroupedName is the group reported for targets without one
+const UngroupedName = "ungrouped"
+
+// RecoveryStats summarizes how long the targets of a group took to recover:
+// from the first failed check of an incident until the target was verified
+// healthy again. Percentiles are over the most recent incidents; Incidents
+// and Total cover every incident since the coordinator started.
+type RecoveryStats struct {
+	Group     string        `json:"group"`
+	Incidents int           `json:"incidents"`
+	Total     time.Duration `json:"total"`
+	Mean      time.Duration `json:"mean"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+}
+
+// RecoverySamples are the recovery times kept for a group, as exported in
+// snapshots
+type RecoverySamples struct {
+	Samples   []time.Duration `json:"samples"` // oldest first
+	Incidents int             `json:"incidents"`
+	Total     time.Duration   `json:"total"`
+}
+
+// RecoveryTimes keeps the recovery times of the last incidents of every
+// target group
+type RecoveryTimes struct {
+	size    int
+	mu      sync.Mutex
+	samples map[string][]time.Duration // per group, oldest first, at most size long
+	counts  map[string]int
+	totals  map[string]time.Duration
+}
+
+// NewRecoveryTimes creates a store keeping up to size recovery times per group
+func NewRecoveryTimes(size int) *RecoveryTimes {
+	return &RecoveryTimes{
+		size:    size,
+		samples: make(map[string][]time.Duration),
+		counts:  make(map[string]int),
+		totals:  make(map[string]time.Duration),
+	}
+}
+
+// Add records how long an incident of a target in group took to recover
+func (r *RecoveryTimes) Add(group string, d time.Duration) {
+	if group == "" {
+		group = UngroupedName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	samples := append(r.samples[group], d)
+	if len(samples) > r.size {
+		samples = samples[len(samples)-r.size:]
+	}
+	r.samples[group] = samples
+	r.counts[group]++
+	r.totals[group] += d
+}
+
+// Stats returns the recovery stats of every group with at least one
+// recovered incident, sorted by group
+func (r *RecoveryTimes) Stats() []RecoveryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]RecoveryStats, 0, len(r.samples))
+	for group, samples := range r.samples {
+		sorted := append([]time.Duration(nil), samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		var sum time.Duration
+		for _, d := range sorted {
+			sum += d
+		}
+		stats = append(stats, RecoveryStats{
+			Group:     group,
+			Incidents: r.counts[group],
+			Total:     r.totals[group],
+			Mean:      sum / time.Duration(len(sorted)),
+			P50:       percentile(sorted, 0.50),
+			P90:       percentile(sorted, 0.90),
+			P99:       percentile(sorted, 0.99),
+			Max:       sorted[len(sorted)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Group < stats[j].Group })
+	return stats
+}
+
+// Export returns the recovery times kept for every group
+func (r *RecoveryTimes) Export() map[string]RecoverySamples {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := make(map[string]RecoverySamples, len(r.samples))
+	for group, samples := range r.samples {
+		groups[group] = RecoverySamples{
+			Samples:   append([]time.Duration(nil), samples...),
+			Incidents: r.counts[group],
+			Total:     r.totals[group],
+		}
+	}
+	return groups
+}
+
+// Restore replaces the recovery times with exported ones, keeping the most
+// recent size samples of each group
+func (r *RecoveryTimes) Restore(groups map[string]RecoverySamples) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.samples = make(map[string][]time.Duration, len(groups))
+	r.counts = make(map[string]int, len(groups))
+	r.totals = make(map[string]time.Duration, len(groups))
+	for group, exported := range groups {
+		samples := exported.Samples
+		if len(samples) == 0 {
+			continue
+		}
+		if len(samples) > r.size {
+			samples = samples[len(samples)-r.size:]
+		}
+		r.samples[group] = append([]time.Duration(nil), samples...)
+		r.counts[group] = max(exported.Incidents, len(samples))
+		r.totals[group] = exported.Total
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
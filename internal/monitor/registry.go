@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+)
+
+// CheckResult is the outcome of a single health check
+type CheckResult struct {
+	Checker   string
+	Healthy   bool
+	Err       error
+	Timestamp time.Time
+	Duration  time.Duration
+
+	// Reason classifies a failure (see FailureReason); empty when healthy
+	Reason string
+}
+
+// newCheckResult builds a result for a check that started at start
+func newCheckResult(checker string, start time.Time, err error) CheckResult {
+	return CheckResult{
+		Checker:   checker,
+		Healthy:   err == nil,
+		Err:       err,
+		Timestamp: start,
+		Duration:  time.Since(start),
+		Reason:    FailureReason(err),
+	}
+}
+
+// Checker verifies the health of a target using a specific protocol
+type Checker interface {
+	Check(ctx context.Context, target CheckTarget) CheckResult
+}
+
+// Registry maps check type names to checkers
+type Registry struct {
+	checkers map[string]Checker
+	mu       sync.RWMutex
+}
+
+// NewRegistry creates a registry with the built-in checkers registered
+func NewRegistry() *Registry {
+	r := &Registry{checkers: make(map[string]Checker)}
+	health := NewHealthChecker()
+	r.Register(CheckTypeTCP, health)
+	r.Register(CheckTypeGateway, NewGatewayChecker(health))
+	r.Register(CheckTypeHTTP, NewHTTPChecker())
+	r.Register(CheckTypeConnect, NewConnectChecker())
+	return r
+}
+
+// Register adds (or replaces) the checker for a check type
+func (r *Registry) Register(checkType string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[checkType] = checker
+}
+
+// Lookup returns the checker registered for a check type
+func (r *Registry) Lookup(checkType string) (Checker, bool) {
+	if checkType == "" {
+		checkType = CheckTypeTCP
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checker, ok := r.checkers[checkType]
+	return checker, ok
+}
+
+// Check runs the checker configured for the target
+func (r *Registry) Check(ctx context.Context, target CheckTarget) CheckResult {
+	checker, ok := r.Lookup(target.CheckType)
+	if !ok {
+		return CheckResult{
+			Checker:   target.CheckType,
+			Err:       fmt.Errorf("no checker registered for type %q", target.CheckType),
+			Timestamp: time.Now(),
+			Reason:    events.ReasonCheckFailed,
+		}
+	}
+
+	// Checkers that build their own results may leave the reason out
+	result := checker.Check(ctx, target)
+	if result.Err != nil && result.Reason == "" {
+		result.Reason = FailureReason(result.Err)
+	}
+	return result
+}
@@ -0,0 +1,232 @@
+// Package plugin runs external checkers and recovery actions, so teams can
+// add bespoke checks (e.g. "the Q3 results table isn't empty") without
+// forking the coordinator. A plugin is any executable: for each check or
+// recovery the coordinator runs it, writes one JSON Request to its stdin and
+// reads one JSON Response from its stdout.
+//
+//	{"version":1,"operation":"check","target":{"name":"q3-joiner","host":"q3-joiner","port":"12346",...,"params":{"table":"q3"}}}
+//	{"ok":false,"message":"table q3 is empty"}
+//
+// Exiting with a non-zero status, timing out or answering something else
+// than a Response counts as a failed check or recovery too.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+)
+
+// ProtocolVersion is the version of Request sent to plugins
+const ProtocolVersion = 1
+
+// Plugin kinds
+const (
+	KindChecker = "checker"
+	KindAction  = "action"
+)
+
+// Operations a plugin is asked to perform
+const (
+	OperationCheck   = "check"
+	OperationRecover = "recover"
+)
+
+// DefaultTimeout bounds a plugin run when its Plugin doesn't set one. Checks
+// are also bounded by the coordinator's check timeout.
+const DefaultTimeout = 30 * time.Second
+
+// maxOutput bounds what is kept of a plugin's stdout and stderr
+const maxOutput = 64 * 1024
+
+// waitDelay is how long a plugin's output is still read after it's killed
+// or exits: a child it forked may hold stdout open, and would otherwise keep
+// the call waiting past its timeout
+const waitDelay = 2 * time.Second
+
+// Plugin is an external checker or recovery action, registered under Name
+// as a check type or recovery action name
+type Plugin struct {
+	Name    string
+	Kind    string
+	Command []string
+	Timeout time.Duration
+}
+
+// Validate checks that the plugin is usable
+func (p Plugin) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("plugin without name")
+	}
+	if p.Kind != KindChecker && p.Kind != KindAction {
+		return fmt.Errorf("plugin %s: invalid kind %q (expected %s or %s)", p.Name, p.Kind, KindChecker, KindAction)
+	}
+	if len(p.Command) == 0 {
+		return fmt.Errorf("plugin %s: command is required", p.Name)
+	}
+	if p.Timeout < 0 {
+		return fmt.Errorf("plugin %s: negative timeout", p.Name)
+	}
+	return nil
+}
+
+// Request is what a plugin reads from stdin
+type Request struct {
+	Version   int    `json:"version"`
+	Operation string `json:"operation"`
+	Target    Target `json:"target"`
+}
+
+// Target describes the target a plugin runs for
+type Target struct {
+	Name          string            `json:"name"`
+	Host          string            `json:"host"`
+	Port          string            `json:"port,omitempty"`
+	ContainerName string            `json:"container_name,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	DockerHost    string            `json:"docker_host,omitempty"`
+	Params        map[string]string `json:"params,omitempty"`
+}
+
+// Response is what a plugin writes to stdout: whether the target is healthy
+// (checkers) or was recovered (actions), and why not. Checkers can classify
+// the failure with one of the events.Reason* reasons (e.g. queue-stalled).
+type Response struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Register registers every plugin with the checker or action registry.
+// Plugins can't replace the built-in checkers and actions.
+func Register(plugins []Plugin, checkers *monitor.Registry, recoveries *recovery.Registry) error {
+	for _, p := range plugins {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		switch p.Kind {
+		case KindChecker:
+			if _, exists := checkers.Lookup(p.Name); exists {
+				return fmt.Errorf("plugin %s: a checker with that name already exists", p.Name)
+			}
+			checkers.Register(p.Name, &Checker{plugin: p})
+		case KindAction:
+			if _, exists := recoveries.Lookup(p.Name); exists {
+				return fmt.Errorf("plugin %s: a recovery action with that name already exists", p.Name)
+			}
+			recoveries.Register(p.Name, &Action{plugin: p})
+		}
+	}
+	return nil
+}
+
+// Checker is a plugin used as a monitor.Checker
+type Checker struct {
+	plugin Plugin
+}
+
+// Check implements monitor.Checker
+func (c *Checker) Check(ctx context.Context, target monitor.CheckTarget) monitor.CheckResult {
+	start := time.Now()
+	err := c.plugin.run(ctx, OperationCheck, target)
+	return monitor.CheckResult{
+		Checker:   c.plugin.Name,
+		Healthy:   err == nil,
+		Err:       err,
+		Timestamp: start,
+		Duration:  time.Since(start),
+	}
+}
+
+// Action is a plugin used as a recovery.Action
+type Action struct {
+	plugin Plugin
+}
+
+// Recover implements recovery.Action
+func (a *Action) Recover(ctx context.Context, target monitor.CheckTarget) error {
+	return a.plugin.run(ctx, OperationRecover, target)
+}
+
+// run runs the plugin once for target and returns why it failed, if it did
+func (p Plugin) run(ctx context.Context, operation string, target monitor.CheckTarget) error {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(Request{
+		Version:   ProtocolVersion,
+		Operation: operation,
+		Target: Target{
+			Name:          target.Name,
+			Host:          target.Host,
+			Port:          target.Port,
+			ContainerName: target.ContainerName,
+			Group:         target.Group,
+			DockerHost:    target.DockerHost,
+			Params:        target.PluginParams,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("plugin %s: failed to encode request: %w", p.Name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: maxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+
+	// A plugin that answered and exited but left a child holding its
+	// output still answered
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("plugin %s timed out after %v", p.Name, timeout)
+		}
+		return fmt.Errorf("plugin %s failed: %w: %s", p.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var response Response
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return fmt.Errorf("plugin %s returned an invalid response: %w", p.Name, err)
+	}
+	if !response.OK {
+		if response.Message == "" {
+			response.Message = "no reason given"
+		}
+		err := fmt.Errorf("plugin %s: %s", p.Name, response.Message)
+		if response.Reason != "" {
+			err = monitor.WithReason(response.Reason, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a chatty plugin can't exhaust memory
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+// Write implements io.Writer
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
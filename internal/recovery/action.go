@@ -0,0 +1,94 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// Names of the built-in recovery actions
+const (
+	ActionRestart   = "restart"
+	ActionRecreate  = "recreate"
+	ActionKillStart = "kill-start"
+	ActionExec      = "exec"
+	ActionWebhook   = "webhook"
+	ActionSwarm     = "swarm"
+	ActionNone      = "none"
+)
+
+// Action recovers an unhealthy target
+type Action interface {
+	Recover(ctx context.Context, target monitor.CheckTarget) error
+}
+
+// ActionFunc adapts a function to the Action interface
+type ActionFunc func(ctx context.Context, target monitor.CheckTarget) error
+
+// Recover implements Action
+func (f ActionFunc) Recover(ctx context.Context, target monitor.CheckTarget) error {
+	return f(ctx, target)
+}
+
+// Registry maps recovery action names to actions
+type Registry struct {
+	actions map[string]Action
+	mu      sync.RWMutex
+
+	// resolve finds the Docker runtime hook commands run on, once the
+	// Docker actions are registered
+	resolve RuntimeResolver
+}
+
+// NewRegistry creates a registry with the "none" action registered
+func NewRegistry() *Registry {
+	r := &Registry{actions: make(map[string]Action)}
+	r.Register(ActionNone, NotifyOnly{})
+	return r
+}
+
+// Register adds (or replaces) the action for a name
+func (r *Registry) Register(name string, action Action) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions[name] = action
+}
+
+// Lookup returns the action registered for a name
+func (r *Registry) Lookup(name string) (Action, bool) {
+	if name == "" {
+		name = ActionRestart
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	action, ok := r.actions[name]
+	return action, ok
+}
+
+// ActionName returns the effective recovery action name for a target
+func ActionName(target monitor.CheckTarget) string {
+	if target.Recovery == "" {
+		return ActionRestart
+	}
+	return target.Recovery
+}
+
+// Recover runs the recovery action configured for the target
+func (r *Registry) Recover(ctx context.Context, target monitor.CheckTarget) error {
+	action, ok := r.Lookup(target.Recovery)
+	if !ok {
+		return fmt.Errorf("no recovery action registered for %q", ActionName(target))
+	}
+	return action.Recover(ctx, target)
+}
+
+// NotifyOnly is the "none" action: failures are reported but nothing is touched
+type NotifyOnly struct{}
+
+// Recover implements Action
+func (NotifyOnly) Recover(context.Context, monitor.CheckTarget) error {
+	return nil
+}
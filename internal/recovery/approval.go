@@ -0,0 +1,74 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// Policies for a restart whose confirmation times out
+const (
+	ConfirmDefaultSkip    = "skip"    // don't restart (the default)
+	ConfirmDefaultRestart = "restart" // restart anyway
+)
+
+// approvalRequest is the body POSTed to confirmation webhooks
+type approvalRequest struct {
+	Target        string    `json:"target"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Action        string    `json:"action"`
+	Evidence      string    `json:"evidence,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Approval is a confirmation webhook's answer
+type Approval struct {
+	Approved bool   `json:"approved"`
+	Approver string `json:"approver,omitempty"`
+}
+
+// RequestApproval asks the target's confirmation webhook whether it may be
+// restarted with action. The webhook answers 2xx with an Approval; any other
+// answer is an error, not a denial, so the caller can fall back to waiting
+// for an operator.
+func RequestApproval(ctx context.Context, target monitor.CheckTarget, action, evidence string) (Approval, error) {
+	body, err := json.Marshal(approvalRequest{
+		Target:        target.Name,
+		ContainerName: target.ContainerName,
+		Action:        action,
+		Evidence:      evidence,
+		Timestamp:     time.Now().UTC(),
+	})
+	if err != nil {
+		return Approval{}, fmt.Errorf("failed to encode approval request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.ConfirmWebhook, bytes.NewReader(body))
+	if err != nil {
+		return Approval{}, fmt.Errorf("failed to create approval request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Approval{}, fmt.Errorf("confirmation webhook %s failed: %w", target.ConfirmWebhook, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Approval{}, fmt.Errorf("confirmation webhook %s returned status %d", target.ConfirmWebhook, resp.StatusCode)
+	}
+	var approval Approval
+	if err := json.NewDecoder(resp.Body).Decode(&approval); err != nil {
+		return Approval{}, fmt.Errorf("confirmation webhook %s returned an invalid answer: %w", target.ConfirmWebhook, err)
+	}
+	if approval.Approver == "" {
+		approval.Approver = target.ConfirmWebhook
+	}
+	return approval, nil
+}
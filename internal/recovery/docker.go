@@ -0,0 +1,139 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// DockerRuntime is the subset of the Docker client used by recovery actions
+type DockerRuntime interface {
+	RestartContainer(ctx context.Context, containerNameOrID string, stopTimeout time.Duration) error
+	StartContainer(ctx context.Context, containerNameOrID string) error
+	KillContainer(ctx context.Context, containerNameOrID string) error
+	RecreateContainer(ctx context.Context, containerNameOrID string) error
+	Exec(ctx context.Context, containerNameOrID string, cmd []string) (docker.ExecResult, error)
+	ForceUpdateService(ctx context.Context, serviceNameOrID string) error
+	CreateContainer(ctx context.Context, name string, spec docker.ContainerSpec) (string, error)
+}
+
+// RuntimeResolver returns the DockerRuntime for the Docker host a target runs on
+type RuntimeResolver func(dockerHost string) (DockerRuntime, error)
+
+// RegisterDocker registers the Docker-backed recovery actions
+func RegisterDocker(r *Registry, resolve RuntimeResolver) {
+	r.mu.Lock()
+	r.resolve = resolve
+	r.mu.Unlock()
+
+	// withRuntime resolves the target's Docker host before running fn
+	withRuntime := func(fn func(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error) Action {
+		return ActionFunc(func(ctx context.Context, target monitor.CheckTarget) error {
+			runtime, err := resolve(target.DockerHost)
+			if err != nil {
+				return err
+			}
+			return fn(ctx, runtime, target)
+		})
+	}
+
+	r.Register(ActionRestart, withRuntime(func(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+		drain(ctx, target)
+		err := restartWithEscalation(ctx, runtime, target)
+		if errors.Is(err, docker.ErrNotFound) && target.ContainerSpec != nil {
+			log.Printf("Container %s no longer exists, recreating it from its compose definition", target.ContainerName)
+			return recreateFromSpec(ctx, runtime, target)
+		}
+		return err
+	}))
+
+	r.Register(ActionRecreate, withRuntime(func(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+		drain(ctx, target)
+		return runtime.RecreateContainer(ctx, target.ContainerName)
+	}))
+
+	r.Register(ActionKillStart, withRuntime(func(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+		if err := runtime.KillContainer(ctx, target.ContainerName); err != nil {
+			return err
+		}
+		return runtime.StartContainer(ctx, target.ContainerName)
+	}))
+
+	r.Register(ActionSwarm, withRuntime(func(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+		if target.SwarmService == "" {
+			return fmt.Errorf("target %s has no swarm service", target.Name)
+		}
+		return runtime.ForceUpdateService(ctx, target.SwarmService)
+	}))
+
+	r.Register(ActionExec, withRuntime(func(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+		if len(target.RecoveryCommand) == 0 {
+			return fmt.Errorf("target %s has no recovery command", target.Name)
+		}
+
+		result, err := runtime.Exec(ctx, target.ContainerName, target.RecoveryCommand)
+		if err != nil {
+			return err
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("%s in %s exited with code %d: %s", strings.Join(target.RecoveryCommand, " "),
+				target.ContainerName, result.ExitCode, strings.TrimSpace(result.Output))
+		}
+		return nil
+	}))
+}
+
+// drain asks the target to drain before it's stopped, when it has a drain
+// timeout. Failing to drain (a hung worker can't answer) doesn't block the
+// restart, it just means in-flight work may be redelivered.
+func drain(ctx context.Context, target monitor.CheckTarget) {
+	if target.DrainTimeout <= 0 {
+		return
+	}
+
+	log.Printf("Draining %s before stopping it (up to %v)", target.Name, target.DrainTimeout)
+	if err := monitor.NewHealthChecker().Drain(ctx, target.Host, target.Port, target.DrainTimeout); err != nil {
+		log.Printf("WARNING: %v, forcing restart of %s", err, target.Name)
+		return
+	}
+	log.Printf("%s drained", target.Name)
+}
+
+// restartWithEscalation restarts the target's container and, if the restart
+// hasn't finished by the target's restart deadline (e.g. a worker ignoring
+// SIGTERM with a long stop timeout), kills and starts it instead so recovery
+// time stays bounded
+func restartWithEscalation(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+	if target.RestartDeadline <= 0 {
+		return runtime.RestartContainer(ctx, target.ContainerName, target.StopTimeout)
+	}
+
+	restartCtx, cancel := context.WithTimeout(ctx, target.RestartDeadline)
+	defer cancel()
+
+	err := runtime.RestartContainer(restartCtx, target.ContainerName, target.StopTimeout)
+	if err == nil || restartCtx.Err() == nil || ctx.Err() != nil {
+		return err
+	}
+
+	log.Printf("Restart of %s exceeded %v, escalating to kill", target.ContainerName, target.RestartDeadline)
+	if err := runtime.KillContainer(ctx, target.ContainerName); err != nil {
+		return err
+	}
+	return runtime.StartContainer(ctx, target.ContainerName)
+}
+
+// recreateFromSpec creates and starts the target's container from its spec
+func recreateFromSpec(ctx context.Context, runtime DockerRuntime, target monitor.CheckTarget) error {
+	id, err := runtime.CreateContainer(ctx, target.ContainerName, *target.ContainerSpec)
+	if err != nil {
+		return err
+	}
+	return runtime.StartContainer(ctx, id)
+}
@@ -0,0 +1,136 @@
+package recovery_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker/dockertest"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/recovery"
+)
+
+// registry returns a registry whose Docker actions run on runtime
+func registry(runtime recovery.DockerRuntime) *recovery.Registry {
+	r := recovery.NewRegistry()
+	recovery.RegisterDocker(r, func(string) (recovery.DockerRuntime, error) { return runtime, nil })
+	return r
+}
+
+func methods(calls []dockertest.Call) []string {
+	names := []string{}
+	for _, call := range calls {
+		names = append(names, call.Method)
+	}
+	return names
+}
+
+func TestDockerActions(t *testing.T) {
+	tests := []struct {
+		action string
+		target monitor.CheckTarget
+		calls  []string
+	}{
+		{recovery.ActionRestart, monitor.CheckTarget{}, []string{"RestartContainer"}},
+		{recovery.ActionRecreate, monitor.CheckTarget{}, []string{"RecreateContainer"}},
+		{recovery.ActionKillStart, monitor.CheckTarget{}, []string{"KillContainer", "StartContainer"}},
+		{recovery.ActionSwarm, monitor.CheckTarget{SwarmService: "filter-1"}, []string{"ForceUpdateService"}},
+		{recovery.ActionExec, monitor.CheckTarget{RecoveryCommand: []string{"kill", "-HUP", "1"}}, []string{"Exec"}},
+		{recovery.ActionNone, monitor.CheckTarget{}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.action, func(t *testing.T) {
+			runtime := dockertest.NewRuntime("filter-1")
+			target := test.target
+			target.Name, target.ContainerName, target.Recovery = "filter-1", "filter-1", test.action
+			if err := registry(runtime).Recover(context.Background(), target); err != nil {
+				t.Fatal(err)
+			}
+			if calls := methods(runtime.Calls()); !reflect.DeepEqual(calls, test.calls) {
+				t.Errorf("calls %v, want %v", calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestExecActionFailsOnANonZeroExit(t *testing.T) {
+	runtime := dockertest.NewRuntime("filter-1")
+	runtime.ExecFunc = func(string, []string) docker.ExecResult {
+		return docker.ExecResult{ExitCode: 1, Output: "no such process"}
+	}
+	target := monitor.CheckTarget{Name: "filter-1", ContainerName: "filter-1", Recovery: recovery.ActionExec, RecoveryCommand: []string{"reload"}}
+	if err := registry(runtime).Recover(context.Background(), target); err == nil {
+		t.Error("a recovery command exiting 1 succeeded")
+	}
+}
+
+func TestRestartRecreatesAMissingContainer(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	runtime := dockertest.NewRuntime()
+	target := monitor.CheckTarget{
+		Name:          "filter-1",
+		ContainerName: "filter-1",
+		ContainerSpec: &docker.ContainerSpec{Image: "filter:latest", Labels: map[string]string{"role": "filter"}},
+	}
+	if err := registry(runtime).Recover(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"RestartContainer", "CreateContainer", "StartContainer"}
+	if calls := methods(runtime.Calls()); !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+	container, ok := runtime.Container("filter-1")
+	if !ok || !container.Running || container.Labels["role"] != "filter" {
+		t.Errorf("container %+v (exists %v), want it created from its spec and running", container, ok)
+	}
+}
+
+func TestRestartOfAMissingContainerWithoutSpecFails(t *testing.T) {
+	runtime := dockertest.NewRuntime()
+	target := monitor.CheckTarget{Name: "filter-1", ContainerName: "filter-1"}
+	if err := registry(runtime).Recover(context.Background(), target); !errors.Is(err, docker.ErrNotFound) {
+		t.Errorf("restart: %v, want not found", err)
+	}
+	if _, ok := runtime.Container("filter-1"); ok {
+		t.Error("a container without a spec was created")
+	}
+}
+
+// hangingRuntime never finishes restarting, like a worker ignoring SIGTERM
+// with a long stop timeout
+type hangingRuntime struct {
+	*dockertest.Runtime
+}
+
+func (h hangingRuntime) RestartContainer(ctx context.Context, name string, stopTimeout time.Duration) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRestartEscalatesToKillPastItsDeadline(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	runtime := dockertest.NewRuntime("filter-1")
+	target := monitor.CheckTarget{Name: "filter-1", ContainerName: "filter-1", RestartDeadline: 10 * time.Millisecond}
+	if err := registry(hangingRuntime{runtime}).Recover(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"KillContainer", "StartContainer"}
+	if calls := methods(runtime.Calls()); !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+	if container, _ := runtime.Container("filter-1"); !container.Running {
+		t.Error("the container wasn't started again")
+	}
+}
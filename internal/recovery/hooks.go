@@ -0,0 +1,116 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// Hook stages
+const (
+	HookPreRestart   = "pre_restart"
+	HookPostRecovery = "post_recovery"
+)
+
+const hookTimeout = 30 * time.Second
+
+// hookPayload is the body POSTed to hook webhooks
+type hookPayload struct {
+	Target        string    `json:"target"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Stage         string    `json:"stage"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// HookFor returns the target's hook for a stage, nil if it has none
+func HookFor(target monitor.CheckTarget, stage string) *monitor.Hook {
+	if stage == HookPostRecovery {
+		return target.PostRecovery
+	}
+	return target.PreRestart
+}
+
+// RunHook runs the target's hook for a stage, if it has one: its command in
+// the target's container, then its webhook. Any 2xx answer counts as
+// success.
+func (r *Registry) RunHook(ctx context.Context, target monitor.CheckTarget, stage string) error {
+	hook := HookFor(target, stage)
+	if hook == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	if len(hook.Command) > 0 {
+		if err := r.runHookCommand(ctx, target, hook.Command); err != nil {
+			return fmt.Errorf("%s hook of %s: %w", stage, target.Name, err)
+		}
+	}
+	if hook.Webhook != "" {
+		if err := postHook(ctx, hook.Webhook, target, stage); err != nil {
+			return fmt.Errorf("%s hook of %s: %w", stage, target.Name, err)
+		}
+	}
+	return nil
+}
+
+// runHookCommand runs a hook command in the target's container
+func (r *Registry) runHookCommand(ctx context.Context, target monitor.CheckTarget, command []string) error {
+	r.mu.RLock()
+	resolve := r.resolve
+	r.mu.RUnlock()
+	if resolve == nil || target.ContainerName == "" {
+		return fmt.Errorf("no container to run %q in", strings.Join(command, " "))
+	}
+
+	runtime, err := resolve(target.DockerHost)
+	if err != nil {
+		return err
+	}
+	result, err := runtime.Exec(ctx, target.ContainerName, command)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s exited with code %d: %s", strings.Join(command, " "), result.ExitCode,
+			strings.TrimSpace(result.Output))
+	}
+	return nil
+}
+
+// postHook calls a hook webhook
+func postHook(ctx context.Context, url string, target monitor.CheckTarget, stage string) error {
+	body, err := json.Marshal(hookPayload{
+		Target:        target.Name,
+		ContainerName: target.ContainerName,
+		Stage:         stage,
+		Timestamp:     time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// ActionSSH runs the target's recovery command on its host over SSH
+	ActionSSH = "ssh"
+
+	sshDialTimeout = 10 * time.Second
+	defaultSSHPort = 22
+)
+
+// SSHHostConfig holds the credentials used to reach a host over SSH
+type SSHHostConfig struct {
+	User           string `yaml:"user"`
+	Port           int    `yaml:"port"`
+	KeyFile        string `yaml:"key_file"`
+	KnownHostsFile string `yaml:"known_hosts_file"`
+}
+
+// SSHExecutor runs commands on remote hosts over SSH. Host keys are always
+// verified against a known_hosts file; there is no insecure mode.
+type SSHExecutor struct {
+	hosts    map[string]SSHHostConfig
+	defaults SSHHostConfig
+}
+
+// NewSSHExecutor creates an executor with per-host credentials. Hosts not in
+// the map use defaults.
+func NewSSHExecutor(hosts map[string]SSHHostConfig, defaults SSHHostConfig) *SSHExecutor {
+	return &SSHExecutor{hosts: hosts, defaults: defaults}
+}
+
+// Runner returns a CommandRunner that runs commands on the given host
+func (e *SSHExecutor) Runner(host string) CommandRunner {
+	return sshRunner{executor: e, host: host}
+}
+
+// configFor merges the host's credentials with the defaults
+func (e *SSHExecutor) configFor(host string) SSHHostConfig {
+	config := e.defaults
+	if hostConfig, ok := e.hosts[host]; ok {
+		if hostConfig.User != "" {
+			config.User = hostConfig.User
+		}
+		if hostConfig.Port != 0 {
+			config.Port = hostConfig.Port
+		}
+		if hostConfig.KeyFile != "" {
+			config.KeyFile = hostConfig.KeyFile
+		}
+		if hostConfig.KnownHostsFile != "" {
+			config.KnownHostsFile = hostConfig.KnownHostsFile
+		}
+	}
+	if config.Port == 0 {
+		config.Port = defaultSSHPort
+	}
+	return config
+}
+
+// Run runs a command on a host and returns its combined output
+func (e *SSHExecutor) Run(ctx context.Context, host string, cmd []string) (string, error) {
+	config := e.configFor(host)
+	if config.User == "" || config.KeyFile == "" || config.KnownHostsFile == "" {
+		return "", fmt.Errorf("incomplete SSH credentials for host %s (user, key_file and known_hosts_file are required)", host)
+	}
+
+	key, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SSH key for host %s: %w", host, err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse SSH key for host %s: %w", host, err)
+	}
+
+	hostKeyCallback, err := knownhosts.New(config.KnownHostsFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to load known hosts for host %s: %w", host, err)
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            config.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(config.Port))
+	conn, err := dialer.DialContext(ctx, "tcp", address, sshDialTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, clientConfig)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("SSH handshake with %s failed: %w", address, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to open SSH session on %s: %w", address, err)
+	}
+	defer session.Close()
+
+	// Abort the command if the context expires
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	command := shellJoin(cmd)
+	output, err := session.CombinedOutput(command)
+	if err != nil {
+		return string(output), fmt.Errorf("%s on %s failed: %w: %s", command, host, err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// sshRunner binds an SSHExecutor to a host
+type sshRunner struct {
+	executor *SSHExecutor
+	host     string
+}
+
+// Run implements CommandRunner
+func (r sshRunner) Run(ctx context.Context, cmd []string) (string, error) {
+	return r.executor.Run(ctx, r.host, cmd)
+}
+
+// SSHCommand runs the target's recovery command (e.g. "docker restart X" or
+// "systemctl restart Y") on the target's host
+type SSHCommand struct {
+	executor *SSHExecutor
+}
+
+// NewSSHCommand creates the ssh recovery action
+func NewSSHCommand(executor *SSHExecutor) *SSHCommand {
+	return &SSHCommand{executor: executor}
+}
+
+// Recover implements Action
+func (a *SSHCommand) Recover(ctx context.Context, target monitor.CheckTarget) error {
+	if len(target.RecoveryCommand) == 0 {
+		return fmt.Errorf("target %s has no recovery command", target.Name)
+	}
+
+	_, err := a.executor.Run(ctx, sshHost(target), target.RecoveryCommand)
+	return err
+}
+
+// sshHost returns the host to SSH into for a target
+func sshHost(target monitor.CheckTarget) string {
+	if target.SSHHost != "" {
+		return target.SSHHost
+	}
+	return target.Host
+}
+
+// shellJoin quotes each argument for a POSIX shell
+func shellJoin(cmd []string) string {
+	quoted := make([]string, len(cmd))
+	for i, arg := range cmd {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
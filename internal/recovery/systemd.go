@@ -0,0 +1,69 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// ActionSystemd restarts the target's systemd unit
+const ActionSystemd = "systemd"
+
+// CommandRunner runs a command on behalf of a recovery action and returns its
+// combined output
+type CommandRunner interface {
+	Run(ctx context.Context, cmd []string) (string, error)
+}
+
+// LocalRunner runs commands on the coordinator's own host
+type LocalRunner struct{}
+
+// Run implements CommandRunner
+func (LocalRunner) Run(ctx context.Context, cmd []string) (string, error) {
+	if len(cmd) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	output, err := exec.CommandContext(ctx, cmd[0], cmd[1:]...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%s failed: %w: %s", strings.Join(cmd, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// Systemd restarts a systemd unit for nodes that don't run in containers.
+// Targets with an SSH host are restarted remotely; otherwise systemctl runs
+// locally and talks to systemd over the system D-Bus, so when the coordinator
+// itself runs in a container the host's /run/systemd and /run/dbus must be
+// mounted.
+type Systemd struct {
+	runner CommandRunner
+	ssh    *SSHExecutor
+}
+
+// NewSystemd creates a systemd action that runs systemctl with the given
+// local runner, or over SSH when ssh is not nil and the target has an SSH host
+func NewSystemd(runner CommandRunner, ssh *SSHExecutor) *Systemd {
+	return &Systemd{runner: runner, ssh: ssh}
+}
+
+// Recover implements Action
+func (s *Systemd) Recover(ctx context.Context, target monitor.CheckTarget) error {
+	if target.Unit == "" {
+		return fmt.Errorf("target %s has no systemd unit", target.Name)
+	}
+
+	runner := s.runner
+	if target.SSHHost != "" {
+		if s.ssh == nil {
+			return fmt.Errorf("target %s requires SSH but no SSH credentials are configured", target.Name)
+		}
+		runner = s.ssh.Runner(target.SSHHost)
+	}
+
+	_, err := runner.Run(ctx, []string{"systemctl", "restart", target.Unit})
+	return err
+}
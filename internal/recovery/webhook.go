@@ -0,0 +1,78 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// webhookPayload is the body POSTed to recovery webhooks
+type webhookPayload struct {
+	Target        string    `json:"target"`
+	Host          string    `json:"host"`
+	Port          string    `json:"port"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Webhook delegates recovery to an external system by POSTing the target as
+// JSON. Any 2xx response counts as success.
+type Webhook struct {
+	defaultURL string
+	client     *http.Client
+}
+
+// NewWebhook creates a webhook action. defaultURL is used for targets that
+// don't set their own WebhookURL.
+func NewWebhook(defaultURL string) *Webhook {
+	return &Webhook{
+		defaultURL: defaultURL,
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Recover implements Action
+func (w *Webhook) Recover(ctx context.Context, target monitor.CheckTarget) error {
+	url := target.WebhookURL
+	if url == "" {
+		url = w.defaultURL
+	}
+	if url == "" {
+		return fmt.Errorf("target %s has no webhook URL", target.Name)
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Target:        target.Name,
+		Host:          target.Host,
+		Port:          target.Port,
+		ContainerName: target.ContainerName,
+		Timestamp:     time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package scaling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/docker"
+)
+
+// LabelScaledFrom marks extra replicas with the container they were cloned
+// from, so they're found again after a restart or a leader change
+const LabelScaledFrom = "coffeeshop.scaled-from"
+
+// maxNameAttempts bounds how many names are tried when the next one is taken
+// by a container the scaler doesn't own
+const maxNameAttempts = 10
+
+// Rule scales a worker by cloning its container while its input queue is
+// backed up. Extras are added one at a time while the backlog is at or above
+// ScaleUpBacklog and removed one at a time once it drops to ScaleDownBacklog.
+type Rule struct {
+	Template         string // container to clone
+	DockerHost       string // empty means the local daemon
+	VHost            string
+	Queue            string
+	ScaleUpBacklog   int
+	ScaleDownBacklog int
+	MaxExtra         int
+	Cooldown         time.Duration // minimum time between two scaling steps
+	StopTimeout      time.Duration
+}
+
+// Validate checks that the rule can be applied
+func (r Rule) Validate() error {
+	switch {
+	case r.Template == "":
+		return fmt.Errorf("autoscale rule has no template container")
+	case r.Queue == "":
+		return fmt.Errorf("autoscale rule for %s has no queue", r.Template)
+	case r.MaxExtra <= 0:
+		return fmt.Errorf("autoscale rule for %s needs max_extra > 0", r.Template)
+	case r.ScaleDownBacklog >= r.ScaleUpBacklog:
+		return fmt.Errorf("autoscale rule for %s needs scale_down_backlog < scale_up_backlog", r.Template)
+	}
+	return nil
+}
+
+// Runtime is the subset of the Docker client the scaler needs
+type Runtime interface {
+	ListContainers(ctx context.Context, labels map[string]string) ([]docker.ContainerSummary, error)
+	CloneContainer(ctx context.Context, source, name string, labels map[string]string) (string, error)
+	StartContainer(ctx context.Context, containerNameOrID string) error
+	StopContainer(ctx context.Context, containerNameOrID string, stopTimeout time.Duration) error
+	RemoveContainer(ctx context.Context, containerNameOrID string) error
+}
+
+// RuntimeResolver returns the runtime for a Docker host
+type RuntimeResolver func(host string) (Runtime, error)
+
+// Change is a scaling step taken by the scaler
+type Change struct {
+	Template  string
+	Container string
+	Up        bool
+	Backlog   int
+}
+
+// Scaler starts and removes extra worker replicas following the backlog of
+// their input queues
+type Scaler struct {
+	rules    []Rule
+	backlog  BacklogSource
+	runtimes RuntimeResolver
+
+	mu         sync.Mutex
+	lastScaled map[string]time.Time
+}
+
+// NewScaler creates a scaler for the given rules
+func NewScaler(rules []Rule, backlog BacklogSource, runtimes RuntimeResolver) *Scaler {
+	return &Scaler{
+		rules:      rules,
+		backlog:    backlog,
+		runtimes:   runtimes,
+		lastScaled: make(map[string]time.Time),
+	}
+}
+
+// Reconcile evaluates every rule once and returns the steps it took. Errors
+// are logged per rule so one unreachable queue doesn't block the others.
+func (s *Scaler) Reconcile(ctx context.Context) []Change {
+	changes := []Change{}
+	for _, rule := range s.rules {
+		change, err := s.reconcileRule(ctx, rule)
+		if err != nil {
+			log.Printf("WARNING: Autoscaling %s failed: %v", rule.Template, err)
+			continue
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes
+}
+
+// reconcileRule takes at most one scaling step for a rule
+func (s *Scaler) reconcileRule(ctx context.Context, rule Rule) (*Change, error) {
+	s.mu.Lock()
+	lastScaled := s.lastScaled[rule.Template]
+	s.mu.Unlock()
+	if time.Since(lastScaled) < rule.Cooldown {
+		return nil, nil
+	}
+
+	vhost := rule.VHost
+	if vhost == "" {
+		vhost = "/"
+	}
+	backlog, err := s.backlog.Backlog(ctx, vhost, rule.Queue)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime, err := s.runtimes(rule.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+	extras, err := listExtras(ctx, runtime, rule.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var change *Change
+	switch {
+	case backlog >= rule.ScaleUpBacklog && len(extras) < rule.MaxExtra:
+		name, err := scaleUp(ctx, runtime, rule.Template, extras)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Scaled up %s: started %s (backlog %d on %s)", rule.Template, name, backlog, rule.Queue)
+		change = &Change{Template: rule.Template, Container: name, Up: true, Backlog: backlog}
+
+	case backlog <= rule.ScaleDownBacklog && len(extras) > 0:
+		// The newest replica goes first
+		name := extras[len(extras)-1].name
+		if err := runtime.StopContainer(ctx, name, rule.StopTimeout); err != nil && !errors.Is(err, docker.ErrNotFound) {
+			return nil, err
+		}
+		if err := runtime.RemoveContainer(ctx, name); err != nil && !errors.Is(err, docker.ErrNotFound) {
+			return nil, err
+		}
+		log.Printf("Scaled down %s: removed %s (backlog %d on %s)", rule.Template, name, backlog, rule.Queue)
+		change = &Change{Template: rule.Template, Container: name, Up: false, Backlog: backlog}
+
+	default:
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	s.lastScaled[rule.Template] = time.Now()
+	s.mu.Unlock()
+	return change, nil
+}
+
+// extra is a replica started by the scaler
+type extra struct {
+	name   string
+	number int
+}
+
+// listExtras returns the template's extra replicas, oldest (lowest number) first
+func listExtras(ctx context.Context, runtime Runtime, template string) ([]extra, error) {
+	containers, err := runtime.ListContainers(ctx, map[string]string{LabelScaledFrom: template})
+	if err != nil {
+		return nil, err
+	}
+
+	extras := make([]extra, 0, len(containers))
+	for _, container := range containers {
+		_, number := splitName(container.Name())
+		extras = append(extras, extra{name: container.Name(), number: number})
+	}
+	sort.Slice(extras, func(i, j int) bool { return extras[i].number < extras[j].number })
+	return extras, nil
+}
+
+// scaleUp clones the template under the next free name and starts it. Names
+// continue the template's numbering (joiner-1 -> joiner-2, joiner-3, ...),
+// skipping names already taken by other containers.
+func scaleUp(ctx context.Context, runtime Runtime, template string, extras []extra) (string, error) {
+	base, number := splitName(template)
+	for _, e := range extras {
+		number = max(number, e.number)
+	}
+
+	labels := map[string]string{LabelScaledFrom: template}
+	for attempt := 0; attempt < maxNameAttempts; attempt++ {
+		number++
+		name := fmt.Sprintf("%s-%d", base, number)
+
+		id, err := runtime.CloneContainer(ctx, template, name, labels)
+		if errors.Is(err, docker.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			if id != "" {
+				// Created but not fully wired up: don't leave it behind
+				runtime.RemoveContainer(ctx, id)
+			}
+			return "", err
+		}
+
+		if err := runtime.StartContainer(ctx, id); err != nil {
+			runtime.RemoveContainer(ctx, id)
+			return "", err
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no free container name for %s after %d attempts", template, maxNameAttempts)
+}
+
+// splitName splits a container name into its base and trailing replica
+// number ("joiner-3" -> "joiner", 3). Names without a number count as 1.
+func splitName(name string) (string, int) {
+	i := strings.LastIndexAny(name, "-_")
+	if i < 0 {
+		return name, 1
+	}
+	number, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return name, 1
+	}
+	return name[:i], number
+}
@@ -0,0 +1,81 @@
+// Package statesync replicates the leader's recovery state (restart counts,
+// quarantines, targets still recovering, ...) to the followers, so whoever
+// takes over after a failover resumes the same policies instead of starting
+// from scratch. The leader pushes a full snapshot every round over TCP; a
+// lost one is simply replaced by the next.
+package statesync
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/dialer"
+)
+
+const (
+	dialTimeout = time.Second
+	ioTimeout   = 2 * time.Second
+)
+
+// State is a snapshot of the leader's recovery state
+type State struct {
+	From          int                  `json:"from"`
+	Term          uint64               `json:"term"` // election term of the leader that sent it
+	Timestamp     time.Time            `json:"timestamp"`
+	RestartCounts map[string]int       `json:"restart_counts,omitempty"`
+	Failures      map[string]int       `json:"failures,omitempty"`
+	Quarantined   []string             `json:"quarantined,omitempty"`
+	Recovering    map[string]time.Time `json:"recovering,omitempty"`
+	BusyUntil     time.Time            `json:"busy_until,omitempty"`
+}
+
+// Send delivers a snapshot to the coordinator at address (host:port)
+func Send(address string, state State) error {
+	conn, err := dialer.Dial("tcp", address, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+	if err := json.NewEncoder(conn).Encode(state); err != nil {
+		return fmt.Errorf("failed to send state to %s: %w", address, err)
+	}
+	return nil
+}
+
+// Listen receives snapshots on address and calls handle for each one. It
+// only returns if the socket can't be opened.
+func Listen(address string, handle func(State)) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for state on %s: %w", address, err)
+	}
+	defer listener.Close()
+
+	log.Printf("Listening for leader state on %s", address)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("Error accepting state connection: %v", err)
+			continue
+		}
+		go receive(conn, handle)
+	}
+}
+
+// receive reads one snapshot from a connection
+func receive(conn net.Conn, handle func(State)) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	var state State
+	if err := json.NewDecoder(conn).Decode(&state); err != nil {
+		log.Printf("WARNING: Ignoring malformed state from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	handle(state)
+}
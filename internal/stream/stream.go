@@ -0,0 +1,143 @@
+// Package stream fans the coordinator's check results and events out to
+// in-process subscribers (alerting, metrics, dashboards, plugins), so a new
+// subsystem subscribes to the bus instead of being wired into the
+// supervisor's loop. Publishing never blocks: a subscriber that falls
+// behind loses messages rather than slowing checks down.
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/events"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/internal/monitor"
+)
+
+// Message kinds
+const (
+	KindCheck = "check" // a health check result
+	KindEvent = "event" // a coordinator event (restarts, recoveries, leadership...)
+)
+
+// DefaultBuffer is how many messages a subscription holds by default before
+// dropping new ones
+const DefaultBuffer = 256
+
+// Message is one item of the stream: Check is set for KindCheck and Event
+// for KindEvent
+type Message struct {
+	Kind   string
+	Target string
+	Check  monitor.CheckResult
+	Event  events.Event
+}
+
+// Bus delivers messages to its subscribers. It implements events.Publisher
+// so it can take events alongside the other publishers.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives a bus's messages of the kinds it subscribed to on C,
+// until it's closed
+type Subscription struct {
+	C <-chan Message
+
+	c       chan Message
+	kinds   map[string]bool // nil means every kind
+	bus     *Bus
+	dropped atomic.Int64
+}
+
+// Subscribe returns a subscription to messages of kinds (every kind if none
+// is given) holding up to buffer undelivered messages; buffer <= 0 means
+// DefaultBuffer. C is closed once the subscription or the bus is closed.
+func (b *Bus) Subscribe(buffer int, kinds ...string) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	c := make(chan Message, buffer)
+	sub := &Subscription{C: c, c: c, bus: b}
+	if len(kinds) > 0 {
+		sub.kinds = make(map[string]bool, len(kinds))
+		for _, kind := range kinds {
+			sub.kinds[kind] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c)
+		return sub
+	}
+	b.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes and closes C
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subscriptions[s]; ok {
+		delete(s.bus.subscriptions, s)
+		close(s.c)
+	}
+}
+
+// Dropped returns how many messages were dropped because C was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// PublishCheck sends a target's check result to the subscribers
+func (b *Bus) PublishCheck(target string, result monitor.CheckResult) {
+	b.send(Message{Kind: KindCheck, Target: target, Check: result})
+}
+
+// Publish implements events.Publisher
+func (b *Bus) Publish(event events.Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	b.send(Message{Kind: KindEvent, Target: event.Target, Event: event})
+}
+
+// Close implements events.Publisher: it closes every subscription
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for sub := range b.subscriptions {
+		close(sub.c)
+	}
+	b.subscriptions = nil
+	return nil
+}
+
+// send delivers a message to every interested subscriber without blocking
+func (b *Bus) send(message Message) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscriptions {
+		if sub.kinds != nil && !sub.kinds[message.Kind] {
+			continue
+		}
+		select {
+		case sub.c <- message:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package supervise keeps the coordinator's internal servers running: a
+// server that fails to bind (its port momentarily busy, say) or stops is
+// started again with exponential backoff instead of taking the whole
+// coordinator down with it.
+package supervise
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+
+	// stableAfter is how long a server must run before a failure starts
+	// the backoff over
+	stableAfter = time.Minute
+)
+
+// Run calls serve until it returns nil, waiting longer after each failure.
+// serve should return nil only when the server was shut down on purpose.
+// It blocks, so it's meant to run on its own goroutine.
+func Run(name string, serve func() error) {
+	backoff := initialBackoff
+	for {
+		started := time.Now()
+		err := serve()
+		if err == nil {
+			return
+		}
+
+		if time.Since(started) >= stableAfter {
+			backoff = initialBackoff
+		}
+		log.Printf("ERROR: %s stopped: %v (restarting in %v)", name, err, backoff)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Transport wraps next so every request made with a traced context gets a
+// span, e.g. the Docker API calls of a recovery
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripper{next: next}
+}
+
+type roundTripper struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if SpanFromContext(req.Context()) == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	_, span := Start(req.Context(), req.Method+" "+req.URL.Path)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.SetError(fmt.Errorf("status %d", resp.StatusCode))
+	}
+	return resp, nil
+}
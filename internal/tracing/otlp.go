@@ -0,0 +1,263 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	flushInterval = 5 * time.Second
+	maxBatchSize  = 256
+	// maxPending drops spans rather than growing without bound while the
+	// collector is unreachable
+	maxPending    = 4096
+	exportTimeout = 10 * time.Second
+)
+
+// OTLP span kinds and status codes
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// spanData is an ended span waiting to be exported
+type spanData struct {
+	name       string
+	context    SpanContext
+	parent     SpanID
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        error
+}
+
+// Exporter batches ended spans and sends them to an OTLP/HTTP collector
+// (JSON encoding) every flushInterval
+type Exporter struct {
+	url         string
+	serviceName string
+	httpClient  *http.Client
+
+	mu      sync.Mutex
+	pending []spanData
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Enable starts exporting spans to the OTLP/HTTP collector at endpoint
+// (e.g. http://otel-collector:4318) under the given service name. The
+// returned exporter must be shut down to flush the last spans.
+func Enable(endpoint, serviceName string) *Exporter {
+	e := &Exporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		httpClient:  &http.Client{Timeout: exportTimeout},
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+
+	exporterMu.Lock()
+	exporter = e
+	exporterMu.Unlock()
+	log.Printf("Exporting traces to %s as %s", e.url, serviceName)
+	return e
+}
+
+// Shutdown stops exporting, flushing the spans not sent yet
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	exporterMu.Lock()
+	if exporter == e {
+		exporter = nil
+	}
+	exporterMu.Unlock()
+
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.flush(ctx)
+}
+
+// add queues an ended span
+func (e *Exporter) add(span spanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pending) >= maxPending {
+		return
+	}
+	e.pending = append(e.pending, span)
+}
+
+// run flushes periodically until stopped
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+			if err := e.flush(ctx); err != nil {
+				log.Printf("WARNING: Failed to export traces: %v", err)
+			}
+			cancel()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// flush sends the pending spans in batches. Spans of a failed batch are
+// dropped: tracing must never hold recovery back.
+func (e *Exporter) flush(ctx context.Context) error {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	for start := 0; start < len(pending); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(pending))
+		if err := e.send(ctx, pending[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send posts one batch of spans
+func (e *Exporter) send(ctx context.Context, spans []spanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", e.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", e.url, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// OTLP/JSON request types (opentelemetry-proto, JSON mapping)
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// request builds the OTLP export request for a batch of spans
+func (e *Exporter) request(spans []spanData) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.context.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.context.SpanID[:]),
+			Name:              span.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		}
+		if span.parent != (SpanID{}) {
+			s.ParentSpanID = hex.EncodeToString(span.parent[:])
+		}
+		for key, value := range span.attributes {
+			s.Attributes = append(s.Attributes, attribute(key, value))
+		}
+		if span.err != nil {
+			s.Status = &otlpStatus{Code: statusCodeError, Message: span.err.Error()}
+		}
+		encoded = append(encoded, s)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{attribute("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "coordinator"}, Spans: encoded}},
+	}}}
+}
+
+// attribute encodes a key/value as an OTLP attribute
+func attribute(key string, value interface{}) otlpAttribute {
+	var v otlpValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		s := strconv.Itoa(value)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
@@ -0,0 +1,185 @@
+// Package tracing records spans of the coordinator's recovery flow (check,
+// decision, restart, verification) and exports them over OTLP, so traces
+// show where recovery time goes. Until Enable is called every span is a
+// no-op: a nil *Span is valid and ignores all calls.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// SpanContext is what's needed to parent a span on another one, even after
+// the parent ended (e.g. to verify a restart minutes later)
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether the context refers to a span
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{}
+}
+
+// Span is a timed operation. Its methods are safe on a nil span.
+type Span struct {
+	name    string
+	context SpanContext
+	parent  SpanID
+	start   time.Time
+
+	mu         sync.Mutex
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+// StartOption customizes a span
+type StartOption func(*startConfig)
+
+type startConfig struct {
+	start  time.Time
+	parent SpanContext
+}
+
+// WithStartTime backdates a span, for operations timed before the span was
+// known to be worth recording
+func WithStartTime(start time.Time) StartOption {
+	return func(c *startConfig) { c.start = start }
+}
+
+// WithParent parents the span on a span context instead of the span in ctx
+func WithParent(parent SpanContext) StartOption {
+	return func(c *startConfig) { c.parent = parent }
+}
+
+type spanKey struct{}
+
+// exporter receives ended spans; nil while tracing is disabled
+var (
+	exporterMu sync.RWMutex
+	exporter   *Exporter
+)
+
+// Start starts a span as a child of the span in ctx (or WithParent) and
+// returns a context carrying it. It returns a nil span when tracing is
+// disabled.
+func Start(ctx context.Context, name string, options ...StartOption) (context.Context, *Span) {
+	if currentExporter() == nil {
+		return ctx, nil
+	}
+
+	config := startConfig{start: time.Now()}
+	if parent := SpanFromContext(ctx); parent != nil {
+		config.parent = parent.context
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
+	span := &Span{name: name, start: config.start, attributes: make(map[string]interface{})}
+	if config.parent.IsValid() {
+		span.context.TraceID = config.parent.TraceID
+		span.parent = config.parent.SpanID
+	} else {
+		rand.Read(span.context.TraceID[:])
+	}
+	rand.Read(span.context.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext returns the span carried by ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Context returns the span's context, to parent later spans on it
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttribute records a key/value on the span. Values are strings, bools,
+// integers or floats; anything else is formatted with %v.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.attributes[key] = value
+	}
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.err = err
+	}
+}
+
+// End ends the span now
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt ends the span at the given time and hands it to the exporter.
+// Only the first call has an effect.
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := spanData{
+		name:       s.name,
+		context:    s.context,
+		parent:     s.parent,
+		start:      s.start,
+		end:        end,
+		attributes: s.attributes,
+		err:        s.err,
+	}
+	s.mu.Unlock()
+
+	if e := currentExporter(); e != nil {
+		e.add(data)
+	}
+}
+
+// String returns the span's trace and span IDs, for logs
+func (s *Span) String() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("trace=%s span=%s", hex.EncodeToString(s.context.TraceID[:]), hex.EncodeToString(s.context.SpanID[:]))
+}
+
+func currentExporter() *Exporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	return exporter
+}
@@ -0,0 +1,96 @@
+// Package vantage carries the followers' health check verdicts to the
+// leader, so it sees every target from several vantage points. Reports are
+// JSON datagrams over UDP: losing one only delays detection by a tick.
+package vantage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	// maxVerdictsPerReport keeps each datagram well under the UDP size limit
+	maxVerdictsPerReport = 100
+	maxDatagramSize      = 65507
+	writeTimeout         = time.Second
+)
+
+// Verdict is a follower's result for one target
+type Verdict struct {
+	Target    string    `json:"target"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// ReceivedAt is when the leader got the verdict, by its own clock. It's
+	// what freshness is judged by, so the follower's clock doesn't matter.
+	ReceivedAt time.Time `json:"-"`
+}
+
+// Report is a batch of verdicts from one coordinator
+type Report struct {
+	From     int       `json:"from"`
+	Verdicts []Verdict `json:"verdicts"`
+
+	// Term is the election term the sender is in, so a leader can tell
+	// reports meant for an earlier one
+	Term uint64 `json:"term"`
+
+	// SentAt is the sender's wall clock when it sent the report, used to
+	// estimate the clock skew between coordinators
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Send delivers the verdicts to the leader at address (host:port), split
+// into as many datagrams as needed
+func Send(address string, from int, term uint64, verdicts []Verdict) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to reach leader at %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	for start := 0; start < len(verdicts); start += maxVerdictsPerReport {
+		end := min(start+maxVerdictsPerReport, len(verdicts))
+		data, err := json.Marshal(Report{From: from, Verdicts: verdicts[start:end], Term: term, SentAt: time.Now()})
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := conn.Write(data); err != nil {
+			return fmt.Errorf("failed to send report to %s: %w", address, err)
+		}
+	}
+	return nil
+}
+
+// Listen receives reports on address and calls handle for each one. It
+// only returns if the socket can't be opened.
+func Listen(address string, handle func(Report)) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for reports on %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	log.Printf("Listening for follower reports on %s", address)
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			log.Printf("Error reading follower report: %v", err)
+			continue
+		}
+
+		var report Report
+		if err := json.Unmarshal(buffer[:n], &report); err != nil {
+			log.Printf("WARNING: Ignoring malformed report from %s: %v", from, err)
+			continue
+		}
+		handle(report)
+	}
+}
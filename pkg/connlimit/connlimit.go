@@ -0,0 +1,185 @@
+// Package connlimit protects the coordinator's TCP listeners (health and
+// election) from misbehaving clients: it bounds how many connections are
+// open at once and how fast each remote IP may open new ones. Connections
+// over a limit are closed as soon as they're accepted, before a goroutine
+// is spawned for them.
+package connlimit
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Limits bound what a listener accepts. The zero value accepts everything.
+type Limits struct {
+	MaxConns   int // Connections open at once; zero means unlimited
+	RatePerIP  int // New connections per second from one IP; zero means unlimited
+	BurstPerIP int // Connections one IP may open at once above its rate; at least RatePerIP
+}
+
+// Enabled reports whether any limit is set
+func (l Limits) Enabled() bool {
+	return l.MaxConns > 0 || l.RatePerIP > 0
+}
+
+const (
+	// rejectLogInterval throttles logging of rejected connections, so an
+	// abusive client can't flood the logs either
+	rejectLogInterval = 10 * time.Second
+
+	// maxTrackedIPs bounds the per-IP buckets; idle ones are pruned first
+	maxTrackedIPs = 4096
+)
+
+// Listener wraps a net.Listener enforcing Limits
+type Listener struct {
+	net.Listener
+	limits Limits
+	name   string
+
+	mu          sync.Mutex
+	open        int
+	buckets     map[string]*bucket
+	rejected    int
+	lastLogged  time.Time
+	lastPruning time.Time
+}
+
+// bucket is a token bucket of connections from one IP
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Wrap returns listener enforcing limits, or listener itself if no limit is
+// set. name identifies the listener in logs.
+func Wrap(listener net.Listener, name string, limits Limits) net.Listener {
+	if !limits.Enabled() {
+		return listener
+	}
+	if limits.BurstPerIP < limits.RatePerIP {
+		limits.BurstPerIP = limits.RatePerIP
+	}
+	return &Listener{Listener: listener, limits: limits, name: name, buckets: make(map[string]*bucket)}
+}
+
+// Accept returns the next connection within the limits, closing the ones
+// over them
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if reason := l.admit(conn.RemoteAddr(), time.Now()); reason != "" {
+			conn.Close()
+			l.reject(conn.RemoteAddr(), reason)
+			continue
+		}
+		return &limitedConn{Conn: conn, release: l.release}, nil
+	}
+}
+
+// admit counts a new connection in, or returns why it's refused
+func (l *Listener) admit(remote net.Addr, now time.Time) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limits.MaxConns > 0 && l.open >= l.limits.MaxConns {
+		return "too many open connections"
+	}
+	if l.limits.RatePerIP > 0 && !l.takeToken(hostOf(remote), now) {
+		return "rate limited"
+	}
+	l.open++
+	return ""
+}
+
+// takeToken takes a token from ip's bucket if there's one. l.mu must be held.
+func (l *Listener) takeToken(ip string, now time.Time) bool {
+	b, ok := l.buckets[ip]
+	if !ok {
+		if len(l.buckets) >= maxTrackedIPs {
+			l.pruneLocked(now)
+		}
+		b = &bucket{tokens: float64(l.limits.BurstPerIP), last: now}
+		l.buckets[ip] = b
+	}
+
+	burst := float64(l.limits.BurstPerIP)
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*float64(l.limits.RatePerIP))
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// pruneLocked forgets IPs whose bucket has refilled, as they're no
+// different from a new IP. If every IP is busy the map is reset rather than
+// growing without bound. l.mu must be held.
+func (l *Listener) pruneLocked(now time.Time) {
+	refill := time.Duration(float64(l.limits.BurstPerIP) / float64(l.limits.RatePerIP) * float64(time.Second))
+	for ip, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, ip)
+		}
+	}
+	if len(l.buckets) >= maxTrackedIPs {
+		l.buckets = make(map[string]*bucket)
+	}
+}
+
+// reject logs refused connections, at most once per rejectLogInterval
+func (l *Listener) reject(remote net.Addr, reason string) {
+	l.mu.Lock()
+	l.rejected++
+	now := time.Now()
+	if now.Sub(l.lastLogged) < rejectLogInterval {
+		l.mu.Unlock()
+		return
+	}
+	rejected := l.rejected
+	l.rejected = 0
+	l.lastLogged = now
+	l.mu.Unlock()
+
+	log.Printf("WARNING: %s refused %d connection(s), last from %s: %s", l.name, rejected, remote, reason)
+}
+
+// release counts a connection out
+func (l *Listener) release() {
+	l.mu.Lock()
+	l.open--
+	l.mu.Unlock()
+}
+
+// limitedConn releases its slot once closed
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+// Close implements net.Conn
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// hostOf returns the IP of a remote address
+func hostOf(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,78 @@
+// Package dialer tunes the TCP connections the coordinator opens: health
+// checks, election messages and heartbeats, state replication, drains and
+// the rest. TCP keepalive makes the kernel probe idle connections, so a peer
+// that crashed without closing its side is noticed within a few probes
+// instead of only when a read times out; the dial timeout bounds connecting;
+// and Nagle's algorithm can be turned back on for links where fewer, fuller
+// packets matter more than latency. The options are set once at startup.
+package dialer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Options tune outbound connections. The zero value keeps Go's defaults
+// and each caller's own dial timeout.
+type Options struct {
+	// Timeout bounds connecting, replacing the caller's own timeout; zero
+	// keeps the caller's
+	Timeout time.Duration
+	// KeepAlive is how long a connection sits idle before the first
+	// keepalive probe, and the time between probes. Zero keeps Go's default
+	// (15s); negative disables keepalive.
+	KeepAlive time.Duration
+	// Nagle turns Nagle's algorithm back on; Go disables it (TCP_NODELAY)
+	// on every connection by default
+	Nagle bool
+}
+
+var (
+	mu      sync.RWMutex
+	current Options
+)
+
+// Configure sets the options of every connection dialed from then on
+func Configure(options Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = options
+}
+
+// Current returns the options in use
+func Current() Options {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// New returns a net.Dialer with the options in use; timeout bounds
+// connecting unless a dial timeout is configured. Connections it dials
+// don't get Nagle turned back on: use DialContext for that.
+func New(timeout time.Duration) *net.Dialer {
+	options := Current()
+	if options.Timeout > 0 {
+		timeout = options.Timeout
+	}
+	return &net.Dialer{Timeout: timeout, KeepAlive: options.KeepAlive}
+}
+
+// DialContext connects to address with the options in use; timeout bounds
+// connecting unless a dial timeout is configured
+func DialContext(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	conn, err := New(timeout).DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok && Current().Nagle {
+		tcp.SetNoDelay(false)
+	}
+	return conn, nil
+}
+
+// Dial is DialContext without a context
+func Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	return DialContext(context.Background(), network, address, timeout)
+}
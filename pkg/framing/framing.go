@@ -0,0 +1,61 @@
+// Package framing is the message codec of the coordinator's TCP protocols
+// (election and health): each message is a line of text terminated by
+// "\n". Reading up to the terminator instead of doing a single Read keeps
+// messages intact when TCP splits one across segments or coalesces two.
+package framing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrTooLong is returned for a message longer than the reader's limit
+var ErrTooLong = errors.New("framing: message too long")
+
+// Reader reads messages from a stream. The embedded bufio.Reader is
+// available for peeking at what hasn't been read yet.
+type Reader struct {
+	*bufio.Reader
+	maxLength int
+}
+
+// NewReader creates a reader for messages of up to maxLength bytes
+func NewReader(r io.Reader, maxLength int) *Reader {
+	return &Reader{
+		Reader:    bufio.NewReaderSize(r, max(maxLength+1, 16)),
+		maxLength: maxLength,
+	}
+}
+
+// ReadMessage reads the next message without its terminator. A last
+// message cut short by EOF is returned as is; io.EOF is only returned when
+// there's nothing left at all.
+func (r *Reader) ReadMessage() (string, error) {
+	line, err := r.ReadSlice('\n')
+	switch {
+	case err == nil:
+	case errors.Is(err, bufio.ErrBufferFull):
+		return "", ErrTooLong
+	case err == io.EOF && len(line) > 0:
+	default:
+		return "", err
+	}
+
+	message := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if len(message) > r.maxLength {
+		return "", ErrTooLong
+	}
+	return message, nil
+}
+
+// WriteMessage writes a message followed by its terminator
+func WriteMessage(w io.Writer, message string) error {
+	if strings.ContainsAny(message, "\r\n") {
+		return fmt.Errorf("framing: message %q contains a line break", message)
+	}
+	_, err := io.WriteString(w, message+"\n")
+	return err
+}
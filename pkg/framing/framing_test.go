@@ -0,0 +1,56 @@
+package framing
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		err   error
+	}{
+		{"one message", "PING/2\n", []string{"PING/2"}, io.EOF},
+		{"several messages", "OK\nLEADER 3 6\n", []string{"OK", "LEADER 3 6"}, io.EOF},
+		{"CRLF terminator", "ELECTION\r\n", []string{"ELECTION"}, io.EOF},
+		{"last message cut by EOF", "OK\nELECTION", []string{"OK", "ELECTION"}, io.EOF},
+		{"empty message", "\nOK\n", []string{"", "OK"}, io.EOF},
+		{"too long", "OK\n" + strings.Repeat("A", 40) + "\n", []string{"OK"}, ErrTooLong},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// One byte per read, as if TCP split every message
+			reader := NewReader(iotest.OneByteReader(strings.NewReader(test.input)), 16)
+			got := []string{}
+			var err error
+			for {
+				var message string
+				if message, err = reader.ReadMessage(); err != nil {
+					break
+				}
+				got = append(got, message)
+			}
+			if strings.Join(got, "|") != strings.Join(test.want, "|") || err != test.err {
+				t.Errorf("read %q, %v; want %q, %v", got, err, test.want, test.err)
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, "LEADER 3 6"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "LEADER 3 6\n" {
+		t.Errorf("wrote %q", buf.String())
+	}
+	if err := WriteMessage(&buf, "OK\nELECTION"); err == nil {
+		t.Error("a message with a line break was written")
+	}
+}
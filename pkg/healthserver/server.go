@@ -0,0 +1,262 @@
+// Package healthserver implements the worker side of the coordinator's health
+// protocol, so workers don't have to hand-roll the PING/PONG listener.
+//
+// The coordinator connects to the health port and sends newline-terminated
+// commands (see pkg/framing), answered in order on the same connection until
+// it's closed or idle for the read timeout; older coordinators send a single
+// bare "PING" or "DRAIN", which is still understood and answered before the
+// connection is closed:
+//
+//	PING   -> PONG (legacy 4-byte exchange)
+//	PING/2 -> PONG/2 (version 2, newline-terminated)
+//	DRAIN  -> DRAINED once the drain hook returns (connection closed if it fails)
+//	STATUS -> a JSON object with the worker's status fields, newline-terminated
+package healthserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/connlimit"
+	"github.com/distribuidos-Coffee-Shop-Analysis/coordinator-service/pkg/framing"
+)
+
+// Protocol messages
+const (
+	MessagePing    = "PING"
+	MessagePong    = "PONG"
+	MessageDrain   = "DRAIN"
+	MessageDrained = "DRAINED"
+	MessageStatus  = "STATUS"
+	MessagePingV2  = "PING/2"
+	MessagePongV2  = "PONG/2\n"
+)
+
+// DefaultPort is the port the coordinator checks unless told otherwise
+const DefaultPort = "12346"
+
+const (
+	readTimeout      = 5 * time.Second
+	maxCommandLength = 16
+
+	// legacyGrace is how long an unterminated legacy command waits for more
+	// bytes before it's taken as complete
+	legacyGrace = 100 * time.Millisecond
+)
+
+// ErrServerClosed is returned by ListenAndServe after Shutdown
+var ErrServerClosed = errors.New("healthserver: server closed")
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithStatus sets the function whose fields are reported to STATUS
+func WithStatus(status func() map[string]interface{}) Option {
+	return func(s *Server) { s.status = status }
+}
+
+// WithDrain sets the hook run on DRAIN. It should stop consuming new work
+// and return once in-flight work is done (or acked back); the coordinator
+// restarts the worker afterwards. The context is cancelled on Shutdown.
+func WithDrain(drain func(ctx context.Context) error) Option {
+	return func(s *Server) { s.drain = drain }
+}
+
+// WithLimits bounds the connections the server accepts (see pkg/connlimit).
+// Every connection also has readTimeout to send its command.
+func WithLimits(limits connlimit.Limits) Option {
+	return func(s *Server) { s.limits = limits }
+}
+
+// Server answers the coordinator's health commands
+type Server struct {
+	address string
+	status  func() map[string]interface{}
+	drain   func(ctx context.Context) error
+	limits  connlimit.Limits
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	conns  sync.WaitGroup
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	closed    bool
+}
+
+// New creates a server listening on address (e.g. ":12346")
+func New(address string, options ...Option) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{address: address, ctx: ctx, cancel: cancel}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ListenAndServe listens on the server's address and answers health commands
+// until Shutdown is called, then returns ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve answers health commands on an existing listener. It can be called
+// for several listeners at once (e.g. localhost and the cluster network);
+// Shutdown closes them all.
+func (s *Server) Serve(listener net.Listener) error {
+	listener = connlimit.Wrap(listener, "Health server", s.limits)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.conns.Add(1)
+		go func() {
+			defer s.conns.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Shutdown stops accepting connections, cancels running drain hooks and
+// waits for in-flight commands until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.mu.Unlock()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isClosed reports whether Shutdown was called
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// handle answers the commands of a connection until the coordinator closes
+// it, stays idle for readTimeout or a command fails. A legacy command is
+// the only one on its connection.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := framing.NewReader(conn, maxCommandLength)
+	for !s.isClosed() {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		command, legacy, err := readCommand(conn, reader)
+		if err != nil || !s.answer(conn, command) || legacy {
+			return
+		}
+	}
+}
+
+// answer answers one command, reporting whether the connection can go on
+func (s *Server) answer(conn net.Conn, command string) bool {
+	var err error
+	switch command {
+	case MessagePingV2:
+		_, err = conn.Write([]byte(MessagePongV2))
+
+	case MessagePing:
+		_, err = conn.Write([]byte(MessagePong))
+
+	case MessageDrain:
+		if s.drain != nil {
+			if err := s.drain(s.ctx); err != nil {
+				log.Printf("healthserver: drain failed: %v", err)
+				return false
+			}
+		}
+		_, err = conn.Write([]byte(MessageDrained))
+
+	case MessageStatus:
+		fields := map[string]interface{}{}
+		if s.status != nil {
+			fields = s.status()
+		}
+		body, encodeErr := json.Marshal(fields)
+		if encodeErr != nil {
+			log.Printf("healthserver: failed to encode status: %v", encodeErr)
+			return false
+		}
+		_, err = conn.Write(append(body, '\n'))
+	}
+	return err == nil
+}
+
+// readCommand reads the next command of a connection and reports whether
+// it's a legacy one. Older coordinators send a bare "PING" or "DRAIN" and
+// wait for the answer, so a legacy command that nothing follows within
+// legacyGrace is taken as complete rather than waiting for a newline that
+// never comes. "PING" is also the start of "PING/2", hence the wait.
+func readCommand(conn net.Conn, reader *framing.Reader) (string, bool, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return "", false, err
+	}
+	for _, legacy := range []string{MessagePing, MessageDrain} {
+		if first[0] != legacy[0] {
+			continue
+		}
+		head, err := reader.Peek(len(legacy))
+		if err != nil || string(head) != legacy || reader.Buffered() > len(legacy) {
+			break
+		}
+
+		conn.SetReadDeadline(time.Now().Add(legacyGrace))
+		_, err = reader.Peek(len(legacy) + 1)
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		var netErr net.Error
+		if (errors.As(err, &netErr) && netErr.Timeout()) || err == io.EOF {
+			reader.Discard(len(legacy))
+			return legacy, true, nil
+		}
+		break
+	}
+	command, err := reader.ReadMessage()
+	return command, false, err
+}
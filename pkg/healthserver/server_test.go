@@ -0,0 +1,126 @@
+package healthserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startServer serves on a free localhost port until the test ends and
+// returns the address
+func startServer(t *testing.T, options ...Option) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := New(listener.Addr().String(), options...)
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+	return listener.Addr().String()
+}
+
+// dial connects to the server, failing the test after readTimeout
+func dial(t *testing.T, address string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(readTimeout))
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// legacyExchange sends a bare command the way older coordinators do and
+// returns everything the server answers before closing the connection
+func legacyExchange(t *testing.T, address, command string) string {
+	t.Helper()
+	conn := dial(t, address)
+	if _, err := io.WriteString(conn, command); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("%s: the server didn't close the connection: %v (answer %q)", command, err, answer)
+	}
+	return string(answer)
+}
+
+func TestLegacyPing(t *testing.T) {
+	address := startServer(t)
+	if answer := legacyExchange(t, address, MessagePing); answer != MessagePong {
+		t.Errorf("PING answered %q, want %q", answer, MessagePong)
+	}
+}
+
+func TestLegacyDrain(t *testing.T) {
+	var drains int32
+	address := startServer(t, WithDrain(func(ctx context.Context) error {
+		atomic.AddInt32(&drains, 1)
+		return nil
+	}))
+	if answer := legacyExchange(t, address, MessageDrain); answer != MessageDrained {
+		t.Errorf("DRAIN answered %q, want %q", answer, MessageDrained)
+	}
+	if n := atomic.LoadInt32(&drains); n != 1 {
+		t.Errorf("the drain hook ran %d times, want once", n)
+	}
+}
+
+func TestPingV2(t *testing.T) {
+	conn := dial(t, startServer(t))
+	if _, err := io.WriteString(conn, MessagePingV2+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != MessagePongV2 {
+		t.Errorf("PING/2 answered %q, want %q", answer, MessagePongV2)
+	}
+}
+
+func TestFramedCommandsShareAConnection(t *testing.T) {
+	address := startServer(t, WithStatus(func() map[string]interface{} {
+		return map[string]interface{}{"queue": "orders"}
+	}))
+	conn := dial(t, address)
+	if _, err := io.WriteString(conn, "PING/2\nSTATUS\nPING/2\n"); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	answers := make([]string, 3)
+	for i := range answers {
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("answer %d: %v (answers so far %q)", i+1, err, answers[:i])
+		}
+		answers[i] = answer
+	}
+	if answers[0] != MessagePongV2 || answers[2] != MessagePongV2 {
+		t.Errorf("PING/2 answered %q and %q, want %q", answers[0], answers[2], MessagePongV2)
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal([]byte(answers[1]), &status); err != nil || status["queue"] != "orders" {
+		t.Errorf("STATUS answered %q, want the status fields", answers[1])
+	}
+
+	// The connection is still open for more
+	if _, err := io.WriteString(conn, MessagePingV2+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	if answer, err := reader.ReadString('\n'); err != nil || answer != MessagePongV2 {
+		t.Errorf("a later PING/2 answered %q, %v", answer, err)
+	}
+}